@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/distr-sh/distr/internal/types"
 	"github.com/google/uuid"
 )
@@ -10,6 +12,28 @@ type AgentResource struct {
 	Namespace      string             `json:"namespace,omitempty"`
 	MetricsEnabled bool               `json:"metricsEnabled"`
 	Deployments    []AgentDeployment  `json:"deployments,omitempty"`
+	// PrunePolicy is set if the Docker agent should clean up images and containers after upgrades.
+	PrunePolicy *types.DockerPrunePolicy `json:"prunePolicy,omitempty"`
+	// VolumeBackupPolicy is set if the Docker agent should snapshot the volumes of deployments before upgrades.
+	VolumeBackupPolicy *types.DockerVolumeBackupPolicy `json:"volumeBackupPolicy,omitempty"`
+	// AccessKeyRotation is set if the agent should replace its target secret.
+	AccessKeyRotation *AgentAccessKeyRotation `json:"accessKeyRotation,omitempty"`
+	// ClientCertificate is set if the agent should replace its client certificate.
+	ClientCertificate *AgentClientCertificate `json:"clientCertificate,omitempty"`
+	// BundleKey is the base64 encoded key that deployment bundles for this agent are signed and encrypted with.
+	BundleKey string `json:"bundleKey,omitempty"`
+}
+
+// AgentAccessKeyRotation contains a new target secret for the agent.
+// The agent confirms the rotation by logging in with the new secret.
+type AgentAccessKeyRotation struct {
+	TargetSecret string `json:"targetSecret"`
+}
+
+// AgentClientCertificate contains a base64 encoded PEM client certificate and private key for mTLS authentication.
+type AgentClientCertificate struct {
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
 }
 
 type AgentRegistryAuth struct {
@@ -23,27 +47,46 @@ type AgentDeployment struct {
 	RegistryAuth map[string]AgentRegistryAuth `json:"registryAuth"`
 	LogsEnabled  bool                         `json:"logsEnabled"`
 	ForceRestart bool                         `json:"forceRestart"`
+	// PrePullImages is set if all images of the revision should be pulled before any service is updated.
+	PrePullImages bool `json:"prePullImages"`
+	// SignatureRequired is set if images and charts from the Distr registry must have a signature that can be
+	// verified with one of the TrustedSigningKeys (PEM encoded public keys or certificates) before they are deployed.
+	SignatureRequired  bool     `json:"signatureRequired,omitempty"`
+	TrustedSigningKeys []string `json:"trustedSigningKeys,omitempty"`
 
 	// Docker specific data
 
 	ComposeFile []byte            `json:"composeFile"`
 	EnvFile     []byte            `json:"envFile"`
 	DockerType  *types.DockerType `json:"dockerType"`
+	// RestoreVolumeSnapshotID is set if the volumes of the deployment should be restored from the given snapshot
+	// before the revision is applied.
+	RestoreVolumeSnapshotID *uuid.UUID `json:"restoreVolumeSnapshotId,omitempty"`
 
 	// Kubernetes specific data
 
 	ReleaseName        string         `json:"releaseName"`
+	Namespace          string         `json:"namespace,omitempty"`
 	ChartUrl           string         `json:"chartUrl"`
 	ChartName          string         `json:"chartName"`
 	ChartVersion       string         `json:"chartVersion"`
 	Values             map[string]any `json:"values"`
 	IgnoreRevisionSkew bool           `json:"ignoreRevisionSkew"`
+	RunHelmTests       bool           `json:"runHelmTests"`
+
+	// KubernetesType is nil for Helm deployments.
+	// For other types, ManifestFile contains the manifests or kustomization archive of the application version and
+	// Values is used as a kustomization overlay.
+	KubernetesType *types.KubernetesType `json:"kubernetesType,omitempty"`
+	ManifestFile   []byte                `json:"manifestFile,omitempty"`
 }
 
 type AgentDeploymentStatus struct {
 	RevisionID uuid.UUID                  `json:"revisionId"`
 	Type       types.DeploymentStatusType `json:"type"`
 	Message    string                     `json:"message"`
+	// CreatedAt is set if the status was queued by the agent while the hub was unreachable
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
 }
 
 type AgentDeploymentTargetMetrics struct {
@@ -51,4 +94,47 @@ type AgentDeploymentTargetMetrics struct {
 	CPUUsage       float64 `json:"cpuUsage" db:"cpu_usage"`
 	MemoryBytes    int64   `json:"memoryBytes" db:"memory_bytes"`
 	MemoryUsage    float64 `json:"memoryUsage" db:"memory_usage"`
+	// CreatedAt is set if the metrics were queued by the agent while the hub was unreachable
+	CreatedAt *time.Time `json:"createdAt,omitempty" db:"-"`
+	// Deployments contains the resource usage of the containers of each deployment
+	Deployments []AgentDeploymentMetrics `json:"deployments,omitempty" db:"-"`
+	// Disks contains the usage of the filesystems that are used by deployments
+	Disks []AgentDiskMetrics `json:"disks,omitempty" db:"disks"`
+}
+
+// DisksAboveThreshold returns all disks whose usage is greater than or equal to the given threshold.
+func (m AgentDeploymentTargetMetrics) DisksAboveThreshold(threshold float64) []AgentDiskMetrics {
+	var result []AgentDiskMetrics
+	for _, disk := range m.Disks {
+		if disk.Usage() >= threshold {
+			result = append(result, disk)
+		}
+	}
+	return result
+}
+
+type AgentDiskType string
+
+const (
+	AgentDiskTypeDockerRoot            AgentDiskType = "dockerRoot"
+	AgentDiskTypeVolume                AgentDiskType = "volume"
+	AgentDiskTypePersistentVolumeClaim AgentDiskType = "persistentVolumeClaim"
+)
+
+type AgentDiskMetrics struct {
+	Type AgentDiskType `json:"type"`
+	// Name is the name of the Docker volume or "<namespace>/<name>" of the PersistentVolumeClaim.
+	// It is empty for the Docker root directory.
+	Name string `json:"name,omitempty"`
+	// TotalBytes is 0 if the disk has no size of its own, like Docker volumes.
+	TotalBytes int64 `json:"totalBytes"`
+	UsedBytes  int64 `json:"usedBytes"`
+}
+
+// Usage returns the fraction of the disk that is used, or 0 if the size of the disk is unknown.
+func (d AgentDiskMetrics) Usage() float64 {
+	if d.TotalBytes <= 0 {
+		return 0
+	}
+	return float64(d.UsedBytes) / float64(d.TotalBytes)
 }
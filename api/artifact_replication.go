@@ -0,0 +1,16 @@
+package api
+
+import "github.com/google/uuid"
+
+type CreateArtifactReplicationTargetRequest struct {
+	CustomerOrganizationID uuid.UUID  `json:"customerOrganizationId"`
+	RegistryURL            string     `json:"registryUrl"`
+	Username               *string    `json:"username,omitempty"`
+	PasswordSecretID       *uuid.UUID `json:"passwordSecretId,omitempty"`
+}
+
+type UpdateArtifactReplicationTargetRequest struct {
+	RegistryURL      string     `json:"registryUrl"`
+	Username         *string    `json:"username,omitempty"`
+	PasswordSecretID *uuid.UUID `json:"passwordSecretId,omitempty"`
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeploymentEventRecord is a Kubernetes event concerning a resource that belongs to a deployment.
+//
+// UID is the UID of the Kubernetes event. Events that are reported repeatedly are identified by their UID and
+// update the Count and LastTimestamp of the existing record.
+type DeploymentEventRecord struct {
+	DeploymentID         uuid.UUID `json:"deploymentId"`
+	DeploymentRevisionID uuid.UUID `json:"deploymentRevisionId"`
+	UID                  string    `json:"uid"`
+	Resource             string    `json:"resource"`
+	Type                 string    `json:"type"`
+	Reason               string    `json:"reason"`
+	Message              string    `json:"message"`
+	Count                int32     `json:"count"`
+	FirstTimestamp       time.Time `json:"firstTimestamp"`
+	LastTimestamp        time.Time `json:"lastTimestamp"`
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentDeploymentMetrics contains the resource usage of all containers of a deployment.
+type AgentDeploymentMetrics struct {
+	DeploymentID         uuid.UUID               `json:"deploymentId"`
+	DeploymentRevisionID uuid.UUID               `json:"deploymentRevisionId"`
+	Containers           []AgentContainerMetrics `json:"containers"`
+}
+
+// AgentContainerMetrics contains the resource usage of a Docker container or a container of a Kubernetes pod.
+type AgentContainerMetrics struct {
+	// Name is the name of the Docker container or "<pod>/<container>" for Kubernetes deployments
+	Name             string `json:"name"`
+	CPUUsageMillis   int64  `json:"cpuUsageMillis"`
+	MemoryBytes      int64  `json:"memoryBytes"`
+	MemoryLimitBytes *int64 `json:"memoryLimitBytes,omitempty"`
+	RestartCount     int32  `json:"restartCount"`
+	// Disk and network I/O are the totals since the container was started and only reported by the Docker agent
+	DiskReadBytes  *int64 `json:"diskReadBytes,omitempty"`
+	DiskWriteBytes *int64 `json:"diskWriteBytes,omitempty"`
+	NetworkRxBytes *int64 `json:"networkRxBytes,omitempty"`
+	NetworkTxBytes *int64 `json:"networkTxBytes,omitempty"`
+}
+
+type DeploymentContainerMetrics struct {
+	CreatedAt            time.Time `json:"createdAt"`
+	DeploymentRevisionID uuid.UUID `json:"deploymentRevisionId"`
+	AgentContainerMetrics
+}
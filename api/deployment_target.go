@@ -18,12 +18,15 @@ type DeploymentRequest struct {
 	ApplicationVersionID uuid.UUID         `json:"applicationVersionId"`
 	ApplicationLicenseID *uuid.UUID        `json:"applicationLicenseId"`
 	ReleaseName          *string           `json:"releaseName"`
+	Namespace            *string           `json:"namespace"`
 	ValuesYaml           []byte            `json:"valuesYaml"`
 	DockerType           *types.DockerType `json:"dockerType"`
 	EnvFileData          []byte            `json:"envFileData"`
 	LogsEnabled          bool              `json:"logsEnabled"`
 	ForceRestart         bool              `json:"forceRestart"`
 	IgnoreRevisionSkew   bool              `json:"ignoreRevisionSkew"`
+	RunHelmTests         bool              `json:"runHelmTests"`
+	PrePullImages        bool              `json:"prePullImages"`
 }
 
 func (d *DeploymentRequest) GetValuesYAML() []byte {
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeploymentVolumeSnapshot describes a snapshot of the named volumes of a Docker Compose deployment that was created
+// by the Docker agent before a new revision was applied.
+type DeploymentVolumeSnapshot struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// DeploymentID and DeploymentRevisionID refer to the revision that was running when the snapshot was created
+	DeploymentID         uuid.UUID `json:"deploymentId"`
+	DeploymentRevisionID uuid.UUID `json:"deploymentRevisionId"`
+	Volumes              []string  `json:"volumes"`
+	SizeBytes            int64     `json:"sizeBytes"`
+}
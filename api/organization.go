@@ -5,12 +5,13 @@ import (
 )
 
 type CreateUpdateOrganizationRequest struct {
-	Name                   string  `json:"name"`
-	Slug                   *string `json:"slug"`
-	PreConnectScript       *string `json:"preConnectScript"`
-	PostConnectScript      *string `json:"postConnectScript"`
-	ConnectScriptIsSudo    bool    `json:"connectScriptIsSudo"`
-	ArtifactVersionMutable bool    `json:"artifactVersionMutable"`
+	Name                      string  `json:"name"`
+	Slug                      *string `json:"slug"`
+	PreConnectScript          *string `json:"preConnectScript"`
+	PostConnectScript         *string `json:"postConnectScript"`
+	ConnectScriptIsSudo       bool    `json:"connectScriptIsSudo"`
+	ArtifactVersionMutable    bool    `json:"artifactVersionMutable"`
+	ArtifactSignatureRequired bool    `json:"artifactSignatureRequired"`
 }
 
 type OrganizationResponse struct {
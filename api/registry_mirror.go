@@ -0,0 +1,8 @@
+package api
+
+type CreateRegistryMirrorRequest struct {
+	Prefix             string  `json:"prefix"`
+	UpstreamRepository string  `json:"upstreamRepository"`
+	Username           *string `json:"username,omitempty"`
+	Password           *string `json:"password,omitempty"`
+}
@@ -0,0 +1,5 @@
+package api
+
+type CreateUpdateRegistryWebhookRequest struct {
+	URL string `json:"url"`
+}
@@ -10,6 +10,9 @@ import (
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/docker/cli/cli/compose/convert"
+	composeapi "github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +22,9 @@ type AgentDeployment struct {
 	ProjectName string           `json:"projectName"`
 	DockerType  types.DockerType `json:"docker_type,omitempty"`
 	LogsEnabled bool             `json:"logsEnabled"`
+	// ImageHistory contains the images that were used by each revision of the deployment, newest first.
+	// It is used to remove the images of old revisions according to the prune policy.
+	ImageHistory [][]string `json:"imageHistory,omitempty"`
 }
 
 func (d AgentDeployment) GetDeploymentID() uuid.UUID {
@@ -29,6 +35,15 @@ func (d AgentDeployment) GetDeploymentRevisionID() uuid.UUID {
 	return d.RevisionID
 }
 
+// ContainerFilter returns a filter that matches all containers of the deployment.
+func (d AgentDeployment) ContainerFilter() filters.Args {
+	if d.DockerType == types.DockerTypeSwarm {
+		return filters.NewArgs(filters.Arg("label", convert.LabelNamespace+"="+d.ProjectName))
+	} else {
+		return filters.NewArgs(filters.Arg("label", composeapi.ProjectLabel+"="+d.ProjectName))
+	}
+}
+
 func (d *AgentDeployment) FileName() string {
 	return path.Join(agentDeploymentDir(), d.ID.String())
 }
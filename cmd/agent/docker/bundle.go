@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/distr-sh/distr/internal/agentbundle"
+	"github.com/distr-sh/distr/internal/types"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// runApplyBundle installs all deployments contained in the bundle at the given path without contacting the hub.
+// Status updates are added to the outbox and sent once the agent is online again.
+func runApplyBundle(ctx context.Context, name string) error {
+	client.SetOffline(true)
+
+	bundle, err := agentbundle.Open(name, bundleKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := bundle.Close(); err != nil {
+			logger.Warn("could not clean up bundle", zap.Error(err))
+		}
+	}()
+
+	logger.Info("applying bundle",
+		zap.Time("createdAt", bundle.Metadata.CreatedAt),
+		zap.Stringer("deploymentId", bundle.Metadata.DeploymentID),
+		zap.Stringer("revisionId", bundle.Metadata.RevisionID))
+
+	if err := loadBundleImages(ctx, bundle); err != nil {
+		return err
+	}
+
+	for _, deployment := range bundle.Resource.Deployments {
+		if deployment.DockerType == nil {
+			return errors.New("cannot apply deployment because docker type is nil")
+		}
+
+		agentDeployment, status, err := DockerEngineApply(ctx, deployment)
+		if err == nil {
+			multierr.AppendInto(&err, SaveDeployment(*agentDeployment))
+		}
+
+		if err != nil {
+			multierr.AppendInto(&err, client.StatusWithError(ctx, deployment.RevisionID, err))
+			return err
+		} else if err := client.Status(
+			ctx, deployment.RevisionID, types.DeploymentStatusTypeProgressing, status,
+		); err != nil {
+			logger.Warn("could not queue status", zap.Error(err))
+		}
+
+		logger.Info("deployment applied", zap.Stringer("deploymentId", deployment.ID))
+	}
+
+	return nil
+}
+
+// bundleKey returns the bundle key that the hub issued for this agent.
+func bundleKey(metadata agentbundle.Metadata) ([]byte, error) {
+	if targetID := os.Getenv("DISTR_TARGET_ID"); targetID != metadata.DeploymentTargetID.String() {
+		return nil, fmt.Errorf("bundle was created for deployment target %v", metadata.DeploymentTargetID)
+	} else if key := os.Getenv("DISTR_BUNDLE_KEY"); key == "" {
+		return nil, errors.New("DISTR_BUNDLE_KEY is not set, please connect the agent once or reinstall it")
+	} else {
+		return base64.StdEncoding.DecodeString(key)
+	}
+}
+
+// loadBundleImages loads all images of the bundle into the docker engine.
+func loadBundleImages(ctx context.Context, bundle *agentbundle.Bundle) error {
+	images := bundle.Metadata.Images
+	if bundle.Metadata.AgentImage != "" {
+		images = append(images, bundle.Metadata.AgentImage)
+	}
+	if len(images) == 0 {
+		logger.Info("bundle does not contain any images")
+		return nil
+	}
+
+	logger.Info("loading images", zap.Strings("images", images))
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(agentbundle.WriteTar(pw, bundle.OCILayoutDir()))
+	}()
+
+	cmd := exec.CommandContext(ctx, "docker", "load")
+	cmd.Stdin = pr
+	out, err := cmd.CombinedOutput()
+	logger.Debug("docker load returned", zap.String("output", string(out)), zap.Error(err))
+	if err != nil {
+		return fmt.Errorf("docker load failed: %w: %v", err, string(out))
+	}
+	return nil
+}
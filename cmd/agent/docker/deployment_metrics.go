@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/docker/docker/api/types/container"
+	"go.uber.org/zap"
+)
+
+// collectDeploymentMetrics returns the resource usage of the containers of all deployments that are running on this
+// host. Errors are logged and only cause the affected deployment or container to be skipped.
+func collectDeploymentMetrics(ctx context.Context) []api.AgentDeploymentMetrics {
+	deployments, err := GetExistingDeployments()
+	if err != nil {
+		logger.Warn("could not get deployments for metrics", zap.Error(err))
+		return nil
+	}
+
+	var result []api.AgentDeploymentMetrics
+	for _, d := range deployments {
+		containers, err := dockerCli.Client().ContainerList(ctx, container.ListOptions{Filters: d.ContainerFilter()})
+		if err != nil {
+			logger.Warn("could not list containers for metrics", zap.Any("deploymentId", d.ID), zap.Error(err))
+			continue
+		}
+		metrics := api.AgentDeploymentMetrics{DeploymentID: d.ID, DeploymentRevisionID: d.RevisionID}
+		for _, c := range containers {
+			if containerMetrics, err := getContainerMetrics(ctx, c); err != nil {
+				logger.Warn("could not get container metrics", zap.String("container", c.ID), zap.Error(err))
+			} else {
+				metrics.Containers = append(metrics.Containers, *containerMetrics)
+			}
+		}
+		if len(metrics.Containers) > 0 {
+			result = append(result, metrics)
+		}
+	}
+	return result
+}
+
+func getContainerMetrics(ctx context.Context, c container.Summary) (*api.AgentContainerMetrics, error) {
+	inspect, err := dockerCli.Client().ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	// stats are requested without streaming, so that the response contains the previous CPU usage
+	response, err := dockerCli.Client().ContainerStats(ctx, c.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var stats container.StatsResponse
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+
+	metrics := api.AgentContainerMetrics{
+		Name:           strings.TrimPrefix(inspect.Name, "/"),
+		CPUUsageMillis: calculateCPUUsageMillis(stats),
+		MemoryBytes:    int64(calculateMemoryUsage(stats.MemoryStats)),
+		RestartCount:   int32(inspect.RestartCount),
+	}
+	if stats.MemoryStats.Limit > 0 {
+		limit := int64(stats.MemoryStats.Limit)
+		metrics.MemoryLimitBytes = &limit
+	}
+
+	var diskRead, diskWrite, networkRx, networkTx int64
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			diskRead += int64(entry.Value)
+		case "write":
+			diskWrite += int64(entry.Value)
+		}
+	}
+	for _, network := range stats.Networks {
+		networkRx += int64(network.RxBytes)
+		networkTx += int64(network.TxBytes)
+	}
+	metrics.DiskReadBytes = &diskRead
+	metrics.DiskWriteBytes = &diskWrite
+	metrics.NetworkRxBytes = &networkRx
+	metrics.NetworkTxBytes = &networkTx
+	return &metrics, nil
+}
+
+// calculateCPUUsageMillis returns the CPU usage in millicores, the same way it is calculated by "docker stats".
+func calculateCPUUsageMillis(stats container.StatsResponse) int64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+	return int64(cpuDelta / systemDelta * onlineCPUs * 1000)
+}
+
+// calculateMemoryUsage returns the memory usage without the page cache, the same way it is calculated by
+// "docker stats".
+func calculateMemoryUsage(stats container.MemoryStats) uint64 {
+	// cgroup v1 reports total_inactive_file, cgroup v2 reports inactive_file
+	if v, ok := stats.Stats["total_inactive_file"]; ok && v < stats.Usage {
+		return stats.Usage - v
+	} else if v, ok := stats.Stats["inactive_file"]; ok && v < stats.Usage {
+		return stats.Usage - v
+	}
+	return stats.Usage
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"go.uber.org/zap"
+)
+
+// diskMetricsInterval limits how often disk metrics are collected, because calculating the size of volumes requires
+// the Docker daemon to walk through all files.
+const diskMetricsInterval = 5 * time.Minute
+
+var diskMetricsCache = struct {
+	sync.Mutex
+	collectedAt time.Time
+	disks       []api.AgentDiskMetrics
+}{}
+
+// collectDiskMetrics returns the usage of the filesystem of the Docker root directory and the size of all named
+// volumes. Volumes are stored in the Docker root directory and have no size of their own, so their total size is
+// not reported. Results are cached for [diskMetricsInterval].
+func collectDiskMetrics(ctx context.Context) []api.AgentDiskMetrics {
+	diskMetricsCache.Lock()
+	defer diskMetricsCache.Unlock()
+	if time.Since(diskMetricsCache.collectedAt) < diskMetricsInterval {
+		return diskMetricsCache.disks
+	}
+
+	// The root filesystem of the agent container is an overlay that is stored in the Docker root directory,
+	// so its usage is the usage of the filesystem that contains images, containers and volumes.
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs("/", &stat); err != nil {
+		logger.Warn("could not get filesystem usage", zap.Error(err))
+		return nil
+	}
+	disks := []api.AgentDiskMetrics{{
+		Type:       api.AgentDiskTypeDockerRoot,
+		TotalBytes: int64(stat.Blocks) * int64(stat.Bsize),
+		UsedBytes:  int64(stat.Blocks-stat.Bfree) * int64(stat.Bsize),
+	}}
+
+	if usage, err := dockerCli.Client().DiskUsage(ctx, dockertypes.DiskUsageOptions{
+		Types: []dockertypes.DiskUsageObject{dockertypes.VolumeObject},
+	}); err != nil {
+		logger.Warn("could not get volume disk usage", zap.Error(err))
+	} else {
+		for _, volume := range usage.Volumes {
+			if volume.Driver != "local" || volume.UsageData == nil || volume.UsageData.Size < 0 {
+				continue
+			}
+			disks = append(disks, api.AgentDiskMetrics{
+				Type:      api.AgentDiskTypeVolume,
+				Name:      volume.Name,
+				UsedBytes: volume.UsageData.Size,
+			})
+		}
+	}
+
+	diskMetricsCache.collectedAt = time.Now()
+	diskMetricsCache.disks = disks
+	return disks
+}
@@ -32,28 +32,16 @@ func ApplyComposeFile(ctx context.Context, deployment api.AgentDeployment) (*Age
 		return nil, "", err
 	}
 
-	var envFile *os.File
-	if deployment.EnvFile != nil {
-		if envFile, err = os.CreateTemp("", "distr-env"); err != nil {
-			logger.Error("", zap.Error(err))
-			return nil, "", fmt.Errorf("failed to create env file in tmp directory: %w", err)
-		} else {
-			if _, err = envFile.Write(deployment.EnvFile); err != nil {
-				logger.Error("", zap.Error(err))
-				return nil, "", fmt.Errorf("failed to write env file: %w", err)
-			}
-			_ = envFile.Close()
-			defer func() {
-				if err := os.Remove(envFile.Name()); err != nil {
-					logger.Error("failed to remove env file from tmp directory", zap.Error(err))
-				}
-			}()
-		}
+	envFile, err := createEnvFile(deployment)
+	if err != nil {
+		return nil, "", err
+	} else if envFile != "" {
+		defer removeEnvFile(envFile)
 	}
 
 	composeArgs := []string{"compose"}
-	if envFile != nil {
-		composeArgs = append(composeArgs, fmt.Sprintf("--env-file=%v", envFile.Name()))
+	if envFile != "" {
+		composeArgs = append(composeArgs, fmt.Sprintf("--env-file=%v", envFile))
 	}
 	composeArgs = append(composeArgs, "-f", "-", "up", "-d", "--quiet-pull")
 
@@ -73,6 +61,32 @@ func ApplyComposeFile(ctx context.Context, deployment api.AgentDeployment) (*Age
 	}
 }
 
+// createEnvFile writes the env file of the deployment to a temporary file and returns its name.
+// It returns an empty string if the deployment has no env file.
+func createEnvFile(deployment api.AgentDeployment) (string, error) {
+	if deployment.EnvFile == nil {
+		return "", nil
+	}
+	envFile, err := os.CreateTemp("", "distr-env")
+	if err != nil {
+		logger.Error("", zap.Error(err))
+		return "", fmt.Errorf("failed to create env file in tmp directory: %w", err)
+	}
+	defer envFile.Close()
+	if _, err = envFile.Write(deployment.EnvFile); err != nil {
+		logger.Error("", zap.Error(err))
+		removeEnvFile(envFile.Name())
+		return "", fmt.Errorf("failed to write env file: %w", err)
+	}
+	return envFile.Name(), nil
+}
+
+func removeEnvFile(name string) {
+	if err := os.Remove(name); err != nil {
+		logger.Error("failed to remove env file from tmp directory", zap.Error(err))
+	}
+}
+
 func ApplyComposeFileSwarm(ctx context.Context, deployment api.AgentDeployment) (*AgentDeployment, string, error) {
 	// Step 1 Ensure Docker Swarm is initialized
 	initCmd := exec.CommandContext(ctx, "docker", "info", "--format", "{{.Swarm.LocalNodeState}}")
@@ -154,6 +168,16 @@ func UninstallDockerCompose(ctx context.Context, deployment AgentDeployment) err
 	return nil
 }
 
+// RunDockerComposeDown removes the containers and networks of the project but keeps its volumes.
+func RunDockerComposeDown(ctx context.Context, projectName string) error {
+	cmd := exec.CommandContext(ctx, "docker", "compose", "--project-name", projectName, "down")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %v", err, string(out))
+	}
+	return nil
+}
+
 func UninstallDockerSwarm(ctx context.Context, deployment AgentDeployment) error {
 	cmd := exec.CommandContext(ctx, "docker", "stack", "rm", deployment.ProjectName)
 	out, err := cmd.CombinedOutput()
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentauth"
+	"github.com/distr-sh/distr/internal/agentbundle"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distribution/reference"
+	"github.com/docker/cli/cli/command"
+	dockerconfig "github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// progressMessage holds the message that is sent with progress status updates while a deployment is being applied.
+type progressMessage struct {
+	mut     sync.RWMutex
+	message string
+}
+
+func newProgressMessage(message string) *progressMessage {
+	return &progressMessage{message: message}
+}
+
+func (p *progressMessage) Set(message string) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.message = message
+}
+
+func (p *progressMessage) Get() string {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.message
+}
+
+// PullImages pulls all images of the given deployment before any service is updated, so that the time during which
+// services are unavailable is as short as possible. The progress of all pulls is reported as a percentage.
+func PullImages(ctx context.Context, deployment api.AgentDeployment, progress *progressMessage) error {
+	if *deployment.DockerType == types.DockerTypeSwarm {
+		// images of swarm services are pulled by the nodes the tasks are scheduled on
+		logger.Info("skipping image pre-pull for docker swarm deployment")
+		return nil
+	}
+
+	images, err := getComposeImages(ctx, deployment)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadDockerConfig(deployment)
+	if err != nil {
+		return fmt.Errorf("failed to load docker config: %w", err)
+	}
+
+	logger.Info("pulling images", zap.Strings("images", images))
+	for i, img := range images {
+		if err := pullImage(ctx, cfg, img, func(percent int) {
+			total := (i*100 + percent) / len(images)
+			progress.Set(fmt.Sprintf("pulling images: %v%% (%v of %v)", total, i+1, len(images)))
+		}); err != nil {
+			return fmt.Errorf("failed to pull image %v: %w", img, err)
+		}
+	}
+	progress.Set("applying docker compose…")
+	return nil
+}
+
+// getComposeImages returns the images of all services of the compose file, with all variables substituted.
+func getComposeImages(ctx context.Context, deployment api.AgentDeployment) ([]string, error) {
+	envFile, err := createEnvFile(deployment)
+	if err != nil {
+		return nil, err
+	} else if envFile != "" {
+		defer removeEnvFile(envFile)
+	}
+
+	composeArgs := []string{"compose"}
+	if envFile != "" {
+		composeArgs = append(composeArgs, fmt.Sprintf("--env-file=%v", envFile))
+	}
+	composeArgs = append(composeArgs, "-f", "-", "config", "--images")
+
+	cmd := exec.CommandContext(ctx, "docker", composeArgs...)
+	cmd.Stdin = bytes.NewReader(deployment.ComposeFile)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get images of compose file: %w: %v", err, stderr.String())
+	}
+
+	var images []string
+	for line := range strings.Lines(string(out)) {
+		if img := strings.TrimSpace(line); img != "" {
+			images = append(images, img)
+		}
+	}
+	return images, nil
+}
+
+// loadDockerConfig loads the docker config that contains the registry credentials of the deployment.
+func loadDockerConfig(deployment api.AgentDeployment) (*configfile.ConfigFile, error) {
+	if len(DockerConfigEnv(deployment)) > 0 {
+		return dockerconfig.Load(agentauth.DockerConfigDir(deployment))
+	}
+	return dockerconfig.LoadDefaultConfigFile(os.Stderr), nil
+}
+
+// pullImage pulls a single image and calls onProgress with the percentage of bytes that were downloaded so far.
+func pullImage(ctx context.Context, cfg *configfile.ConfigFile, img string, onProgress func(percent int)) error {
+	if agentenv.NetworkSettingsConfigured {
+		return pullImageWithAgent(ctx, cfg, img, onProgress)
+	}
+
+	registryAuth, err := command.RetrieveAuthTokenFromImage(cfg, img)
+	if err != nil {
+		return err
+	}
+
+	rc, err := dockerCli.Client().ImagePull(ctx, img, image.PullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	type layerProgress struct{ current, total int64 }
+	layers := map[string]layerProgress{}
+	decoder := json.NewDecoder(rc)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		} else if msg.Error != nil {
+			return msg.Error
+		}
+
+		if msg.ID == "" {
+			continue
+		}
+		switch {
+		case msg.Status == "Downloading" && msg.Progress != nil && msg.Progress.Total > 0:
+			layers[msg.ID] = layerProgress{current: msg.Progress.Current, total: msg.Progress.Total}
+		case msg.Status == "Download complete" || msg.Status == "Pull complete" || msg.Status == "Already exists":
+			if layer, ok := layers[msg.ID]; ok {
+				layers[msg.ID] = layerProgress{current: layer.total, total: layer.total}
+			}
+		default:
+			continue
+		}
+
+		var current, total int64
+		for _, layer := range layers {
+			current += layer.current
+			total += layer.total
+		}
+		if total > 0 {
+			onProgress(int(current * 100 / total))
+		}
+	}
+	onProgress(100)
+	return nil
+}
+
+// pullImageWithAgent pulls a single image with the HTTP client of the agent, so that its proxy and CA certificate
+// settings are used, and loads it into the docker engine.
+func pullImageWithAgent(
+	ctx context.Context,
+	cfg *configfile.ConfigFile,
+	img string,
+	onProgress func(percent int),
+) error {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return err
+	}
+	named = reference.TagNameOnly(named)
+
+	domain := reference.Domain(named)
+	host, authKey := domain, domain
+	if domain == "docker.io" {
+		host, authKey = "registry-1.docker.io", "https://index.docker.io/v1/"
+	}
+	authConfig, err := cfg.GetAuthConfig(authKey)
+	if err != nil {
+		return err
+	}
+	repo, err := remote.NewRepository(host + "/" + reference.Path(named))
+	if err != nil {
+		return err
+	}
+	repo.PlainHTTP = domain == agentenv.DistrRegistryHost && agentenv.DistrRegistryPlainHTTP
+	repo.Client = &auth.Client{
+		Client: retry.DefaultClient,
+		Cache:  auth.NewCache(),
+		Credential: auth.StaticCredential(host, auth.Credential{
+			Username:     authConfig.Username,
+			Password:     authConfig.Password,
+			RefreshToken: authConfig.IdentityToken,
+			AccessToken:  authConfig.RegistryToken,
+		}),
+	}
+
+	var src string
+	if digested, ok := named.(reference.Digested); ok {
+		src = digested.Digest().String()
+	} else {
+		src = named.(reference.Tagged).Tag()
+	}
+
+	dir, err := os.MkdirTemp("", "distr-image-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	store, err := oci.New(dir)
+	if err != nil {
+		return err
+	}
+	opts := oras.DefaultCopyOptions
+	opts.WithTargetPlatform(&ocispec.Platform{OS: "linux", Architecture: runtime.GOARCH})
+	// The full reference is used as tag so that "docker load" can restore the image name.
+	if _, err := oras.Copy(ctx, repo, src, store, named.String(), opts); err != nil {
+		return err
+	}
+	onProgress(50)
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(agentbundle.WriteTar(pw, dir))
+	}()
+	resp, err := dockerCli.Client().ImageLoad(ctx, pr)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		} else if msg.Error != nil {
+			return msg.Error
+		}
+	}
+	onProgress(100)
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/signal"
+	"path"
 	"slices"
 	"syscall"
 	"time"
@@ -23,6 +25,7 @@ import (
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"oras.land/oras-go/v2/registry/remote/auth"
 )
 
 var (
@@ -44,7 +47,19 @@ var (
 )
 
 func init() {
+	if err := agentenv.InstallCACertificates(); err != nil {
+		logger.Fatal("could not install CA certificates", zap.Error(err))
+	}
 	platformLoggingCore.Collector = &deploymenttargetlogs.BufferedCollector{Delegate: client}
+	client.UseOutbox(agentclient.NewFileOutbox(path.Join(ScratchDir(), "outbox")))
+	client.UseResourceCache(agentclient.NewFileResourceCache(path.Join(ScratchDir(), "resource")))
+	client.SetOffline(agentenv.Offline)
+	client.UseSecretStore(storeSecrets)
+	if err := loadStoredSecrets(); err != nil {
+		logger.Warn("could not load stored credentials", zap.Error(err))
+	} else if _, err := client.ReloadFromEnv(); err != nil {
+		logger.Fatal("could not reload client from environment", zap.Error(err))
+	}
 	if agentenv.AgentVersionID == "" {
 		logger.Warn("AgentVersionID is not set. self updates will be disabled")
 	}
@@ -66,6 +81,15 @@ func main() {
 
 	ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 
+	if len(os.Args) > 1 && os.Args[1] == "apply-bundle" {
+		if len(os.Args) != 3 {
+			logger.Fatal("usage: agent apply-bundle <path>")
+		} else if err := runApplyBundle(ctx, os.Args[2]); err != nil {
+			logger.Fatal("could not apply bundle", zap.Error(err))
+		}
+		return
+	}
+
 	context.AfterFunc(ctx, func() { logger.Info("shutdown signal received") })
 
 	logger.Info("docker agent is starting",
@@ -91,10 +115,26 @@ loop:
 			break loop
 		}
 
-		if resource, err := client.Resource(ctx); err != nil {
+		var resource *api.AgentResource
+		var err error
+		if agentenv.Offline {
+			logger.Debug("agent is offline, using cached resource")
+			if resource, err = client.CachedResource(); err != nil {
+				logger.Warn("no cached resource available", zap.Error(err))
+			}
+		} else if resource, err = client.Resource(ctx); err != nil {
 			logger.Error("failed to get resource", zap.Error(err))
-		} else {
-			if agentenv.AgentVersionID != "" {
+			if resource, err = client.CachedResource(); err != nil {
+				logger.Warn("no cached resource available", zap.Error(err))
+			} else {
+				logger.Warn("hub is unreachable, continuing with cached resource")
+			}
+		} else if err := client.FlushOutbox(ctx); err != nil {
+			logger.Warn("failed to send queued requests", zap.Error(err))
+		}
+
+		if resource != nil {
+			if agentenv.AgentVersionID != "" && !client.IsOffline() {
 				if agentenv.AgentVersionID != resource.Version.ID.String() {
 					logger.Info("agent version has changed. starting self-update")
 					if err := RunAgentSelfUpdate(ctx); err != nil {
@@ -135,21 +175,32 @@ loop:
 							logger.Error("could not uninstall deployment", zap.Error(err))
 						} else if err := DeleteDeployment(deployment); err != nil {
 							logger.Error("could not delete deployment", zap.Error(err))
+						} else if err := DeleteVolumeSnapshots(deployment.ID); err != nil {
+							logger.Error("could not delete volume snapshots", zap.Error(err))
 						}
 					}
 				}
 			}
 
+			if !client.IsOffline() {
+				ReportVolumeSnapshots(ctx)
+			}
+
 			if len(resource.Deployments) == 0 {
 				logger.Info("no deployment in resource response")
 				continue
 			}
 
+			var upgraded bool
 			for _, deployment := range resource.Deployments {
 				var agentDeployment *AgentDeployment
 				var status string
 				statusType := types.DeploymentStatusTypeProgressing
-				_, err = agentauth.EnsureAuth(ctx, client.RawToken(), deployment)
+				var err error
+				var authClient *auth.Client
+				if !client.IsOffline() {
+					authClient, err = agentauth.EnsureAuth(ctx, client.RawToken(), deployment)
+				}
 				if err != nil {
 					logger.Error("docker auth error", zap.Error(err))
 				} else {
@@ -164,12 +215,38 @@ loop:
 					}
 
 					if agentDeployment == nil || agentDeployment.RevisionID != deployment.RevisionID {
+						previousDeployment := agentDeployment
 						func() {
 							progressCtx, progressCancel := context.WithCancel(ctx)
 							defer progressCancel()
-							go sendProgressInterval(progressCtx, deployment.RevisionID)
+							progress := newProgressMessage("applying docker compose…")
+							go sendProgressInterval(progressCtx, deployment.RevisionID, progress)
+
+							if err = VerifySignatures(ctx, deployment, authClient, progress); err != nil {
+								return
+							}
+
+							// The agent pulls the images itself if a proxy is configured, because the docker daemon
+							// would not use it.
+							if deployment.PrePullImages || agentenv.NetworkSettingsConfigured {
+								if err = PullImages(ctx, deployment, progress); err != nil {
+									return
+								}
+							}
+
+							if deployment.RestoreVolumeSnapshotID != nil {
+								err = RestoreVolumeSnapshot(ctx, deployment, *deployment.RestoreVolumeSnapshotID)
+							} else if previousDeployment != nil && resource.VolumeBackupPolicy != nil {
+								err = CreateVolumeSnapshot(
+									ctx, *previousDeployment, deployment.RevisionID, *resource.VolumeBackupPolicy,
+								)
+							}
+							if err != nil {
+								return
+							}
 
 							if agentDeployment, status, err = DockerEngineApply(ctx, deployment); err == nil {
+								UpdateImageHistory(ctx, agentDeployment, previousDeployment)
 								multierr.AppendInto(&err, SaveDeployment(*agentDeployment))
 							}
 
@@ -177,6 +254,7 @@ loop:
 								multierr.AppendInto(&err, RunDockerRestart(ctx, *agentDeployment))
 							}
 						}()
+						upgraded = upgraded || (err == nil && previousDeployment != nil)
 					} else {
 						if statusType1, statusMessage, err1 := CheckStatus(ctx, *agentDeployment); err1 != nil {
 							multierr.AppendInto(&err, err1)
@@ -197,11 +275,17 @@ loop:
 					logger.Error("failed to send status", zap.Error(err))
 				}
 			}
+
+			if upgraded && resource.PrunePolicy != nil {
+				if err := RunPrune(ctx, *resource.PrunePolicy); err != nil {
+					logger.Error("prune failed", zap.Error(err))
+				}
+			}
 		}
 	}
 }
 
-func sendProgressInterval(ctx context.Context, revisionID uuid.UUID) {
+func sendProgressInterval(ctx context.Context, revisionID uuid.UUID, progress *progressMessage) {
 	tick := time.Tick(agentenv.Interval)
 	for {
 		select {
@@ -214,7 +298,7 @@ func sendProgressInterval(ctx context.Context, revisionID uuid.UUID) {
 				ctx,
 				revisionID,
 				types.DeploymentStatusTypeProgressing,
-				"applying docker compose…",
+				progress.Get(),
 			)
 			if err != nil {
 				logger.Warn("error updating status", zap.Error(err))
@@ -116,6 +116,8 @@ func startMetrics(ctx context.Context) {
 			CPUUsage:       usage,
 			MemoryBytes:    memoryTotal,
 			MemoryUsage:    memoryUsed,
+			Deployments:    collectDeploymentMetrics(ctx),
+			Disks:          collectDiskMetrics(ctx),
 		}); err != nil {
 			logger.Error("failed to report metrics", zap.Error(err))
 			return err
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/swarm"
+	"go.uber.org/zap"
+)
+
+// maxImageHistory limits the number of revisions that are recorded in the image history of a deployment.
+const maxImageHistory = 20
+
+// UpdateImageHistory records the images of the current revision of the deployment in front of the image history of
+// the previous revision.
+func UpdateImageHistory(ctx context.Context, deployment *AgentDeployment, previous *AgentDeployment) {
+	images, err := getDeploymentImages(ctx, *deployment)
+	if err != nil {
+		logger.Warn("could not get images of deployment", zap.Any("deploymentId", deployment.ID), zap.Error(err))
+	}
+	deployment.ImageHistory = [][]string{images}
+	if previous != nil {
+		deployment.ImageHistory = append(deployment.ImageHistory, previous.ImageHistory...)
+	}
+	if len(deployment.ImageHistory) > maxImageHistory {
+		deployment.ImageHistory = deployment.ImageHistory[:maxImageHistory]
+	}
+}
+
+// getDeploymentImages returns the image IDs of all containers of the deployment.
+// For Docker Swarm, the image references of the services are returned instead, because the tasks of the services
+// might not be running on this node.
+func getDeploymentImages(ctx context.Context, deployment AgentDeployment) ([]string, error) {
+	var images []string
+	if deployment.DockerType == types.DockerTypeSwarm {
+		services, err := dockerCli.Client().ServiceList(
+			ctx,
+			swarm.ServiceListOptions{Filters: deployment.ContainerFilter()},
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range services {
+			if spec := service.Spec.TaskTemplate.ContainerSpec; spec != nil {
+				images = append(images, spec.Image)
+			}
+		}
+	} else {
+		containers, err := dockerCli.Client().ContainerList(
+			ctx,
+			container.ListOptions{All: true, Filters: deployment.ContainerFilter()},
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range containers {
+			images = append(images, c.ImageID)
+		}
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
+
+// RunPrune removes the images of all revisions that are older than the revisions that should be kept according to
+// the prune policy. Depending on the policy, stopped containers of all deployments and dangling images are removed
+// as well.
+// Images that are still in use can not be removed and are skipped. They stay in the image history, so that removing
+// them is attempted again by the next prune.
+func RunPrune(ctx context.Context, policy types.DockerPrunePolicy) error {
+	deployments, err := GetExistingDeployments()
+	if err != nil {
+		return err
+	}
+	apiClient := dockerCli.Client()
+
+	keep := map[string]struct{}{}
+	var remove []string
+	for _, d := range deployments {
+		for i, images := range d.ImageHistory {
+			if i < policy.KeepRevisions {
+				for _, image := range images {
+					keep[image] = struct{}{}
+				}
+			} else {
+				remove = append(remove, images...)
+			}
+		}
+	}
+	slices.Sort(remove)
+	remove = slices.Compact(remove)
+
+	var reclaimedBytes uint64
+	var removedImages, removedContainers int
+	notRemoved := map[string]struct{}{}
+	for _, img := range remove {
+		if _, ok := keep[img]; ok {
+			continue
+		}
+		inspect, err := apiClient.ImageInspect(ctx, img)
+		if err != nil {
+			logger.Debug("skipping image that can not be inspected", zap.String("image", img), zap.Error(err))
+			continue
+		}
+		if _, err := apiClient.ImageRemove(ctx, img, image.RemoveOptions{PruneChildren: true}); err != nil {
+			logger.Warn("could not remove image", zap.String("image", img), zap.Error(err))
+			notRemoved[img] = struct{}{}
+		} else {
+			reclaimedBytes += uint64(inspect.Size)
+			removedImages++
+		}
+	}
+
+	for _, d := range deployments {
+		if len(d.ImageHistory) > policy.KeepRevisions {
+			d.ImageHistory = trimImageHistory(d.ImageHistory, policy.KeepRevisions, notRemoved)
+			if err := SaveDeployment(d); err != nil {
+				return err
+			}
+		}
+	}
+
+	if policy.PruneStoppedContainers {
+		for _, d := range deployments {
+			if report, err := apiClient.ContainersPrune(ctx, d.ContainerFilter()); err != nil {
+				logger.Warn("could not prune stopped containers", zap.String("project", d.ProjectName), zap.Error(err))
+			} else {
+				reclaimedBytes += report.SpaceReclaimed
+				removedContainers += len(report.ContainersDeleted)
+			}
+		}
+	}
+
+	if policy.PruneDanglingImages {
+		if report, err := apiClient.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", "true"))); err != nil {
+			logger.Warn("could not prune dangling images", zap.Error(err))
+		} else {
+			reclaimedBytes += report.SpaceReclaimed
+			for _, item := range report.ImagesDeleted {
+				if item.Deleted != "" {
+					removedImages++
+				}
+			}
+		}
+	}
+
+	logger.Info("prune finished",
+		zap.Int("removedImages", removedImages),
+		zap.Int("removedContainers", removedContainers),
+		zap.Uint64("reclaimedBytes", reclaimedBytes))
+	return nil
+}
+
+// trimImageHistory keeps the first keepRevisions revisions of the history. Older revisions only keep the images that
+// could not be removed, and revisions without such images are dropped.
+func trimImageHistory(history [][]string, keepRevisions int, notRemoved map[string]struct{}) [][]string {
+	result := slices.Clip(history[:keepRevisions])
+	for _, images := range history[keepRevisions:] {
+		images = slices.DeleteFunc(slices.Clone(images), func(img string) bool {
+			_, ok := notRemoved[img]
+			return !ok
+		})
+		if len(images) > 0 {
+			result = append(result, images)
+		}
+	}
+	return result
+}
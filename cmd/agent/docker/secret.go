@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+// storedSecrets are credentials that were replaced by the hub, keyed by the name of their environment variable.
+// Replaces is the hash of the target secret in the environment of the agent container at the time the credentials
+// were stored. It is used to detect whether the agent was reconnected with a new secret in the meantime.
+type storedSecrets struct {
+	Values   map[string]string `json:"values"`
+	Replaces string            `json:"replaces"`
+}
+
+var initialTargetSecretHash = hashTargetSecret(os.Getenv("DISTR_TARGET_SECRET"))
+
+func storedSecretsPath() string {
+	return path.Join(ScratchDir(), "secrets")
+}
+
+func hashTargetSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// storeSecrets adds the given credentials to the file in the scratch directory.
+// They are also used by the next self-update, because the agent manifest is patched with the current environment.
+func storeSecrets(ctx context.Context, values map[string]string) error {
+	stored, err := readStoredSecrets()
+	if err != nil {
+		return err
+	} else if stored == nil {
+		stored = &storedSecrets{Values: map[string]string{}}
+	}
+	maps.Copy(stored.Values, values)
+	stored.Replaces = initialTargetSecretHash
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	tmpName := storedSecretsPath() + ".tmp"
+	if err := os.WriteFile(tmpName, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, storedSecretsPath())
+}
+
+func readStoredSecrets() (*storedSecrets, error) {
+	data, err := os.ReadFile(storedSecretsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var stored storedSecrets
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	if stored.Values == nil {
+		stored.Values = map[string]string{}
+	}
+	return &stored, nil
+}
+
+// loadStoredSecrets updates the environment with the credentials that were replaced by the hub, unless the agent
+// was reconnected with a new secret since then.
+func loadStoredSecrets() error {
+	stored, err := readStoredSecrets()
+	if err != nil || stored == nil {
+		return err
+	}
+
+	if secret, ok := stored.Values["DISTR_TARGET_SECRET"]; (ok && secret == os.Getenv("DISTR_TARGET_SECRET")) ||
+		stored.Replaces == initialTargetSecretHash {
+		logger.Info("using credentials replaced by the hub")
+		for key, value := range stored.Values {
+			if err := os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	} else {
+		logger.Info("target secret was changed since the credentials were replaced, discarding them",
+			zap.String("path", storedSecretsPath()))
+		return os.Remove(storedSecretsPath())
+	}
+}
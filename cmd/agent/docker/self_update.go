@@ -12,6 +12,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// agentContainerName is the name of the agent container as it is created by the agent's Docker Compose file.
+const agentContainerName = "distr-agent-1"
+
 func RunAgentSelfUpdate(ctx context.Context) error {
 	if manifest, err := client.Manifest(ctx); err != nil {
 		return fmt.Errorf("error fetching agent manifest: %w", err)
@@ -31,6 +34,13 @@ func PatchAgentManifest(manifest map[string]any) error {
 		if svc, ok := svcs["agent"].(map[string]any); ok {
 			if env, ok := svc["environment"].(map[string]any); ok {
 				env["DISTR_TARGET_SECRET"] = os.Getenv("DISTR_TARGET_SECRET")
+				if certificate := os.Getenv("DISTR_CLIENT_CERTIFICATE"); certificate != "" {
+					env["DISTR_CLIENT_CERTIFICATE"] = certificate
+					env["DISTR_CLIENT_KEY"] = os.Getenv("DISTR_CLIENT_KEY")
+				}
+				if bundleKey := os.Getenv("DISTR_BUNDLE_KEY"); bundleKey != "" {
+					env["DISTR_BUNDLE_KEY"] = bundleKey
+				}
 			} else {
 				return errors.New("env is not an object")
 			}
@@ -92,7 +102,7 @@ func ApplyAgentComposeFile(ctx context.Context, manifest map[string]any) error {
 		"--env", "HOST_DOCKER_CONFIG_DIR="+os.Getenv("HOST_DOCKER_CONFIG_DIR"),
 		// TODO: Not sure if it's correct to assume this will always be the correct container name,
 		// but AFAIK there is no reliable way to get the name of a container from the "inside"
-		"--volumes-from", "distr-agent-1",
+		"--volumes-from", agentContainerName,
 		imageName,
 		"docker", "compose", "-f", file.Name(), "up", "-d",
 	)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/signature"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// VerifySignatures verifies the signatures of all images of the deployment that are hosted in the Distr registry if
+// the vendor requires signed artifacts. The client is nil if the agent is offline. In that case, signatures can not be
+// verified and the deployment is refused.
+func VerifySignatures(
+	ctx context.Context,
+	deployment api.AgentDeployment,
+	client *auth.Client,
+	progress *progressMessage,
+) error {
+	if !deployment.SignatureRequired {
+		return nil
+	} else if client == nil {
+		return errors.New("signatures can not be verified while the agent is offline")
+	}
+
+	previousMessage := progress.Get()
+	progress.Set("verifying signatures…")
+	defer progress.Set(previousMessage)
+
+	trusted, err := signature.ParsePublicKeys(deployment.TrustedSigningKeys)
+	if err != nil {
+		return fmt.Errorf("invalid trusted signing key: %w", err)
+	}
+	images, err := getComposeImages(ctx, deployment)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if !strings.HasPrefix(img, agentenv.DistrRegistryHost+"/") {
+			continue
+		}
+		if err := signature.VerifyImage(ctx, img, client, agentenv.DistrRegistryPlainHTTP, trusted); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+		logger.Info("verified signature", zap.String("image", img))
+	}
+	return nil
+}
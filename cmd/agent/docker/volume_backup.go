@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	composeapi "github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	volumeSnapshotMetadataFile = "snapshot.json"
+	// volumeHelperMountPath is the path where a volume is mounted in the helper container that is used to copy its
+	// contents. It must not exist in the agent image, otherwise Docker would populate new volumes with its contents.
+	volumeHelperMountPath = "/distr-volume"
+)
+
+// volumeSnapshotsReported is false if the volume snapshots changed since they were last reported to the hub.
+var volumeSnapshotsReported bool
+
+// failedVolumeSnapshotRestores contains the errors of restores that failed, by the revision that requested the
+// restore. A failed restore might leave the volumes in an inconsistent state, so it is not retried until the agent is
+// restarted.
+var failedVolumeSnapshotRestores = map[uuid.UUID]error{}
+
+// volumeSnapshot is the metadata of a volume snapshot as it is stored by the agent.
+type volumeSnapshot struct {
+	api.DeploymentVolumeSnapshot
+	ProjectName   string                 `json:"projectName"`
+	VolumeDetails []volumeSnapshotVolume `json:"volumeDetails"`
+	// TargetRevisionID is the revision that was about to be applied when the snapshot was created.
+	TargetRevisionID uuid.UUID `json:"targetRevisionId"`
+}
+
+// volumeSnapshotVolume contains everything that is needed to recreate a volume when the snapshot is restored.
+type volumeSnapshotVolume struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	DriverOpts map[string]string `json:"driverOpts,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+func volumeSnapshotDir() string {
+	return path.Join(ScratchDir(), "volume-snapshots")
+}
+
+func deploymentVolumeSnapshotDir(deploymentID uuid.UUID) string {
+	return path.Join(volumeSnapshotDir(), deploymentID.String())
+}
+
+// CreateVolumeSnapshot archives all named volumes of the given Docker Compose deployment into the scratch directory
+// before the target revision is applied and removes the oldest snapshots of the deployment that exceed the backup
+// policy.
+// The containers of the deployment are stopped while the volumes are copied, so that the archives are consistent.
+// They are started again if the snapshot fails. Otherwise, they are recreated when the new revision is applied.
+//
+// Only one snapshot is created for an upgrade from the revision of the deployment to the target revision. If applying
+// the target revision fails and is retried, the volumes might already be partially migrated, so the existing snapshot
+// is kept instead.
+func CreateVolumeSnapshot(
+	ctx context.Context,
+	deployment AgentDeployment,
+	targetRevisionID uuid.UUID,
+	policy types.DockerVolumeBackupPolicy,
+) (err error) {
+	if deployment.DockerType == types.DockerTypeSwarm {
+		logger.Info("skipping volume snapshot because it is not supported for Docker Swarm",
+			zap.Any("deploymentId", deployment.ID))
+		return nil
+	}
+
+	if snapshots, err := getDeploymentVolumeSnapshots(deployment.ID); err != nil {
+		return fmt.Errorf("could not get volume snapshots: %w", err)
+	} else if slices.ContainsFunc(snapshots, func(s volumeSnapshot) bool {
+		return s.DeploymentRevisionID == deployment.RevisionID && s.TargetRevisionID == targetRevisionID
+	}) {
+		logger.Info("skipping volume snapshot because it already exists for this upgrade",
+			zap.Any("deploymentId", deployment.ID),
+			zap.Any("targetRevisionId", targetRevisionID))
+		return nil
+	}
+
+	apiClient := dockerCli.Client()
+	volumes, err := apiClient.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", composeapi.ProjectLabel+"="+deployment.ProjectName)),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list volumes: %w", err)
+	} else if len(volumes.Volumes) == 0 {
+		return nil
+	}
+
+	containers, err := apiClient.ContainerList(ctx, container.ListOptions{Filters: deployment.ContainerFilter()})
+	if err != nil {
+		return fmt.Errorf("could not list containers: %w", err)
+	}
+	for _, c := range containers {
+		if err := apiClient.ContainerStop(ctx, c.ID, container.StopOptions{}); err != nil {
+			return fmt.Errorf("could not stop container %v: %w", c.ID, err)
+		}
+	}
+	defer func() {
+		if err != nil {
+			for _, c := range containers {
+				if err := apiClient.ContainerStart(ctx, c.ID, container.StartOptions{}); err != nil {
+					logger.Warn("could not start container", zap.String("container", c.ID), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	snapshot := volumeSnapshot{
+		DeploymentVolumeSnapshot: api.DeploymentVolumeSnapshot{
+			ID:                   uuid.New(),
+			CreatedAt:            time.Now().UTC(),
+			DeploymentID:         deployment.ID,
+			DeploymentRevisionID: deployment.RevisionID,
+		},
+		ProjectName:      deployment.ProjectName,
+		TargetRevisionID: targetRevisionID,
+	}
+	dir := path.Join(deploymentVolumeSnapshotDir(deployment.ID), snapshot.ID.String())
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if err := os.RemoveAll(dir); err != nil {
+				logger.Warn("could not remove incomplete volume snapshot", zap.Error(err))
+			}
+		}
+	}()
+
+	logger.Info("creating volume snapshot",
+		zap.Any("deploymentId", deployment.ID),
+		zap.Int("volumes", len(volumes.Volumes)))
+	for _, v := range volumes.Volumes {
+		size, err := backupVolume(ctx, v.Name, path.Join(dir, v.Name+".tar"))
+		if err != nil {
+			return fmt.Errorf("could not back up volume %v: %w", v.Name, err)
+		}
+		snapshot.Volumes = append(snapshot.Volumes, v.Name)
+		snapshot.SizeBytes += size
+		snapshot.VolumeDetails = append(snapshot.VolumeDetails, volumeSnapshotVolume{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			DriverOpts: v.Options,
+			Labels:     v.Labels,
+		})
+	}
+
+	if data, err := json.Marshal(snapshot); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(dir, volumeSnapshotMetadataFile), data, 0o600); err != nil {
+		return err
+	}
+	volumeSnapshotsReported = false
+	logger.Info("volume snapshot created",
+		zap.Any("deploymentId", deployment.ID),
+		zap.Any("snapshotId", snapshot.ID),
+		zap.Int64("sizeBytes", snapshot.SizeBytes))
+
+	if err := cleanupVolumeSnapshots(deployment.ID, policy.KeepSnapshots); err != nil {
+		logger.Warn("could not remove old volume snapshots", zap.Error(err))
+	}
+	return nil
+}
+
+// RestoreVolumeSnapshot removes the containers of the deployment and replaces its volumes with the contents of the
+// given snapshot. The containers are recreated when the revision of the snapshot is applied afterwards.
+//
+// If the restore fails, the error is returned again for the same revision without retrying the restore.
+func RestoreVolumeSnapshot(ctx context.Context, deployment api.AgentDeployment, snapshotID uuid.UUID) error {
+	if err, ok := failedVolumeSnapshotRestores[deployment.RevisionID]; ok {
+		return fmt.Errorf("volume snapshot restore failed and is not retried: %w", err)
+	}
+	if err := restoreVolumeSnapshot(ctx, deployment.ID, snapshotID); err != nil {
+		failedVolumeSnapshotRestores[deployment.RevisionID] = err
+		return err
+	}
+	return nil
+}
+
+func restoreVolumeSnapshot(ctx context.Context, deploymentID uuid.UUID, snapshotID uuid.UUID) error {
+	dir := path.Join(deploymentVolumeSnapshotDir(deploymentID), snapshotID.String())
+	snapshot, err := readVolumeSnapshot(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("volume snapshot %v does not exist on this deployment target", snapshotID)
+	} else if err != nil {
+		return fmt.Errorf("could not read volume snapshot %v: %w", snapshotID, err)
+	}
+
+	logger.Info("restoring volume snapshot",
+		zap.Any("deploymentId", deploymentID),
+		zap.Any("snapshotId", snapshotID),
+		zap.Time("snapshotCreatedAt", snapshot.CreatedAt))
+
+	if err := RunDockerComposeDown(ctx, snapshot.ProjectName); err != nil {
+		return fmt.Errorf("could not remove containers: %w", err)
+	}
+
+	apiClient := dockerCli.Client()
+	for _, v := range snapshot.VolumeDetails {
+		if _, err := apiClient.VolumeInspect(ctx, v.Name); err == nil {
+			if err := apiClient.VolumeRemove(ctx, v.Name, false); err != nil {
+				return fmt.Errorf("could not remove volume %v: %w", v.Name, err)
+			}
+		}
+		if _, err := apiClient.VolumeCreate(ctx, volume.CreateOptions{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			DriverOpts: v.DriverOpts,
+			Labels:     v.Labels,
+		}); err != nil {
+			return fmt.Errorf("could not create volume %v: %w", v.Name, err)
+		}
+		if err := restoreVolume(ctx, v.Name, path.Join(dir, v.Name+".tar")); err != nil {
+			return fmt.Errorf("could not restore volume %v: %w", v.Name, err)
+		}
+	}
+
+	logger.Info("volume snapshot restored", zap.Any("deploymentId", deploymentID), zap.Any("snapshotId", snapshotID))
+	return nil
+}
+
+// GetVolumeSnapshots returns all volume snapshots that exist on this deployment target.
+func GetVolumeSnapshots() ([]api.DeploymentVolumeSnapshot, error) {
+	deploymentDirs, err := os.ReadDir(volumeSnapshotDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	result := []api.DeploymentVolumeSnapshot{}
+	for _, deploymentDir := range deploymentDirs {
+		if deploymentID, err := uuid.Parse(deploymentDir.Name()); err == nil {
+			if snapshots, err := getDeploymentVolumeSnapshots(deploymentID); err != nil {
+				return nil, err
+			} else {
+				for _, snapshot := range snapshots {
+					result = append(result, snapshot.DeploymentVolumeSnapshot)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// DeleteVolumeSnapshots removes all volume snapshots of the given deployment.
+func DeleteVolumeSnapshots(deploymentID uuid.UUID) error {
+	volumeSnapshotsReported = false
+	return os.RemoveAll(deploymentVolumeSnapshotDir(deploymentID))
+}
+
+// ReportVolumeSnapshots sends the volume snapshots to the hub if they changed since they were last reported.
+func ReportVolumeSnapshots(ctx context.Context) {
+	if volumeSnapshotsReported {
+		return
+	}
+	if snapshots, err := GetVolumeSnapshots(); err != nil {
+		logger.Warn("could not get volume snapshots", zap.Error(err))
+	} else if err := client.ReportVolumeSnapshots(ctx, snapshots); err != nil {
+		logger.Warn("could not report volume snapshots", zap.Error(err))
+	} else {
+		volumeSnapshotsReported = true
+	}
+}
+
+// getDeploymentVolumeSnapshots returns all complete snapshots of the given deployment, newest first.
+func getDeploymentVolumeSnapshots(deploymentID uuid.UUID) ([]volumeSnapshot, error) {
+	dir := deploymentVolumeSnapshotDir(deploymentID)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var result []volumeSnapshot
+	for _, entry := range entries {
+		if snapshot, err := readVolumeSnapshot(path.Join(dir, entry.Name())); err == nil {
+			result = append(result, *snapshot)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	slices.SortFunc(result, func(a, b volumeSnapshot) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return result, nil
+}
+
+func readVolumeSnapshot(dir string) (*volumeSnapshot, error) {
+	data, err := os.ReadFile(path.Join(dir, volumeSnapshotMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	var snapshot volumeSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func cleanupVolumeSnapshots(deploymentID uuid.UUID, keep int) error {
+	snapshots, err := getDeploymentVolumeSnapshots(deploymentID)
+	if err != nil || len(snapshots) <= keep {
+		return err
+	}
+	for _, snapshot := range snapshots[keep:] {
+		logger.Info("removing old volume snapshot",
+			zap.Any("deploymentId", deploymentID),
+			zap.Any("snapshotId", snapshot.ID))
+		if err := os.RemoveAll(path.Join(deploymentVolumeSnapshotDir(deploymentID), snapshot.ID.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupVolume writes the contents of the volume as tar archive to the given file and returns the size of the archive.
+func backupVolume(ctx context.Context, volumeName string, fileName string) (int64, error) {
+	var size int64
+	err := withVolumeHelperContainer(ctx, volumeName, true, func(containerID string) error {
+		reader, _, err := dockerCli.Client().CopyFromContainer(ctx, containerID, volumeHelperMountPath)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		file, err := os.Create(fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		size, err = io.Copy(file, reader)
+		return err
+	})
+	return size, err
+}
+
+// restoreVolume extracts the tar archive that was created by [backupVolume] into the volume.
+func restoreVolume(ctx context.Context, volumeName string, fileName string) error {
+	return withVolumeHelperContainer(ctx, volumeName, false, func(containerID string) error {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		// The archive contains the mount path as top level directory, so it has to be extracted into the root.
+		return dockerCli.Client().CopyToContainer(ctx, containerID, "/", file, container.CopyToContainerOptions{
+			CopyUIDGID: true,
+		})
+	})
+}
+
+// withVolumeHelperContainer creates a container that mounts the given volume, without starting it, so that files can
+// be copied from and to the volume. The container uses the image of the agent, because it is always available.
+func withVolumeHelperContainer(
+	ctx context.Context,
+	volumeName string,
+	readOnly bool,
+	fn func(containerID string) error,
+) error {
+	apiClient := dockerCli.Client()
+	agentContainer, err := apiClient.ContainerInspect(ctx, agentContainerName)
+	if err != nil {
+		return fmt.Errorf("could not get agent image: %w", err)
+	}
+	helper, err := apiClient.ContainerCreate(
+		ctx,
+		&container.Config{Image: agentContainer.Image},
+		&container.HostConfig{Mounts: []mount.Mount{{
+			Type:          mount.TypeVolume,
+			Source:        volumeName,
+			Target:        volumeHelperMountPath,
+			ReadOnly:      readOnly,
+			VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+		}}},
+		nil,
+		nil,
+		"",
+	)
+	if err != nil {
+		return fmt.Errorf("could not create helper container: %w", err)
+	}
+	defer func() {
+		if err := apiClient.ContainerRemove(
+			context.WithoutCancel(ctx),
+			helper.ID,
+			container.RemoveOptions{Force: true},
+		); err != nil {
+			logger.Warn("could not remove helper container", zap.String("container", helper.ID), zap.Error(err))
+		}
+	}()
+	return fn(helper.ID)
+}
@@ -6,9 +6,12 @@ import (
 	"fmt"
 
 	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/google/uuid"
 	"helm.sh/helm/v3/pkg/release"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	applyconfigurationscorev1 "k8s.io/client-go/applyconfigurations/core/v1"
 )
 
@@ -20,6 +23,42 @@ type AgentDeployment struct {
 	ReleaseName  string    `json:"releaseName"`
 	HelmRevision int       `json:"helmRevision"`
 	LogsEnabled  bool      `json:"logsEnabled"`
+	// Namespace is empty if the deployment is installed in the namespace of the agent.
+	Namespace string `json:"namespace,omitempty"`
+	// KubernetesType is nil for Helm deployments.
+	KubernetesType *types.KubernetesType `json:"kubernetesType,omitempty"`
+	// Resources contains all resources that were applied for deployments that are not managed by Helm.
+	Resources []ResourceReference `json:"resources,omitempty"`
+	// HelmTestError is set if the Helm tests of this revision failed. It is reported instead of the status check
+	// result until the deployment is updated.
+	HelmTestError string `json:"helmTestError,omitempty"`
+}
+
+// ResourceReference identifies a resource that was applied by the agent.
+type ResourceReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func NewResourceReference(obj *unstructured.Unstructured) ResourceReference {
+	return ResourceReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+func (r ResourceReference) GroupVersionKind() schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(r.APIVersion, r.Kind)
+}
+
+// IsSameResource compares the group, kind, namespace and name of two references, but not the version.
+func (r ResourceReference) IsSameResource(other ResourceReference) bool {
+	return r.GroupVersionKind().GroupKind() == other.GroupVersionKind().GroupKind() &&
+		r.Namespace == other.Namespace && r.Name == other.Name
 }
 
 func (d AgentDeployment) GetDeploymentID() uuid.UUID {
@@ -30,6 +69,15 @@ func (d AgentDeployment) GetDeploymentRevisionID() uuid.UUID {
 	return d.RevisionID
 }
 
+func (d AgentDeployment) IsHelm() bool {
+	return d.KubernetesType == nil || *d.KubernetesType == types.KubernetesTypeHelm
+}
+
+// TargetNamespace returns the namespace that the deployment is installed in.
+func (d AgentDeployment) TargetNamespace(agentNamespace string) string {
+	return deploymentNamespace(agentNamespace, d.Namespace)
+}
+
 func (d *AgentDeployment) SecretName() string {
 	return fmt.Sprintf("sh.distr.agent.v1.%v", d.ReleaseName)
 }
@@ -41,7 +89,18 @@ func NewAgentDeployment(deployment api.AgentDeployment, release *release.Release
 		ID:           deployment.ID,
 		RevisionID:   deployment.RevisionID,
 		LogsEnabled:  deployment.LogsEnabled,
+		Namespace:    deployment.Namespace,
+	}
+}
+
+// deploymentNamespace returns the namespace of a deployment, which defaults to the namespace of the agent.
+// AgentDeployment resources are always stored in the namespace of the agent, so that they can be found even if the
+// deployment is installed in a different namespace.
+func deploymentNamespace(agentNamespace, namespace string) string {
+	if namespace == "" {
+		return agentNamespace
 	}
+	return namespace
 }
 
 func PullSecretName(releaseName string) string {
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/distr-sh/distr/internal/agentbundle"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// runApplyBundle installs all deployments contained in the bundle at the given path without contacting the hub.
+// Status updates are added to the outbox and sent once the agent is online again.
+func runApplyBundle(ctx context.Context, name string) error {
+	agentClient.SetOffline(true)
+
+	bundle, err := agentbundle.Open(name, bundleKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := bundle.Close(); err != nil {
+			logger.Warn("could not clean up bundle", zap.Error(err))
+		}
+	}()
+
+	logger.Info("applying bundle",
+		zap.Time("createdAt", bundle.Metadata.CreatedAt),
+		zap.Stringer("deploymentId", bundle.Metadata.DeploymentID),
+		zap.Stringer("revisionId", bundle.Metadata.RevisionID))
+
+	if len(bundle.Metadata.Images) > 0 || bundle.Metadata.AgentImage != "" {
+		logger.Warn("bundle contains images that can not be loaded by the agent. " +
+			"please make sure they are available in a registry reachable by the cluster")
+	}
+
+	namespace := bundle.Resource.Namespace
+	existingDeployments, err := GetExistingDeployments(ctx, namespace)
+	if err != nil {
+		return fmt.Errorf("could not get existing deployments: %w", err)
+	}
+
+	for _, deployment := range bundle.Resource.Deployments {
+		if !isHelmDeployment(deployment) {
+			logger.Debug("deployment does not use a helm chart", zap.Stringer("deploymentId", deployment.ID))
+		} else if registry.IsOCI(deployment.ChartUrl) {
+			ref := agentbundle.ChartReference(deployment.ChartUrl, deployment.ChartVersion)
+			if !slices.Contains(bundle.Metadata.Charts, ref) {
+				return fmt.Errorf("chart %v is not contained in the bundle", ref)
+			}
+			chartPath := path.Join(bundle.Dir, "chart.tgz")
+			if err := bundle.ExtractChart(ctx, ref, chartPath); err != nil {
+				return err
+			}
+			// With an empty chart URL, helm loads the chart from the local file.
+			deployment.ChartName = chartPath
+			deployment.ChartUrl = ""
+		} else {
+			logger.Warn("chart is not contained in the bundle, trying to use the chart repository",
+				zap.String("chartUrl", deployment.ChartUrl))
+		}
+
+		var currentDeployment *AgentDeployment
+		for _, existing := range existingDeployments {
+			if isSameDeployment(existing, deployment) {
+				currentDeployment = &existing
+				break
+			}
+		}
+		if err := verifyLatestHelmRelease(
+			ctx, deploymentNamespace(namespace, deployment.Namespace), deployment, currentDeployment,
+		); err != nil {
+			if errors.Is(err, driver.ErrReleaseNotFound) {
+				logger.Info("current helm release does not exist")
+			} else {
+				pushErrorStatus(ctx, deployment, err)
+				return fmt.Errorf("refusing to install or update: %w", err)
+			}
+		}
+
+		runInstallOrUpgrade(ctx, namespace, deployment, currentDeployment)
+	}
+
+	return nil
+}
+
+// bundleKey returns the bundle key that the hub issued for this agent.
+func bundleKey(metadata agentbundle.Metadata) ([]byte, error) {
+	if targetID := os.Getenv("DISTR_TARGET_ID"); targetID != metadata.DeploymentTargetID.String() {
+		return nil, fmt.Errorf("bundle was created for deployment target %v", metadata.DeploymentTargetID)
+	} else if key := os.Getenv("DISTR_BUNDLE_KEY"); key == "" {
+		return nil, errors.New("DISTR_BUNDLE_KEY is not set, please connect the agent once or reinstall it")
+	} else {
+		return base64.StdEncoding.DecodeString(key)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// collectDeploymentMetrics returns the resource usage of the pod containers of all deployments.
+// Errors are logged and only cause the affected namespace or deployment to be skipped.
+func collectDeploymentMetrics(ctx context.Context, agentNamespace string) []api.AgentDeploymentMetrics {
+	existingDeployments, err := GetExistingDeployments(ctx, agentNamespace)
+	if err != nil {
+		logger.Warn("could not get deployments for metrics", zap.Error(err))
+		return nil
+	}
+
+	deploymentsByNamespace := map[string][]AgentDeployment{}
+	for _, d := range existingDeployments {
+		namespace := d.TargetNamespace(agentNamespace)
+		deploymentsByNamespace[namespace] = append(deploymentsByNamespace[namespace], d)
+	}
+
+	var result []api.AgentDeploymentMetrics
+	for namespace, deployments := range deploymentsByNamespace {
+		if metrics, err := collectNamespaceDeploymentMetrics(ctx, namespace, deployments); err != nil {
+			logger.Warn("could not get deployment metrics", zap.String("namespace", namespace), zap.Error(err))
+		} else {
+			result = append(result, metrics...)
+		}
+	}
+	return result
+}
+
+func collectNamespaceDeploymentMetrics(
+	ctx context.Context,
+	namespace string,
+	deployments []AgentDeployment,
+) ([]api.AgentDeploymentMetrics, error) {
+	deploymentsByResource := map[string]AgentDeployment{}
+	for _, d := range deployments {
+		if keys, err := getDeploymentResourceKeys(ctx, namespace, d); err != nil {
+			logger.Warn("could not get resources for deployment", zap.Any("deploymentId", d.ID), zap.Error(err))
+		} else {
+			for _, key := range keys {
+				deploymentsByResource[key] = d
+			}
+		}
+	}
+	if len(deploymentsByResource) == 0 {
+		return nil, nil
+	}
+
+	owners, err := getControllerOwners(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not get resource owners: %w", err)
+	}
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list pods: %w", err)
+	}
+	podsByName := make(map[string]corev1.Pod, len(pods.Items))
+	for _, pod := range pods.Items {
+		podsByName[pod.Name] = pod
+	}
+	podMetricsList, err := metricsClientSet.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list pod metrics: %w", err)
+	}
+
+	metricsByDeployment := map[uuid.UUID]*api.AgentDeploymentMetrics{}
+	var result []*api.AgentDeploymentMetrics
+	for _, podMetrics := range podMetricsList.Items {
+		key := resourceKey("Pod", podMetrics.Name)
+		for range maxOwnerDepth {
+			if _, ok := deploymentsByResource[key]; ok {
+				break
+			} else if owner, ok := owners[key]; ok {
+				key = owner
+			} else {
+				break
+			}
+		}
+		d, ok := deploymentsByResource[key]
+		if !ok {
+			continue
+		}
+		metrics, ok := metricsByDeployment[d.ID]
+		if !ok {
+			metrics = &api.AgentDeploymentMetrics{DeploymentID: d.ID, DeploymentRevisionID: d.RevisionID}
+			metricsByDeployment[d.ID] = metrics
+			result = append(result, metrics)
+		}
+
+		restartCounts := map[string]int32{}
+		memoryLimits := map[string]int64{}
+		if pod, ok := podsByName[podMetrics.Name]; ok {
+			for _, status := range pod.Status.ContainerStatuses {
+				restartCounts[status.Name] = status.RestartCount
+			}
+			for _, container := range pod.Spec.Containers {
+				if limit := container.Resources.Limits.Memory(); !limit.IsZero() {
+					memoryLimits[container.Name] = limit.Value()
+				}
+			}
+		}
+
+		for _, container := range podMetrics.Containers {
+			containerMetrics := api.AgentContainerMetrics{
+				Name:           fmt.Sprintf("%v/%v", podMetrics.Name, container.Name),
+				CPUUsageMillis: container.Usage.Cpu().MilliValue(),
+				MemoryBytes:    container.Usage.Memory().Value(),
+				RestartCount:   restartCounts[container.Name],
+			}
+			if limit, ok := memoryLimits[container.Name]; ok {
+				containerMetrics.MemoryLimitBytes = &limit
+			}
+			metrics.Containers = append(metrics.Containers, containerMetrics)
+		}
+	}
+
+	metrics := make([]api.AgentDeploymentMetrics, len(result))
+	for i, m := range result {
+		metrics[i] = *m
+	}
+	return metrics, nil
+}
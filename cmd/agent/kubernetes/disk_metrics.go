@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/distr-sh/distr/api"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// kubeletStatsForbiddenOnce makes sure that missing permissions for the kubelet stats summary are only logged once.
+var kubeletStatsForbiddenOnce sync.Once
+
+// kubeletStatsSummary contains the fields of the kubelet stats summary API that are needed for volume metrics.
+type kubeletStatsSummary struct {
+	Pods []struct {
+		Volumes []struct {
+			CapacityBytes *uint64 `json:"capacityBytes"`
+			UsedBytes     *uint64 `json:"usedBytes"`
+			PVCRef        *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// collectDiskMetrics returns the usage of all PersistentVolumeClaims that are mounted by pods in the namespaces of
+// the agent and its deployments. The usage is taken from the stats summary of the kubelet of each node.
+//
+// Reading the stats summary requires access to the nodes/proxy resource. If the agent is not allowed to access it, no
+// disk metrics are reported.
+func collectDiskMetrics(ctx context.Context, agentNamespace string, nodes []corev1.Node) []api.AgentDiskMetrics {
+	namespaces := map[string]struct{}{agentNamespace: {}}
+	if deployments, err := GetExistingDeployments(ctx, agentNamespace); err != nil {
+		logger.Warn("could not get deployments for disk metrics", zap.Error(err))
+	} else {
+		for _, d := range deployments {
+			namespaces[d.TargetNamespace(agentNamespace)] = struct{}{}
+		}
+	}
+
+	var result []api.AgentDiskMetrics
+	seen := map[string]struct{}{}
+	for _, node := range nodes {
+		summary, err := getKubeletStatsSummary(ctx, node.Name)
+		if apierrors.IsForbidden(err) {
+			kubeletStatsForbiddenOnce.Do(func() {
+				logger.Info("agent is not allowed to read kubelet stats, disk metrics are not reported", zap.Error(err))
+			})
+			return nil
+		} else if err != nil {
+			logger.Warn("could not get kubelet stats summary", zap.String("node", node.Name), zap.Error(err))
+			continue
+		}
+		for _, pod := range summary.Pods {
+			for _, volume := range pod.Volumes {
+				if volume.PVCRef == nil || volume.CapacityBytes == nil || volume.UsedBytes == nil {
+					continue
+				} else if _, ok := namespaces[volume.PVCRef.Namespace]; !ok {
+					continue
+				}
+				// a PVC that is mounted by multiple pods is only reported once
+				name := fmt.Sprintf("%v/%v", volume.PVCRef.Namespace, volume.PVCRef.Name)
+				if _, ok := seen[name]; ok {
+					continue
+				}
+				seen[name] = struct{}{}
+				result = append(result, api.AgentDiskMetrics{
+					Type:       api.AgentDiskTypePersistentVolumeClaim,
+					Name:       name,
+					TotalBytes: int64(*volume.CapacityBytes),
+					UsedBytes:  int64(*volume.UsedBytes),
+				})
+			}
+		}
+	}
+	return result
+}
+
+func getKubeletStatsSummary(ctx context.Context, nodeName string) (*kubeletStatsSummary, error) {
+	data, err := k8sClient.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var summary kubeletStatsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
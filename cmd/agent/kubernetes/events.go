@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// maxOwnerDepth limits how many controller owner references are followed to find the resource of a deployment,
+	// e.g. Pod -> ReplicaSet -> Deployment or Pod -> Job -> CronJob.
+	maxOwnerDepth   = 4
+	eventsChunkSize = 100
+)
+
+type eventsWatcher struct {
+	// namespace is the namespace of the agent, which contains the AgentDeployment resources
+	namespace string
+	// exported contains the resource version of every event that was already sent to the hub
+	exported map[k8stypes.UID]string
+}
+
+func NewEventsWatcher(namespace string) *eventsWatcher {
+	return &eventsWatcher{
+		namespace: namespace,
+		exported:  make(map[k8stypes.UID]string),
+	}
+}
+
+func (ew *eventsWatcher) Watch(ctx context.Context, d time.Duration) {
+	logger.Debug("events watcher is starting to watch",
+		zap.String("namespace", ew.namespace),
+		zap.Duration("interval", d))
+	tick := time.Tick(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			ew.collect(ctx)
+		}
+	}
+}
+
+func (ew *eventsWatcher) collect(ctx context.Context) {
+	logger.Debug("getting events")
+	existingDeployments, err := GetExistingDeployments(ctx, ew.namespace)
+	if err != nil {
+		logger.Error("could not get existing deployments", zap.Error(err))
+		return
+	}
+
+	deploymentsByNamespace := map[string][]AgentDeployment{}
+	for _, d := range existingDeployments {
+		namespace := d.TargetNamespace(ew.namespace)
+		deploymentsByNamespace[namespace] = append(deploymentsByNamespace[namespace], d)
+	}
+
+	var records []api.DeploymentEventRecord
+	exported := make(map[k8stypes.UID]string, len(ew.exported))
+	for namespace, deployments := range deploymentsByNamespace {
+		if namespaceRecords, err := ew.collectNamespace(ctx, namespace, deployments, exported); err != nil {
+			logger.Warn("could not get events", zap.String("namespace", namespace), zap.Error(err))
+		} else {
+			records = append(records, namespaceRecords...)
+		}
+	}
+
+	logger.Sugar().Debugf("exporting %v event records", len(records))
+	for chunk := range slices.Chunk(records, eventsChunkSize) {
+		if err := agentClient.ExportDeploymentEvents(ctx, chunk); err != nil {
+			logger.Warn("error exporting events", zap.Error(err))
+			// events that could not be exported are retried in the next iteration
+			for _, record := range chunk {
+				delete(exported, k8stypes.UID(record.UID))
+			}
+		}
+	}
+	ew.exported = exported
+}
+
+// collectNamespace returns records for all events in the given namespace that concern resources of the given
+// deployments and were not exported yet. All events that are returned or were already exported are added to exported.
+func (ew *eventsWatcher) collectNamespace(
+	ctx context.Context,
+	namespace string,
+	deployments []AgentDeployment,
+	exported map[k8stypes.UID]string,
+) ([]api.DeploymentEventRecord, error) {
+	deploymentsByResource := map[string]AgentDeployment{}
+	for _, d := range deployments {
+		if keys, err := getDeploymentResourceKeys(ctx, namespace, d); err != nil {
+			logger.Warn("could not get resources for deployment", zap.Any("deploymentId", d.ID), zap.Error(err))
+		} else {
+			for _, key := range keys {
+				deploymentsByResource[key] = d
+			}
+		}
+	}
+	if len(deploymentsByResource) == 0 {
+		return nil, nil
+	}
+
+	owners, err := getControllerOwners(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("could not get resource owners: %w", err)
+	}
+
+	events, err := k8sClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not list events: %w", err)
+	}
+
+	var records []api.DeploymentEventRecord
+	for _, event := range events.Items {
+		if version, ok := ew.exported[event.UID]; ok && version == event.ResourceVersion {
+			exported[event.UID] = version
+			continue
+		}
+		key := resourceKey(event.InvolvedObject.Kind, event.InvolvedObject.Name)
+		for range maxOwnerDepth {
+			if _, ok := deploymentsByResource[key]; ok {
+				break
+			} else if owner, ok := owners[key]; ok {
+				key = owner
+			} else {
+				break
+			}
+		}
+		if d, ok := deploymentsByResource[key]; ok {
+			records = append(records, toDeploymentEventRecord(d, event))
+			exported[event.UID] = event.ResourceVersion
+		}
+	}
+	return records, nil
+}
+
+// getControllerOwners returns the controller of every Pod, ReplicaSet and Job in the namespace, so that events of
+// these resources can be attributed to the resource that is part of a deployment.
+func getControllerOwners(ctx context.Context, namespace string) (map[string]string, error) {
+	owners := map[string]string{}
+	addOwner := func(kind string, obj metav1.Object) {
+		if ref := metav1.GetControllerOf(obj); ref != nil {
+			owners[resourceKey(kind, obj.GetName())] = resourceKey(ref.Kind, ref.Name)
+		}
+	}
+	if pods, err := k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		return nil, err
+	} else {
+		for _, pod := range pods.Items {
+			addOwner("Pod", &pod)
+		}
+	}
+	if replicaSets, err := k8sClient.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		return nil, err
+	} else {
+		for _, replicaSet := range replicaSets.Items {
+			addOwner("ReplicaSet", &replicaSet)
+		}
+	}
+	if jobs, err := k8sClient.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{}); err != nil {
+		return nil, err
+	} else {
+		for _, job := range jobs.Items {
+			addOwner("Job", &job)
+		}
+	}
+	return owners, nil
+}
+
+// getDeploymentResourceKeys returns the keys of all resources of a deployment without requesting the resources
+// themselves, because events should also be reported for resources that could not be created.
+func getDeploymentResourceKeys(ctx context.Context, namespace string, deployment AgentDeployment) ([]string, error) {
+	if deployment.IsHelm() {
+		resources, err := GetHelmManifest(ctx, namespace, deployment.ReleaseName)
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]string, len(resources))
+		for i, resource := range resources {
+			keys[i] = resourceKey(resource.GetKind(), resource.GetName())
+		}
+		return keys, nil
+	} else {
+		keys := make([]string, len(deployment.Resources))
+		for i, ref := range deployment.Resources {
+			keys[i] = resourceKey(ref.Kind, ref.Name)
+		}
+		return keys, nil
+	}
+}
+
+func resourceKey(kind, name string) string {
+	return fmt.Sprintf("%v/%v", kind, name)
+}
+
+func toDeploymentEventRecord(deployment AgentDeployment, event corev1.Event) api.DeploymentEventRecord {
+	record := api.DeploymentEventRecord{
+		DeploymentID:         deployment.ID,
+		DeploymentRevisionID: deployment.RevisionID,
+		UID:                  string(event.UID),
+		Resource:             resourceKey(event.InvolvedObject.Kind, event.InvolvedObject.Name),
+		Type:                 event.Type,
+		Reason:               event.Reason,
+		Message:              event.Message,
+		Count:                max(event.Count, 1),
+		FirstTimestamp:       event.FirstTimestamp.Time,
+		LastTimestamp:        event.LastTimestamp.Time,
+	}
+	// events created with the events.k8s.io API only have an event time and optionally a series
+	if record.FirstTimestamp.IsZero() {
+		record.FirstTimestamp = event.EventTime.Time
+	}
+	if record.FirstTimestamp.IsZero() {
+		record.FirstTimestamp = event.CreationTimestamp.Time
+	}
+	if event.Series != nil {
+		record.Count = max(event.Series.Count, record.Count)
+		if record.LastTimestamp.IsZero() {
+			record.LastTimestamp = event.Series.LastObservedTime.Time
+		}
+	}
+	if record.LastTimestamp.IsZero() {
+		record.LastTimestamp = record.FirstTimestamp
+	}
+	return record
+}
@@ -3,21 +3,28 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/agentauth"
 	"github.com/distr-sh/distr/internal/agentenv"
 	"github.com/distr-sh/distr/internal/util"
+	"go.uber.org/zap"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
+// helmTestLogLines is the number of log lines of each test pod that are included in the deployment status.
+const helmTestLogLines int64 = 20
+
 var (
 	helmEnvSettings       = cli.New()
 	helmActionConfigCache = make(map[string]*action.Configuration)
@@ -37,7 +44,7 @@ func GetHelmActionConfig(
 	if agentenv.DistrRegistryPlainHTTP {
 		clientOpts = append(clientOpts, registry.ClientOptPlainHTTP())
 	}
-	if deployment != nil {
+	if deployment != nil && !agentClient.IsOffline() {
 		if authorizer, err := agentauth.EnsureAuth(ctx, agentClient.RawToken(), *deployment); err != nil {
 			return nil, err
 		} else if rc, err := registry.NewClient(
@@ -165,6 +172,68 @@ func RunHelmUninstall(ctx context.Context, namespace, releaseName string) error
 	return nil
 }
 
+// HelmTestResult is the result of a single test hook of a Helm release.
+type HelmTestResult struct {
+	Name  string
+	Phase release.HookPhase
+	// Logs contains the last lines of the logs of the test pod, if it still exists.
+	Logs string
+}
+
+func (r HelmTestResult) String() string {
+	phase := r.Phase
+	if phase == "" {
+		phase = "NotRun"
+	}
+	if r.Logs == "" {
+		return fmt.Sprintf("%v: %v", r.Name, phase)
+	}
+	return fmt.Sprintf("%v: %v\n%v", r.Name, phase, strings.TrimRight(r.Logs, "\n"))
+}
+
+// RunHelmTest runs the test hooks of the given release and returns the result of each test.
+// The results are also returned if a test failed.
+func RunHelmTest(ctx context.Context, namespace, releaseName string) ([]HelmTestResult, error) {
+	cfg, err := GetHelmActionConfig(ctx, namespace, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	testAction := action.NewReleaseTesting(cfg)
+	testAction.Namespace = namespace
+	testAction.Timeout = 5 * time.Minute
+	rel, err := testAction.Run(releaseName)
+	if rel == nil {
+		return nil, err
+	}
+
+	var results []HelmTestResult
+	for _, hook := range rel.Hooks {
+		if !slices.Contains(hook.Events, release.HookTest) {
+			continue
+		}
+		result := HelmTestResult{Name: hook.Name, Phase: hook.LastRun.Phase}
+		if hook.Kind == "Pod" && hook.LastRun.Phase != "" {
+			result.Logs = getHelmTestPodLogs(ctx, namespace, hook.Name)
+		}
+		results = append(results, result)
+	}
+	return results, err
+}
+
+// getHelmTestPodLogs returns the last lines of the logs of a test pod.
+// Test pods might already have been deleted due to their hook deletion policy, in which case no logs are returned.
+func getHelmTestPodLogs(ctx context.Context, namespace, name string) string {
+	logs, err := k8sClient.CoreV1().Pods(namespace).
+		GetLogs(name, &corev1.PodLogOptions{TailLines: util.PtrTo(helmTestLogLines)}).
+		DoRaw(ctx)
+	if err != nil {
+		logger.Debug("could not get logs of helm test pod", zap.String("pod", name), zap.Error(err))
+		return ""
+	}
+	return string(logs)
+}
+
 func GetHelmManifest(ctx context.Context, namespace, releaseName string) ([]*unstructured.Unstructured, error) {
 	cfg, err := GetHelmActionConfig(ctx, namespace, nil)
 	if err != nil {
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/kustomization"
+	"github.com/distr-sh/distr/internal/util"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/action"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	labelPrePull = "agent.distr.sh/pre-pull"
+
+	prePullTimeout      = 10 * time.Minute
+	prePullPollInterval = 5 * time.Second
+)
+
+// PullImages pulls all images of the given deployment on every node before any resource is updated.
+// This is done with a temporary DaemonSet that has one container for each image, which is deleted as soon as all
+// images are present on all nodes. The progress is reported as a percentage of pulled images.
+func PullImages(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	isUpgrade bool,
+	progress *progressStatusRunner,
+) error {
+	images, err := getDeploymentImages(ctx, namespace, deployment, isUpgrade)
+	if err != nil {
+		return fmt.Errorf("could not get images of deployment: %w", err)
+	} else if len(images) == 0 {
+		logger.Info("deployment has no images to pull")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, prePullTimeout)
+	defer cancel()
+
+	logger.Info("pulling images", zap.Strings("images", images))
+	daemonSet, err := createPrePullDaemonSet(ctx, namespace, deployment, images)
+	if err != nil {
+		return fmt.Errorf("could not create pre-pull DaemonSet: %w", err)
+	}
+	defer func() {
+		if err := deletePrePullDaemonSet(context.WithoutCancel(ctx), namespace, daemonSet.Name); err != nil {
+			logger.Warn("could not delete pre-pull DaemonSet", zap.Error(err))
+		}
+	}()
+
+	tick := time.NewTicker(prePullPollInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("images were not pulled in time: %w", ctx.Err())
+		case <-tick.C:
+		}
+
+		if done, err := checkPrePullProgress(ctx, namespace, daemonSet.Name, len(images), progress); err != nil {
+			return err
+		} else if done {
+			logger.Info("all images pulled")
+			return nil
+		}
+	}
+}
+
+// getDeploymentImages renders the resources of the deployment without applying them and returns the images of all
+// containers.
+func getDeploymentImages(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	isUpgrade bool,
+) ([]string, error) {
+	var objects []*unstructured.Unstructured
+	if isHelmDeployment(deployment) {
+		manifest, err := renderHelmManifest(ctx, namespace, deployment, isUpgrade)
+		if err != nil {
+			return nil, err
+		}
+		if objects, err = DecodeResourceYaml([]byte(manifest)); err != nil {
+			return nil, err
+		}
+	} else {
+		rendered, err := kustomization.Build(*deployment.KubernetesType, deployment.ManifestFile, deployment.Values)
+		if err != nil {
+			return nil, err
+		}
+		if objects, err = DecodeResourceYaml(rendered); err != nil {
+			return nil, err
+		}
+	}
+
+	var images []string
+	for _, obj := range objects {
+		podSpec := podSpecFields(obj.GetKind())
+		if podSpec == nil {
+			continue
+		}
+		for _, field := range []string{"initContainers", "containers"} {
+			containers, _, err := unstructured.NestedSlice(obj.Object, append(podSpec, field)...)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %v in %v %v: %w", field, obj.GetKind(), obj.GetName(), err)
+			}
+			for _, c := range containers {
+				if container, ok := c.(map[string]any); ok {
+					if image, ok := container["image"].(string); ok && image != "" && !slices.Contains(images, image) {
+						images = append(images, image)
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(images)
+	return images, nil
+}
+
+// renderHelmManifest renders the chart of the deployment with a dry-run install, similar to "helm template".
+func renderHelmManifest(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	isUpgrade bool,
+) (string, error) {
+	cfg, err := GetHelmActionConfig(ctx, namespace, &deployment)
+	if err != nil {
+		return "", err
+	}
+
+	// the values are copied because RunHelmPreflight adds the image pull secret to them
+	deployment.Values = runtime.DeepCopyJSON(deployment.Values)
+
+	installAction := action.NewInstall(cfg)
+	installAction.ReleaseName = deployment.ReleaseName
+	installAction.Namespace = namespace
+	installAction.DryRun = true
+	installAction.IsUpgrade = isUpgrade
+	installAction.PlainHTTP = agentenv.DistrRegistryPlainHTTP
+	if chart, err := RunHelmPreflight(&installAction.ChartPathOptions, deployment); err != nil {
+		return "", fmt.Errorf("helm preflight failed: %w", err)
+	} else if release, err := installAction.RunWithContext(ctx, chart, deployment.Values); err != nil {
+		return "", fmt.Errorf("helm template failed: %w", err)
+	} else {
+		return release.Manifest, nil
+	}
+}
+
+func createPrePullDaemonSet(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	images []string,
+) (*appsv1.DaemonSet, error) {
+	name := fmt.Sprintf("distr-pre-pull-%v", deployment.ReleaseName)
+	// a DaemonSet from a previous attempt that was interrupted might still exist
+	if err := deletePrePullDaemonSet(ctx, namespace, name); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	labels := map[string]string{labelPrePull: deployment.RevisionID.String()}
+	containers := make([]corev1.Container, len(images))
+	for i, image := range images {
+		containers[i] = corev1.Container{
+			Name:  fmt.Sprintf("image-%v", i),
+			Image: image,
+			// the container only has to be created for the image to be pulled, so it does not matter whether this
+			// command exists in the image
+			Command: []string{"true"},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1m"),
+					corev1.ResourceMemory: resource.MustParse("4Mi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("16Mi"),
+				},
+			},
+		}
+	}
+
+	pullSecrets := []corev1.LocalObjectReference{{Name: PullSecretName(deployment.ReleaseName)}}
+	return k8sClient.AppsV1().DaemonSets(namespace).Create(
+		ctx,
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers:                    containers,
+						ImagePullSecrets:              pullSecrets,
+						TerminationGracePeriodSeconds: util.PtrTo(int64(0)),
+					},
+				},
+			},
+		},
+		metav1.CreateOptions{},
+	)
+}
+
+func deletePrePullDaemonSet(ctx context.Context, namespace, name string) error {
+	return k8sClient.AppsV1().DaemonSets(namespace).Delete(
+		ctx,
+		name,
+		metav1.DeleteOptions{PropagationPolicy: util.PtrTo(metav1.DeletePropagationBackground)},
+	)
+}
+
+// checkPrePullProgress updates the progress message with the percentage of images that are present on the nodes the
+// DaemonSet is scheduled on and returns true if all images were pulled.
+// An error is returned if an image can not be pulled.
+func checkPrePullProgress(
+	ctx context.Context,
+	namespace string,
+	name string,
+	imageCount int,
+	progress *progressStatusRunner,
+) (bool, error) {
+	daemonSet, err := k8sClient.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	} else if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		return false, nil
+	} else if daemonSet.Status.DesiredNumberScheduled == 0 {
+		logger.Warn("pre-pull DaemonSet is not scheduled on any node")
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(daemonSet.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return false, err
+	}
+
+	var pulled int
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if waiting := status.State.Waiting; waiting != nil {
+				switch waiting.Reason {
+				case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+					return false, fmt.Errorf("could not pull image %v on node %v: %v",
+						status.Image, pod.Spec.NodeName, conditionMessage(waiting.Reason, waiting.Message))
+				case "", "ContainerCreating", "PodInitializing":
+					if status.ImageID == "" {
+						continue
+					}
+				}
+			}
+			pulled++
+		}
+	}
+
+	total := int(daemonSet.Status.DesiredNumberScheduled) * imageCount
+	progress.SetMessage(fmt.Sprintf("pulling images: %v%% (%v images on %v nodes)",
+		min(pulled*100/total, 100), imageCount, daemonSet.Status.DesiredNumberScheduled))
+	return pulled >= total, nil
+}
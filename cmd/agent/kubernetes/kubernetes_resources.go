@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
 
+	"github.com/distr-sh/distr/internal/util"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -57,34 +62,126 @@ func FromUnstructured(src *unstructured.Unstructured) (runtime.Object, error) {
 	}
 }
 
+// getResourceClient returns a client for resources of the given kind.
+// The returned bool is true if the resources are namespaced, in which case the client is scoped to namespace.
+func getResourceClient(namespace string, gvk schema.GroupVersionKind) (dynamic.ResourceInterface, bool, error) {
+	if mapping, err := k8sRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		return nil, false, err
+	} else if mapping.Scope.Name() != meta.RESTScopeNameRoot {
+		return k8sDynamicClient.Resource(mapping.Resource).Namespace(namespace), true, nil
+	} else {
+		return k8sDynamicClient.Resource(mapping.Resource), false, nil
+	}
+}
+
 func ApplyResources(ctx context.Context, namespace string, objects []*unstructured.Unstructured) error {
 	for _, obj := range objects {
-		gvk := obj.GroupVersionKind()
-		if mapping, err := k8sRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if resource, _, err := getResourceClient(namespace, obj.GroupVersionKind()); err != nil {
 			return err
-		} else {
-			var resource dynamic.ResourceInterface
-			if mapping.Scope.Name() != meta.RESTScopeNameRoot {
-				resource = k8sDynamicClient.Resource(mapping.Resource).Namespace(namespace)
-			} else {
-				resource = k8sDynamicClient.Resource(mapping.Resource)
+		} else if _, err := resource.Get(ctx, obj.GetName(), v1.GetOptions{}); k8serrors.IsNotFound(err) {
+			logger.Debug("creating resource",
+				zap.String("resourceNamespace", namespace), zap.String("resourceName", obj.GetName()))
+			if _, err := resource.Create(ctx, obj, v1.CreateOptions{}); err != nil {
+				return err
 			}
-			if _, err := resource.Get(ctx, obj.GetName(), v1.GetOptions{}); k8serrors.IsNotFound(err) {
-				logger.Debug("creating resource",
-					zap.String("resourceNamespace", namespace), zap.String("resourceName", obj.GetName()))
-				if _, err := resource.Create(ctx, obj, v1.CreateOptions{}); err != nil {
-					return err
-				}
-			} else if err == nil {
-				logger.Debug("updating resource",
-					zap.String("resourceNamespace", namespace), zap.String("resourceName", obj.GetName()))
-				if _, err := resource.Update(ctx, obj, v1.UpdateOptions{}); err != nil {
-					return err
-				}
-			} else {
+		} else if err == nil {
+			logger.Debug("updating resource",
+				zap.String("resourceNamespace", namespace), zap.String("resourceName", obj.GetName()))
+			if _, err := resource.Update(ctx, obj, v1.UpdateOptions{}); err != nil {
 				return err
 			}
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServerSideApplyResources applies the given objects with server-side apply and returns references to them.
+// Namespaced objects are always applied in the given namespace. Field conflicts are resolved in favor of the agent.
+func ServerSideApplyResources(
+	ctx context.Context,
+	namespace string,
+	objects []*unstructured.Unstructured,
+) ([]ResourceReference, error) {
+	refs := make([]ResourceReference, 0, len(objects))
+	for _, obj := range objects {
+		resource, namespaced, err := getResourceClient(namespace, obj.GroupVersionKind())
+		if err != nil {
+			return refs, err
+		}
+		if namespaced {
+			obj.SetNamespace(namespace)
+		} else {
+			obj.SetNamespace("")
+		}
+		logger.Debug("applying resource",
+			zap.String("resourceKind", obj.GetKind()),
+			zap.String("resourceNamespace", obj.GetNamespace()),
+			zap.String("resourceName", obj.GetName()))
+		if _, err := resource.Apply(
+			ctx,
+			obj.GetName(),
+			obj,
+			v1.ApplyOptions{Force: true, FieldManager: "distr-agent"},
+		); err != nil {
+			return refs, fmt.Errorf("could not apply %v %v: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		refs = append(refs, NewResourceReference(obj))
+	}
+	return refs, nil
+}
+
+// GetResources returns the current state of the referenced resources.
+func GetResources(ctx context.Context, refs []ResourceReference) ([]*unstructured.Unstructured, error) {
+	result := make([]*unstructured.Unstructured, 0, len(refs))
+	for _, ref := range refs {
+		if resource, _, err := getResourceClient(ref.Namespace, ref.GroupVersionKind()); err != nil {
+			return nil, err
+		} else if obj, err := resource.Get(ctx, ref.Name, v1.GetOptions{}); err != nil {
+			return nil, err
+		} else {
+			result = append(result, obj)
+		}
+	}
+	return result, nil
+}
+
+// DeleteResources deletes the referenced resources in reverse order. Resources that do not exist are ignored.
+func DeleteResources(ctx context.Context, refs []ResourceReference) error {
+	for _, ref := range slices.Backward(refs) {
+		logger.Debug("deleting resource",
+			zap.String("resourceKind", ref.Kind),
+			zap.String("resourceNamespace", ref.Namespace),
+			zap.String("resourceName", ref.Name))
+		if resource, _, err := getResourceClient(ref.Namespace, ref.GroupVersionKind()); err != nil {
+			return err
+		} else if err := resource.Delete(
+			ctx,
+			ref.Name,
+			v1.DeleteOptions{PropagationPolicy: util.PtrTo(v1.DeletePropagationBackground)},
+		); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete %v %v: %w", ref.Kind, ref.Name, err)
 		}
 	}
 	return nil
 }
+
+// EnsureNamespace creates the namespace with the given name if it does not exist yet.
+func EnsureNamespace(ctx context.Context, name string) error {
+	if _, err := k8sClient.CoreV1().Namespaces().Get(ctx, name, v1.GetOptions{}); err == nil {
+		return nil
+	} else if !k8serrors.IsNotFound(err) {
+		return err
+	}
+	logger.Info("creating namespace", zap.String("namespace", name))
+	_, err := k8sClient.CoreV1().Namespaces().Create(
+		ctx,
+		&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: name}},
+		v1.CreateOptions{FieldManager: "distr-agent"},
+	)
+	if k8serrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
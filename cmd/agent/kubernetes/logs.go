@@ -21,7 +21,8 @@ import (
 type logsWatcher struct {
 	logsExporter deploymentlogs.Exporter
 	last         map[uuid.UUID]metav1.Time
-	namespace    string
+	// namespace is the namespace of the agent, which contains the AgentDeployment resources
+	namespace string
 }
 
 func NewLogsWatcher(namespace string) *logsWatcher {
@@ -64,9 +65,10 @@ func (lw *logsWatcher) collect(ctx context.Context) {
 			continue
 		}
 
+		namespace := d.TargetNamespace(lw.namespace)
 		var resources []runtime.Object
-		if resUnstr, err := GetHelmManifest(ctx, lw.namespace, d.ReleaseName); err != nil {
-			logger.Error("could not get helm manifest for deployment", zap.Error(err))
+		if resUnstr, err := GetDeploymentResources(ctx, namespace, d); err != nil {
+			logger.Error("could not get resources for deployment", zap.Error(err))
 			continue
 		} else {
 			resources = FromUnstructuredSlice(resUnstr)
@@ -83,7 +85,7 @@ func (lw *logsWatcher) collect(ctx context.Context) {
 
 			var resourceName string
 			if metaObj, ok := obj.(metav1.Object); ok {
-				metaObj.SetNamespace(lw.namespace)
+				metaObj.SetNamespace(namespace)
 				logger = logger.With(zap.String("resourceName", metaObj.GetName()))
 
 				if restMapping, err := k8sRestMapper.RESTMapping(obj.GetObjectKind().GroupVersionKind().GroupKind()); err != nil {
@@ -9,6 +9,7 @@ import (
 	"path"
 	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -57,7 +58,18 @@ var (
 )
 
 func init() {
+	if err := agentenv.InstallCACertificates(); err != nil {
+		logger.Fatal("could not install CA certificates", zap.Error(err))
+	}
 	platformLoggingCore.Collector = &deploymenttargetlogs.BufferedCollector{Delegate: agentClient}
+	if namespace, _, err := k8sConfigFlags.ToRawKubeConfigLoader().Namespace(); err != nil {
+		logger.Warn("could not determine agent namespace. outbox and secret rotation will be disabled", zap.Error(err))
+	} else {
+		agentClient.UseOutbox(NewSecretOutbox(namespace))
+		agentClient.UseResourceCache(NewSecretResourceCache(namespace))
+		agentClient.UseSecretStore(NewSecretStore(namespace))
+	}
+	agentClient.SetOffline(agentenv.Offline)
 	if agentenv.AgentVersionID == "" {
 		logger.Warn("AgentVersionID is not set. self updates will be disabled")
 	}
@@ -84,6 +96,15 @@ func main() {
 
 	ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 
+	if len(os.Args) > 1 && os.Args[1] == "apply-bundle" {
+		if len(os.Args) != 3 {
+			logger.Fatal("usage: agent apply-bundle <path>")
+		} else if err := runApplyBundle(ctx, os.Args[2]); err != nil {
+			logger.Fatal("could not apply bundle", zap.Error(err))
+		}
+		return
+	}
+
 	context.AfterFunc(ctx, func() { logger.Info("shutdown signal received") })
 
 	logger.Info("kubernetes agent is starting",
@@ -103,6 +124,8 @@ func main() {
 	var metricsCancelFunc context.CancelFunc
 	var logsWatcher *logsWatcher
 	var logsCancelFunc context.CancelFunc
+	var eventsWatcher *eventsWatcher
+	var eventsCancelFunc context.CancelFunc
 	tick := time.Tick(agentenv.Interval)
 	for ctx.Err() == nil {
 		select {
@@ -119,20 +142,33 @@ func main() {
 			logger.Debug("agent client config unchanged")
 		}
 
-		res, err := agentClient.Resource(ctx)
-		if err != nil {
+		var res *api.AgentResource
+		var err error
+		if agentenv.Offline {
+			logger.Debug("agent is offline, using cached resource")
+			if res, err = agentClient.CachedResource(); err != nil {
+				logger.Warn("no cached resource available", zap.Error(err))
+				continue
+			}
+		} else if res, err = agentClient.Resource(ctx); err != nil {
 			logger.Error("could not get resource", zap.Error(err))
-			continue
+			if res, err = agentClient.CachedResource(); err != nil {
+				logger.Warn("no cached resource available", zap.Error(err))
+				continue
+			}
+			logger.Warn("hub is unreachable, continuing with cached resource")
+		} else if err := agentClient.FlushOutbox(ctx); err != nil {
+			logger.Warn("failed to send queued requests", zap.Error(err))
 		}
 
-		if runSelfUpdateIfNeeded(ctx, res.Namespace, res.Version) {
+		if !agentClient.IsOffline() && runSelfUpdateIfNeeded(ctx, res.Namespace, res.Version) {
 			continue
 		}
 
 		if res.MetricsEnabled && metricsCancelFunc == nil {
 			var metricsCtx context.Context
 			metricsCtx, metricsCancelFunc = context.WithCancel(ctx)
-			go watchMetrics(metricsCtx)
+			go watchMetrics(metricsCtx, res.Namespace)
 		} else if !res.MetricsEnabled && metricsCancelFunc != nil {
 			metricsCancelFunc()
 			metricsCancelFunc = nil
@@ -148,6 +184,16 @@ func main() {
 			go logsWatcher.Watch(ctx, 30*time.Second)
 		}
 
+		if eventsWatcher == nil || eventsWatcher.namespace != res.Namespace {
+			if eventsCancelFunc != nil {
+				eventsCancelFunc()
+			}
+			ctx, cancel := context.WithCancel(ctx)
+			eventsWatcher = NewEventsWatcher(res.Namespace)
+			eventsCancelFunc = cancel
+			go eventsWatcher.Watch(ctx, 30*time.Second)
+		}
+
 		existingDeployments, err := GetExistingDeployments(ctx, res.Namespace)
 		if err != nil {
 			logger.Error("could not get existing deployments", zap.Error(err))
@@ -164,7 +210,7 @@ func main() {
 			)
 			if !resourceHasExistingDeployment {
 				logger.Info("uninstalling orphan deployment", zap.String("id", existing.ID.String()))
-				if err := RunHelmUninstall(ctx, res.Namespace, existing.ReleaseName); err != nil {
+				if err := UninstallDeployment(ctx, existing.TargetNamespace(res.Namespace), existing); err != nil {
 					logger.Warn("could not uninstall old deployment", zap.Error(err))
 				} else if err := DeleteDeployment(ctx, res.Namespace, existing); err != nil {
 					logger.Warn("could not delete old AgentDeployment resource", zap.Error(err))
@@ -185,7 +231,8 @@ func main() {
 					break
 				}
 			}
-			if err := verifyLatestHelmRelease(ctx, res.Namespace, deployment, currentDeployment); err != nil {
+			namespace := deploymentNamespace(res.Namespace, deployment.Namespace)
+			if err := verifyLatestHelmRelease(ctx, namespace, deployment, currentDeployment); err != nil {
 				if errors.Is(err, driver.ErrReleaseNotFound) {
 					logger.Info("current helm release does not exist")
 				} else {
@@ -229,7 +276,9 @@ func verifyLatestHelmRelease(
 	deployment api.AgentDeployment,
 	currentDeployment *AgentDeployment,
 ) error {
-	if latestRelease, err := GetLatestHelmRelease(ctx, namespace, deployment); err != nil {
+	if !isHelmDeployment(deployment) {
+		return nil
+	} else if latestRelease, err := GetLatestHelmRelease(ctx, namespace, deployment); err != nil {
 		return fmt.Errorf("could not get latest helm revision: %w", err)
 	} else if currentDeployment == nil {
 		return fmt.Errorf("helm release %v already exists but was not created by the agent", latestRelease.Name)
@@ -247,15 +296,28 @@ func verifyLatestHelmRelease(
 	}
 }
 
+// runInstallOrUpgrade installs or upgrades the given deployment in its target namespace.
+// The AgentDeployment resource is always saved in agentNamespace.
 func runInstallOrUpgrade(
 	ctx context.Context,
-	namespace string,
+	agentNamespace string,
 	deployment api.AgentDeployment,
 	currentDeployment *AgentDeployment,
 ) {
 	progress := Progress(deployment)
+	namespace := deploymentNamespace(agentNamespace, deployment.Namespace)
+
+	if namespace != agentNamespace {
+		if err := EnsureNamespace(ctx, namespace); err != nil {
+			logger.Error("failed to ensure namespace", zap.Error(err))
+			pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure namespace %v: %w", namespace, err))
+			return
+		}
+	}
 
-	if _, err := agentauth.EnsureAuth(ctx, agentClient.RawToken(), deployment); err != nil {
+	if agentClient.IsOffline() {
+		logger.Debug("agent is offline, skipping registry authentication")
+	} else if _, err := agentauth.EnsureAuth(ctx, agentClient.RawToken(), deployment); err != nil {
 		logger.Error("failed to ensure docker auth", zap.Error(err))
 		pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure docker auth: %w", err))
 	} else if err := ensureImagePullSecret(ctx, namespace, deployment); err != nil {
@@ -263,37 +325,70 @@ func runInstallOrUpgrade(
 		pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure image pull secret: %w", err))
 	}
 
-	if currentDeployment == nil {
+	if currentDeployment != nil && (currentDeployment.IsHelm() != isHelmDeployment(deployment) ||
+		currentDeployment.TargetNamespace(agentNamespace) != namespace) {
+		logger.Info("kubernetes type or namespace of deployment has changed. uninstalling previous revision")
+		currentNamespace := currentDeployment.TargetNamespace(agentNamespace)
+		if err := UninstallDeployment(ctx, currentNamespace, *currentDeployment); err != nil {
+			logger.Error("uninstall error", zap.Error(err))
+			pushErrorStatus(ctx, deployment, fmt.Errorf("could not uninstall previous revision: %w", err))
+			return
+		}
+		currentDeployment = nil
+	}
+
+	if !isHelmDeployment(deployment) &&
+		(currentDeployment == nil || currentDeployment.RevisionID != deployment.RevisionID) {
+		runManifestApply(ctx, agentNamespace, deployment, currentDeployment)
+	} else if currentDeployment == nil {
+		successMessage := "helm install succeeded"
 		err := progress.Run(ctx, func() error {
-			if installedDeployment, err := RunHelmInstall(ctx, namespace, deployment); err != nil {
+			if err := verifySignaturesIfRequired(ctx, namespace, deployment, false, progress); err != nil {
+				return err
+			} else if err := pullImagesIfEnabled(ctx, namespace, deployment, false, progress); err != nil {
+				return err
+			}
+			installedDeployment, err := RunHelmInstall(ctx, namespace, deployment)
+			if err != nil {
 				return fmt.Errorf("helm install failed: %w", err)
-			} else if err = SaveDeployment(ctx, namespace, *installedDeployment); err != nil {
+			}
+			testErr := runHelmTestsIfEnabled(ctx, namespace, deployment, installedDeployment, &successMessage)
+			if err = SaveDeployment(ctx, agentNamespace, *installedDeployment); err != nil {
 				return fmt.Errorf("could not save latest deployment: %w", err)
 			}
-			return nil
+			return testErr
 		})
 		if err != nil {
 			logger.Error("install error", zap.Error(err))
 			pushErrorStatus(ctx, deployment, fmt.Errorf("install error: %w", err))
 		} else {
-			logger.Info("helm install succeeded")
-			pushRunningStatus(ctx, deployment, "helm install succeeded")
+			logger.Info(successMessage)
+			pushRunningStatus(ctx, deployment, successMessage)
 		}
 	} else if currentDeployment.RevisionID != deployment.RevisionID {
 		successMessage := "helm upgrade succeeded"
 		err := progress.Run(ctx, func() error {
-			if updatedDeployment, err := RunHelmUpgrade(ctx, namespace, deployment); err != nil {
+			if err := verifySignaturesIfRequired(ctx, namespace, deployment, true, progress); err != nil {
+				return err
+			} else if err := pullImagesIfEnabled(ctx, namespace, deployment, true, progress); err != nil {
+				return err
+			}
+			updatedDeployment, err := RunHelmUpgrade(ctx, namespace, deployment)
+			if err != nil {
 				return fmt.Errorf("helm upgrade failed: %w", err)
-			} else if err := SaveDeployment(ctx, namespace, *updatedDeployment); err != nil {
-				return fmt.Errorf("could not save latest deployment: %w", err)
-			} else if deployment.ForceRestart {
+			}
+			if deployment.ForceRestart {
 				if err := ForceRestart(ctx, namespace, *updatedDeployment); err != nil {
 					pushErrorStatus(ctx, deployment, fmt.Errorf("%v; force restart error: %w", successMessage, err))
 				} else {
 					successMessage += "; force restart succeeded"
 				}
 			}
-			return nil
+			testErr := runHelmTestsIfEnabled(ctx, namespace, deployment, updatedDeployment, &successMessage)
+			if err := SaveDeployment(ctx, agentNamespace, *updatedDeployment); err != nil {
+				return fmt.Errorf("could not save latest deployment: %w", err)
+			}
+			return testErr
 		})
 		if err != nil {
 			logger.Error("upgrade error", zap.Error(err))
@@ -306,13 +401,16 @@ func runInstallOrUpgrade(
 		logger.Info("no action required. running status check")
 		if currentDeployment.LogsEnabled != deployment.LogsEnabled {
 			currentDeployment.LogsEnabled = deployment.LogsEnabled
-			if err := SaveDeployment(ctx, namespace, *currentDeployment); err != nil {
+			if err := SaveDeployment(ctx, agentNamespace, *currentDeployment); err != nil {
 				logger.Error("could not save latest deployment", zap.Error(err))
 				pushErrorStatus(ctx, deployment, fmt.Errorf("could not save latest deployment: %w", err))
 			}
-		} else if resources, err := GetHelmManifest(ctx, namespace, deployment.ReleaseName); err != nil {
-			logger.Warn("could not get helm manifest", zap.Error(err))
-			pushErrorStatus(ctx, deployment, fmt.Errorf("could not get helm manifest: %w", err))
+		} else if currentDeployment.HelmTestError != "" {
+			logger.Warn("helm test of current revision failed, skipping status check")
+			pushErrorStatus(ctx, deployment, errors.New(currentDeployment.HelmTestError))
+		} else if resources, err := GetDeploymentResources(ctx, namespace, *currentDeployment); err != nil {
+			logger.Warn("could not get deployment resources", zap.Error(err))
+			pushErrorStatus(ctx, deployment, fmt.Errorf("could not get deployment resources: %w", err))
 		} else {
 			var err error
 			for _, resource := range resources {
@@ -333,12 +431,114 @@ func runInstallOrUpgrade(
 	}
 }
 
+// runHelmTestsIfEnabled runs the tests of the installed release if they are enabled for the deployment.
+// The result of each test is appended to successMessage. If a test fails, the error is also stored in
+// installedDeployment so that it keeps being reported for this revision.
+func runHelmTestsIfEnabled(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	installedDeployment *AgentDeployment,
+	successMessage *string,
+) error {
+	if !deployment.RunHelmTests {
+		return nil
+	}
+	logger.Info("running helm tests", zap.String("releaseName", installedDeployment.ReleaseName))
+	results, err := RunHelmTest(ctx, namespace, installedDeployment.ReleaseName)
+	var details strings.Builder
+	for _, result := range results {
+		details.WriteString("\n")
+		details.WriteString(result.String())
+	}
+	if err != nil {
+		installedDeployment.HelmTestError = fmt.Sprintf("helm test failed: %v%v", err, details.String())
+		return errors.New(installedDeployment.HelmTestError)
+	} else if len(results) == 0 {
+		*successMessage += "; no helm tests defined"
+	} else {
+		*successMessage += fmt.Sprintf("; %v helm tests passed%v", len(results), details.String())
+	}
+	return nil
+}
+
+// pullImagesIfEnabled pulls the images of the deployment before it is applied if this is enabled for the deployment.
+func pullImagesIfEnabled(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	isUpgrade bool,
+	progress *progressStatusRunner,
+) error {
+	if !deployment.PrePullImages {
+		return nil
+	}
+	progress.SetMessage("pulling images")
+	if err := PullImages(ctx, namespace, deployment, isUpgrade, progress); err != nil {
+		return fmt.Errorf("image pre-pull failed: %w", err)
+	}
+	progress.SetMessage("helm operation in progress")
+	return nil
+}
+
+func runManifestApply(
+	ctx context.Context,
+	agentNamespace string,
+	deployment api.AgentDeployment,
+	currentDeployment *AgentDeployment,
+) {
+	namespace := deploymentNamespace(agentNamespace, deployment.Namespace)
+	successMessage := "apply succeeded"
+	progress := Progress(deployment)
+	err := progress.Run(ctx, func() error {
+		isUpgrade := currentDeployment != nil
+		if err := verifySignaturesIfRequired(ctx, namespace, deployment, isUpgrade, progress); err != nil {
+			return err
+		} else if err := pullImagesIfEnabled(ctx, namespace, deployment, isUpgrade, progress); err != nil {
+			return err
+		} else if appliedDeployment, err := RunManifestApply(ctx, namespace, deployment, currentDeployment); err != nil {
+			return fmt.Errorf("apply failed: %w", err)
+		} else if err := SaveDeployment(ctx, agentNamespace, *appliedDeployment); err != nil {
+			return fmt.Errorf("could not save latest deployment: %w", err)
+		} else if deployment.ForceRestart && currentDeployment != nil {
+			if err := ForceRestart(ctx, namespace, *appliedDeployment); err != nil {
+				pushErrorStatus(ctx, deployment, fmt.Errorf("%v; force restart error: %w", successMessage, err))
+			} else {
+				successMessage += "; force restart succeeded"
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("apply error", zap.Error(err))
+		pushErrorStatus(ctx, deployment, err)
+	} else {
+		logger.Info(successMessage)
+		pushRunningStatus(ctx, deployment, successMessage)
+	}
+}
+
 type progressStatusRunner struct {
 	deployment api.AgentDeployment
+	mut        sync.RWMutex
+	message    string
 }
 
 func Progress(deployment api.AgentDeployment) *progressStatusRunner {
-	return &progressStatusRunner{deployment: deployment}
+	return &progressStatusRunner{deployment: deployment, message: "helm operation in progress"}
+}
+
+// SetMessage changes the message that is sent with the following progress updates.
+func (psr *progressStatusRunner) SetMessage(message string) {
+	psr.mut.Lock()
+	defer psr.mut.Unlock()
+	psr.message = message
+}
+
+func (psr *progressStatusRunner) getMessage() string {
+	psr.mut.RLock()
+	defer psr.mut.RUnlock()
+	return psr.message
 }
 
 func (psr *progressStatusRunner) Run(ctx context.Context, f func() error) error {
@@ -354,7 +554,7 @@ func (psr *progressStatusRunner) Run(ctx context.Context, f func() error) error
 				return
 			case <-tick:
 				logger.Info("sending progress update")
-				pushProgressingStatus(ctx, psr.deployment)
+				pushProgressingStatus(ctx, psr.deployment, psr.getMessage())
 			}
 		}
 	}(progressCtx)
@@ -374,13 +574,8 @@ func pushRunningStatus(ctx context.Context, deployment api.AgentDeployment, stat
 	}
 }
 
-func pushProgressingStatus(ctx context.Context, deployment api.AgentDeployment) {
-	if err := agentClient.Status(
-		ctx,
-		deployment.RevisionID,
-		types.DeploymentStatusTypeProgressing,
-		"helm operation in progress",
-	); err != nil {
+func pushProgressingStatus(ctx context.Context, deployment api.AgentDeployment, status string) {
+	if err := agentClient.Status(ctx, deployment.RevisionID, types.DeploymentStatusTypeProgressing, status); err != nil {
 		logger.Warn("status push failed", zap.Error(err))
 	}
 }
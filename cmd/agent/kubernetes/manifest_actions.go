@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/kustomization"
+	"github.com/distr-sh/distr/internal/types"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func isHelmDeployment(deployment api.AgentDeployment) bool {
+	return deployment.KubernetesType == nil || *deployment.KubernetesType == types.KubernetesTypeHelm
+}
+
+// RunManifestApply renders the manifests of the given deployment, using its values as kustomization overlay, and
+// applies the resulting resources with server-side apply.
+// Resources of the current deployment that are no longer part of the manifests are deleted afterwards.
+func RunManifestApply(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	currentDeployment *AgentDeployment,
+) (*AgentDeployment, error) {
+	rendered, err := kustomization.Build(*deployment.KubernetesType, deployment.ManifestFile, deployment.Values)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := DecodeResourceYaml(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode rendered manifests: %w", err)
+	}
+	if err := addImagePullSecretToResources(deployment.ReleaseName, objects); err != nil {
+		return nil, err
+	}
+
+	refs, err := ServerSideApplyResources(ctx, namespace, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentDeployment != nil {
+		prune := slices.DeleteFunc(slices.Clone(currentDeployment.Resources), func(ref ResourceReference) bool {
+			return slices.ContainsFunc(refs, ref.IsSameResource)
+		})
+		if len(prune) > 0 {
+			logger.Info("pruning resources that are no longer part of the deployment", zap.Int("count", len(prune)))
+			if err := DeleteResources(ctx, prune); err != nil {
+				return nil, fmt.Errorf("prune failed: %w", err)
+			}
+		}
+	}
+
+	return &AgentDeployment{
+		ID:             deployment.ID,
+		RevisionID:     deployment.RevisionID,
+		ReleaseName:    deployment.ReleaseName,
+		LogsEnabled:    deployment.LogsEnabled,
+		Namespace:      deployment.Namespace,
+		KubernetesType: deployment.KubernetesType,
+		Resources:      refs,
+	}, nil
+}
+
+// UninstallDeployment removes all resources of the given deployment, either with Helm or by deleting the resources
+// that were applied by the agent.
+func UninstallDeployment(ctx context.Context, namespace string, deployment AgentDeployment) error {
+	if deployment.IsHelm() {
+		return RunHelmUninstall(ctx, namespace, deployment.ReleaseName)
+	} else {
+		return DeleteResources(ctx, deployment.Resources)
+	}
+}
+
+// GetDeploymentResources returns the resources that belong to the given deployment.
+func GetDeploymentResources(
+	ctx context.Context,
+	namespace string,
+	deployment AgentDeployment,
+) ([]*unstructured.Unstructured, error) {
+	if deployment.IsHelm() {
+		return GetHelmManifest(ctx, namespace, deployment.ReleaseName)
+	} else {
+		return GetResources(ctx, deployment.Resources)
+	}
+}
+
+// addImagePullSecretToResources adds the image pull secret of the deployment to all pod specs, similar to
+// addImagePullSecretToValues for Helm charts.
+func addImagePullSecretToResources(releaseName string, objects []*unstructured.Unstructured) error {
+	for _, obj := range objects {
+		podSpec := podSpecFields(obj.GetKind())
+		if podSpec == nil {
+			continue
+		}
+		fields := append(podSpec, "imagePullSecrets")
+		secrets, _, err := unstructured.NestedSlice(obj.Object, fields...)
+		if err != nil {
+			return fmt.Errorf("invalid imagePullSecrets in %v %v: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		secrets = append(secrets, map[string]any{"name": PullSecretName(releaseName)})
+		if err := unstructured.SetNestedSlice(obj.Object, secrets, fields...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// podSpecFields returns the path of the pod spec in resources of the given kind or nil if the kind has no pod spec.
+func podSpecFields(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		return []string{"spec", "template", "spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+}
@@ -9,13 +9,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func watchMetrics(ctx context.Context) {
+func watchMetrics(ctx context.Context, agentNamespace string) {
 	logger.Info("starting metrics watch")
 	tick := time.Tick(30 * time.Second)
 	for ctx.Err() == nil {
 		select {
 		case <-tick:
-			doReportMetrics(ctx)
+			doReportMetrics(ctx, agentNamespace)
 		case <-ctx.Done():
 			logger.Info("stopping to watch metrics")
 			return
@@ -23,12 +23,13 @@ func watchMetrics(ctx context.Context) {
 	}
 }
 
-func doReportMetrics(ctx context.Context) {
+func doReportMetrics(ctx context.Context, agentNamespace string) {
 	var cpuCapacityM int64
 	var cpuUsageM int64
 	var memoryCapacityBytes int64
 	var memoryUsageBytes int64
-	if nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err != nil {
+	nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
 		logger.Error("getting nodes failed", zap.Error(err))
 		return
 	} else {
@@ -61,6 +62,8 @@ func doReportMetrics(ctx context.Context) {
 			CPUUsage:       float64(cpuUsageM) / float64(cpuCapacityM),
 			MemoryBytes:    memoryCapacityBytes,
 			MemoryUsage:    float64(memoryUsageBytes) / float64(memoryCapacityBytes),
+			Deployments:    collectDeploymentMetrics(ctx, agentNamespace),
+			Disks:          collectDiskMetrics(ctx, agentNamespace, nodes.Items),
 		}); err != nil {
 			logger.Error("failed to report metrics", zap.Error(err))
 		}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/agentclient"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	outboxSecretName = "distr-agent-outbox"
+	outboxDataKey    = "outbox"
+	// maxOutboxSecretSize is kept well below the Secret size limit of 1MiB
+	maxOutboxSecretSize = 768 * 1024
+)
+
+// secretOutbox is an [agentclient.Outbox] that stores entries in a Secret in the namespace of the agent.
+type secretOutbox struct {
+	namespace string
+}
+
+func NewSecretOutbox(namespace string) agentclient.Outbox {
+	return &secretOutbox{namespace: namespace}
+}
+
+func (o *secretOutbox) Append(entry agentclient.OutboxEntry) error {
+	ctx := context.TODO()
+	newData, err := agentclient.EncodeOutboxEntries([]agentclient.OutboxEntry{entry})
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := k8sClient.CoreV1().Secrets(o.namespace).Get(ctx, outboxSecretName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: outboxSecretName, Namespace: o.namespace},
+				Data:       map[string][]byte{outboxDataKey: newData},
+			}
+			_, err = k8sClient.CoreV1().Secrets(o.namespace).Create(ctx, secret, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Treat as conflict so that the operation is retried with the existing secret
+				return apierrors.NewConflict(corev1.Resource("secrets"), outboxSecretName, err)
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+		if len(secret.Data[outboxDataKey])+len(newData) > maxOutboxSecretSize {
+			return agentclient.ErrOutboxFull
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[outboxDataKey] = append(secret.Data[outboxDataKey], newData...)
+		_, err = k8sClient.CoreV1().Secrets(o.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (o *secretOutbox) Peek() ([]agentclient.OutboxEntry, error) {
+	secret, err := k8sClient.CoreV1().Secrets(o.namespace).Get(context.TODO(), outboxSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	entries, err := agentclient.DecodeOutboxEntries(secret.Data[outboxDataKey])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode outbox: %w", err)
+	}
+	return entries, nil
+}
+
+func (o *secretOutbox) Ack(n int) error {
+	ctx := context.TODO()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := k8sClient.CoreV1().Secrets(o.namespace).Get(ctx, outboxSecretName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// Entries are only ever appended, so the first n entries are still the ones returned by Peek.
+		entries, err := agentclient.DecodeOutboxEntries(secret.Data[outboxDataKey])
+		if err != nil {
+			return fmt.Errorf("could not decode outbox: %w", err)
+		}
+		data, err := agentclient.EncodeOutboxEntries(entries[min(n, len(entries)):])
+		if err != nil {
+			return err
+		}
+		secret.Data = map[string][]byte{outboxDataKey: data}
+		_, err = k8sClient.CoreV1().Secrets(o.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
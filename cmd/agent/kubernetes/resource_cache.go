@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/distr-sh/distr/internal/agentclient"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	applyconfigurationscorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+const resourceCacheSecretName = "distr-agent-resource-cache"
+
+// secretResourceCache is an [agentclient.ResourceCache] that stores the resource in a Secret in the namespace of the
+// agent.
+type secretResourceCache struct {
+	namespace string
+}
+
+func NewSecretResourceCache(namespace string) agentclient.ResourceCache {
+	return &secretResourceCache{namespace: namespace}
+}
+
+func (c *secretResourceCache) Save(data []byte) error {
+	cfg := applyconfigurationscorev1.Secret(resourceCacheSecretName, c.namespace)
+	cfg.WithData(map[string][]byte{"resource": data})
+	_, err := k8sClient.CoreV1().Secrets(c.namespace).Apply(
+		context.TODO(),
+		cfg,
+		metav1.ApplyOptions{Force: true, FieldManager: "distr-agent"},
+	)
+	return err
+}
+
+func (c *secretResourceCache) Load() ([]byte, error) {
+	secret, err := k8sClient.CoreV1().Secrets(c.namespace).
+		Get(context.TODO(), resourceCacheSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, agentclient.ErrNoCachedResource
+	} else if err != nil {
+		return nil, err
+	} else if data, ok := secret.Data["resource"]; !ok {
+		return nil, agentclient.ErrNoCachedResource
+	} else {
+		return data, nil
+	}
+}
@@ -19,9 +19,9 @@ import (
 func ForceRestart(ctx context.Context, namespace string, d AgentDeployment) error {
 	logger := logger.With(zap.Any("deploymentId", d.ID))
 	logger.Info("performing force restart")
-	manifest, err := GetHelmManifest(ctx, namespace, d.ReleaseName)
+	manifest, err := GetDeploymentResources(ctx, namespace, d)
 	if err != nil {
-		return fmt.Errorf("could not get helm manifest: %w", err)
+		return fmt.Errorf("could not get deployment resources: %w", err)
 	}
 
 	var aggregateErr error
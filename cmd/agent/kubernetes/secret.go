@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"github.com/distr-sh/distr/internal/agentclient"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const authSecretName = "distr-agent-auth"
+
+// NewSecretStore returns an [agentclient.SecretStore] that writes credentials to the auth Secret in the namespace of
+// the agent. The Secret is mounted into the agent container, so the new values are also used after a restart.
+func NewSecretStore(namespace string) agentclient.SecretStore {
+	return func(ctx context.Context, values map[string]string) error {
+		return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			secrets := k8sClient.CoreV1().Secrets(namespace)
+			secret, err := secrets.Get(ctx, authSecretName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if secret.Data == nil {
+				secret.Data = map[string][]byte{}
+			}
+			for key, value := range values {
+				secret.Data[key] = []byte(value)
+			}
+			_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+			return err
+		})
+	}
+}
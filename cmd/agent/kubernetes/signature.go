@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentauth"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/signature"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// verifySignaturesIfRequired verifies the signatures of the chart and all images of the deployment that are hosted in
+// the Distr registry if the vendor requires signed artifacts. If the agent is offline, signatures can not be verified
+// and the deployment is refused.
+func verifySignaturesIfRequired(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	isUpgrade bool,
+	progress *progressStatusRunner,
+) error {
+	if !deployment.SignatureRequired {
+		return nil
+	} else if agentClient.IsOffline() {
+		return errors.New("signatures can not be verified while the agent is offline")
+	}
+
+	previousMessage := progress.getMessage()
+	progress.SetMessage("verifying signatures")
+	defer progress.SetMessage(previousMessage)
+
+	trusted, err := signature.ParsePublicKeys(deployment.TrustedSigningKeys)
+	if err != nil {
+		return fmt.Errorf("invalid trusted signing key: %w", err)
+	}
+	client, err := agentauth.EnsureAuth(ctx, agentClient.RawToken(), deployment)
+	if err != nil {
+		return err
+	}
+	refs, err := getDeploymentImages(ctx, namespace, deployment, isUpgrade)
+	if err != nil {
+		return fmt.Errorf("could not get images of deployment: %w", err)
+	}
+	if isHelmDeployment(deployment) && registry.IsOCI(deployment.ChartUrl) {
+		// helm replaces "+" in chart versions with "_" because "+" is not allowed in OCI tags
+		refs = append(refs, strings.TrimPrefix(deployment.ChartUrl, "oci://")+":"+
+			strings.ReplaceAll(deployment.ChartVersion, "+", "_"))
+	}
+
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref, agentenv.DistrRegistryHost+"/") {
+			continue
+		}
+		if err := signature.VerifyImage(ctx, ref, client, agentenv.DistrRegistryPlainHTTP, trusted); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+		logger.Info("verified signature", zap.String("ref", ref))
+	}
+	return nil
+}
@@ -3,11 +3,23 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
+	"go.uber.org/zap"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// maxFailureReasons limits the number of pod failure reasons that are added to a status message.
+const maxFailureReasons = 3
+
 func CheckStatus(ctx context.Context, namespace string, resource *unstructured.Unstructured) error {
 	switch resource.GetKind() {
 	case "Deployment":
@@ -15,28 +27,342 @@ func CheckStatus(ctx context.Context, namespace string, resource *unstructured.U
 			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
 			return err
 		} else if deployment.Status.ReadyReplicas < *deployment.Spec.Replicas {
-			return ReplicasError(resource, deployment.Status.ReadyReplicas, *deployment.Spec.Replicas)
+			return ReplicasError(resource, deployment.Status.ReadyReplicas, *deployment.Spec.Replicas,
+				getPodFailureReasons(ctx, namespace, deployment.Spec.Selector))
 		}
 	case "StatefulSet":
 		if statefulSet, err := k8sClient.AppsV1().StatefulSets(namespace).
 			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
 			return err
 		} else if statefulSet.Status.ReadyReplicas < *statefulSet.Spec.Replicas {
-			return ReplicasError(resource, statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas)
+			return ReplicasError(resource, statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas,
+				getPodFailureReasons(ctx, namespace, statefulSet.Spec.Selector))
 		}
 	case "DaemonSet":
 		if daemonSet, err := k8sClient.AppsV1().DaemonSets(namespace).
 			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
 			return err
 		} else if daemonSet.Status.NumberUnavailable > 0 {
-			return ReplicasError(resource, daemonSet.Status.DesiredNumberScheduled, daemonSet.Status.NumberReady)
+			return ReplicasError(resource, daemonSet.Status.NumberReady, daemonSet.Status.DesiredNumberScheduled,
+				getPodFailureReasons(ctx, namespace, daemonSet.Spec.Selector))
+		}
+	case "Job":
+		if job, err := k8sClient.BatchV1().Jobs(namespace).
+			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
+			return err
+		} else {
+			return checkJobStatus(ctx, namespace, resource, job)
+		}
+	case "CronJob":
+		return checkCronJobStatus(ctx, namespace, resource)
+	case "PersistentVolumeClaim":
+		if pvc, err := k8sClient.CoreV1().PersistentVolumeClaims(namespace).
+			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
+			return err
+		} else if pvc.Status.Phase != corev1.ClaimBound {
+			return ResourceStatusError(resource, fmt.Sprintf("phase is %v", pvc.Status.Phase))
+		}
+	case "Service":
+		if service, err := k8sClient.CoreV1().Services(namespace).
+			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
+			return err
+		} else if service.Spec.Type == corev1.ServiceTypeLoadBalancer && len(service.Status.LoadBalancer.Ingress) == 0 {
+			return ResourceStatusError(resource, "no load balancer ingress assigned")
+		}
+	case "Ingress":
+		if ingress, err := k8sClient.NetworkingV1().Ingresses(namespace).
+			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
+			return err
+		} else {
+			return checkIngressStatus(ctx, resource, ingress)
+		}
+	case "HorizontalPodAutoscaler":
+		if hpa, err := k8sClient.AutoscalingV2().HorizontalPodAutoscalers(namespace).
+			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
+			return err
+		} else {
+			return checkHPAStatus(resource, hpa)
+		}
+	default:
+		return checkConditions(ctx, namespace, resource)
+	}
+	return nil
+}
+
+func checkJobStatus(
+	ctx context.Context,
+	namespace string,
+	resource *unstructured.Unstructured,
+	job *batchv1.Job,
+) error {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return ResourceStatusError(resource, withReasons(
+				fmt.Sprintf("job failed: %v", conditionMessage(string(condition.Reason), condition.Message)),
+				getPodFailureReasons(ctx, namespace, job.Spec.Selector),
+			))
+		}
+	}
+	if job.Status.Active > 0 && job.Status.Failed > 0 {
+		return ResourceStatusError(resource, withReasons(
+			fmt.Sprintf("%v of %v attempts failed", job.Status.Failed, job.Status.Failed+job.Status.Succeeded),
+			getPodFailureReasons(ctx, namespace, job.Spec.Selector),
+		))
+	}
+	return nil
+}
+
+// checkCronJobStatus checks the most recent job that was created by the CronJob.
+//
+// The CronJob controller names its jobs after the CronJob and the scheduled time in minutes, so the most recent job
+// can be fetched directly instead of listing all jobs of the namespace.
+func checkCronJobStatus(ctx context.Context, namespace string, resource *unstructured.Unstructured) error {
+	cronJob, err := k8sClient.BatchV1().CronJobs(namespace).Get(ctx, resource.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	} else if cronJob.Status.LastScheduleTime == nil {
+		return nil
+	}
+
+	jobName := fmt.Sprintf("%v-%v", cronJob.Name, cronJob.Status.LastScheduleTime.Unix()/60)
+	lastJob, err := k8sClient.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lastJob = nil
+	} else if err != nil {
+		return err
+	} else if !slices.ContainsFunc(lastJob.OwnerReferences, func(ref metav1.OwnerReference) bool {
+		return ref.UID == cronJob.UID
+	}) {
+		lastJob = nil
+	}
+
+	if lastJob != nil {
+		if err := checkJobStatus(ctx, namespace, resource, lastJob); err != nil {
+			return fmt.Errorf("last scheduled job %v: %w", lastJob.Name, err)
+		}
+	} else if cronJob.Status.LastSuccessfulTime == nil ||
+		cronJob.Status.LastSuccessfulTime.Before(cronJob.Status.LastScheduleTime) {
+		return ResourceStatusError(resource, "last scheduled job did not succeed")
+	}
+	return nil
+}
+
+// ingressStatusAnnotation can be set on an IngressClass to override whether its controller publishes a load balancer
+// status ("true") or not ("false").
+const ingressStatusAnnotation = "distr.sh/load-balancer-status"
+
+// ingressControllersWithStatus are the controllers that are known to publish a load balancer status for all of their
+// Ingresses. Other controllers, such as Traefik in its default configuration, never do.
+var ingressControllersWithStatus = []string{
+	"k8s.io/ingress-nginx",
+	"k8s.io/ingress-gce",
+	"ingress.k8s.aws/alb",
+	"azure/application-gateway",
+	"haproxy.org/ingress-controller",
+}
+
+// checkIngressStatus checks that a load balancer address was assigned to the Ingress, but only if the controller of
+// its IngressClass publishes one.
+func checkIngressStatus(ctx context.Context, resource *unstructured.Unstructured, ingress *networkingv1.Ingress) error {
+	if len(ingress.Status.LoadBalancer.Ingress) > 0 {
+		return nil
+	}
+	ingressClass, err := getIngressClass(ctx, ingress)
+	if err != nil {
+		// Agents with namespace scope are not allowed to read the cluster scoped IngressClasses.
+		logger.Debug("could not get ingress class", zap.String("ingress", ingress.Name), zap.Error(err))
+		return nil
+	} else if ingressClass == nil || !ingressClassPublishesStatus(ingressClass) {
+		return nil
+	}
+	return ResourceStatusError(resource, "no load balancer ingress assigned")
+}
+
+// getIngressClass returns the IngressClass of the Ingress or the default IngressClass, if the Ingress does not
+// specify one. If there is no such IngressClass, nil is returned.
+func getIngressClass(ctx context.Context, ingress *networkingv1.Ingress) (*networkingv1.IngressClass, error) {
+	var className string
+	if ingress.Spec.IngressClassName != nil {
+		className = *ingress.Spec.IngressClassName
+	} else {
+		className = ingress.Annotations["kubernetes.io/ingress.class"]
+	}
+	if className != "" {
+		ingressClass, err := k8sClient.NetworkingV1().IngressClasses().Get(ctx, className, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return ingressClass, err
+	}
+	ingressClasses, err := k8sClient.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, ingressClass := range ingressClasses.Items {
+		if ingressClass.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			return &ingressClass, nil
+		}
+	}
+	return nil, nil
+}
+
+func ingressClassPublishesStatus(ingressClass *networkingv1.IngressClass) bool {
+	if value, ok := ingressClass.Annotations[ingressStatusAnnotation]; ok {
+		return value == "true"
+	}
+	return slices.Contains(ingressControllersWithStatus, ingressClass.Spec.Controller)
+}
+
+func checkHPAStatus(resource *unstructured.Unstructured, hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	for _, condition := range hpa.Status.Conditions {
+		if condition.Status != corev1.ConditionFalse {
+			continue
 		}
+		// ScalingActive is false with reason ScalingDisabled if the target was scaled to zero on purpose
+		if condition.Type == autoscalingv2.AbleToScale ||
+			(condition.Type == autoscalingv2.ScalingActive && condition.Reason != "ScalingDisabled") {
+			return ResourceStatusError(resource, fmt.Sprintf("%v is false: %v",
+				condition.Type, conditionMessage(condition.Reason, condition.Message)))
+		}
+	}
+	if hpa.Status.DesiredReplicas > hpa.Spec.MaxReplicas ||
+		(hpa.Status.CurrentReplicas >= hpa.Spec.MaxReplicas && hasScalingLimitedCondition(hpa)) {
+		return ResourceStatusError(resource,
+			fmt.Sprintf("scaling is limited by the maximum of %v replicas", hpa.Spec.MaxReplicas))
 	}
 	return nil
 }
 
-func ReplicasError(resource *unstructured.Unstructured, ready, desired int32) error {
-	return ResourceStatusError(resource, fmt.Sprintf("ReadyReplicas (%v) is less than desired (%v)", ready, desired))
+func hasScalingLimitedCondition(hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	return slices.ContainsFunc(hpa.Status.Conditions, func(condition autoscalingv2.HorizontalPodAutoscalerCondition) bool {
+		return condition.Type == autoscalingv2.ScalingLimited && condition.Status == corev1.ConditionTrue &&
+			condition.Reason == "TooManyReplicas"
+	})
+}
+
+// builtinKindsWithConditions are the built-in kinds that are not checked otherwise but report their health in
+// status.conditions.
+var builtinKindsWithConditions = []schema.GroupKind{
+	{Kind: "Pod"},
+	{Group: "apiregistration.k8s.io", Kind: "APIService"},
+}
+
+// isBuiltinGroup returns true for API groups that are part of Kubernetes itself and therefore not defined by a CRD.
+func isBuiltinGroup(group string) bool {
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+// checkConditions evaluates the status.conditions of custom resources and of the built-in kinds that report their
+// health this way. Other resources, like ConfigMaps, are not fetched at all.
+// A resource is unhealthy if its Ready or Available condition is false or any of its Failed, Degraded or Stalled
+// conditions are true.
+func checkConditions(ctx context.Context, namespace string, resource *unstructured.Unstructured) error {
+	gvk := resource.GroupVersionKind()
+	if isBuiltinGroup(gvk.Group) && !slices.Contains(builtinKindsWithConditions, gvk.GroupKind()) {
+		return nil
+	}
+	client, _, err := getResourceClient(namespace, resource.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	current, err := client.Get(ctx, resource.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	conditions, _, err := unstructured.NestedSlice(current.Object, "status", "conditions")
+	if err != nil {
+		return nil
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		conditionType, _ := condition["type"].(string)
+		status, _ := condition["status"].(string)
+		reason, _ := condition["reason"].(string)
+		message, _ := condition["message"].(string)
+		switch {
+		case (conditionType == "Ready" || conditionType == "Available") && status == string(metav1.ConditionFalse),
+			(conditionType == "Failed" || conditionType == "Degraded" || conditionType == "Stalled") &&
+				status == string(metav1.ConditionTrue):
+			return ResourceStatusError(resource,
+				fmt.Sprintf("%v is %v: %v", conditionType, status, conditionMessage(reason, message)))
+		}
+	}
+	return nil
+}
+
+// getPodFailureReasons returns the reasons why pods matching the given selector are not ready, such as the waiting
+// reason and last termination message of their containers.
+func getPodFailureReasons(ctx context.Context, namespace string, selector *metav1.LabelSelector) []string {
+	if selector == nil {
+		return nil
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil
+	}
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		logger.Warn("could not list pods for status check", zap.Error(err))
+		return nil
+	}
+
+	var reasons []string
+	addReason := func(reason string) {
+		if !slices.Contains(reasons, reason) {
+			reasons = append(reasons, reason)
+		}
+	}
+	for _, pod := range pods.Items {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+				addReason(fmt.Sprintf("pod %v can not be scheduled: %v",
+					pod.Name, conditionMessage(condition.Reason, condition.Message)))
+			}
+		}
+		for _, status := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" &&
+				waiting.Reason != "ContainerCreating" && waiting.Reason != "PodInitializing" {
+				addReason(fmt.Sprintf("container %v in pod %v is waiting: %v",
+					status.Name, pod.Name, conditionMessage(waiting.Reason, waiting.Message)))
+			}
+			if terminated := status.LastTerminationState.Terminated; terminated != nil && !status.Ready {
+				addReason(fmt.Sprintf("container %v in pod %v last terminated with exit code %v: %v",
+					status.Name, pod.Name, terminated.ExitCode,
+					conditionMessage(terminated.Reason, terminated.Message)))
+			}
+		}
+	}
+	if len(reasons) > maxFailureReasons {
+		reasons = append(reasons[:maxFailureReasons], fmt.Sprintf("and %v more", len(reasons)-maxFailureReasons))
+	}
+	return reasons
+}
+
+func conditionMessage(reason, message string) string {
+	if message == "" {
+		return reason
+	} else if reason == "" {
+		return strings.TrimSpace(message)
+	} else {
+		return fmt.Sprintf("%v (%v)", reason, strings.TrimSpace(message))
+	}
+}
+
+func withReasons(msg string, reasons []string) string {
+	if len(reasons) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%v; %v", msg, strings.Join(reasons, "; "))
+}
+
+func ReplicasError(resource *unstructured.Unstructured, ready, desired int32, reasons []string) error {
+	return ResourceStatusError(resource, withReasons(
+		fmt.Sprintf("ReadyReplicas (%v) is less than desired (%v)", ready, desired),
+		reasons,
+	))
 }
 
 func ResourceStatusError(resource *unstructured.Unstructured, msg string) error {
@@ -27,7 +27,10 @@ const (
 	deploymentRevisionStatus  = "DeploymentRevisionStatus"
 	deploymentLogRecord       = "DeploymentLogRecord"
 	deploymentTargetLogRecord = "DeploymentTargetLogRecord"
+	deploymentEventRecord     = "DeploymentEventRecord"
 	oidcState                 = "OIDCState"
+	artifactRegistry          = "Registry"
+	artifactRetention         = "ArtifactRetention"
 )
 
 type CleanupOptions struct {
@@ -40,13 +43,16 @@ func NewCleanupCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use: "cleanup <type>",
 		Long: fmt.Sprintf(
-			"type must be one of: %v, %v, %v, %v, %v, %v",
+			"type must be one of: %v, %v, %v, %v, %v, %v, %v, %v, %v",
 			deploymentTargetStatus,
 			deploymentRevisionStatus,
 			deploymentTargetMetrics,
 			deploymentLogRecord,
 			deploymentTargetLogRecord,
+			deploymentEventRecord,
 			oidcState,
+			artifactRegistry,
+			artifactRetention,
 		),
 		Short: "delete old data",
 		Args:  cobra.ExactArgs(1),
@@ -56,7 +62,10 @@ func NewCleanupCommand() *cobra.Command {
 			deploymentTargetMetrics,
 			deploymentLogRecord,
 			deploymentTargetLogRecord,
+			deploymentEventRecord,
 			oidcState,
+			artifactRegistry,
+			artifactRetention,
 		},
 		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
 		Run: func(cmd *cobra.Command, args []string) {
@@ -93,8 +102,22 @@ func runCleanup(ctx context.Context, opts CleanupOptions) error {
 		cleanupFunc = cleanup.RunDeploymentLogRecordCleanup
 	case deploymentTargetLogRecord:
 		cleanupFunc = cleanup.RunDeploymentTargetLogRecordCleanup
+	case deploymentEventRecord:
+		cleanupFunc = cleanup.RunDeploymentEventRecordCleanup
 	case oidcState:
 		cleanupFunc = cleanup.RunOIDCStateCleanup
+	case artifactRegistry:
+		if !env.RegistryEnabled() {
+			log.Error("registry cleanup requires REGISTRY_ENABLED")
+			return errors.New("registry is not enabled")
+		}
+		cleanupFunc = cleanup.RunRegistryGarbageCollection
+	case artifactRetention:
+		if !env.RegistryEnabled() {
+			log.Error("artifact retention requires REGISTRY_ENABLED")
+			return errors.New("registry is not enabled")
+		}
+		cleanupFunc = cleanup.RunArtifactRetention
 	default:
 		log.Sugar().Errorf("invalid cleanup type: %v", opts.Type)
 		return errors.New("invalid cleanup type")
@@ -13,7 +13,7 @@ import {
 } from '@angular/core';
 import {FormControl, FormGroup, FormsModule, ReactiveFormsModule, Validators} from '@angular/forms';
 import {ActivatedRoute, Router, RouterLink} from '@angular/router';
-import {Application, ApplicationVersion, HelmChartType} from '@distr-sh/distr-sdk';
+import {Application, ApplicationVersion, HelmChartType, KubernetesType} from '@distr-sh/distr-sdk';
 import {FaIconComponent} from '@fortawesome/angular-fontawesome';
 import {
   faArchive,
@@ -128,6 +128,8 @@ export class ApplicationDetailComponent implements OnInit, OnDestroy {
     linkTemplate: new FormControl(''),
     kubernetes: new FormGroup(
       {
+        kubernetesType: new FormControl<KubernetesType>('helm', {nonNullable: true}),
+        manifests: new FormControl('', Validators.required),
         chartType: new FormControl<HelmChartType>('repository', {
           nonNullable: true,
           validators: Validators.required,
@@ -192,6 +194,9 @@ export class ApplicationDetailComponent implements OnInit, OnDestroy {
           this.newVersionForm.controls.kubernetes.controls.chartName.disable();
         }
       });
+    this.newVersionForm.controls.kubernetes.controls.kubernetesType.valueChanges
+      .pipe(takeUntil(this.destroyed$))
+      .subscribe(() => this.updateKubernetesTypeControls());
   }
 
   ngOnDestroy() {
@@ -254,19 +259,27 @@ export class ApplicationDetailComponent implements OnInit, OnDestroy {
         );
       } else {
         const versionFormVal = this.newVersionForm.controls.kubernetes.value;
-        const version = {
-          name: this.newVersionForm.controls.versionName.value!,
-          linkTemplate: this.newVersionForm.controls.linkTemplate.value!,
-          chartType: versionFormVal.chartType!,
-          chartName: versionFormVal.chartName ?? undefined,
-          chartUrl: versionFormVal.chartUrl!,
-          chartVersion: versionFormVal.chartVersion!,
-        };
+        const version: ApplicationVersion =
+          versionFormVal.kubernetesType === 'manifests'
+            ? {
+                name: this.newVersionForm.controls.versionName.value!,
+                linkTemplate: this.newVersionForm.controls.linkTemplate.value!,
+                kubernetesType: versionFormVal.kubernetesType,
+              }
+            : {
+                name: this.newVersionForm.controls.versionName.value!,
+                linkTemplate: this.newVersionForm.controls.linkTemplate.value!,
+                chartType: versionFormVal.chartType!,
+                chartName: versionFormVal.chartName ?? undefined,
+                chartUrl: versionFormVal.chartUrl!,
+                chartVersion: versionFormVal.chartVersion!,
+              };
         res = this.applicationService.createApplicationVersionForKubernetes(
           application,
           version,
           versionFormVal.baseValues,
-          versionFormVal.template
+          versionFormVal.template,
+          versionFormVal.manifests
         );
       }
 
@@ -299,9 +312,15 @@ export class ApplicationDetailComponent implements OnInit, OnDestroy {
       try {
         const template = await firstValueFrom(this.applicationService.getTemplateFile(application.id!, version.id!));
         const baseValues = await firstValueFrom(this.applicationService.getValuesFile(application.id!, version.id!));
+        const manifests =
+          version.kubernetesType === 'manifests'
+            ? await firstValueFrom(this.applicationService.getManifestFile(application.id!, version.id!))
+            : undefined;
         return {
           linkTemplate: version.linkTemplate,
           kubernetes: {
+            kubernetesType: version.kubernetesType === 'manifests' ? version.kubernetesType : 'helm',
+            manifests,
             chartType: version.chartType,
             chartName: version.chartName,
             chartUrl: version.chartUrl,
@@ -450,12 +469,34 @@ export class ApplicationDetailComponent implements OnInit, OnDestroy {
     if (app.type === 'kubernetes') {
       enableControlsWithoutEvent(this.newVersionForm.controls.kubernetes);
       disableControlsWithoutEvent(this.newVersionForm.controls.docker);
+      this.updateKubernetesTypeControls();
     } else {
       enableControlsWithoutEvent(this.newVersionForm.controls.docker);
       disableControlsWithoutEvent(this.newVersionForm.controls.kubernetes);
     }
   }
 
+  private updateKubernetesTypeControls() {
+    const controls = this.newVersionForm.controls.kubernetes.controls;
+    if (controls.kubernetesType.value === 'manifests') {
+      controls.manifests.enable({emitEvent: false});
+      controls.chartType.disable({emitEvent: false});
+      controls.chartName.disable({emitEvent: false});
+      controls.chartUrl.disable({emitEvent: false});
+      controls.chartVersion.disable({emitEvent: false});
+    } else {
+      controls.manifests.disable({emitEvent: false});
+      controls.chartType.enable({emitEvent: false});
+      controls.chartUrl.enable({emitEvent: false});
+      controls.chartVersion.enable({emitEvent: false});
+      if (controls.chartType.value === 'repository') {
+        controls.chartName.enable({emitEvent: false});
+      } else {
+        controls.chartName.disable({emitEvent: false});
+      }
+    }
+  }
+
   public async uploadImage(data: Application) {
     const fileId = await firstValueFrom(this.imageUploadService.showDialog({imageUrl: data.imageUrl}));
     if (!fileId || data.imageUrl?.includes(fileId)) {
@@ -1,9 +1,18 @@
 import {OverlayModule} from '@angular/cdk/overlay';
-import {AsyncPipe} from '@angular/common';
+import {AsyncPipe, UpperCasePipe} from '@angular/common';
 import {Component, inject, resource, signal} from '@angular/core';
 import {ActivatedRoute, Router} from '@angular/router';
 import {FaIconComponent} from '@fortawesome/angular-fontawesome';
-import {faBox, faEllipsisVertical, faTrash, faXmark} from '@fortawesome/free-solid-svg-icons';
+import {
+  faBox,
+  faBug,
+  faDownload,
+  faEllipsisVertical,
+  faFileLines,
+  faShieldHalved,
+  faTrash,
+  faXmark,
+} from '@fortawesome/free-solid-svg-icons';
 import {catchError, distinctUntilChanged, filter, firstValueFrom, map, NEVER, switchMap, tap} from 'rxjs';
 import {getRemoteEnvironment} from '../../../env/remote';
 import {RelativeDatePipe} from '../../../util/dates';
@@ -16,6 +25,7 @@ import {UuidComponent} from '../../components/uuid';
 import {RequireVendorDirective} from '../../directives/required-role.directive';
 import {
   ArtifactsService,
+  ArtifactVersionAttachment,
   ArtifactWithTags,
   HasDownloads,
   TaggedArtifactVersion,
@@ -32,6 +42,7 @@ import {ArtifactsDownloadCountComponent, ArtifactsDownloadedByComponent, Artifac
   imports: [
     FaIconComponent,
     AsyncPipe,
+    UpperCasePipe,
     UuidComponent,
     RelativeDatePipe,
     ArtifactsDownloadCountComponent,
@@ -58,8 +69,12 @@ export class ArtifactVersionsComponent {
 
   protected readonly faBox = faBox;
   protected readonly faXmark = faXmark;
+  protected readonly faShieldHalved = faShieldHalved;
   protected readonly faTrash = faTrash;
   protected readonly faEllipsisVertical = faEllipsisVertical;
+  protected readonly faFileLines = faFileLines;
+  protected readonly faBug = faBug;
+  protected readonly faDownload = faDownload;
 
   protected readonly showDropdown = signal(false);
 
@@ -172,4 +187,23 @@ export class ArtifactVersionsComponent {
       )
       .subscribe();
   }
+
+  public async downloadAttachment(artifact: ArtifactWithTags, attachment: ArtifactVersionAttachment) {
+    try {
+      const blob = await firstValueFrom(this.artifacts.downloadAttachment(artifact.id, attachment.id));
+      const url = window.URL.createObjectURL(blob);
+      const a = document.createElement('a');
+      a.href = url;
+      a.download = `${artifact.name}-${attachment.type}-${attachment.format}.json`;
+      document.body.appendChild(a);
+      a.click();
+      window.URL.revokeObjectURL(url);
+      document.body.removeChild(a);
+    } catch (e) {
+      const msg = getFormDisplayedError(e);
+      if (msg) {
+        this.toast.error(msg);
+      }
+    }
+  }
 }
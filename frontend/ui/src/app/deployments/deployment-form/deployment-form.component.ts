@@ -21,7 +21,7 @@ import {
   Validators,
 } from '@angular/forms';
 import {RouterLink} from '@angular/router';
-import {DeploymentRequest, DeploymentType} from '@distr-sh/distr-sdk';
+import {DeploymentRequest, DeploymentTargetScope, DeploymentType} from '@distr-sh/distr-sdk';
 import {
   BehaviorSubject,
   catchError,
@@ -39,7 +39,12 @@ import {
   takeUntil,
 } from 'rxjs';
 import {isArchived} from '../../../util/dates';
-import {HELM_RELEASE_NAME_MAX_LENGTH, HELM_RELEASE_NAME_REGEX} from '../../../util/validation';
+import {
+  HELM_RELEASE_NAME_MAX_LENGTH,
+  HELM_RELEASE_NAME_REGEX,
+  KUBERNETES_NAMESPACE_MAX_LENGTH,
+  KUBERNETES_RESOURCE_NAME_REGEX,
+} from '../../../util/validation';
 import {EditorComponent} from '../../components/editor.component';
 import {AutotrimDirective} from '../../directives/autotrim.directive';
 import {ApplicationsService} from '../../services/applications.service';
@@ -54,11 +59,14 @@ export type DeploymentFormValue = Partial<{
   applicationLicenseId: string;
   valuesYaml: string;
   releaseName: string;
+  namespace: string;
   envFileData: string;
   swarmMode: boolean;
   logsEnabled: boolean;
   forceRestart: boolean;
   ignoreRevisionSkew: boolean;
+  runHelmTests: boolean;
+  prePullImages: boolean;
 }>;
 
 export function mapToDeploymentRequest(value: DeploymentFormValue, deploymentTargetId: string): DeploymentRequest {
@@ -68,12 +76,15 @@ export function mapToDeploymentRequest(value: DeploymentFormValue, deploymentTar
     applicationLicenseId: value.applicationLicenseId || undefined,
     deploymentId: value.deploymentId || undefined,
     releaseName: value.releaseName || undefined,
+    namespace: value.namespace || undefined,
     valuesYaml: value.valuesYaml ? btoa(value.valuesYaml) : undefined,
     dockerType: value.swarmMode ? 'swarm' : 'compose',
     envFileData: value.envFileData ? btoa(value.envFileData) : undefined,
     logsEnabled: value.logsEnabled ?? false,
     forceRestart: value.forceRestart ?? false,
     ignoreRevisionSkew: value.ignoreRevisionSkew ?? false,
+    runHelmTests: value.runHelmTests ?? false,
+    prePullImages: value.prePullImages ?? false,
   };
 }
 
@@ -94,6 +105,7 @@ type DeploymentFormValueCallback = (v: DeploymentFormValue | undefined) => void;
 export class DeploymentFormComponent implements OnInit, AfterViewInit, OnDestroy, ControlValueAccessor {
   disableApplicationSelect = input(false);
   deploymentType = input<DeploymentType>('docker');
+  deploymentTargetScope = input<DeploymentTargetScope>();
   customerOrganizationId = input<string>();
   deploymentTargetName = input<string>('default');
 
@@ -119,12 +131,18 @@ export class DeploymentFormComponent implements OnInit, AfterViewInit, OnDestroy
       Validators.maxLength(HELM_RELEASE_NAME_MAX_LENGTH),
       Validators.pattern(HELM_RELEASE_NAME_REGEX),
     ]),
+    namespace: this.fb.nonNullable.control({value: '', disabled: true}, [
+      Validators.maxLength(KUBERNETES_NAMESPACE_MAX_LENGTH),
+      Validators.pattern(KUBERNETES_RESOURCE_NAME_REGEX),
+    ]),
     valuesYaml: this.fb.nonNullable.control(''),
     envFileData: this.fb.nonNullable.control(''),
     swarmMode: this.fb.nonNullable.control<boolean>(false),
     logsEnabled: this.fb.nonNullable.control<boolean>(true),
     forceRestart: this.fb.nonNullable.control<boolean>(false),
     ignoreRevisionSkew: this.fb.nonNullable.control<boolean>(false),
+    runHelmTests: this.fb.nonNullable.control<boolean>(false),
+    prePullImages: this.fb.nonNullable.control<boolean>(false),
   });
   protected readonly composeFile = this.fb.nonNullable.control({disabled: true, value: ''});
 
@@ -153,6 +171,7 @@ export class DeploymentFormComponent implements OnInit, AfterViewInit, OnDestroy
   );
 
   private readonly deploymentType$ = toObservable(this.deploymentType);
+  private readonly deploymentTargetScope$ = toObservable(this.deploymentTargetScope);
   private readonly customerOrganizationId$ = toObservable(this.customerOrganizationId);
 
   protected readonly allLicenses$ = this.featureFlags.isLicensingEnabled$.pipe(
@@ -306,6 +325,18 @@ export class DeploymentFormComponent implements OnInit, AfterViewInit, OnDestroy
       }
     });
 
+    // helm tests can only be run for application versions that are deployed as a Helm chart
+    combineLatest([this.deploymentType$, this.availableApplicationVersions$, this.applicationVersionId$])
+      .pipe(takeUntil(this.destroyed$))
+      .subscribe(([deploymentType, versions, versionId]) => {
+        const version = versions.find((av) => av.id === versionId);
+        if (deploymentType === 'kubernetes' && (!version?.kubernetesType || version.kubernetesType === 'helm')) {
+          this.deployForm.controls.runHelmTests.enable();
+        } else {
+          this.deployForm.controls.runHelmTests.disable();
+        }
+      });
+
     combineLatest([this.deploymentId$, this.deploymentType$]).subscribe(([deploymentId, deploymentType]) => {
       if (deploymentType === 'kubernetes' && deploymentId) {
         this.deployForm.controls.ignoreRevisionSkew.enable();
@@ -314,6 +345,17 @@ export class DeploymentFormComponent implements OnInit, AfterViewInit, OnDestroy
       }
     });
 
+    combineLatest([this.deploymentId$, this.deploymentType$, this.deploymentTargetScope$])
+      .pipe(takeUntil(this.destroyed$))
+      .subscribe(([deploymentId, deploymentType, scope]) => {
+        // the namespace of a deployment can only be chosen when it is created
+        if (deploymentType === 'kubernetes' && scope === 'cluster' && !deploymentId) {
+          this.deployForm.controls.namespace.enable();
+        } else {
+          this.deployForm.controls.namespace.disable();
+        }
+      });
+
     this.deploymentId$.pipe(takeUntil(this.destroyed$)).subscribe((id) => {
       if (id) {
         this.deployForm.controls.applicationId.disable();
@@ -62,6 +62,7 @@ import {
           <app-deployment-form
             [formControl]="deployForm"
             [deploymentType]="deploymentTarget().type"
+            [deploymentTargetScope]="deploymentTarget().scope"
             [customerOrganizationId]="deploymentTarget().customerOrganization?.id"
             [deploymentTargetName]="deploymentTarget().name"></app-deployment-form>
           <button
@@ -200,6 +200,14 @@ export class DeploymentTargetCardComponent {
     namespace: new FormControl<string | undefined>({value: undefined, disabled: true}),
     scope: new FormControl<DeploymentTargetScope>({value: 'namespace', disabled: true}),
     metricsEnabled: new FormControl<boolean>(true),
+    diskUsageWarningThreshold: new FormControl<number | undefined>(undefined, [
+      Validators.min(1),
+      Validators.max(100),
+    ]),
+    httpProxy: new FormControl<string | undefined>(undefined),
+    httpsProxy: new FormControl<string | undefined>(undefined),
+    noProxy: new FormControl<string | undefined>(undefined),
+    caCertificates: new FormControl<string | undefined>(undefined),
     customResources: new FormControl<boolean>(false, {nonNullable: true}),
     resources: new FormGroup({
       cpuRequest: new FormControl<string>('100m', {
@@ -219,6 +227,22 @@ export class DeploymentTargetCardComponent {
         validators: [Validators.required, Validators.pattern(RESOURCE_QUANTITY_REGEX)],
       }),
     }),
+    pruneEnabled: new FormControl<boolean>(false, {nonNullable: true}),
+    prunePolicy: new FormGroup({
+      keepRevisions: new FormControl<number>(2, {
+        nonNullable: true,
+        validators: [Validators.required, Validators.min(1)],
+      }),
+      pruneDanglingImages: new FormControl<boolean>(true, {nonNullable: true}),
+      pruneStoppedContainers: new FormControl<boolean>(true, {nonNullable: true}),
+    }),
+    volumeBackupEnabled: new FormControl<boolean>(false, {nonNullable: true}),
+    volumeBackupPolicy: new FormGroup({
+      keepSnapshots: new FormControl<number>(3, {
+        nonNullable: true,
+        validators: [Validators.required, Validators.min(1)],
+      }),
+    }),
   });
   protected editFormLoading = false;
 
@@ -238,6 +262,20 @@ export class DeploymentTargetCardComponent {
         this.editForm.controls.resources.disable();
       }
     });
+    this.editForm.controls.pruneEnabled.valueChanges.pipe(takeUntilDestroyed()).subscribe((value) => {
+      if (value) {
+        this.editForm.controls.prunePolicy.enable();
+      } else {
+        this.editForm.controls.prunePolicy.disable();
+      }
+    });
+    this.editForm.controls.volumeBackupEnabled.valueChanges.pipe(takeUntilDestroyed()).subscribe((value) => {
+      if (value) {
+        this.editForm.controls.volumeBackupPolicy.enable();
+      } else {
+        this.editForm.controls.volumeBackupPolicy.disable();
+      }
+    });
   }
 
   protected async showDeploymentModal(deployment?: DeploymentWithLatestRevision) {
@@ -257,12 +295,25 @@ export class DeploymentTargetCardComponent {
         type: val.type!,
         deployments: [],
         metricsEnabled: val.metricsEnabled ?? false,
+        diskUsageWarningThreshold: val.diskUsageWarningThreshold ? val.diskUsageWarningThreshold / 100 : undefined,
+        httpProxy: val.httpProxy || undefined,
+        httpsProxy: val.httpsProxy || undefined,
+        noProxy: val.noProxy || undefined,
+        caCertificates: val.caCertificates || undefined,
         resources: val.resources && {
           cpuRequest: val.resources.cpuRequest!,
           cpuLimit: val.resources.cpuLimit!,
           memoryRequest: val.resources.memoryRequest!,
           memoryLimit: val.resources.memoryLimit!,
         },
+        prunePolicy: val.prunePolicy && {
+          keepRevisions: val.prunePolicy.keepRevisions!,
+          pruneDanglingImages: val.prunePolicy.pruneDanglingImages ?? false,
+          pruneStoppedContainers: val.prunePolicy.pruneStoppedContainers ?? false,
+        },
+        volumeBackupPolicy: val.volumeBackupPolicy && {
+          keepSnapshots: val.volumeBackupPolicy.keepSnapshots!,
+        },
       };
 
       try {
@@ -307,6 +358,10 @@ export class DeploymentTargetCardComponent {
     this.editForm.patchValue({
       ...dt,
       customResources: !!dt.resources,
+      pruneEnabled: !!dt.prunePolicy,
+      volumeBackupEnabled: !!dt.volumeBackupPolicy,
+      diskUsageWarningThreshold:
+        dt.diskUsageWarningThreshold !== undefined ? Math.round(dt.diskUsageWarningThreshold * 100) : undefined,
     });
     if (dt.scope === 'namespace') {
       this.editForm.controls.metricsEnabled.disable();
@@ -319,6 +374,15 @@ export class DeploymentTargetCardComponent {
       this.editForm.controls.customResources.setValue(false);
       this.editForm.controls.customResources.disable();
     }
+    if (dt.type === 'docker') {
+      this.editForm.controls.pruneEnabled.enable();
+      this.editForm.controls.volumeBackupEnabled.enable();
+    } else {
+      this.editForm.controls.pruneEnabled.setValue(false);
+      this.editForm.controls.pruneEnabled.disable();
+      this.editForm.controls.volumeBackupEnabled.setValue(false);
+      this.editForm.controls.volumeBackupEnabled.disable();
+    }
   }
 
   protected async openInstructionsModal() {
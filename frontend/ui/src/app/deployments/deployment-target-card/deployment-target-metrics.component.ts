@@ -1,12 +1,12 @@
 import {OverlayModule} from '@angular/cdk/overlay';
 import {PercentPipe} from '@angular/common';
-import {Component, input} from '@angular/core';
+import {Component, computed, input} from '@angular/core';
 import {ReactiveFormsModule} from '@angular/forms';
 import {BytesPipe} from '../../../util/units';
 import {drawerFlyInOut} from '../../animations/drawer';
 import {dropdownAnimation} from '../../animations/dropdown';
 import {modalFlyInOut} from '../../animations/modal';
-import {DeploymentTargetLatestMetrics} from '../../services/deployment-target-metrics.service';
+import {AgentDiskMetrics, DeploymentTargetLatestMetrics} from '../../services/deployment-target-metrics.service';
 
 @Component({
   selector: 'app-deployment-target-metrics',
@@ -19,6 +19,26 @@ export class DeploymentTargetMetricsComponent {
   public readonly fullVersion = input(false);
   public readonly metrics = input.required<DeploymentTargetLatestMetrics>();
 
+  protected readonly diskUsage = computed(() => {
+    const disks = this.metrics().disks ?? [];
+    return disks.length > 0 ? Math.max(...disks.map((disk) => this.getDiskUsage(disk))) : undefined;
+  });
+
+  protected getDiskUsage(disk: AgentDiskMetrics): number {
+    return disk.totalBytes > 0 ? disk.usedBytes / disk.totalBytes : 0;
+  }
+
+  protected getDiskName(disk: AgentDiskMetrics): string {
+    switch (disk.type) {
+      case 'dockerRoot':
+        return 'Docker root directory';
+      case 'volume':
+        return `Volume ${disk.name}`;
+      case 'persistentVolumeClaim':
+        return `PVC ${disk.name}`;
+    }
+  }
+
   protected getPercentClass(usage: number | undefined): string {
     const val = Math.ceil((usage || 0) * 100);
     const mod5 = val % 5;
@@ -44,6 +44,7 @@ export class OrganizationSettingsComponent implements OnInit {
     postConnectScript: this.fb.control<string | undefined>(undefined),
     connectScriptIsSudo: this.fb.control<boolean>(false),
     artifactVersionMutable: this.fb.control<boolean>(false),
+    artifactSignatureRequired: this.fb.control<boolean>(false),
   });
   formLoading = signal(false);
 
@@ -56,6 +57,7 @@ export class OrganizationSettingsComponent implements OnInit {
       this.form.patchValue({
         ...this.organization,
         artifactVersionMutable: this.organization.features?.includes('artifact_version_mutable') ?? false,
+        artifactSignatureRequired: this.organization.features?.includes('artifact_signature_required') ?? false,
       });
     } catch (e) {
       const msg = getFormDisplayedError(e);
@@ -79,6 +81,7 @@ export class OrganizationSettingsComponent implements OnInit {
             postConnectScript: this.form.value.postConnectScript?.trim(),
             connectScriptIsSudo: this.form.value.connectScriptIsSudo ?? false,
             artifactVersionMutable: this.form.value.artifactVersionMutable ?? false,
+            artifactSignatureRequired: this.form.value.artifactSignatureRequired ?? false,
           })
         );
         this.toast.success('Settings saved successfully');
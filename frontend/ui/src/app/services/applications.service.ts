@@ -65,6 +65,10 @@ export class ApplicationsService implements CrudService<Application> {
     return this.getFile(applicationId, versionId, 'compose-file');
   }
 
+  getManifestFile(applicationId: string, versionId: string): Observable<string | null> {
+    return this.getFile(applicationId, versionId, 'manifest-file');
+  }
+
   private getFile(applicationId: string, versionId: string, file: string): Observable<string | null> {
     return this.httpClient
       .get(`${this.applicationsUrl}/${applicationId}/versions/${versionId}/${file}`, {responseType: 'text'})
@@ -98,7 +102,8 @@ export class ApplicationsService implements CrudService<Application> {
     application: Application,
     applicationVersion: ApplicationVersion,
     baseValues?: string | null,
-    template?: string | null
+    template?: string | null,
+    manifests?: string | null
   ): Observable<ApplicationVersion> {
     const formData = new FormData();
     if (baseValues) {
@@ -107,6 +112,9 @@ export class ApplicationsService implements CrudService<Application> {
     if (template) {
       formData.append('templatefile', new Blob([template], {type: 'application/yaml'}));
     }
+    if (manifests) {
+      formData.append('manifestfile', new Blob([manifests], {type: 'application/yaml'}));
+    }
     return this.doCreateVersion(application, applicationVersion, formData);
   }
 
@@ -29,6 +29,26 @@ export interface Vulnerability {
   severity: VulnerabilitySeverity[];
 }
 
+export interface VulnerabilityCounts {
+  critical: number;
+  high: number;
+  medium: number;
+  low: number;
+  unknown: number;
+}
+
+export interface ArtifactVersionAttachment {
+  id: string;
+  createdAt: string;
+  type: 'sbom' | 'vulnerability_report';
+  format: 'spdx' | 'cyclonedx' | 'sarif';
+  mediaType: string;
+  size: number;
+  packageCount?: number;
+  licenses?: string[];
+  vulnerabilities?: VulnerabilityCounts;
+}
+
 export interface BaseArtifact {
   id: string;
   name: string;
@@ -53,6 +73,8 @@ export interface TaggedArtifactVersion extends HasDownloads {
   lastScannedAt?: string;
   imageUrl?: string;
   inferredType: 'generic' | 'container-image' | 'helm-chart';
+  signatureStatus?: 'verified' | 'unverified';
+  attachments?: ArtifactVersionAttachment[];
 }
 
 export interface ArtifactWithTags extends Artifact {
@@ -96,6 +118,12 @@ export class ArtifactsService {
       .pipe(tap((it) => this.cache.save(it)));
   }
 
+  public downloadAttachment(artifactId: string, attachmentId: string): Observable<Blob> {
+    return this.http.get(`${this.artifactsUrl}/${artifactId}/attachments/${attachmentId}/download`, {
+      responseType: 'blob',
+    });
+  }
+
   public deleteArtifact(artifactId: string): Observable<void> {
     return this.http.delete<void>(`${this.artifactsUrl}/${artifactId}`).pipe(
       tap(() => {
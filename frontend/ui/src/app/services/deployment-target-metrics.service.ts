@@ -2,15 +2,25 @@ import {HttpClient} from '@angular/common/http';
 import {inject, Injectable} from '@angular/core';
 import {Observable, shareReplay, switchMap, timer} from 'rxjs';
 
+export interface AgentDiskMetrics {
+  type: 'dockerRoot' | 'volume' | 'persistentVolumeClaim';
+  name?: string;
+  totalBytes: number;
+  usedBytes: number;
+}
+
 interface AgentDeploymentTargetMetrics {
   cpuCoresMillis: number;
   cpuUsage: number;
   memoryBytes: number;
   memoryUsage: number;
+  disks?: AgentDiskMetrics[];
 }
 
 export interface DeploymentTargetLatestMetrics extends AgentDeploymentTargetMetrics {
   id: string;
+  diskUsageWarningThreshold?: number;
+  diskUsageWarnings?: AgentDiskMetrics[];
 }
 
 @Injectable({
@@ -232,6 +232,7 @@ export class RegistryTutorialComponent implements OnInit, AfterViewInit, OnDestr
             this.organizationService.update({
               ...this.organization!,
               artifactVersionMutable: this.organization?.features.includes('artifact_version_mutable') ?? false,
+              artifactSignatureRequired: this.organization?.features.includes('artifact_signature_required') ?? false,
               slug: formVal.slug!,
             })
           );
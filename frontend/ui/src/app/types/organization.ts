@@ -1,7 +1,7 @@
 import {BaseModel, Named, UserRole} from '@distr-sh/distr-sdk';
 import {SubscriptionType} from './subscription';
 
-export type Feature = 'licensing' | 'pre_post_scripts' | 'artifact_version_mutable';
+export type Feature = 'licensing' | 'pre_post_scripts' | 'artifact_version_mutable' | 'artifact_signature_required';
 
 export interface SubscriptionLimits {
   maxCustomerOrganizations: number;
@@ -16,6 +16,7 @@ export interface CreateUpdateOrganizationRequest {
   postConnectScript?: string;
   connectScriptIsSudo: boolean;
   artifactVersionMutable: boolean;
+  artifactSignatureRequired: boolean;
 }
 
 export interface Organization extends BaseModel, Named {
@@ -1,5 +1,6 @@
 export const KUBERNETES_RESOURCE_MAX_LENGTH = 253;
 export const KUBERNETES_RESOURCE_NAME_REGEX = /^[a-z0-9]([-a-z0-9]*[a-z0-9])?$/;
+export const KUBERNETES_NAMESPACE_MAX_LENGTH = 63;
 export const HELM_RELEASE_NAME_REGEX = /^[a-z0-9]([-a-z0-9]*)?[a-z0-9]$/;
 export const HELM_RELEASE_NAME_MAX_LENGTH = 53;
 
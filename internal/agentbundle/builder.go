@@ -0,0 +1,112 @@
+package agentbundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/distr-sh/distr/api"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// Builder assembles a bundle in a temporary directory.
+// Use [Builder.WriteTo] to write the signed archive and [Builder.Close] to clean up afterwards.
+type Builder struct {
+	dir      string
+	metadata Metadata
+	resource api.AgentResource
+	store    *oci.Store
+}
+
+func NewBuilder(metadata Metadata, resource api.AgentResource) (*Builder, error) {
+	dir, err := os.MkdirTemp("", "distr-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	store, err := oci.New(path.Join(dir, OCILayoutDirName))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	metadata.FormatVersion = FormatVersion
+	return &Builder{dir: dir, metadata: metadata, resource: resource, store: store}, nil
+}
+
+// AddImage copies the image with the given reference from its registry into the bundle.
+func (b *Builder) AddImage(ctx context.Context, ref string, client remote.Client, plainHTTP bool) error {
+	if err := b.copyRemote(ctx, ref, client, plainHTTP); err != nil {
+		return fmt.Errorf("could not add image %v: %w", ref, err)
+	}
+	b.metadata.Images = append(b.metadata.Images, ref)
+	return nil
+}
+
+// AddChart copies the OCI helm chart with the given reference from its registry into the bundle.
+func (b *Builder) AddChart(ctx context.Context, ref string, client remote.Client, plainHTTP bool) error {
+	if err := b.copyRemote(ctx, ref, client, plainHTTP); err != nil {
+		return fmt.Errorf("could not add chart %v: %w", ref, err)
+	}
+	b.metadata.Charts = append(b.metadata.Charts, ref)
+	return nil
+}
+
+// AddAgentImage copies the (publicly available) agent image into the bundle.
+func (b *Builder) AddAgentImage(ctx context.Context, ref string) error {
+	if err := b.copyRemote(ctx, ref, auth.DefaultClient, false); err != nil {
+		return fmt.Errorf("could not add agent image %v: %w", ref, err)
+	}
+	b.metadata.AgentImage = ref
+	return nil
+}
+
+func (b *Builder) copyRemote(ctx context.Context, ref string, client remote.Client, plainHTTP bool) error {
+	parsedRef, err := registry.ParseReference(ref)
+	if err != nil {
+		return err
+	}
+	repo, err := remote.NewRepository(parsedRef.Registry + "/" + parsedRef.Repository)
+	if err != nil {
+		return err
+	}
+	repo.Client = client
+	repo.PlainHTTP = plainHTTP
+	if parsedRef.Reference == "" {
+		parsedRef.Reference = "latest"
+	}
+	// The full reference is used as tag so that "docker load" can restore the image name.
+	_, err = oras.Copy(ctx, repo, parsedRef.Reference, b.store, ref, oras.DefaultCopyOptions)
+	return err
+}
+
+// WriteTo writes the signed bundle archive to w. The resource is encrypted, because it contains secrets like env files
+// and registry credentials.
+func (b *Builder) WriteTo(w io.Writer, key []byte) error {
+	if err := writeJSONFile(path.Join(b.dir, MetadataFileName), b.metadata); err != nil {
+		return err
+	} else if resource, err := json.Marshal(b.resource); err != nil {
+		return err
+	} else if encrypted, err := encrypt(resource, key); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(b.dir, ResourceFileName), encrypted, 0o600); err != nil {
+		return err
+	} else if sums, err := checksums(b.dir); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(b.dir, ChecksumsFileName), sums, 0o600); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(b.dir, SignatureFileName), sign(sums, key), 0o600); err != nil {
+		return err
+	} else {
+		return writeTarGz(w, b.dir)
+	}
+}
+
+func (b *Builder) Close() error {
+	return os.RemoveAll(b.dir)
+}
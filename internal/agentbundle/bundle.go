@@ -0,0 +1,269 @@
+// Package agentbundle implements the archive format used to install deployments on deployment targets that can not
+// connect to the hub (air-gapped environments).
+//
+// A bundle is a gzip compressed tar archive with the following content:
+//
+//	bundle.json      metadata, see [Metadata]
+//	resource.enc     the [api.AgentResource] for the deployment, encrypted with AES-256-GCM
+//	oci/             OCI image layout with all referenced images and charts
+//	SHA256SUMS       checksums of all files above
+//	SHA256SUMS.sig   HMAC-SHA256 of SHA256SUMS, keyed with the bundle key of the deployment target
+//
+// The bundle key of a deployment target is derived from a key that is only known to the hub (see [TargetKey]) and
+// handed to the agent together with its credentials. It is never stored in the database, so the bundle can only be
+// read and created by the hub and by the agent of the deployment target.
+package agentbundle
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/google/uuid"
+)
+
+const (
+	FormatVersion = 3
+
+	MetadataFileName  = "bundle.json"
+	ResourceFileName  = "resource.enc"
+	OCILayoutDirName  = "oci"
+	ChecksumsFileName = "SHA256SUMS"
+	SignatureFileName = "SHA256SUMS.sig"
+)
+
+var ErrInvalidSignature = errors.New("bundle signature is invalid")
+
+type Metadata struct {
+	FormatVersion      int       `json:"formatVersion"`
+	CreatedAt          time.Time `json:"createdAt"`
+	DeploymentTargetID uuid.UUID `json:"deploymentTargetId"`
+	DeploymentID       uuid.UUID `json:"deploymentId"`
+	RevisionID         uuid.UUID `json:"revisionId"`
+	Images             []string  `json:"images,omitempty"`
+	Charts             []string  `json:"charts,omitempty"`
+	AgentImage         string    `json:"agentImage,omitempty"`
+}
+
+// TargetKey derives the bundle key of the given deployment target from the bundle key of the hub.
+func TargetKey(hubKey []byte, deploymentTargetID uuid.UUID) []byte {
+	mac := hmac.New(sha256.New, hubKey)
+	mac.Write([]byte("distr bundle key "))
+	mac.Write([]byte(deploymentTargetID.String()))
+	return mac.Sum(nil)
+}
+
+// Bundle is an extracted and verified bundle.
+type Bundle struct {
+	Dir      string
+	Metadata Metadata
+	Resource api.AgentResource
+}
+
+func (b *Bundle) OCILayoutDir() string {
+	return path.Join(b.Dir, OCILayoutDirName)
+}
+
+// Close removes the extracted bundle content.
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.Dir)
+}
+
+// Open extracts the bundle at the given path into a temporary directory and verifies its integrity.
+// The signing key is obtained by calling keyFunc with the bundle metadata.
+func Open(name string, keyFunc func(Metadata) ([]byte, error)) (*Bundle, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dir, err := os.MkdirTemp("", "distr-bundle-")
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := Bundle{Dir: dir}
+	if err := extractTarGz(file, dir); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("could not extract bundle: %w", err)
+	} else if err := readJSONFile(path.Join(dir, MetadataFileName), &bundle.Metadata); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("could not read bundle metadata: %w", err)
+	} else if bundle.Metadata.FormatVersion != FormatVersion {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("unsupported bundle format version: %v", bundle.Metadata.FormatVersion)
+	} else if key, err := keyFunc(bundle.Metadata); err != nil {
+		_ = bundle.Close()
+		return nil, err
+	} else if err := verify(dir, key); err != nil {
+		_ = bundle.Close()
+		return nil, err
+	} else if encrypted, err := os.ReadFile(path.Join(dir, ResourceFileName)); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("could not read bundle resource: %w", err)
+	} else if resource, err := decrypt(encrypted, key); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("could not decrypt bundle resource: %w", err)
+	} else if err := json.Unmarshal(resource, &bundle.Resource); err != nil {
+		_ = bundle.Close()
+		return nil, fmt.Errorf("could not read bundle resource: %w", err)
+	} else {
+		return &bundle, nil
+	}
+}
+
+// encryptionKey derives the key that is used to encrypt the resource from the signing key, so that the same key is
+// never used for both.
+func encryptionKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("distr bundle resource encryption"))
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if block, err := aes.NewCipher(encryptionKey(key)); err != nil {
+		return nil, err
+	} else {
+		return cipher.NewGCM(block)
+	}
+}
+
+// encrypt returns the nonce followed by the AES-GCM encrypted data.
+func encrypt(data, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func decrypt(data, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	} else if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// checksums returns the content of the SHA256SUMS file for all files in dir.
+func checksums(dir string) ([]byte, error) {
+	var lines []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ChecksumsFileName || rel == SignatureFileName {
+			return nil
+		}
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("%x  %v\n", h.Sum(nil), rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(lines)
+	return []byte(strings.Join(lines, "")), nil
+}
+
+func sign(data, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return []byte(hex.EncodeToString(mac.Sum(nil)))
+}
+
+func verify(dir string, key []byte) error {
+	expectedSums, err := os.ReadFile(path.Join(dir, ChecksumsFileName))
+	if err != nil {
+		return fmt.Errorf("could not read checksums: %w", err)
+	}
+	signature, err := os.ReadFile(path.Join(dir, SignatureFileName))
+	if err != nil {
+		return fmt.Errorf("could not read signature: %w", err)
+	}
+	if !hmac.Equal(bytes.TrimSpace(signature), sign(expectedSums, key)) {
+		return ErrInvalidSignature
+	}
+	if actualSums, err := checksums(dir); err != nil {
+		return err
+	} else if !bytes.Equal(actualSums, expectedSums) {
+		return fmt.Errorf("bundle content does not match checksums: %v", diffChecksums(expectedSums, actualSums))
+	}
+	return nil
+}
+
+func diffChecksums(expected, actual []byte) string {
+	actualLines := map[string]struct{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(actual))
+	for scanner.Scan() {
+		actualLines[scanner.Text()] = struct{}{}
+	}
+	var mismatched []string
+	scanner = bufio.NewScanner(bytes.NewReader(expected))
+	for scanner.Scan() {
+		if _, ok := actualLines[scanner.Text()]; !ok {
+			if _, name, ok := strings.Cut(scanner.Text(), "  "); ok {
+				mismatched = append(mismatched, name)
+			}
+		}
+	}
+	if len(mismatched) == 0 {
+		return "unexpected files"
+	}
+	return strings.Join(mismatched, ", ")
+}
+
+func readJSONFile(name string, v any) error {
+	if file, err := os.Open(name); err != nil {
+		return err
+	} else {
+		defer file.Close()
+		return json.NewDecoder(file).Decode(v)
+	}
+}
+
+func writeJSONFile(name string, v any) error {
+	if file, err := os.Create(name); err != nil {
+		return err
+	} else {
+		defer file.Close()
+		return json.NewEncoder(file).Encode(v)
+	}
+}
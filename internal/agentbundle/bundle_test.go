@@ -0,0 +1,62 @@
+package agentbundle_test
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentbundle"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func writeBundle(t *testing.T, g *WithT, key []byte) (string, agentbundle.Metadata) {
+	metadata := agentbundle.Metadata{DeploymentTargetID: uuid.New(), DeploymentID: uuid.New()}
+	resource := api.AgentResource{Deployments: []api.AgentDeployment{{ID: metadata.DeploymentID}}}
+	builder, err := agentbundle.NewBuilder(metadata, resource)
+	g.Expect(err).NotTo(HaveOccurred())
+	defer builder.Close()
+	var buf bytes.Buffer
+	g.Expect(builder.WriteTo(&buf, key)).To(Succeed())
+	name := path.Join(t.TempDir(), "bundle.tar.gz")
+	g.Expect(os.WriteFile(name, buf.Bytes(), 0o600)).To(Succeed())
+	return name, metadata
+}
+
+func TestOpen(t *testing.T) {
+	g := NewWithT(t)
+	hubKey := []byte("hub key")
+	key := agentbundle.TargetKey(hubKey, uuid.New())
+	name, metadata := writeBundle(t, g, key)
+	bundle, err := agentbundle.Open(name, func(md agentbundle.Metadata) ([]byte, error) {
+		g.Expect(md.DeploymentTargetID).To(Equal(metadata.DeploymentTargetID))
+		return key, nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	defer bundle.Close()
+	g.Expect(bundle.Metadata.FormatVersion).To(Equal(agentbundle.FormatVersion))
+	g.Expect(bundle.Metadata.DeploymentID).To(Equal(metadata.DeploymentID))
+	g.Expect(bundle.Resource.Deployments).To(HaveLen(1))
+	g.Expect(bundle.Resource.Deployments[0].ID).To(Equal(metadata.DeploymentID))
+}
+
+func TestOpenInvalidKey(t *testing.T) {
+	g := NewWithT(t)
+	name, _ := writeBundle(t, g, []byte("key"))
+	_, err := agentbundle.Open(name, func(md agentbundle.Metadata) ([]byte, error) {
+		return []byte("other key"), nil
+	})
+	g.Expect(err).To(MatchError(agentbundle.ErrInvalidSignature))
+}
+
+func TestTargetKey(t *testing.T) {
+	g := NewWithT(t)
+	targetID := uuid.New()
+	key := agentbundle.TargetKey([]byte("hub key"), targetID)
+	g.Expect(key).To(HaveLen(32))
+	g.Expect(agentbundle.TargetKey([]byte("hub key"), targetID)).To(Equal(key))
+	g.Expect(agentbundle.TargetKey([]byte("hub key"), uuid.New())).NotTo(Equal(key))
+	g.Expect(agentbundle.TargetKey([]byte("other hub key"), targetID)).NotTo(Equal(key))
+}
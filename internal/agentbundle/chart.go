@@ -0,0 +1,66 @@
+package agentbundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+)
+
+const helmChartContentMediaType = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+
+// ChartReference returns the OCI reference for the given helm chart URL (with "oci://" scheme) and version.
+func ChartReference(chartUrl, chartVersion string) string {
+	return strings.TrimPrefix(chartUrl, "oci://") + ":" + chartVersion
+}
+
+// ExtractChart writes the chart archive of the OCI helm chart with the given reference to dst.
+func (b *Bundle) ExtractChart(ctx context.Context, ref, dst string) error {
+	store, err := oci.NewFromFS(ctx, os.DirFS(b.OCILayoutDir()))
+	if err != nil {
+		return err
+	}
+	desc, err := store.Resolve(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("chart %v not found in bundle: %w", ref, err)
+	}
+	manifestData, err := content.FetchAll(ctx, store, desc)
+	if err != nil {
+		return err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return err
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == helmChartContentMediaType {
+			return writeBlob(ctx, store, layer, dst)
+		}
+	}
+	return errors.New("manifest does not contain a helm chart layer")
+}
+
+func writeBlob(ctx context.Context, store content.Fetcher, desc ocispec.Descriptor, dst string) error {
+	rc, err := store.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	vr := content.NewVerifyReader(rc, desc)
+	if _, err := io.Copy(file, vr); err != nil {
+		return err
+	}
+	return vr.Verify()
+}
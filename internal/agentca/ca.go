@@ -0,0 +1,186 @@
+package agentca
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotEnabled = errors.New("agent mTLS is not enabled")
+	ErrRevoked    = errors.New("certificate was revoked")
+)
+
+type ca struct {
+	certificate *x509.Certificate
+	key         crypto.Signer
+}
+
+// getCA parses the CA certificate and key from the environment.
+var getCA = sync.OnceValues(func() (*ca, error) {
+	config := env.GetAgentMTLSConfig()
+	if config == nil {
+		return nil, ErrNotEnabled
+	}
+
+	certBlock, _ := pem.Decode(config.CACertificate)
+	if certBlock == nil {
+		return nil, errors.New("CA certificate is not PEM encoded")
+	}
+	certificate, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(config.CAKey)
+	if keyBlock == nil {
+		return nil, errors.New("CA key is not PEM encoded")
+	}
+	var key any
+	switch keyBlock.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CA key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("CA key can not be used for signing")
+	}
+
+	return &ca{certificate: certificate, key: signer}, nil
+})
+
+// Enabled returns true if the hub is configured to issue client certificates for agents.
+func Enabled() bool {
+	return env.GetAgentMTLSConfig() != nil
+}
+
+// Certificate is a PEM encoded client certificate and the corresponding private key.
+type Certificate struct {
+	Certificate []byte
+	Key         []byte
+}
+
+// IssueCertificate creates a new client certificate for the given deployment target.
+// The certificate is recorded in the database, so that it can be revoked when the deployment target is reconnected.
+func IssueCertificate(ctx context.Context, deploymentTargetID, organizationID uuid.UUID) (*Certificate, error) {
+	ca, err := getCA()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   deploymentTargetID.String(),
+			Organization: []string{organizationID.String()},
+		},
+		// allow for some clock skew between hub and agent
+		NotBefore:   now.Add(-5 * time.Minute),
+		NotAfter:    now.Add(env.GetAgentMTLSConfig().CertificateValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, ca.certificate, key.Public(), ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create certificate: %w", err)
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.CreateDeploymentTargetCertificate(
+		ctx, deploymentTargetID, SerialNumber(&template), template.NotAfter,
+	); err != nil {
+		return nil, err
+	}
+
+	return &Certificate{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:         pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}),
+	}, nil
+}
+
+// VerifyCertificate checks that the given client certificate was issued by the CA of the hub and was not revoked.
+func VerifyCertificate(ctx context.Context, certificate *x509.Certificate) error {
+	ca, err := getCA()
+	if err != nil {
+		return err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.certificate)
+	if _, err := certificate.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return err
+	}
+
+	if deploymentTargetID, _, err := Identity(certificate); err != nil {
+		return err
+	} else if valid, err := db.IsDeploymentTargetCertificateValid(
+		ctx, deploymentTargetID, SerialNumber(certificate),
+	); err != nil {
+		return err
+	} else if !valid {
+		return ErrRevoked
+	} else {
+		return nil
+	}
+}
+
+// Identity returns the deployment target ID and organization ID contained in the given client certificate.
+func Identity(certificate *x509.Certificate) (deploymentTargetID uuid.UUID, organizationID uuid.UUID, err error) {
+	if deploymentTargetID, err = uuid.Parse(certificate.Subject.CommonName); err != nil {
+		return deploymentTargetID, organizationID, fmt.Errorf("certificate common name is invalid: %w", err)
+	} else if len(certificate.Subject.Organization) != 1 {
+		return deploymentTargetID, organizationID, errors.New("certificate must have exactly one organization")
+	} else if organizationID, err = uuid.Parse(certificate.Subject.Organization[0]); err != nil {
+		return deploymentTargetID, organizationID, fmt.Errorf("certificate organization is invalid: %w", err)
+	} else {
+		return deploymentTargetID, organizationID, nil
+	}
+}
+
+// SerialNumber returns the serial number of the certificate in the format that is stored in the database.
+func SerialNumber(certificate *x509.Certificate) string {
+	return certificate.SerialNumber.Text(16)
+}
+
+// NeedsRenewal returns true if less than a third of the validity of the given certificate remains.
+func NeedsRenewal(certificate *x509.Certificate) bool {
+	validity := certificate.NotAfter.Sub(certificate.NotBefore)
+	return time.Until(certificate.NotAfter) < validity/3
+}
@@ -0,0 +1,117 @@
+package agentclient
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/distr-sh/distr/api"
+)
+
+var ErrNoCachedResource = errors.New("no cached resource available")
+
+// ResourceCache stores the last [api.AgentResource] that was received from the hub.
+// The data passed to Save is already encrypted.
+type ResourceCache interface {
+	Save(data []byte) error
+	// Load returns the previously saved data or [ErrNoCachedResource] if nothing was saved yet.
+	Load() ([]byte, error)
+}
+
+type fileResourceCache struct {
+	name string
+}
+
+// NewFileResourceCache returns a [ResourceCache] that stores the resource in the file with the given name.
+func NewFileResourceCache(name string) ResourceCache {
+	return &fileResourceCache{name: name}
+}
+
+func (c *fileResourceCache) Save(data []byte) error {
+	// Write to a temporary file first so that the cache is never left in a partially written state.
+	tmpName := c.name + ".tmp"
+	if err := os.WriteFile(tmpName, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, c.name)
+}
+
+func (c *fileResourceCache) Load() ([]byte, error) {
+	data, err := os.ReadFile(c.name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoCachedResource
+	}
+	return data, err
+}
+
+// UseResourceCache configures the cache where the last resource received from the hub is stored.
+func (c *Client) UseResourceCache(cache ResourceCache) {
+	c.resourceCache = cache
+}
+
+// CachedResource returns the last resource that was received from the hub.
+func (c *Client) CachedResource() (*api.AgentResource, error) {
+	if c.resourceCache == nil {
+		return nil, ErrNoCachedResource
+	}
+	data, err := c.resourceCache.Load()
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := c.resourceCacheCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("cached resource is invalid")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt cached resource: %w", err)
+	}
+	var resource api.AgentResource
+	if err := json.Unmarshal(plaintext, &resource); err != nil {
+		return nil, fmt.Errorf("could not decode cached resource: %w", err)
+	}
+	return &resource, nil
+}
+
+func (c *Client) saveCachedResource(resource *api.AgentResource) error {
+	if c.resourceCache == nil {
+		return nil
+	}
+	plaintext, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	gcm, err := c.resourceCacheCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return c.resourceCache.Save(gcm.Seal(nonce, nonce, plaintext, nil))
+}
+
+// resourceCacheCipher returns the cipher used to encrypt the cached resource.
+// The key is derived from the target secret, so the cache can only be read by an agent with the same credentials.
+func (c *Client) resourceCacheCipher() (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, []byte(c.authSecret), []byte(c.authTarget), "distr agent resource cache", 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,76 @@
+package agentclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/distr-sh/distr/api"
+)
+
+// newTransport returns a transport that presents the current client certificate, if one is configured.
+// The certificate is looked up for every new connection, so that a renewed certificate is used without restarting.
+func (c *Client) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if certificate := c.certificate.Load(); certificate != nil {
+				return certificate, nil
+			}
+			// an empty certificate means that no certificate is sent
+			return &tls.Certificate{}, nil
+		},
+	}
+	return transport
+}
+
+func (c *Client) hasClientCertificate() bool {
+	return c.certificate.Load() != nil
+}
+
+// setClientCertificate parses the base64 encoded PEM certificate and key. If both are empty, no client certificate is
+// used.
+func (c *Client) setClientCertificate(certificate, key string) error {
+	if certificate == "" && key == "" {
+		c.certificate.Store(nil)
+		return nil
+	}
+	if certificatePEM, err := base64.StdEncoding.DecodeString(certificate); err != nil {
+		return fmt.Errorf("invalid client certificate: %w", err)
+	} else if keyPEM, err := base64.StdEncoding.DecodeString(key); err != nil {
+		return fmt.Errorf("invalid client key: %w", err)
+	} else if keyPair, err := tls.X509KeyPair(certificatePEM, keyPEM); err != nil {
+		return fmt.Errorf("invalid client certificate: %w", err)
+	} else {
+		c.certificate.Store(&keyPair)
+		return nil
+	}
+}
+
+// applyClientCertificate switches the client to the renewed client certificate issued by the hub.
+func (c *Client) applyClientCertificate(ctx context.Context, certificate api.AgentClientCertificate) error {
+	if err := c.setClientCertificate(certificate.Certificate, certificate.Key); err != nil {
+		return err
+	}
+	c.clientCertificate = certificate.Certificate
+	c.clientKey = certificate.Key
+
+	// Update the environment, so that ReloadFromEnv does not revert to the previous certificate.
+	if err := os.Setenv("DISTR_CLIENT_CERTIFICATE", certificate.Certificate); err != nil {
+		return err
+	} else if err := os.Setenv("DISTR_CLIENT_KEY", certificate.Key); err != nil {
+		return err
+	}
+
+	c.logger.Info("client certificate renewed")
+
+	c.storeSecrets(ctx, map[string]string{
+		"DISTR_CLIENT_CERTIFICATE": certificate.Certificate,
+		"DISTR_CLIENT_KEY":         certificate.Key,
+	})
+
+	return nil
+}
@@ -3,12 +3,14 @@ package agentclient
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/distr-sh/distr/api"
@@ -34,15 +36,25 @@ type clientData struct {
 	metricsEndpoint              string
 	deploymentLogsEndpoint       string
 	deploymentTargetLogsEndpoint string
+	deploymentEventsEndpoint     string
+	volumeSnapshotsEndpoint      string
+	clientCertificate            string
+	clientKey                    string
 }
 
 type Client struct {
 	clientData
-	httpClient *http.Client
-	logger     *zap.Logger
-	token      jwt.Token
-	rawToken   string
-	mutex      sync.Mutex
+	httpClient     *http.Client
+	logger         *zap.Logger
+	token          jwt.Token
+	rawToken       string
+	mutex          sync.Mutex
+	outbox         Outbox
+	offline        bool
+	hubUnreachable atomic.Bool
+	resourceCache  ResourceCache
+	secretStore    SecretStore
+	certificate    atomic.Pointer[tls.Certificate]
 }
 
 func (c *Client) Resource(ctx context.Context) (*api.AgentResource, error) {
@@ -52,10 +64,33 @@ func (c *Client) Resource(ctx context.Context) (*api.AgentResource, error) {
 	} else {
 		req.Header.Set("Content-Type", "application/json")
 		if resp, err := c.doAuthenticated(ctx, req, true); err != nil {
+			c.hubUnreachable.Store(true)
 			return nil, err
 		} else if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 			return nil, err
 		} else {
+			c.hubUnreachable.Store(false)
+			if result.AccessKeyRotation != nil {
+				if err := c.applyAccessKeyRotation(ctx, *result.AccessKeyRotation); err != nil {
+					c.logger.Warn("could not rotate target secret", zap.Error(err))
+				}
+				result.AccessKeyRotation = nil
+			}
+			if result.ClientCertificate != nil {
+				if err := c.applyClientCertificate(ctx, *result.ClientCertificate); err != nil {
+					c.logger.Warn("could not renew client certificate", zap.Error(err))
+				}
+				result.ClientCertificate = nil
+			}
+			if result.BundleKey != "" {
+				if err := c.applyBundleKey(ctx, result.BundleKey); err != nil {
+					c.logger.Warn("could not update bundle key", zap.Error(err))
+				}
+				result.BundleKey = ""
+			}
+			if err := c.saveCachedResource(&result); err != nil {
+				c.logger.Warn("could not update cached resource", zap.Error(err))
+			}
 			return &result, nil
 		}
 	}
@@ -88,6 +123,13 @@ func (c *Client) Status(
 		Message:    message,
 		Type:       statusType,
 	}
+	return c.sendOrEnqueue(
+		OutboxEntry{Status: &deploymentStatus},
+		func() error { return c.sendStatus(ctx, deploymentStatus) },
+	)
+}
+
+func (c *Client) sendStatus(ctx context.Context, deploymentStatus api.AgentDeploymentStatus) error {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(deploymentStatus); err != nil {
 		return err
@@ -104,6 +146,13 @@ func (c *Client) Status(
 }
 
 func (c *Client) ExportDeploymentLogs(ctx context.Context, records []api.DeploymentLogRecord) error {
+	return c.sendOrEnqueue(
+		OutboxEntry{DeploymentLogs: records},
+		func() error { return c.sendDeploymentLogs(ctx, records) },
+	)
+}
+
+func (c *Client) sendDeploymentLogs(ctx context.Context, records []api.DeploymentLogRecord) error {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(records); err != nil {
 		return err
@@ -116,25 +165,89 @@ func (c *Client) ExportDeploymentLogs(ctx context.Context, records []api.Deploym
 	}
 }
 
+// ExportDeploymentEvents sends the given Kubernetes events to the hub.
+// It does nothing if the agent was installed with a manifest that does not contain an events endpoint.
+func (c *Client) ExportDeploymentEvents(ctx context.Context, records []api.DeploymentEventRecord) error {
+	if c.deploymentEventsEndpoint == "" {
+		return nil
+	}
+	return c.sendOrEnqueue(
+		OutboxEntry{DeploymentEvents: records},
+		func() error { return c.sendDeploymentEvents(ctx, records) },
+	)
+}
+
+func (c *Client) sendDeploymentEvents(ctx context.Context, records []api.DeploymentEventRecord) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(records); err != nil {
+		return err
+	} else if req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.deploymentEventsEndpoint, &buf); err != nil {
+		return err
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+		_, err := c.doAuthenticated(ctx, req, true)
+		return err
+	}
+}
+
+// ReportVolumeSnapshots sends all volume snapshots that currently exist on the agent to the hub.
+// It does nothing if the agent was installed with a manifest that does not contain a volume snapshots endpoint.
+func (c *Client) ReportVolumeSnapshots(ctx context.Context, snapshots []api.DeploymentVolumeSnapshot) error {
+	if c.volumeSnapshotsEndpoint == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(snapshots); err != nil {
+		return err
+	} else if req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.volumeSnapshotsEndpoint, &buf); err != nil {
+		return err
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+		_, err := c.doAuthenticated(ctx, req, true)
+		return err
+	}
+}
+
 func (c *Client) ExportDeploymentTargetLogs(records ...api.DeploymentTargetLogRecord) error {
+	return c.sendOrEnqueue(
+		OutboxEntry{DeploymentTargetLogs: records},
+		func() error { return c.sendDeploymentTargetLogs(context.TODO(), records, false) },
+	)
+}
+
+func (c *Client) sendDeploymentTargetLogs(
+	ctx context.Context,
+	records []api.DeploymentTargetLogRecord,
+	loggingEnabled bool,
+) error {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(records); err != nil {
 		return err
-	} else if req, err := http.NewRequest(http.MethodPut, c.deploymentTargetLogsEndpoint, &buf); err != nil {
+	} else if req, err := http.NewRequestWithContext(
+		ctx, http.MethodPut, c.deploymentTargetLogsEndpoint, &buf,
+	); err != nil {
 		return err
 	} else {
 		req.Header.Set("Content-Type", "application/json")
-		_, err := c.doAuthenticated(context.TODO(), req, false)
+		_, err := c.doAuthenticated(ctx, req, loggingEnabled)
 		return err
 	}
 }
 
+// Login obtains a new token from the hub. If a client certificate is configured, the agent authenticates with the
+// certificate instead of the target secret. The token is still needed to authenticate with the registry.
 func (c *Client) Login(ctx context.Context) error {
+	return c.login(ctx, !c.hasClientCertificate())
+}
+
+func (c *Client) login(ctx context.Context, useSecret bool) error {
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.loginEndpoint, nil)
 	if err != nil {
 		return err
 	}
-	req.SetBasicAuth(c.authTarget, c.authSecret)
+	if useSecret {
+		req.SetBasicAuth(c.authTarget, c.authSecret)
+	}
 	if resp, err := c.do(req); err != nil {
 		return err
 	} else {
@@ -190,6 +303,13 @@ func (c *Client) RawToken() string {
 }
 
 func (c *Client) ReportMetrics(ctx context.Context, metrics api.AgentDeploymentTargetMetrics) error {
+	return c.sendOrEnqueue(
+		OutboxEntry{Metrics: &metrics},
+		func() error { return c.sendMetrics(ctx, metrics) },
+	)
+}
+
+func (c *Client) sendMetrics(ctx context.Context, metrics api.AgentDeploymentTargetMetrics) error {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(metrics); err != nil {
 		return err
@@ -230,7 +350,11 @@ func (c *Client) doAuthenticatedNoRetry(
 	if err := c.EnsureToken(ctx, loggingEnabled); err != nil {
 		return nil, err
 	} else {
-		r.Header.Set("Authorization", "Bearer "+c.rawToken)
+		// Agents with a client certificate are authenticated by the certificate. Sending the token as well would
+		// make the hub authenticate the request with the token instead.
+		if !c.hasClientCertificate() {
+			r.Header.Set("Authorization", "Bearer "+c.rawToken)
+		}
 		return c.do(r)
 	}
 }
@@ -261,8 +385,15 @@ func (c *Client) ReloadFromEnv() (changed bool, err error) {
 	} else if d.deploymentTargetLogsEndpoint, err = readEnvVar("DISTR_AGENT_LOGS_ENDPOINT"); err != nil {
 		return changed, err
 	} else {
+		d.deploymentEventsEndpoint = os.Getenv("DISTR_EVENTS_ENDPOINT")
+		d.volumeSnapshotsEndpoint = os.Getenv("DISTR_VOLUME_SNAPSHOTS_ENDPOINT")
+		d.clientCertificate = os.Getenv("DISTR_CLIENT_CERTIFICATE")
+		d.clientKey = os.Getenv("DISTR_CLIENT_KEY")
 		changed = c.clientData != d
 		if changed {
+			if err := c.setClientCertificate(d.clientCertificate, d.clientKey); err != nil {
+				return false, err
+			}
 			c.clientData = d
 			c.ClearToken()
 		}
@@ -271,10 +402,8 @@ func (c *Client) ReloadFromEnv() (changed bool, err error) {
 }
 
 func NewFromEnv(logger *zap.Logger) (*Client, error) {
-	client := Client{
-		httpClient: &http.Client{},
-		logger:     logger,
-	}
+	client := Client{logger: logger}
+	client.httpClient = &http.Client{Transport: client.newTransport()}
 	if _, err := client.ReloadFromEnv(); err != nil {
 		return nil, err
 	}
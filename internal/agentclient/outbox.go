@@ -0,0 +1,249 @@
+package agentclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/httpstatus"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// maxFileOutboxSize is the maximum size of a file based outbox.
+// New entries are dropped if the outbox grows beyond this size.
+const maxFileOutboxSize = 64 * 1024 * 1024
+
+var ErrOutboxFull = errors.New("outbox is full")
+
+// OutboxEntry is a request to the hub that could not be sent because the agent is offline or the hub is unreachable.
+// Exactly one of the payload fields is set.
+type OutboxEntry struct {
+	CreatedAt            time.Time                         `json:"createdAt"`
+	Status               *api.AgentDeploymentStatus        `json:"status,omitempty"`
+	Metrics              *api.AgentDeploymentTargetMetrics `json:"metrics,omitempty"`
+	DeploymentLogs       []api.DeploymentLogRecord         `json:"deploymentLogs,omitempty"`
+	DeploymentTargetLogs []api.DeploymentTargetLogRecord   `json:"deploymentTargetLogs,omitempty"`
+	DeploymentEvents     []api.DeploymentEventRecord       `json:"deploymentEvents,omitempty"`
+}
+
+// Outbox is a persistent queue of requests that are sent to the hub once the agent is online again.
+type Outbox interface {
+	Append(entry OutboxEntry) error
+	// Peek returns the entries of the outbox in the order they were added without removing them.
+	Peek() ([]OutboxEntry, error)
+	// Ack removes the first n entries that were returned by the last call to Peek.
+	Ack(n int) error
+}
+
+// UseOutbox configures the outbox that is used to queue requests while the hub can not be reached.
+func (c *Client) UseOutbox(outbox Outbox) {
+	c.outbox = outbox
+}
+
+// SetOffline enables or disables the offline mode. In offline mode, status updates, metrics and logs are added to the
+// outbox instead of being sent to the hub.
+func (c *Client) SetOffline(offline bool) {
+	c.offline = offline
+}
+
+// IsOffline returns true if the offline mode is enabled or if the last attempt to get the resource from the hub failed.
+func (c *Client) IsOffline() bool {
+	return c.offline || c.hubUnreachable.Load()
+}
+
+// sendOrEnqueue calls send unless the client is offline. If the client is offline or send fails because the hub is
+// unreachable, the entry is added to the outbox instead.
+func (c *Client) sendOrEnqueue(entry OutboxEntry, send func() error) error {
+	if c.offline || (c.outbox != nil && c.hubUnreachable.Load()) {
+		return c.enqueue(entry)
+	} else if err := send(); err == nil || c.outbox == nil || isRejected(err) {
+		// Requests that were rejected by the hub are not retried.
+		return err
+	} else if err1 := c.enqueue(entry); err1 != nil {
+		return multierr.Append(err, err1)
+	} else {
+		return nil
+	}
+}
+
+func (c *Client) enqueue(entry OutboxEntry) error {
+	if c.outbox == nil {
+		return errors.New("client is offline and no outbox is configured")
+	}
+	entry.CreatedAt = time.Now()
+	if entry.Status != nil && entry.Status.CreatedAt == nil {
+		entry.Status.CreatedAt = &entry.CreatedAt
+	}
+	if entry.Metrics != nil && entry.Metrics.CreatedAt == nil {
+		entry.Metrics.CreatedAt = &entry.CreatedAt
+	}
+	return c.outbox.Append(entry)
+}
+
+// isRejected returns true if err is a response of the hub that would not change if the request was sent again.
+func isRejected(err error) bool {
+	return errors.Is(err, httpstatus.ErrHttpStatus) && !errors.Is(err, httpstatus.ErrHttpServerStatus)
+}
+
+// FlushOutbox sends the entries of the outbox to the hub in the order they were added.
+// Entries are only removed from the outbox after they were sent. Sending stops at the first entry that fails, so that
+// it is retried together with all later entries on the next call.
+func (c *Client) FlushOutbox(ctx context.Context) error {
+	if c.outbox == nil || c.IsOffline() {
+		return nil
+	}
+	entries, err := c.outbox.Peek()
+	if err != nil {
+		return fmt.Errorf("could not read outbox: %w", err)
+	} else if len(entries) == 0 {
+		return nil
+	}
+	c.logger.Info("sending queued requests from outbox", zap.Int("count", len(entries)))
+	for i, entry := range entries {
+		if err := c.sendOutboxEntry(ctx, entry); isRejected(err) {
+			// The hub rejected the request (e.g. because the deployment was deleted in the meantime), so sending it again
+			// would not succeed either.
+			c.logger.Warn("dropping queued request", zap.Error(err))
+		} else if err != nil {
+			return multierr.Append(err, c.ackOutbox(i))
+		}
+	}
+	return c.ackOutbox(len(entries))
+}
+
+func (c *Client) ackOutbox(n int) error {
+	if n == 0 {
+		return nil
+	} else if err := c.outbox.Ack(n); err != nil {
+		return fmt.Errorf("could not remove sent entries from outbox: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) sendOutboxEntry(ctx context.Context, entry OutboxEntry) error {
+	if entry.Status != nil {
+		return c.sendStatus(ctx, *entry.Status)
+	} else if entry.Metrics != nil {
+		return c.sendMetrics(ctx, *entry.Metrics)
+	} else if len(entry.DeploymentLogs) > 0 {
+		return c.sendDeploymentLogs(ctx, entry.DeploymentLogs)
+	} else if len(entry.DeploymentTargetLogs) > 0 {
+		return c.sendDeploymentTargetLogs(ctx, entry.DeploymentTargetLogs, true)
+	} else if len(entry.DeploymentEvents) > 0 {
+		return c.sendDeploymentEvents(ctx, entry.DeploymentEvents)
+	}
+	return nil
+}
+
+type fileOutbox struct {
+	name string
+}
+
+// NewFileOutbox returns an [Outbox] that stores entries in the file with the given name, one JSON object per line.
+// Entries are only ever appended to the file, so it is safe to use from multiple processes at the same time.
+// Peek and Ack must only be called by a single process.
+func NewFileOutbox(name string) Outbox {
+	return &fileOutbox{name: name}
+}
+
+func (o *fileOutbox) Append(entry OutboxEntry) error {
+	if fi, err := os.Stat(o.name); err == nil && fi.Size() > maxFileOutboxSize {
+		return ErrOutboxFull
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(o.name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+func (o *fileOutbox) takenName() string {
+	return o.name + ".taken"
+}
+
+func (o *fileOutbox) Peek() ([]OutboxEntry, error) {
+	// Renaming the file is atomic, so entries appended concurrently end up either in the taken file or in a new one.
+	// Entries left in the taken file by a previous call are older than all others, so the taken file is only replaced
+	// once it has been fully acknowledged.
+	takenName := o.takenName()
+	if _, err := os.Stat(takenName); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(o.name, takenName); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(takenName)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeOutboxEntries(data)
+}
+
+func (o *fileOutbox) Ack(n int) error {
+	takenName := o.takenName()
+	data, err := os.ReadFile(takenName)
+	if err != nil {
+		return err
+	}
+	entries, err := DecodeOutboxEntries(data)
+	if err != nil {
+		return err
+	} else if n >= len(entries) {
+		return os.Remove(takenName)
+	}
+	if data, err = EncodeOutboxEntries(entries[n:]); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash does not leave a partially written outbox behind.
+	tmpName := takenName + ".tmp"
+	if err := os.WriteFile(tmpName, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, takenName)
+}
+
+// DecodeOutboxEntries decodes entries in the format written by [NewFileOutbox].
+func DecodeOutboxEntries(data []byte) ([]OutboxEntry, error) {
+	var entries []OutboxEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, maxFileOutboxSize)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry OutboxEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid outbox entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// EncodeOutboxEntries encodes entries in the format written by [NewFileOutbox].
+func EncodeOutboxEntries(entries []OutboxEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package agentclient
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	. "github.com/onsi/gomega"
+)
+
+func TestFileOutbox(t *testing.T) {
+	g := NewWithT(t)
+	outbox := NewFileOutbox(path.Join(t.TempDir(), "outbox"))
+	entry := func(i int) OutboxEntry {
+		return OutboxEntry{
+			CreatedAt:      time.Unix(int64(i), 0).UTC(),
+			DeploymentLogs: []api.DeploymentLogRecord{{Body: "log"}},
+		}
+	}
+
+	entries, err := outbox.Peek()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+
+	g.Expect(outbox.Append(entry(1))).To(Succeed())
+	g.Expect(outbox.Append(entry(2))).To(Succeed())
+	entries, err = outbox.Peek()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(Equal([]OutboxEntry{entry(1), entry(2)}))
+
+	// Entries appended after Peek are returned after the remaining entries of the previous call.
+	g.Expect(outbox.Append(entry(3))).To(Succeed())
+	g.Expect(outbox.Ack(1)).To(Succeed())
+	entries, err = outbox.Peek()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(Equal([]OutboxEntry{entry(2)}))
+
+	g.Expect(outbox.Ack(1)).To(Succeed())
+	entries, err = outbox.Peek()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(Equal([]OutboxEntry{entry(3)}))
+
+	g.Expect(outbox.Ack(1)).To(Succeed())
+	entries, err = outbox.Peek()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entries).To(BeEmpty())
+}
@@ -0,0 +1,70 @@
+package agentclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/distr-sh/distr/api"
+	"go.uber.org/zap"
+)
+
+// SecretStore persists credentials that were replaced by the hub so that they are used again after the agent
+// restarts. The keys of values are the names of the environment variables the credentials are read from.
+type SecretStore func(ctx context.Context, values map[string]string) error
+
+// UseSecretStore configures where credentials are persisted after they were replaced by the hub.
+func (c *Client) UseSecretStore(store SecretStore) {
+	c.secretStore = store
+}
+
+// applyAccessKeyRotation switches the client to the new target secret issued by the hub.
+// Logging in with the new secret confirms the rotation to the hub.
+// If that fails, the previous secret is kept and the hub issues a new secret with the next resource request.
+func (c *Client) applyAccessKeyRotation(ctx context.Context, rotation api.AgentAccessKeyRotation) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	previousSecret := c.authSecret
+	c.authSecret = rotation.TargetSecret
+	if err := c.login(ctx, true); err != nil {
+		c.authSecret = previousSecret
+		return fmt.Errorf("login with rotated target secret failed: %w", err)
+	}
+
+	// Update the environment, so that ReloadFromEnv does not revert to the previous secret.
+	if err := os.Setenv("DISTR_TARGET_SECRET", rotation.TargetSecret); err != nil {
+		return err
+	}
+
+	c.logger.Info("target secret rotated")
+
+	// The hub accepts the previous secret for a grace period and issues another secret if it is used.
+	c.storeSecrets(ctx, map[string]string{"DISTR_TARGET_SECRET": rotation.TargetSecret})
+
+	return nil
+}
+
+// applyBundleKey stores the key for deployment bundles, so that bundles can be applied while the hub is not
+// reachable. The key only changes if the hub is configured with a new key.
+func (c *Client) applyBundleKey(ctx context.Context, key string) error {
+	if key == os.Getenv("DISTR_BUNDLE_KEY") {
+		return nil
+	} else if err := os.Setenv("DISTR_BUNDLE_KEY", key); err != nil {
+		return err
+	}
+
+	c.logger.Info("bundle key updated")
+
+	c.storeSecrets(ctx, map[string]string{"DISTR_BUNDLE_KEY": key})
+
+	return nil
+}
+
+func (c *Client) storeSecrets(ctx context.Context, values map[string]string) {
+	if c.secretStore == nil {
+		c.logger.Warn("no secret store configured, replaced credentials will be lost on restart")
+	} else if err := c.secretStore(ctx, values); err != nil {
+		c.logger.Error("could not persist replaced credentials", zap.Error(err))
+	}
+}
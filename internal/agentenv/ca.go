@@ -0,0 +1,38 @@
+package agentenv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultCertDirectories are the directories that Go searches for CA certificates on Linux if SSL_CERT_DIR is unset.
+var defaultCertDirectories = []string{"/etc/ssl/certs", "/etc/pki/tls/certs"}
+
+// InstallCACertificates makes the additional CA certificates from DISTR_CA_CERTIFICATES trusted by all TLS clients of
+// the agent process, including the hub client, Helm and ORAS.
+// This works by adding a directory to SSL_CERT_DIR, so it must be called before the first TLS connection is made.
+func InstallCACertificates() error {
+	encoded := os.Getenv("DISTR_CA_CERTIFICATES")
+	if encoded == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("DISTR_CA_CERTIFICATES is not base64 encoded: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "distr-ca-certificates")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path.Join(dir, "ca-certificates.pem"), data, 0o644); err != nil {
+		return err
+	}
+	dirs := defaultCertDirectories
+	if current := os.Getenv("SSL_CERT_DIR"); current != "" {
+		dirs = strings.Split(current, ":")
+	}
+	return os.Setenv("SSL_CERT_DIR", strings.Join(append([]string{dir}, dirs...), ":"))
+}
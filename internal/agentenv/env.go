@@ -1,6 +1,7 @@
 package agentenv
 
 import (
+	"os"
 	"strconv"
 	"time"
 
@@ -13,4 +14,11 @@ var (
 	Interval               = envutil.GetEnvParsedOrDefault("DISTR_INTERVAL", envparse.PositiveDuration, 5*time.Second)
 	DistrRegistryHost      = envutil.GetEnv("DISTR_REGISTRY_HOST")
 	DistrRegistryPlainHTTP = envutil.GetEnvParsedOrDefault("DISTR_REGISTRY_PLAIN_HTTP", strconv.ParseBool, false)
+	// Offline disables all communication with the hub. Deployments are installed from bundles and status updates and
+	// logs are queued until the agent is online again.
+	Offline = envutil.GetEnvParsedOrDefault("DISTR_OFFLINE", strconv.ParseBool, false)
+	// NetworkSettingsConfigured is true if the agent was configured with a proxy or additional CA certificates.
+	// Other clients, like the Docker daemon, do not know about these settings.
+	NetworkSettingsConfigured = os.Getenv("HTTP_PROXY") != "" || os.Getenv("HTTPS_PROXY") != "" ||
+		os.Getenv("DISTR_CA_CERTIFICATES") != ""
 )
@@ -9,6 +9,8 @@ import (
 	"path"
 	"text/template"
 
+	"github.com/distr-sh/distr/internal/agentbundle"
+	"github.com/distr-sh/distr/internal/agentca"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	"github.com/distr-sh/distr/internal/customdomains"
 	"github.com/distr-sh/distr/internal/env"
@@ -26,7 +28,10 @@ func Get(
 		return nil, err
 	} else if data, err := getTemplateData(deploymentTarget, org, secret); err != nil {
 		return nil, err
+	} else if err := addClientCertificate(ctx, data, deploymentTarget, secret); err != nil {
+		return nil, err
 	} else {
+		addBundleKey(data, deploymentTarget, secret)
 		var buf bytes.Buffer
 		return &buf, tmpl.Execute(&buf, data)
 	}
@@ -38,13 +43,15 @@ func getTemplateData(
 	secret *string,
 ) (map[string]any, error) {
 	var (
-		loginEndpoint     string
-		manifestEndpoint  string
-		resourcesEndpoint string
-		statusEndpoint    string
-		metricsEndpoint   string
-		logsEndpoint      string
-		agentLogsEndpoint string
+		loginEndpoint           string
+		manifestEndpoint        string
+		resourcesEndpoint       string
+		statusEndpoint          string
+		metricsEndpoint         string
+		logsEndpoint            string
+		agentLogsEndpoint       string
+		eventsEndpoint          string
+		volumeSnapshotsEndpoint string
 	)
 
 	if u, err := url.Parse(customdomains.AppDomainOrDefault(org)); err != nil {
@@ -58,25 +65,29 @@ func getTemplateData(
 		metricsEndpoint = u.JoinPath("metrics").String()
 		logsEndpoint = u.JoinPath("logs").String()
 		agentLogsEndpoint = u.JoinPath("deployment-target-logs").String()
+		eventsEndpoint = u.JoinPath("events").String()
+		volumeSnapshotsEndpoint = u.JoinPath("volume-snapshots").String()
 	}
 
 	result := map[string]any{
-		"agentDockerConfig": base64.StdEncoding.EncodeToString(env.AgentDockerConfig()),
-		"agentInterval":     env.AgentInterval(),
-		"agentVersion":      deploymentTarget.AgentVersion.Name,
-		"agentVersionId":    deploymentTarget.AgentVersion.ID,
-		"loginEndpoint":     loginEndpoint,
-		"manifestEndpoint":  manifestEndpoint,
-		"metricsEndpoint":   metricsEndpoint,
-		"registryEnabled":   env.RegistryEnabled(),
-		"registryHost":      customdomains.RegistryDomainOrDefault(org),
-		"registryPlainHttp": buildconfig.IsDevelopment(),
-		"resourcesEndpoint": resourcesEndpoint,
-		"statusEndpoint":    statusEndpoint,
-		"targetId":          deploymentTarget.ID,
-		"targetSecret":      secret,
-		"logsEndpoint":      logsEndpoint,
-		"agentLogsEndpoint": agentLogsEndpoint,
+		"agentDockerConfig":       base64.StdEncoding.EncodeToString(env.AgentDockerConfig()),
+		"agentInterval":           env.AgentInterval(),
+		"agentVersion":            deploymentTarget.AgentVersion.Name,
+		"agentVersionId":          deploymentTarget.AgentVersion.ID,
+		"loginEndpoint":           loginEndpoint,
+		"manifestEndpoint":        manifestEndpoint,
+		"metricsEndpoint":         metricsEndpoint,
+		"registryEnabled":         env.RegistryEnabled(),
+		"registryHost":            customdomains.RegistryDomainOrDefault(org),
+		"registryPlainHttp":       buildconfig.IsDevelopment(),
+		"resourcesEndpoint":       resourcesEndpoint,
+		"statusEndpoint":          statusEndpoint,
+		"targetId":                deploymentTarget.ID,
+		"targetSecret":            secret,
+		"logsEndpoint":            logsEndpoint,
+		"agentLogsEndpoint":       agentLogsEndpoint,
+		"eventsEndpoint":          eventsEndpoint,
+		"volumeSnapshotsEndpoint": volumeSnapshotsEndpoint,
 	}
 	if deploymentTarget.Namespace != nil {
 		result["targetNamespace"] = *deploymentTarget.Namespace
@@ -87,9 +98,51 @@ func getTemplateData(
 	if deploymentTarget.Resources != nil {
 		result["targetResources"] = deploymentTarget.Resources
 	}
+	if deploymentTarget.HTTPProxy != nil {
+		result["httpProxy"] = *deploymentTarget.HTTPProxy
+	}
+	if deploymentTarget.HTTPSProxy != nil {
+		result["httpsProxy"] = *deploymentTarget.HTTPSProxy
+	}
+	if deploymentTarget.NoProxy != nil {
+		result["noProxy"] = *deploymentTarget.NoProxy
+	}
+	if deploymentTarget.CACertificates != nil {
+		result["caCertificates"] = base64.StdEncoding.EncodeToString([]byte(*deploymentTarget.CACertificates))
+	}
 	return result, nil
 }
 
+// addClientCertificate issues a new client certificate for the agent if mTLS is enabled.
+// Like the secret, the certificate is only included when the agent is connected, not in self-updates.
+func addClientCertificate(
+	ctx context.Context,
+	data map[string]any,
+	deploymentTarget types.DeploymentTargetWithCreatedBy,
+	secret *string,
+) error {
+	if secret == nil || !agentca.Enabled() {
+		return nil
+	}
+	if certificate, err := agentca.IssueCertificate(
+		ctx, deploymentTarget.ID, deploymentTarget.OrganizationID,
+	); err != nil {
+		return err
+	} else {
+		data["clientCertificate"] = base64.StdEncoding.EncodeToString(certificate.Certificate)
+		data["clientKey"] = base64.StdEncoding.EncodeToString(certificate.Key)
+		return nil
+	}
+}
+
+// addBundleKey adds the key for deployment bundles if bundles are enabled. Like the secret, it is only included when
+// the agent is connected, so that agents in air-gapped environments can apply bundles without ever reaching the hub.
+func addBundleKey(data map[string]any, deploymentTarget types.DeploymentTargetWithCreatedBy, secret *string) {
+	if key := env.AgentBundleKey(); secret != nil && key != nil {
+		data["bundleKey"] = base64.StdEncoding.EncodeToString(agentbundle.TargetKey(key, deploymentTarget.ID))
+	}
+}
+
 func getTemplate(deploymentTarget types.DeploymentTargetWithCreatedBy) (*template.Template, error) {
 	if deploymentTarget.Type == types.DeploymentTypeDocker {
 		return resources.GetTemplate(path.Join(
@@ -105,3 +158,12 @@ func getTemplate(deploymentTarget types.DeploymentTargetWithCreatedBy) (*templat
 		))
 	}
 }
+
+// AgentImage returns the image reference of the agent for the given deployment target.
+// It must be kept in sync with the image used in the manifest templates.
+func AgentImage(deploymentTarget types.DeploymentTargetWithCreatedBy) string {
+	if deploymentTarget.Type == types.DeploymentTypeKubernetes {
+		return "ghcr.io/distr-sh/distr/kubernetes-agent:" + deploymentTarget.AgentVersion.Name
+	}
+	return "ghcr.io/distr-sh/distr/docker-agent:" + deploymentTarget.AgentVersion.Name
+}
@@ -0,0 +1,366 @@
+// Package attachment summarizes SBOMs and vulnerability reports that are pushed as OCI referrers of artifact versions.
+//
+// Supported formats are SPDX and CycloneDX (JSON) for SBOMs and SARIF for vulnerability reports. CycloneDX documents
+// may also contain vulnerabilities, in which case they are treated as vulnerability reports if they do not list any
+// components.
+package attachment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	MediaTypeSPDX      = "application/spdx+json"
+	MediaTypeCycloneDX = "application/vnd.cyclonedx+json"
+	MediaTypeSARIF     = "application/sarif+json"
+	mediaTypeSPDXText  = "text/spdx+json"
+	// MaxDocumentSize is the maximum size of a document that is parsed. Larger documents are stored but not summarized.
+	MaxDocumentSize = 16 << 20
+)
+
+// BlobOpener returns a reader for the content of the blob with the given digest.
+type BlobOpener func(ctx context.Context, digest digest.Digest) (io.ReadCloser, error)
+
+type referrerManifest struct {
+	ArtifactType string `json:"artifactType"`
+	Config       struct {
+		MediaType string `json:"mediaType"`
+	} `json:"config"`
+	Layers []struct {
+		MediaType string        `json:"mediaType"`
+		Digest    digest.Digest `json:"digest"`
+		Size      int64         `json:"size"`
+	} `json:"layers"`
+	Subject *struct {
+		Digest digest.Digest `json:"digest"`
+	} `json:"subject"`
+}
+
+func formatOf(mediaType string) (types.ArtifactAttachmentFormat, bool) {
+	switch mediaType {
+	case MediaTypeSPDX, mediaTypeSPDXText:
+		return types.ArtifactAttachmentFormatSPDX, true
+	case MediaTypeCycloneDX:
+		return types.ArtifactAttachmentFormatCycloneDX, true
+	case MediaTypeSARIF:
+		return types.ArtifactAttachmentFormatSARIF, true
+	default:
+		return "", false
+	}
+}
+
+// Extract returns summaries of all SBOMs and vulnerability reports contained in the given manifest. If the manifest
+// is not a referrer or does not contain any supported documents, an empty result is returned.
+func Extract(ctx context.Context, manifestData []byte, open BlobOpener) ([]types.ArtifactVersionAttachment, error) {
+	var manifest referrerManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil || manifest.Subject == nil {
+		return nil, nil
+	}
+
+	var result []types.ArtifactVersionAttachment
+	for _, layer := range manifest.Layers {
+		format, ok := formatOf(layer.MediaType)
+		if !ok && len(manifest.Layers) == 1 {
+			// tools like oras only set the artifact type and use a generic media type for the document
+			if format, ok = formatOf(manifest.ArtifactType); !ok {
+				format, ok = formatOf(manifest.Config.MediaType)
+			}
+		}
+		if !ok {
+			continue
+		} else if layer.Size > MaxDocumentSize {
+			return nil, fmt.Errorf("document %v is too large", layer.Digest)
+		}
+
+		attachment, err := parseBlob(ctx, format, layer.Digest, open)
+		if err != nil {
+			return nil, fmt.Errorf("invalid document %v: %w", layer.Digest, err)
+		}
+		attachment.SubjectDigest = types.Digest(manifest.Subject.Digest)
+		attachment.MediaType = layer.MediaType
+		attachment.BlobDigest = types.Digest(layer.Digest)
+		attachment.Size = layer.Size
+		result = append(result, *attachment)
+	}
+	return result, nil
+}
+
+func parseBlob(
+	ctx context.Context,
+	format types.ArtifactAttachmentFormat,
+	d digest.Digest,
+	open BlobOpener,
+) (*types.ArtifactVersionAttachment, error) {
+	rc, err := open(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return Parse(format, io.LimitReader(rc, MaxDocumentSize))
+}
+
+// Parse summarizes the document in the given format.
+func Parse(format types.ArtifactAttachmentFormat, r io.Reader) (*types.ArtifactVersionAttachment, error) {
+	switch format {
+	case types.ArtifactAttachmentFormatSPDX:
+		return parseSPDX(r)
+	case types.ArtifactAttachmentFormatCycloneDX:
+		return parseCycloneDX(r)
+	case types.ArtifactAttachmentFormatSARIF:
+		return parseSARIF(r)
+	default:
+		return nil, fmt.Errorf("unsupported format: %v", format)
+	}
+}
+
+func parseSPDX(r io.Reader) (*types.ArtifactVersionAttachment, error) {
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			LicenseConcluded string `json:"licenseConcluded"`
+			LicenseDeclared  string `json:"licenseDeclared"`
+		} `json:"packages"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	} else if doc.SPDXVersion == "" {
+		return nil, errors.New("not an SPDX document")
+	}
+
+	var licenses licenseSet
+	for _, pkg := range doc.Packages {
+		if !licenses.add(pkg.LicenseConcluded) {
+			licenses.add(pkg.LicenseDeclared)
+		}
+	}
+	return &types.ArtifactVersionAttachment{
+		Type:         types.ArtifactAttachmentTypeSBOM,
+		Format:       types.ArtifactAttachmentFormatSPDX,
+		PackageCount: util.PtrTo(len(doc.Packages)),
+		Licenses:     licenses.sorted(),
+	}, nil
+}
+
+type cycloneDXComponent struct {
+	Licenses []struct {
+		License *struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	} `json:"licenses"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+func parseCycloneDX(r io.Reader) (*types.ArtifactVersionAttachment, error) {
+	var bom struct {
+		BOMFormat       string               `json:"bomFormat"`
+		Components      []cycloneDXComponent `json:"components"`
+		Vulnerabilities []struct {
+			Ratings []struct {
+				Severity string `json:"severity"`
+			} `json:"ratings"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.NewDecoder(r).Decode(&bom); err != nil {
+		return nil, err
+	} else if bom.BOMFormat != "CycloneDX" {
+		return nil, errors.New("not a CycloneDX document")
+	}
+
+	var packageCount int
+	var licenses licenseSet
+	var visit func([]cycloneDXComponent)
+	visit = func(components []cycloneDXComponent) {
+		for _, component := range components {
+			packageCount++
+			for _, license := range component.Licenses {
+				if license.Expression != "" {
+					licenses.add(license.Expression)
+				} else if license.License != nil && !licenses.add(license.License.ID) {
+					licenses.add(license.License.Name)
+				}
+			}
+			visit(component.Components)
+		}
+	}
+	visit(bom.Components)
+
+	result := types.ArtifactVersionAttachment{Format: types.ArtifactAttachmentFormatCycloneDX}
+	if len(bom.Vulnerabilities) > 0 {
+		var counts types.VulnerabilityCounts
+		for _, vuln := range bom.Vulnerabilities {
+			severity := severityUnknown
+			for _, rating := range vuln.Ratings {
+				severity = max(severity, parseSeverity(rating.Severity))
+			}
+			addVulnerability(&counts, severity)
+		}
+		result.Vulnerabilities = &counts
+	}
+	if packageCount == 0 && result.Vulnerabilities != nil {
+		result.Type = types.ArtifactAttachmentTypeVulnerabilityReport
+	} else {
+		result.Type = types.ArtifactAttachmentTypeSBOM
+		result.PackageCount = &packageCount
+		result.Licenses = licenses.sorted()
+	}
+	return &result, nil
+}
+
+func parseSARIF(r io.Reader) (*types.ArtifactVersionAttachment, error) {
+	type rule struct {
+		ID         string `json:"id"`
+		Properties struct {
+			SecuritySeverity string `json:"security-severity"`
+		} `json:"properties"`
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []rule `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex *int   `json:"ruleIndex"`
+				Level     string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, err
+	} else if log.Version == "" {
+		return nil, errors.New("not a SARIF document")
+	}
+
+	var counts types.VulnerabilityCounts
+	for _, run := range log.Runs {
+		rules := run.Tool.Driver.Rules
+		for _, result := range run.Results {
+			var r *rule
+			if result.RuleIndex != nil && *result.RuleIndex >= 0 && *result.RuleIndex < len(rules) {
+				r = &rules[*result.RuleIndex]
+			} else if i := slices.IndexFunc(rules, func(r rule) bool { return r.ID == result.RuleID }); i >= 0 {
+				r = &rules[i]
+			}
+
+			severity := severityUnknown
+			if r != nil && r.Properties.SecuritySeverity != "" {
+				if score, err := strconv.ParseFloat(r.Properties.SecuritySeverity, 64); err == nil {
+					severity = severityFromScore(score)
+				}
+			}
+			if severity == severityUnknown {
+				severity = severityFromLevel(result.Level)
+			}
+			addVulnerability(&counts, severity)
+		}
+	}
+	return &types.ArtifactVersionAttachment{
+		Type:            types.ArtifactAttachmentTypeVulnerabilityReport,
+		Format:          types.ArtifactAttachmentFormatSARIF,
+		Vulnerabilities: &counts,
+	}, nil
+}
+
+type severity int
+
+const (
+	severityUnknown severity = iota
+	severityLow
+	severityMedium
+	severityHigh
+	severityCritical
+)
+
+func parseSeverity(s string) severity {
+	switch s {
+	case "critical":
+		return severityCritical
+	case "high":
+		return severityHigh
+	case "medium":
+		return severityMedium
+	case "low", "info":
+		return severityLow
+	default:
+		return severityUnknown
+	}
+}
+
+// severityFromScore maps a CVSS score to its qualitative severity rating.
+func severityFromScore(score float64) severity {
+	switch {
+	case score >= 9:
+		return severityCritical
+	case score >= 7:
+		return severityHigh
+	case score >= 4:
+		return severityMedium
+	case score > 0:
+		return severityLow
+	default:
+		return severityUnknown
+	}
+}
+
+func severityFromLevel(level string) severity {
+	switch level {
+	case "error":
+		return severityHigh
+	case "warning":
+		return severityMedium
+	case "note":
+		return severityLow
+	default:
+		return severityUnknown
+	}
+}
+
+func addVulnerability(counts *types.VulnerabilityCounts, s severity) {
+	switch s {
+	case severityCritical:
+		counts.Critical++
+	case severityHigh:
+		counts.High++
+	case severityMedium:
+		counts.Medium++
+	case severityLow:
+		counts.Low++
+	default:
+		counts.Unknown++
+	}
+}
+
+type licenseSet map[string]struct{}
+
+// add adds the license unless it is empty or one of the SPDX placeholders for missing information and reports whether
+// it was added.
+func (s *licenseSet) add(license string) bool {
+	if license == "" || license == "NOASSERTION" || license == "NONE" {
+		return false
+	}
+	if *s == nil {
+		*s = licenseSet{}
+	}
+	(*s)[license] = struct{}{}
+	return true
+}
+
+func (s licenseSet) sorted() []string {
+	return slices.Sorted(maps.Keys(s))
+}
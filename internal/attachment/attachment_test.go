@@ -0,0 +1,123 @@
+package attachment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+)
+
+const subject = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+
+func TestParseSPDX(t *testing.T) {
+	g := NewWithT(t)
+	result, err := Parse(types.ArtifactAttachmentFormatSPDX, strings.NewReader(`{
+		"spdxVersion": "SPDX-2.3",
+		"packages": [
+			{"licenseConcluded": "MIT", "licenseDeclared": "Apache-2.0"},
+			{"licenseConcluded": "NOASSERTION", "licenseDeclared": "Apache-2.0"},
+			{"licenseConcluded": "NOASSERTION", "licenseDeclared": "NOASSERTION"}
+		]
+	}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Type).To(Equal(types.ArtifactAttachmentTypeSBOM))
+	g.Expect(result.PackageCount).To(HaveValue(Equal(3)))
+	g.Expect(result.Licenses).To(Equal([]string{"Apache-2.0", "MIT"}))
+	g.Expect(result.Vulnerabilities).To(BeNil())
+
+	_, err = Parse(types.ArtifactAttachmentFormatSPDX, strings.NewReader(`{"bomFormat": "CycloneDX"}`))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestParseCycloneDX(t *testing.T) {
+	g := NewWithT(t)
+	result, err := Parse(types.ArtifactAttachmentFormatCycloneDX, strings.NewReader(`{
+		"bomFormat": "CycloneDX",
+		"components": [
+			{"licenses": [{"license": {"id": "MIT"}}], "components": [{"licenses": [{"expression": "GPL-2.0 OR MIT"}]}]},
+			{"licenses": [{"license": {"name": "Custom"}}]}
+		],
+		"vulnerabilities": [
+			{"ratings": [{"severity": "medium"}, {"severity": "critical"}]},
+			{"ratings": []}
+		]
+	}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Type).To(Equal(types.ArtifactAttachmentTypeSBOM))
+	g.Expect(result.PackageCount).To(HaveValue(Equal(3)))
+	g.Expect(result.Licenses).To(Equal([]string{"Custom", "GPL-2.0 OR MIT", "MIT"}))
+	g.Expect(result.Vulnerabilities).To(HaveValue(Equal(types.VulnerabilityCounts{Critical: 1, Unknown: 1})))
+
+	result, err = Parse(types.ArtifactAttachmentFormatCycloneDX, strings.NewReader(`{
+		"bomFormat": "CycloneDX",
+		"vulnerabilities": [{"ratings": [{"severity": "high"}]}]
+	}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Type).To(Equal(types.ArtifactAttachmentTypeVulnerabilityReport))
+	g.Expect(result.PackageCount).To(BeNil())
+	g.Expect(result.Vulnerabilities).To(HaveValue(Equal(types.VulnerabilityCounts{High: 1})))
+}
+
+func TestParseSARIF(t *testing.T) {
+	g := NewWithT(t)
+	result, err := Parse(types.ArtifactAttachmentFormatSARIF, strings.NewReader(`{
+		"version": "2.1.0",
+		"runs": [{
+			"tool": {"driver": {"rules": [
+				{"id": "CVE-1", "properties": {"security-severity": "9.8"}},
+				{"id": "CVE-2", "properties": {"security-severity": "5.0"}},
+				{"id": "CVE-3"}
+			]}},
+			"results": [
+				{"ruleId": "CVE-1", "ruleIndex": 0, "level": "error"},
+				{"ruleId": "CVE-2", "level": "warning"},
+				{"ruleId": "CVE-3", "level": "note"},
+				{"ruleId": "CVE-4"}
+			]
+		}]
+	}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result.Type).To(Equal(types.ArtifactAttachmentTypeVulnerabilityReport))
+	g.Expect(result.Vulnerabilities).To(HaveValue(Equal(types.VulnerabilityCounts{
+		Critical: 1, Medium: 1, Low: 1, Unknown: 1,
+	})))
+}
+
+func TestExtract(t *testing.T) {
+	g := NewWithT(t)
+
+	doc := `{"bomFormat": "CycloneDX", "components": [{}]}`
+	open := func(ctx context.Context, d digest.Digest) (io.ReadCloser, error) {
+		if d == digest.FromString(doc) {
+			return io.NopCloser(strings.NewReader(doc)), nil
+		}
+		return nil, fmt.Errorf("blob %v not found", d)
+	}
+
+	manifest := fmt.Appendf(nil,
+		`{"artifactType":%q,"layers":[{"mediaType":"application/json","digest":%q,"size":%v}],"subject":{"digest":%q}}`,
+		MediaTypeCycloneDX, digest.FromString(doc), len(doc), subject)
+	result, err := Extract(t.Context(), manifest, open)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(HaveLen(1))
+	g.Expect(result[0].Format).To(Equal(types.ArtifactAttachmentFormatCycloneDX))
+	g.Expect(result[0].SubjectDigest).To(Equal(types.Digest(subject)))
+	g.Expect(result[0].BlobDigest).To(Equal(types.Digest(digest.FromString(doc))))
+	g.Expect(result[0].Size).To(Equal(int64(len(doc))))
+	g.Expect(result[0].PackageCount).To(HaveValue(Equal(1)))
+
+	// manifests without a subject are not referrers
+	manifest = fmt.Appendf(nil, `{"layers":[{"mediaType":%q,"digest":%q,"size":%v}]}`,
+		MediaTypeCycloneDX, digest.FromString(doc), len(doc))
+	g.Expect(Extract(t.Context(), manifest, open)).To(BeEmpty())
+
+	manifest = fmt.Appendf(nil, `{"layers":[{"mediaType":%q,"digest":%q,"size":%v}],"subject":{"digest":%q}}`,
+		MediaTypeSPDX, digest.FromString(doc), len(doc), subject)
+	_, err = Extract(t.Context(), manifest, open)
+	g.Expect(err).To(HaveOccurred())
+}
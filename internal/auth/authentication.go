@@ -2,16 +2,21 @@ package auth
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"net/http"
+	"net/netip"
 
+	"github.com/distr-sh/distr/internal/agentca"
 	"github.com/distr-sh/distr/internal/authjwt"
 	"github.com/distr-sh/distr/internal/authn"
 	"github.com/distr-sh/distr/internal/authn/authinfo"
 	"github.com/distr-sh/distr/internal/authn/authkey"
+	"github.com/distr-sh/distr/internal/authn/clientcert"
 	"github.com/distr-sh/distr/internal/authn/jwt"
 	"github.com/distr-sh/distr/internal/authn/token"
 	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
 	"github.com/getsentry/sentry-go"
 	"go.uber.org/zap"
 )
@@ -33,7 +38,15 @@ var Authentication = authn.New(
 	),
 )
 
-// AgentAuthentication supports only Bearer JWT tokens
+// AgentCertificateAuthenticator authenticates agents with a client certificate issued by the hub.
+// The certificate is taken from a header set by the TLS terminating reverse proxy.
+var AgentCertificateAuthenticator = authn.Chain3(
+	clientcert.NewExtractor(agentClientCertificateFromHeader),
+	clientcert.Authenticator(agentca.VerifyCertificate),
+	authinfo.AgentCertificateAuthenticator(),
+)
+
+// AgentAuthentication supports Bearer JWT tokens and client certificates
 var AgentAuthentication = authn.New(
 	authn.Chain3(
 		token.NewExtractor(token.WithExtractorFuncs(token.FromHeader("Bearer"))),
@@ -41,8 +54,50 @@ var AgentAuthentication = authn.New(
 		authinfo.AgentJWTAuthenticator(),
 		// for agents, db check is done in the agent auth middleware, therefore no DbAuthenticator here
 	),
+	AgentCertificateAuthenticator,
+)
+
+var agentClientCertificateFromHeader = clientcert.FromHeader(
+	agentClientCertificateHeader,
+	isTrustedAgentClientCertificateProxy,
 )
 
+// AgentClientCertificate returns the client certificate that the TLS terminating reverse proxy forwarded with the
+// request, or nil if there is none. The certificate is not verified.
+func AgentClientCertificate(r *http.Request) (*x509.Certificate, error) {
+	return agentClientCertificateFromHeader(r)
+}
+
+func agentClientCertificateHeader() *string {
+	if config := env.GetAgentMTLSConfig(); config != nil {
+		return &config.ClientCertificateHeader
+	}
+	return nil
+}
+
+// isTrustedAgentClientCertificateProxy checks the direct peer of the connection and not the request IP address,
+// because the latter can be set by the client with X-Forwarded-For.
+func isTrustedAgentClientCertificateProxy(r *http.Request) bool {
+	config := env.GetAgentMTLSConfig()
+	if config == nil {
+		return false
+	}
+	peer := internalctx.GetPeerAddress(r.Context())
+	var addr netip.Addr
+	if addrPort, err := netip.ParseAddrPort(peer); err == nil {
+		addr = addrPort.Addr()
+	} else if addr, err = netip.ParseAddr(peer); err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // ArtifactsAuthentication supports Basic auth login for OCI clients, where the password should be a PAT.
 // The given PAT is verified against the database, to make sure that the user still exists.
 var ArtifactsAuthentication = authn.New(
@@ -0,0 +1,30 @@
+package authinfo
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/agentca"
+	"github.com/distr-sh/distr/internal/authn"
+)
+
+func FromAgentCertificate(certificate *x509.Certificate) (*SimpleAgentAuthInfo, error) {
+	var result SimpleAgentAuthInfo
+	if targetID, orgID, err := agentca.Identity(certificate); err != nil {
+		return nil, fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+	} else {
+		result.deploymentTargetID = targetID
+		result.organizationID = orgID
+	}
+	result.rawToken = certificate
+	return &result, nil
+}
+
+func AgentCertificateAuthenticator() authn.Authenticator[*x509.Certificate, AgentAuthInfo] {
+	return authn.AuthenticatorFunc[*x509.Certificate, AgentAuthInfo](
+		func(ctx context.Context, certificate *x509.Certificate) (AgentAuthInfo, error) {
+			return FromAgentCertificate(certificate)
+		},
+	)
+}
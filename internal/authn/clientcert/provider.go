@@ -0,0 +1,73 @@
+package clientcert
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/distr-sh/distr/internal/authn"
+)
+
+type CertificateExtractorFunc func(r *http.Request) (*x509.Certificate, error)
+
+// NewExtractor returns an authenticator that returns the first client certificate found by the given functions.
+func NewExtractor(fns ...CertificateExtractorFunc) authn.RequestAuthenticator[*x509.Certificate] {
+	return authn.AuthenticatorFunc[*http.Request, *x509.Certificate](
+		func(ctx context.Context, r *http.Request) (*x509.Certificate, error) {
+			for _, fn := range fns {
+				if certificate, err := fn(r); err != nil {
+					return nil, fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+				} else if certificate != nil {
+					return certificate, nil
+				}
+			}
+			return nil, authn.ErrNoAuthentication
+		},
+	)
+}
+
+// FromHeader returns the URL encoded PEM client certificate from the header with the name returned by headerName.
+// This is intended for reverse proxies that terminate TLS, like nginx with $ssl_client_escaped_cert.
+// If headerName returns nil, no certificate is extracted.
+// Anyone can set the header, so it is only accepted in requests for which trusted returns true, which should be the
+// case only for requests that come directly from the proxy.
+func FromHeader(headerName func() *string, trusted func(r *http.Request) bool) CertificateExtractorFunc {
+	return func(r *http.Request) (*x509.Certificate, error) {
+		name := headerName()
+		if name == nil {
+			return nil, nil
+		}
+		value := r.Header.Get(*name)
+		if value == "" {
+			return nil, nil
+		}
+		if !trusted(r) {
+			return nil, errors.New("client certificate header is not allowed from this address")
+		} else if unescaped, err := url.QueryUnescape(value); err != nil {
+			return nil, err
+		} else if block, _ := pem.Decode([]byte(unescaped)); block == nil {
+			return nil, errors.New("client certificate header does not contain a PEM encoded certificate")
+		} else {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// Authenticator verifies the client certificate with the given function.
+func Authenticator(
+	verify func(context.Context, *x509.Certificate) error,
+) authn.Authenticator[*x509.Certificate, *x509.Certificate] {
+	return authn.AuthenticatorFunc[*x509.Certificate, *x509.Certificate](
+		func(ctx context.Context, certificate *x509.Certificate) (*x509.Certificate, error) {
+			if err := verify(ctx, certificate); err != nil {
+				return nil, fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+			} else {
+				return certificate, nil
+			}
+		},
+	)
+}
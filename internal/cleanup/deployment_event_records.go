@@ -0,0 +1,16 @@
+package cleanup
+
+import (
+	"context"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"go.uber.org/zap"
+)
+
+func RunDeploymentEventRecordCleanup(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	count, err := db.CleanupDeploymentEventRecords(ctx)
+	log.Info("DeploymentEventRecord cleanup finished", zap.Int64("rowsDeleted", count), zap.Error(err))
+	return err
+}
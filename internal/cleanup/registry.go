@@ -0,0 +1,38 @@
+package cleanup
+
+import (
+	"context"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/registry/blob/storage"
+	"github.com/distr-sh/distr/internal/registry/gc"
+	"github.com/distr-sh/distr/internal/registry/retention"
+	"go.uber.org/zap"
+)
+
+func RunRegistryGarbageCollection(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	result, err := gc.Run(ctx, storage.NewBlobHandler(ctx), gc.Options{
+		GracePeriod: env.RegistryGCGracePeriod(),
+		DryRun:      env.RegistryGCDryRun(),
+	})
+	log.Info("registry garbage collection finished",
+		zap.Bool("dryRun", env.RegistryGCDryRun()),
+		zap.Int64("manifestsDeleted", result.DeletedManifests),
+		zap.Int64("blobsDeleted", result.DeletedBlobs),
+		zap.Int64("bytesDeleted", result.DeletedBytes),
+		zap.Error(err))
+	return err
+}
+
+func RunArtifactRetention(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	result, err := retention.Run(ctx, retention.Options{DryRun: env.ArtifactRetentionDryRun()})
+	log.Info("artifact retention finished",
+		zap.Bool("dryRun", env.ArtifactRetentionDryRun()),
+		zap.Int64("tagsDeleted", result.DeletedTags),
+		zap.Int64("manifestsDeleted", result.DeletedManifests),
+		zap.Error(err))
+	return err
+}
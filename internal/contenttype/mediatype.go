@@ -10,6 +10,9 @@ const (
 	MediaTypeJSON             = "application/json"
 	MediaTypeYAML             = "application/yaml"
 	MediaTypeOctetStream      = "application/octet-stream"
+	MediaTypeGzip             = "application/gzip"
+	MediaTypeXGzip            = "application/x-gzip"
+	MediaTypeXCompressedTar   = "application/x-compressed-tar"
 	MediaTypeTextYAML         = "text/yaml"
 	MediaTypeTextPlain        = "text/plain"
 	MediaTypeTextXYaml        = "text/x-yaml"
@@ -25,6 +28,14 @@ func IsYaml(header textproto.MIMEHeader) error {
 		MediaTypeApplicationXYaml)
 }
 
+func IsGzip(header textproto.MIMEHeader) error {
+	return HasMediaType(header,
+		MediaTypeGzip,
+		MediaTypeXGzip,
+		MediaTypeXCompressedTar,
+		MediaTypeOctetStream)
+}
+
 func HasMediaType(header textproto.MIMEHeader, acceptedContentTypes ...string) error {
 	contentType, err := ParseContentType(header.Get("Content-Type"))
 	if err != nil {
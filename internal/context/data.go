@@ -123,3 +123,16 @@ func GetRequestIPAddress(ctx context.Context) string {
 func WithRequestIPAddress(ctx context.Context, address string) context.Context {
 	return context.WithValue(ctx, ctxKeyIPAddress, address)
 }
+
+// GetPeerAddress returns the address of the direct peer of the connection. Unlike the request IP address, it is not
+// taken from headers like X-Forwarded-For.
+func GetPeerAddress(ctx context.Context) string {
+	if val, ok := ctx.Value(ctxKeyPeerAddress).(string); ok {
+		return val
+	}
+	return ""
+}
+
+func WithPeerAddress(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, ctxKeyPeerAddress, address)
+}
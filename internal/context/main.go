@@ -25,6 +25,7 @@ const (
 	ctxKeyApplicationLicense
 	ctxKeyArtifactLicense
 	ctxKeyIPAddress
+	ctxKeyPeerAddress
 	ctxKeyOIDCer
 )
 
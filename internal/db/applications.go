@@ -18,12 +18,13 @@ import (
 const (
 	applicationOutputExpr        = `a.id, a.created_at, a.organization_id, a.name, a.type, a.image_id`
 	applicationVersionOutputExpr = `av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-		av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.values_file_data, av.template_file_data,
-	 av.compose_file_data`
+		av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.kubernetes_type, av.values_file_data,
+		av.template_file_data, av.compose_file_data, av.manifest_file_data`
 	applicationWithVersionsOutputExpr = applicationOutputExpr + `,
 		coalesce((
 			SELECT array_agg(row(av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-				av.chart_type, av.chart_name, av.chart_url, av.chart_version) ORDER BY av.created_at ASC)
+				av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.kubernetes_type)
+				ORDER BY av.created_at ASC)
 			FROM ApplicationVersion av
 			WHERE av.application_id = a.id
 		), array[]::record[]) AS versions `
@@ -31,7 +32,8 @@ const (
 	applicationWithLicensedVersionsOutputExpr = applicationOutputExpr + `,
 		coalesce((
 			SELECT array_agg(row(av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-				av.chart_type, av.chart_name, av.chart_url, av.chart_version) ORDER BY av.created_at ASC)
+				av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.kubernetes_type)
+				ORDER BY av.created_at ASC)
 			FROM ApplicationVersion av
 			WHERE av.application_id = a.id and
 				((av.id IN
@@ -215,13 +217,14 @@ func CreateApplicationVersion(ctx context.Context, applicationVersion *types.App
 	db := internalctx.GetDb(ctx)
 
 	args := pgx.NamedArgs{
-		"name":          applicationVersion.Name,
-		"linkTemplate":  applicationVersion.LinkTemplate,
-		"applicationId": applicationVersion.ApplicationID,
-		"chartType":     applicationVersion.ChartType,
-		"chartName":     applicationVersion.ChartName,
-		"chartUrl":      applicationVersion.ChartUrl,
-		"chartVersion":  applicationVersion.ChartVersion,
+		"name":           applicationVersion.Name,
+		"linkTemplate":   applicationVersion.LinkTemplate,
+		"applicationId":  applicationVersion.ApplicationID,
+		"chartType":      applicationVersion.ChartType,
+		"chartName":      applicationVersion.ChartName,
+		"chartUrl":       applicationVersion.ChartUrl,
+		"chartVersion":   applicationVersion.ChartVersion,
+		"kubernetesType": applicationVersion.KubernetesType,
 	}
 	if applicationVersion.ComposeFileData != nil {
 		args["composeFileData"] = applicationVersion.ComposeFileData
@@ -232,15 +235,18 @@ func CreateApplicationVersion(ctx context.Context, applicationVersion *types.App
 	if applicationVersion.TemplateFileData != nil {
 		args["templateFileData"] = applicationVersion.TemplateFileData
 	}
+	if applicationVersion.ManifestFileData != nil {
+		args["manifestFileData"] = applicationVersion.ManifestFileData
+	}
 
 	row, err := db.Query(ctx,
 		`INSERT INTO ApplicationVersion AS av (name, link_template, application_id, chart_type, chart_name, chart_url,
-				chart_version, compose_file_data, values_file_data, template_file_data)
-		VALUES (@name, @linkTemplate, @applicationId, @chartType, @chartName, @chartUrl, @chartVersion,
-			@composeFileData::bytea, @valuesFileData::bytea, @templateFileData::bytea)
+				chart_version, kubernetes_type, compose_file_data, values_file_data, template_file_data, manifest_file_data)
+		VALUES (@name, @linkTemplate, @applicationId, @chartType, @chartName, @chartUrl, @chartVersion, @kubernetesType,
+			@composeFileData::bytea, @valuesFileData::bytea, @templateFileData::bytea, @manifestFileData::bytea)
 		RETURNING av.id, av.created_at, av.archived_at, av.name, av.link_template, av.chart_type, av.chart_name,
-			av.chart_url, av.chart_version, av.values_file_data, av.template_file_data, av.compose_file_data,
-			av.application_id`,
+			av.chart_url, av.chart_version, av.kubernetes_type, av.values_file_data, av.template_file_data,
+			av.compose_file_data, av.manifest_file_data, av.application_id`,
 		args)
 	if err != nil {
 		return fmt.Errorf("can not create ApplicationVersion: %w", err)
@@ -317,3 +323,45 @@ func UpdateApplicationImage(ctx context.Context, application *types.Application,
 	}
 	return nil
 }
+
+// GetApplicationVersionsInUse returns all versions of applications of the organization that are included in an
+// application license or deployed by the latest revision of any deployment.
+// A license without explicitly selected versions includes all versions of its application.
+func GetApplicationVersionsInUse(ctx context.Context, orgID uuid.UUID) ([]types.ApplicationVersion, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+applicationVersionOutputExpr+`
+		FROM ApplicationVersion av
+		JOIN Application a ON a.id = av.application_id
+		WHERE a.organization_id = @orgId
+			AND (
+				EXISTS (
+					SELECT 1 FROM ApplicationLicense_ApplicationVersion alav
+					WHERE alav.application_version_id = av.id
+				)
+				OR EXISTS (
+					SELECT 1 FROM ApplicationLicense al
+					WHERE al.application_id = a.id
+						AND NOT EXISTS (
+							SELECT 1 FROM ApplicationLicense_ApplicationVersion alav
+							WHERE alav.application_license_id = al.id
+						)
+				)
+				OR av.id IN (
+					SELECT DISTINCT ON (dr.deployment_id) dr.application_version_id
+					FROM DeploymentRevision dr
+					ORDER BY dr.deployment_id, dr.created_at DESC
+				)
+			)`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ApplicationVersion: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ApplicationVersion]); err != nil {
+		return nil, fmt.Errorf("could not collect ApplicationVersion: %w", err)
+	} else {
+		return result, nil
+	}
+}
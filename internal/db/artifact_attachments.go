@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const artifactVersionAttachmentOutputExpr = `
+	at.id,
+	at.created_at,
+	at.artifact_version_id,
+	at.subject_digest,
+	at.type,
+	at.format,
+	at.media_type,
+	at.blob_digest,
+	at.size,
+	at.package_count,
+	at.licenses,
+	at.vulnerabilities`
+
+// SetArtifactVersionAttachments replaces all attachments of the given referrer manifest version.
+func SetArtifactVersionAttachments(
+	ctx context.Context,
+	artifactVersionID uuid.UUID,
+	attachments []types.ArtifactVersionAttachment,
+) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(
+		ctx,
+		`DELETE FROM ArtifactVersionAttachment WHERE artifact_version_id = @artifactVersionId`,
+		pgx.NamedArgs{"artifactVersionId": artifactVersionID},
+	); err != nil {
+		return fmt.Errorf("could not delete ArtifactVersionAttachment: %w", err)
+	}
+	for _, attachment := range attachments {
+		// a nil slice would be encoded as NULL
+		licenses := attachment.Licenses
+		if licenses == nil {
+			licenses = []string{}
+		}
+		if _, err := db.Exec(
+			ctx,
+			`INSERT INTO ArtifactVersionAttachment (
+				artifact_version_id, subject_digest, type, format, media_type, blob_digest, size, package_count,
+				licenses, vulnerabilities
+			) VALUES (
+				@artifactVersionId, @subjectDigest, @type, @format, @mediaType, @blobDigest, @size, @packageCount,
+				@licenses, @vulnerabilities
+			)`,
+			pgx.NamedArgs{
+				"artifactVersionId": artifactVersionID,
+				"subjectDigest":     attachment.SubjectDigest,
+				"type":              attachment.Type,
+				"format":            attachment.Format,
+				"mediaType":         attachment.MediaType,
+				"blobDigest":        attachment.BlobDigest,
+				"size":              attachment.Size,
+				"packageCount":      attachment.PackageCount,
+				"licenses":          licenses,
+				"vulnerabilities":   attachment.Vulnerabilities,
+			},
+		); err != nil {
+			return fmt.Errorf("could not insert ArtifactVersionAttachment: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetArtifactVersionAttachmentsForArtifact returns the attachments of all versions of the given artifact.
+func GetArtifactVersionAttachmentsForArtifact(
+	ctx context.Context,
+	artifactID uuid.UUID,
+) ([]types.ArtifactVersionAttachment, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactVersionAttachmentOutputExpr+`
+		FROM ArtifactVersionAttachment at
+		JOIN ArtifactVersion av ON av.id = at.artifact_version_id
+		WHERE av.artifact_id = @artifactId
+		ORDER BY at.created_at DESC`,
+		pgx.NamedArgs{"artifactId": artifactID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersionAttachment: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactVersionAttachment]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactVersionAttachment: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetArtifactVersionAttachment(
+	ctx context.Context,
+	artifactID uuid.UUID,
+	id uuid.UUID,
+) (*types.ArtifactVersionAttachment, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactVersionAttachmentOutputExpr+`
+		FROM ArtifactVersionAttachment at
+		JOIN ArtifactVersion av ON av.id = at.artifact_version_id
+		WHERE at.id = @id AND av.artifact_id = @artifactId`,
+		pgx.NamedArgs{"id": id, "artifactId": artifactID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersionAttachment: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(
+		rows, pgx.RowToAddrOfStructByName[types.ArtifactVersionAttachment],
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apierrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("could not collect ArtifactVersionAttachment: %w", err)
+	} else {
+		return result, nil
+	}
+}
@@ -0,0 +1,380 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authkey"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	artifactEventOutputExpr = `
+		e.id,
+		e.created_at,
+		e.organization_id,
+		e.artifact_id,
+		e.artifact_name,
+		e.type,
+		e.reference,
+		e.digest_before,
+		e.digest_after,
+		e.useraccount_id,
+		u.email AS useraccount_email,
+		e.access_token_id,
+		t.label AS access_token_label,
+		e.remote_address`
+	artifactEventJoinExpr = `
+		LEFT JOIN UserAccount u ON u.id = e.useraccount_id
+		LEFT JOIN AccessToken t ON t.id = e.access_token_id`
+	registryWebhookOutputExpr = `
+		w.id,
+		w.created_at,
+		w.organization_id,
+		w.url,
+		w.secret`
+)
+
+// CreateArtifactEvent inserts the event. The artifact is looked up by its name and the access token by its key, if
+// the change was made with a personal access token.
+func CreateArtifactEvent(ctx context.Context, event *types.ArtifactEvent, accessTokenKey *authkey.Key) error {
+	var key []byte
+	if accessTokenKey != nil {
+		key = accessTokenKey[:]
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`WITH inserted AS (
+			INSERT INTO ArtifactEvent (
+				organization_id,
+				artifact_id,
+				artifact_name,
+				type,
+				reference,
+				digest_before,
+				digest_after,
+				useraccount_id,
+				access_token_id,
+				remote_address
+			)
+			VALUES (
+				@orgId,
+				(SELECT id FROM Artifact WHERE organization_id = @orgId AND name = @artifactName),
+				@artifactName,
+				@type,
+				@reference,
+				@digestBefore,
+				@digestAfter,
+				@userId,
+				(SELECT id FROM AccessToken WHERE key = @accessTokenKey),
+				@remoteAddress
+			)
+			RETURNING *
+		)
+		SELECT `+artifactEventOutputExpr+`
+		FROM inserted e`+artifactEventJoinExpr,
+		pgx.NamedArgs{
+			"orgId":          event.OrganizationID,
+			"artifactName":   event.ArtifactName,
+			"type":           event.Type,
+			"reference":      event.Reference,
+			"digestBefore":   event.DigestBefore,
+			"digestAfter":    event.DigestAfter,
+			"userId":         event.UserAccountID,
+			"accessTokenKey": key,
+			"remoteAddress":  event.RemoteAddress,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert ArtifactEvent: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactEvent]); err != nil {
+		return fmt.Errorf("could not collect ArtifactEvent: %w", err)
+	} else {
+		*event = result
+		return nil
+	}
+}
+
+// CreateArtifactDeletionEvents inserts a delete event for each of the given artifact versions, which must not have
+// been deleted yet. The IDs of the inserted events are returned.
+func CreateArtifactDeletionEvents(
+	ctx context.Context,
+	versionIDs []uuid.UUID,
+	userID *uuid.UUID,
+	remoteAddress *string,
+) ([]uuid.UUID, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO ArtifactEvent (
+			organization_id,
+			artifact_id,
+			artifact_name,
+			type,
+			reference,
+			digest_before,
+			useraccount_id,
+			remote_address
+		)
+		SELECT a.organization_id, a.id, a.name, 'delete', v.name, v.manifest_blob_digest, @userId, @remoteAddress
+		FROM ArtifactVersion v
+		JOIN Artifact a ON a.id = v.artifact_id
+		WHERE v.id = ANY(@versionIds)
+		RETURNING id`,
+		pgx.NamedArgs{"versionIds": versionIDs, "userId": userID, "remoteAddress": remoteAddress},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not insert ArtifactEvent: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactEvent: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetArtifactEventsWithIDs returns the events with the given IDs.
+func GetArtifactEventsWithIDs(ctx context.Context, ids []uuid.UUID) ([]types.ArtifactEvent, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactEventOutputExpr+`
+		FROM ArtifactEvent e`+artifactEventJoinExpr+`
+		WHERE e.id = ANY(@ids)`,
+		pgx.NamedArgs{"ids": ids},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactEvent: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactEvent]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactEvent: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetArtifactEvents returns the latest events of the artifact that happened before the given time.
+func GetArtifactEvents(
+	ctx context.Context,
+	artifactID uuid.UUID,
+	count int,
+	before time.Time,
+) ([]types.ArtifactEvent, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactEventOutputExpr+`
+		FROM ArtifactEvent e`+artifactEventJoinExpr+`
+		WHERE e.artifact_id = @artifactId
+			AND e.created_at < @before
+		ORDER BY e.created_at DESC
+		LIMIT @count`,
+		pgx.NamedArgs{
+			"artifactId": artifactID,
+			"count":      count,
+			"before":     before,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactEvent: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactEvent]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactEvent: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetLatestArtifactEventTime returns the time of the most recent event of the organization or nil if there is none.
+func GetLatestArtifactEventTime(ctx context.Context, orgID uuid.UUID) (*time.Time, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT max(created_at) FROM ArtifactEvent WHERE organization_id = @orgId`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactEvent: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[*time.Time]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactEvent: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetRegistryWebhooks(ctx context.Context, orgID uuid.UUID) ([]types.RegistryWebhook, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+registryWebhookOutputExpr+`
+		FROM RegistryWebhook w
+		WHERE w.organization_id = @orgId
+		ORDER BY w.created_at`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query RegistryWebhook: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.RegistryWebhook]); err != nil {
+		return nil, fmt.Errorf("could not collect RegistryWebhook: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func CreateRegistryWebhook(ctx context.Context, webhook *types.RegistryWebhook) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO RegistryWebhook AS w (organization_id, url, secret)
+		VALUES (@orgId, @url, @secret)
+		RETURNING `+registryWebhookOutputExpr,
+		pgx.NamedArgs{
+			"orgId":  webhook.OrganizationID,
+			"url":    webhook.URL,
+			"secret": webhook.Secret,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert RegistryWebhook: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.RegistryWebhook]); err != nil {
+		return fmt.Errorf("could not collect RegistryWebhook: %w", err)
+	} else {
+		*webhook = result
+		return nil
+	}
+}
+
+func UpdateRegistryWebhook(ctx context.Context, webhook *types.RegistryWebhook) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`UPDATE RegistryWebhook AS w SET url = @url
+		WHERE w.id = @id AND w.organization_id = @orgId
+		RETURNING `+registryWebhookOutputExpr,
+		pgx.NamedArgs{
+			"id":    webhook.ID,
+			"orgId": webhook.OrganizationID,
+			"url":   webhook.URL,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update RegistryWebhook: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.RegistryWebhook])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierrors.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("could not collect RegistryWebhook: %w", err)
+	}
+	*webhook = result
+	return nil
+}
+
+func DeleteRegistryWebhookWithID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM RegistryWebhook WHERE id = @id AND organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete RegistryWebhook: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// CreateRegistryWebhookDeliveries queues the given events for delivery to all registry webhooks of their
+// organization.
+func CreateRegistryWebhookDeliveries(ctx context.Context, eventIDs []uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(
+		ctx,
+		`INSERT INTO RegistryWebhookDelivery (registry_webhook_id, artifact_event_id)
+		SELECT w.id, e.id
+		FROM ArtifactEvent e
+		JOIN RegistryWebhook w ON w.organization_id = e.organization_id
+		WHERE e.id = ANY(@eventIds)`,
+		pgx.NamedArgs{"eventIds": eventIDs},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert RegistryWebhookDelivery: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueRegistryWebhookDeliveries returns up to limit deliveries whose next attempt is due, oldest first.
+// The next attempt of the returned deliveries is postponed by lease, so that concurrent callers do not claim them
+// again while they are being delivered.
+func ClaimDueRegistryWebhookDeliveries(
+	ctx context.Context,
+	limit int,
+	lease time.Duration,
+) ([]types.RegistryWebhookDelivery, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`WITH due AS (
+			SELECT id FROM RegistryWebhookDelivery
+			WHERE next_attempt_at <= current_timestamp
+			ORDER BY next_attempt_at
+			LIMIT @limit
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE RegistryWebhookDelivery d
+		SET next_attempt_at = current_timestamp + @lease
+		FROM due, RegistryWebhook w
+		WHERE d.id = due.id AND w.id = d.registry_webhook_id
+		RETURNING d.id, d.attempts, d.registry_webhook_id, w.url, w.secret, d.artifact_event_id`,
+		pgx.NamedArgs{"limit": limit, "lease": lease},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not claim RegistryWebhookDelivery: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.RegistryWebhookDelivery]); err != nil {
+		return nil, fmt.Errorf("could not collect RegistryWebhookDelivery: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func DeleteRegistryWebhookDeliveryWithID(ctx context.Context, id uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(ctx, `DELETE FROM RegistryWebhookDelivery WHERE id = @id`, pgx.NamedArgs{"id": id}); err != nil {
+		return fmt.Errorf("could not delete RegistryWebhookDelivery: %w", err)
+	}
+	return nil
+}
+
+// UpdateRegistryWebhookDeliveryFailed records a failed attempt and schedules the next one.
+func UpdateRegistryWebhookDeliveryFailed(
+	ctx context.Context,
+	id uuid.UUID,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(
+		ctx,
+		`UPDATE RegistryWebhookDelivery
+		SET attempts = attempts + 1, next_attempt_at = @nextAttemptAt, last_error = @lastError
+		WHERE id = @id`,
+		pgx.NamedArgs{"id": id, "nextAttemptAt": nextAttemptAt, "lastError": lastError},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update RegistryWebhookDelivery: %w", err)
+	}
+	return nil
+}
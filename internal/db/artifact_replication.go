@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	artifactReplicationTargetOutputExpr = `
+		t.id,
+		t.created_at,
+		t.organization_id,
+		t.customer_organization_id,
+		t.registry_url,
+		t.username,
+		t.password_secret_id`
+	artifactReplicationStatusOutputExpr = `
+		s.artifact_replication_target_id,
+		s.artifact_version_id,
+		s.updated_at,
+		s.synced_at,
+		s.tags,
+		s.error`
+)
+
+func GetArtifactReplicationTargets(ctx context.Context, orgID uuid.UUID) ([]types.ArtifactReplicationTarget, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactReplicationTargetOutputExpr+`
+		FROM ArtifactReplicationTarget t
+		WHERE t.organization_id = @orgId
+		ORDER BY t.created_at`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactReplicationTarget: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactReplicationTarget]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactReplicationTarget: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetAllArtifactReplicationTargets returns the replication targets of all organizations.
+func GetAllArtifactReplicationTargets(ctx context.Context) ([]types.ArtifactReplicationTarget, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactReplicationTargetOutputExpr+`
+		FROM ArtifactReplicationTarget t
+		ORDER BY t.created_at`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactReplicationTarget: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactReplicationTarget]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactReplicationTarget: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetArtifactReplicationTargetByID(
+	ctx context.Context,
+	id uuid.UUID,
+	orgID uuid.UUID,
+) (*types.ArtifactReplicationTarget, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactReplicationTargetOutputExpr+`
+		FROM ArtifactReplicationTarget t
+		WHERE t.id = @id AND t.organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactReplicationTarget: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[types.ArtifactReplicationTarget])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactReplicationTarget: %w", err)
+	}
+	return result, nil
+}
+
+func CreateArtifactReplicationTarget(ctx context.Context, target *types.ArtifactReplicationTarget) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO ArtifactReplicationTarget AS t
+			(organization_id, customer_organization_id, registry_url, username, password_secret_id)
+		VALUES (@orgId, @customerOrgId, @registryUrl, @username, @passwordSecretId)
+		RETURNING `+artifactReplicationTargetOutputExpr,
+		pgx.NamedArgs{
+			"orgId":            target.OrganizationID,
+			"customerOrgId":    target.CustomerOrganizationID,
+			"registryUrl":      target.RegistryURL,
+			"username":         target.Username,
+			"passwordSecretId": target.PasswordSecretID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert ArtifactReplicationTarget: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactReplicationTarget]); err != nil {
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) && pgError.Code == pgerrcode.UniqueViolation {
+			return apierrors.NewConflict("the customer organization already has a replication target")
+		}
+		return fmt.Errorf("could not collect ArtifactReplicationTarget: %w", err)
+	} else {
+		*target = result
+		return nil
+	}
+}
+
+// UpdateArtifactReplicationTarget updates the registry and credentials of the target. The replication status of all
+// versions is reset, so that they are copied to the new registry by the next replication run.
+func UpdateArtifactReplicationTarget(ctx context.Context, target *types.ArtifactReplicationTarget) error {
+	return RunTx(ctx, func(ctx context.Context) error {
+		db := internalctx.GetDb(ctx)
+		rows, err := db.Query(
+			ctx,
+			`UPDATE ArtifactReplicationTarget AS t SET
+				registry_url = @registryUrl,
+				username = @username,
+				password_secret_id = @passwordSecretId
+			WHERE t.id = @id AND t.organization_id = @orgId
+			RETURNING `+artifactReplicationTargetOutputExpr,
+			pgx.NamedArgs{
+				"id":               target.ID,
+				"orgId":            target.OrganizationID,
+				"registryUrl":      target.RegistryURL,
+				"username":         target.Username,
+				"passwordSecretId": target.PasswordSecretID,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("could not update ArtifactReplicationTarget: %w", err)
+		}
+		result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactReplicationTarget])
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierrors.ErrNotFound
+		} else if err != nil {
+			return fmt.Errorf("could not collect ArtifactReplicationTarget: %w", err)
+		}
+		if _, err := db.Exec(
+			ctx,
+			`DELETE FROM ArtifactReplicationStatus WHERE artifact_replication_target_id = @id`,
+			pgx.NamedArgs{"id": target.ID},
+		); err != nil {
+			return fmt.Errorf("could not delete ArtifactReplicationStatus: %w", err)
+		}
+		*target = result
+		return nil
+	})
+}
+
+func DeleteArtifactReplicationTargetWithID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM ArtifactReplicationTarget WHERE id = @id AND organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete ArtifactReplicationTarget: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func GetArtifactReplicationStatuses(
+	ctx context.Context,
+	targetID uuid.UUID,
+) ([]types.ArtifactReplicationStatus, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactReplicationStatusOutputExpr+`
+		FROM ArtifactReplicationStatus s
+		WHERE s.artifact_replication_target_id = @targetId`,
+		pgx.NamedArgs{"targetId": targetID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactReplicationStatus: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactReplicationStatus]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactReplicationStatus: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// SetArtifactReplicationStatus stores the result of a replication of an artifact version. synced_at is only updated
+// if the replication succeeded.
+func SetArtifactReplicationStatus(ctx context.Context, status *types.ArtifactReplicationStatus) error {
+	tags := status.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO ArtifactReplicationStatus AS s
+			(artifact_replication_target_id, artifact_version_id, synced_at, tags, error)
+		VALUES (@targetId, @versionId, CASE WHEN @error::TEXT IS NULL THEN current_timestamp END, @tags, @error)
+		ON CONFLICT (artifact_replication_target_id, artifact_version_id) DO UPDATE SET
+			updated_at = current_timestamp,
+			synced_at = coalesce(EXCLUDED.synced_at, s.synced_at),
+			tags = EXCLUDED.tags,
+			error = EXCLUDED.error
+		RETURNING `+artifactReplicationStatusOutputExpr,
+		pgx.NamedArgs{
+			"targetId":  status.ArtifactReplicationTargetID,
+			"versionId": status.ArtifactVersionID,
+			"tags":      tags,
+			"error":     status.Error,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not upsert ArtifactReplicationStatus: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactReplicationStatus]); err != nil {
+		return fmt.Errorf("could not collect ArtifactReplicationStatus: %w", err)
+	} else {
+		*status = result
+		return nil
+	}
+}
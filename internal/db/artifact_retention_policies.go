@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const artifactRetentionPolicyOutputExpr = `
+	p.id,
+	p.created_at,
+	p.organization_id,
+	p.artifact_id,
+	p.tag_pattern,
+	p.keep_last_tags,
+	p.untagged_max_age_days`
+
+func GetArtifactRetentionPolicies(ctx context.Context, orgID uuid.UUID) ([]types.ArtifactRetentionPolicy, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactRetentionPolicyOutputExpr+`
+		FROM ArtifactRetentionPolicy p
+		WHERE p.organization_id = @orgId
+		ORDER BY p.created_at`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactRetentionPolicy: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactRetentionPolicy]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactRetentionPolicy: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetOrganizationIDsWithArtifactRetentionPolicies returns the IDs of all organizations that have at least one
+// retention policy.
+func GetOrganizationIDsWithArtifactRetentionPolicies(ctx context.Context) ([]uuid.UUID, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx, `SELECT DISTINCT organization_id FROM ArtifactRetentionPolicy`)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactRetentionPolicy: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactRetentionPolicy: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetArtifactRetentionPolicyByID(
+	ctx context.Context,
+	id uuid.UUID,
+	orgID uuid.UUID,
+) (*types.ArtifactRetentionPolicy, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactRetentionPolicyOutputExpr+`
+		FROM ArtifactRetentionPolicy p
+		WHERE p.id = @id AND p.organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactRetentionPolicy: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(
+		rows, pgx.RowToAddrOfStructByName[types.ArtifactRetentionPolicy],
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apierrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("could not collect ArtifactRetentionPolicy: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func CreateArtifactRetentionPolicy(ctx context.Context, policy *types.ArtifactRetentionPolicy) error {
+	db := internalctx.GetDb(ctx)
+	if err := checkRetentionPolicyArtifact(ctx, policy); err != nil {
+		return err
+	}
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO ArtifactRetentionPolicy AS p (
+			organization_id, artifact_id, tag_pattern, keep_last_tags, untagged_max_age_days
+		) VALUES (
+			@orgId, @artifactId, @tagPattern, @keepLastTags, @untaggedMaxAgeDays
+		) RETURNING `+artifactRetentionPolicyOutputExpr,
+		pgx.NamedArgs{
+			"orgId":              policy.OrganizationID,
+			"artifactId":         policy.ArtifactID,
+			"tagPattern":         policy.TagPattern,
+			"keepLastTags":       policy.KeepLastTags,
+			"untaggedMaxAgeDays": policy.UntaggedMaxAgeDays,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert ArtifactRetentionPolicy: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactRetentionPolicy]); err != nil {
+		return fmt.Errorf("could not collect ArtifactRetentionPolicy: %w", err)
+	} else {
+		*policy = result
+		return nil
+	}
+}
+
+func UpdateArtifactRetentionPolicy(ctx context.Context, policy *types.ArtifactRetentionPolicy) error {
+	db := internalctx.GetDb(ctx)
+	if err := checkRetentionPolicyArtifact(ctx, policy); err != nil {
+		return err
+	}
+	rows, err := db.Query(
+		ctx,
+		`UPDATE ArtifactRetentionPolicy AS p SET
+			artifact_id = @artifactId,
+			tag_pattern = @tagPattern,
+			keep_last_tags = @keepLastTags,
+			untagged_max_age_days = @untaggedMaxAgeDays
+		WHERE p.id = @id AND p.organization_id = @orgId
+		RETURNING `+artifactRetentionPolicyOutputExpr,
+		pgx.NamedArgs{
+			"id":                 policy.ID,
+			"orgId":              policy.OrganizationID,
+			"artifactId":         policy.ArtifactID,
+			"tagPattern":         policy.TagPattern,
+			"keepLastTags":       policy.KeepLastTags,
+			"untaggedMaxAgeDays": policy.UntaggedMaxAgeDays,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update ArtifactRetentionPolicy: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactRetentionPolicy]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apierrors.ErrNotFound
+		}
+		return fmt.Errorf("could not collect ArtifactRetentionPolicy: %w", err)
+	} else {
+		*policy = result
+		return nil
+	}
+}
+
+func DeleteArtifactRetentionPolicyWithID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM ArtifactRetentionPolicy WHERE id = @id AND organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete ArtifactRetentionPolicy: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// checkRetentionPolicyArtifact makes sure that the artifact of the policy belongs to the organization of the policy.
+func checkRetentionPolicyArtifact(ctx context.Context, policy *types.ArtifactRetentionPolicy) error {
+	if policy.ArtifactID == nil {
+		return nil
+	}
+	db := internalctx.GetDb(ctx)
+	var exists bool
+	if err := db.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM Artifact WHERE id = @artifactId AND organization_id = @orgId)`,
+		pgx.NamedArgs{"artifactId": policy.ArtifactID, "orgId": policy.OrganizationID},
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("could not query Artifact: %w", err)
+	} else if !exists {
+		return apierrors.NewBadRequest("artifact does not exist")
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	artifactVersionSignatureOutputExpr = `
+		s.id,
+		s.created_at,
+		s.artifact_version_id,
+		s.subject_digest,
+		s.format,
+		s.signed_digest,
+		s.payload,
+		s.signature,
+		s.algorithm,
+		s.certificates`
+	artifactSigningKeyOutputExpr = `
+		k.id,
+		k.created_at,
+		k.organization_id,
+		k.name,
+		k.public_key`
+)
+
+// SetArtifactVersionSignatures replaces all signatures of the given signature manifest version.
+func SetArtifactVersionSignatures(
+	ctx context.Context,
+	artifactVersionID uuid.UUID,
+	sigs []types.ArtifactVersionSignature,
+) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(
+		ctx,
+		`DELETE FROM ArtifactVersionSignature WHERE artifact_version_id = @artifactVersionId`,
+		pgx.NamedArgs{"artifactVersionId": artifactVersionID},
+	); err != nil {
+		return fmt.Errorf("could not delete ArtifactVersionSignature: %w", err)
+	}
+	for _, sig := range sigs {
+		// a nil slice would be encoded as NULL
+		certificates := sig.Certificates
+		if certificates == nil {
+			certificates = [][]byte{}
+		}
+		if _, err := db.Exec(
+			ctx,
+			`INSERT INTO ArtifactVersionSignature (
+				artifact_version_id, subject_digest, format, signed_digest, payload, signature, algorithm, certificates
+			) VALUES (
+				@artifactVersionId, @subjectDigest, @format, @signedDigest, @payload, @signature, @algorithm,
+				@certificates
+			)`,
+			pgx.NamedArgs{
+				"artifactVersionId": artifactVersionID,
+				"subjectDigest":     sig.SubjectDigest,
+				"format":            sig.Format,
+				"signedDigest":      sig.SignedDigest,
+				"payload":           sig.Payload,
+				"signature":         sig.Signature,
+				"algorithm":         sig.Algorithm,
+				"certificates":      certificates,
+			},
+		); err != nil {
+			return fmt.Errorf("could not insert ArtifactVersionSignature: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetArtifactVersionSignaturesForArtifact returns the signatures of all versions of the given artifact.
+func GetArtifactVersionSignaturesForArtifact(
+	ctx context.Context,
+	artifactID uuid.UUID,
+) ([]types.ArtifactVersionSignature, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactVersionSignatureOutputExpr+`
+		FROM ArtifactVersionSignature s
+		JOIN ArtifactVersion sv ON sv.id = s.artifact_version_id
+		WHERE sv.artifact_id = @artifactId
+		ORDER BY s.created_at`,
+		pgx.NamedArgs{"artifactId": artifactID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersionSignature: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactVersionSignature]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactVersionSignature: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetArtifactVersionSignaturesForReference returns all signatures that apply to the version with the given tag or
+// digest. These are the signatures of the version itself and of all image indexes of the same artifact that contain
+// it.
+func GetArtifactVersionSignaturesForReference(
+	ctx context.Context,
+	orgName, name, reference string,
+) ([]types.ArtifactVersionSignature, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`WITH Target AS (
+			SELECT DISTINCT av.artifact_id, av.manifest_blob_digest
+			FROM Artifact a
+			JOIN Organization o ON o.id = a.organization_id
+			JOIN ArtifactVersion av ON a.id = av.artifact_id
+			WHERE o.slug = @orgName
+				AND a.name = @name
+				AND (av.name = @reference OR av.manifest_blob_digest = @reference)
+		)
+		SELECT `+artifactVersionSignatureOutputExpr+`
+		FROM ArtifactVersionSignature s
+		JOIN ArtifactVersion sv ON sv.id = s.artifact_version_id
+		JOIN Target t ON t.artifact_id = sv.artifact_id
+		WHERE s.subject_digest = t.manifest_blob_digest
+			OR s.subject_digest IN (
+				SELECT av.manifest_blob_digest
+				FROM ArtifactVersion av
+				JOIN ArtifactVersionPart avp ON av.id = avp.artifact_version_id
+				WHERE av.artifact_id = t.artifact_id AND avp.artifact_blob_digest = t.manifest_blob_digest
+			)`,
+		pgx.NamedArgs{"orgName": orgName, "name": name, "reference": reference},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersionSignature: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactVersionSignature]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactVersionSignature: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// IsArtifactSignatureManifest checks whether the version with the given tag or digest is a signature manifest.
+func IsArtifactSignatureManifest(ctx context.Context, orgName, name, reference string) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	var exists bool
+	if err := db.QueryRow(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1
+			FROM Artifact a
+			JOIN Organization o ON o.id = a.organization_id
+			JOIN ArtifactVersion av ON a.id = av.artifact_id
+			JOIN ArtifactVersion sv ON sv.artifact_id = a.id AND sv.manifest_blob_digest = av.manifest_blob_digest
+			JOIN ArtifactVersionSignature s ON s.artifact_version_id = sv.id
+			WHERE o.slug = @orgName
+				AND a.name = @name
+				AND (av.name = @reference OR av.manifest_blob_digest = @reference)
+		)`,
+		pgx.NamedArgs{"orgName": orgName, "name": name, "reference": reference},
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("could not query ArtifactVersionSignature: %w", err)
+	}
+	return exists, nil
+}
+
+func GetArtifactSigningKeys(ctx context.Context, orgID uuid.UUID) ([]types.ArtifactSigningKey, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+artifactSigningKeyOutputExpr+`
+		FROM ArtifactSigningKey k
+		WHERE k.organization_id = @orgId
+		ORDER BY k.name`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactSigningKey: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactSigningKey]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactSigningKey: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func CreateArtifactSigningKey(ctx context.Context, key *types.ArtifactSigningKey) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO ArtifactSigningKey AS k (organization_id, name, public_key)
+		VALUES (@orgId, @name, @publicKey)
+		RETURNING `+artifactSigningKeyOutputExpr,
+		pgx.NamedArgs{"orgId": key.OrganizationID, "name": key.Name, "publicKey": key.PublicKey},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert ArtifactSigningKey: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ArtifactSigningKey]); err != nil {
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) && pgError.Code == pgerrcode.UniqueViolation {
+			return apierrors.NewConflict("a signing key with this name already exists")
+		}
+		return fmt.Errorf("could not collect ArtifactSigningKey: %w", err)
+	} else {
+		*key = result
+		return nil
+	}
+}
+
+func DeleteArtifactSigningKeyWithID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM ArtifactSigningKey WHERE id = @id AND organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete ArtifactSigningKey: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
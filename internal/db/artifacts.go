@@ -408,7 +408,19 @@ func CheckLicenseForArtifact(
 				JOIN Organization o ON o.id = a.organization_id
 				WHERE o.slug = @orgName
 				AND a.name = @name
-				AND (avx.name = @reference OR avx.manifest_blob_digest = @reference)
+				AND (
+					avx.name = @reference
+					OR avx.manifest_blob_digest = @reference
+					-- signature manifests are accessible together with their subject
+					OR avx.manifest_blob_digest IN (
+						SELECT s.subject_digest
+						FROM ArtifactVersionSignature s
+						JOIN ArtifactVersion sv ON sv.id = s.artifact_version_id
+						JOIN ArtifactVersion svx
+							ON svx.artifact_id = sv.artifact_id AND svx.manifest_blob_digest = sv.manifest_blob_digest
+						WHERE sv.artifact_id = a.id AND (svx.name = @reference OR svx.manifest_blob_digest = @reference)
+					)
+				)
 			UNION ALL
 			SELECT DISTINCT av.id, av.artifact_id, av.manifest_blob_digest
 				FROM ArtifactVersion av
@@ -464,6 +476,15 @@ func CheckLicenseForArtifactBlob(ctx context.Context, digest string,
 				FROM ArtifactVersion av
 				JOIN ArtifactVersionPart avp ON av.id = avp.artifact_version_id
 				WHERE avp.artifact_blob_digest = @digest
+			UNION
+			-- blobs of signature manifests are accessible together with the subject of the signature
+			SELECT av.id, av.artifact_id, av.manifest_blob_digest
+				FROM ArtifactVersion av
+				JOIN ArtifactVersion sv ON sv.artifact_id = av.artifact_id
+				JOIN ArtifactVersionSignature s
+					ON s.artifact_version_id = sv.id AND s.subject_digest = av.manifest_blob_digest
+				JOIN ArtifactVersionPart avp ON sv.id = avp.artifact_version_id
+				WHERE avp.artifact_blob_digest = @digest
 			UNION ALL
 			SELECT DISTINCT av.id, av.artifact_id, av.manifest_blob_digest
 				FROM ArtifactVersion av
@@ -925,6 +946,99 @@ func IsLastTagOfArtifact(ctx context.Context, artifactID uuid.UUID, tagName stri
 	return tagCount == 1, nil
 }
 
+// DeleteArtifactTag deletes a tag of an artifact, unless it is the last tag of the artifact or the deletion would
+// break license access. It should be called inside a transaction.
+func DeleteArtifactTag(ctx context.Context, artifactID uuid.UUID, tagName string) error {
+	// Step 1: Validate version exists and fetch it
+	version, err := GetArtifactVersionByTag(ctx, artifactID, tagName)
+	if err != nil {
+		return err
+	}
+
+	// Step 2: Fetch all versions with the same digest
+	versionsWithSameDigest, err := GetArtifactVersionsByDigest(ctx, artifactID, string(version.ManifestBlobDigest))
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Enhanced license check
+	if err := CheckArtifactVersionDeletionForLicenses(ctx, artifactID, version, versionsWithSameDigest); err != nil {
+		return err
+	}
+
+	// Step 4: Check if this is the last non-SHA tag of the artifact
+	isLast, err := IsLastTagOfArtifact(ctx, artifactID, tagName)
+	if err != nil {
+		return err
+	}
+	if isLast {
+		return apierrors.NewConflict(
+			"Cannot delete tag: it is the last tag of the artifact. At least one tag must remain for the artifact.",
+		)
+	}
+
+	// Step 5: Delete the tag
+	return DeleteArtifactVersion(ctx, artifactID, tagName)
+}
+
+// DeleteArtifactManifest deletes the manifest with the given digest and all tags pointing to it, unless the
+// artifact would be left without tags or the manifest is referenced in a license.
+// It should be called inside a transaction.
+func DeleteArtifactManifest(ctx context.Context, artifactID uuid.UUID, digest string) error {
+	versions, err := GetArtifactVersionsByDigest(ctx, artifactID, digest)
+	if err != nil {
+		return err
+	}
+	var shaVersion *types.ArtifactVersion
+	var hasTags bool
+	for i, v := range versions {
+		if isDigestName(v.Name) {
+			shaVersion = &versions[i]
+		} else {
+			hasTags = true
+		}
+	}
+	if shaVersion == nil {
+		return apierrors.ErrNotFound
+	}
+
+	// Only the SHA version is passed, because all tags pointing to it are deleted as well
+	if err := CheckArtifactVersionDeletionForLicenses(
+		ctx, artifactID, shaVersion, []types.ArtifactVersion{*shaVersion},
+	); err != nil {
+		return err
+	}
+
+	if hasTags {
+		var otherTagCount int64
+		if err := internalctx.GetDb(ctx).QueryRow(ctx, `
+			SELECT count(*)
+			FROM ArtifactVersion
+			WHERE artifact_id = @artifactId
+			AND manifest_blob_digest != @digest
+			AND name NOT LIKE '%:%'`,
+			pgx.NamedArgs{"artifactId": artifactID, "digest": digest},
+		).Scan(&otherTagCount); err != nil {
+			return fmt.Errorf("could not count tags: %w", err)
+		} else if otherTagCount == 0 {
+			return apierrors.NewConflict(
+				"Cannot delete manifest: it is referenced by the last tags of the artifact. " +
+					"At least one tag must remain for the artifact.",
+			)
+		}
+	}
+
+	if _, err := internalctx.GetDb(ctx).Exec(ctx, `
+		DELETE FROM ArtifactVersion
+		WHERE artifact_id = @artifactId
+		AND manifest_blob_digest = @digest`,
+		pgx.NamedArgs{"artifactId": artifactID, "digest": digest},
+	); err != nil {
+		return fmt.Errorf("could not delete manifest: %w", err)
+	}
+	return nil
+}
+
 func DeleteArtifactVersion(ctx context.Context, artifactID uuid.UUID, tagName string) error {
 	db := internalctx.GetDb(ctx)
 
@@ -953,3 +1067,160 @@ func DeleteArtifactVersion(ctx context.Context, artifactID uuid.UUID, tagName st
 
 	return nil
 }
+
+// GetUntaggedArtifactVersions returns all versions older than minAge that are not referenced by any tag or image
+// index of the same artifact and are not explicitly included in a license.
+// If artifactID is not nil, only versions of this artifact are returned.
+func GetUntaggedArtifactVersions(
+	ctx context.Context,
+	artifactID *uuid.UUID,
+	minAge time.Duration,
+) ([]types.ArtifactVersion, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+artifactVersionOutputExpr+`
+		FROM ArtifactVersion v
+		WHERE v.name LIKE '%:%'
+			AND (@artifactId::UUID IS NULL OR v.artifact_id = @artifactId)
+			AND current_timestamp - v.created_at > @minAge
+			AND NOT EXISTS (
+				SELECT 1 FROM ArtifactVersion t
+				WHERE t.artifact_id = v.artifact_id
+					AND t.name NOT LIKE '%:%'
+					AND t.manifest_blob_digest = v.manifest_blob_digest
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM ArtifactVersionPart avp
+				JOIN ArtifactVersion iv ON iv.id = avp.artifact_version_id
+				WHERE iv.artifact_id = v.artifact_id AND avp.artifact_blob_digest = v.manifest_blob_digest
+			)
+			AND NOT EXISTS (SELECT 1 FROM ArtifactLicense_Artifact ala WHERE ala.artifact_version_id = v.id)`,
+		pgx.NamedArgs{"artifactId": artifactID, "minAge": minAge},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersion: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactVersion]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactVersion: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// ArtifactVersionWithDigestExists checks whether the artifact has a version with the given manifest digest.
+func ArtifactVersionWithDigestExists(ctx context.Context, artifactID uuid.UUID, digest types.Digest) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	var exists bool
+	if err := db.QueryRow(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM ArtifactVersion WHERE artifact_id = @artifactId AND manifest_blob_digest = @digest
+		)`,
+		pgx.NamedArgs{"artifactId": artifactID, "digest": digest},
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("could not query ArtifactVersion: %w", err)
+	}
+	return exists, nil
+}
+
+func DeleteArtifactVersionsWithIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	if cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM ArtifactVersion WHERE id = ANY(@ids)`,
+		pgx.NamedArgs{"ids": ids},
+	); err != nil {
+		return 0, fmt.Errorf("could not delete ArtifactVersion: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
+
+// GetReferencedArtifactBlobDigests returns the digests of all blobs that are referenced by any artifact version,
+// except for the versions with the given IDs.
+// Manifest digests are included because manifests of older versions were stored as blobs.
+func GetReferencedArtifactBlobDigests(ctx context.Context, excludeVersionIDs []uuid.UUID) ([]types.Digest, error) {
+	if excludeVersionIDs == nil {
+		// a nil slice would be encoded as NULL, which excludes all rows
+		excludeVersionIDs = []uuid.UUID{}
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT avp.artifact_blob_digest
+		FROM ArtifactVersionPart avp
+		WHERE NOT avp.artifact_version_id = ANY(@excludeIds)
+		UNION
+		SELECT v.manifest_blob_digest
+		FROM ArtifactVersion v
+		WHERE NOT v.id = ANY(@excludeIds)`,
+		pgx.NamedArgs{"excludeIds": excludeVersionIDs},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersionPart: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowTo[types.Digest]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactVersionPart: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// IsArtifactBlobReferenced checks whether any artifact version references the blob with the given digest.
+func IsArtifactBlobReferenced(ctx context.Context, digest types.Digest) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	var exists bool
+	if err := db.QueryRow(
+		ctx,
+		`SELECT EXISTS (SELECT 1 FROM ArtifactVersionPart WHERE artifact_blob_digest = @digest)
+			OR EXISTS (SELECT 1 FROM ArtifactVersion WHERE manifest_blob_digest = @digest)`,
+		pgx.NamedArgs{"digest": digest},
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("could not query ArtifactVersionPart: %w", err)
+	}
+	return exists, nil
+}
+
+// GetArtifactTags returns all tags of the artifact, most recently pushed first.
+func GetArtifactTags(ctx context.Context, artifactID uuid.UUID) ([]types.ArtifactVersion, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+artifactVersionOutputExpr+`
+		FROM ArtifactVersion v
+		WHERE v.artifact_id = @artifactId AND v.name NOT LIKE '%:%'
+		ORDER BY v.created_at DESC, v.name`,
+		pgx.NamedArgs{"artifactId": artifactID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactVersion: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ArtifactVersion]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactVersion: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetLicensedArtifactVersionDigests returns the manifest digests of all versions of the artifact that are explicitly
+// included in an artifact license.
+func GetLicensedArtifactVersionDigests(ctx context.Context, artifactID uuid.UUID) ([]types.Digest, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT DISTINCT v.manifest_blob_digest
+		FROM ArtifactLicense_Artifact ala
+		JOIN ArtifactVersion v ON v.id = ala.artifact_version_id
+		WHERE ala.artifact_id = @artifactId`,
+		pgx.NamedArgs{"artifactId": artifactID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ArtifactLicense_Artifact: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowTo[types.Digest]); err != nil {
+		return nil, fmt.Errorf("could not collect ArtifactLicense_Artifact: %w", err)
+	} else {
+		return result, nil
+	}
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	deploymentContainerMetricsOutputExpr = `
+	cm.id, cm.created_at, cm.deployment_id, cm.deployment_revision_id, cm.name, cm.cpu_usage_millis, cm.memory_bytes,
+	cm.memory_limit_bytes, cm.restart_count, cm.disk_read_bytes, cm.disk_write_bytes, cm.network_rx_bytes,
+	cm.network_tx_bytes
+	`
+)
+
+// FilterDeploymentMetrics returns the given metrics without the entries of deployment revisions that do not belong to
+// the deployment target, e.g. because the deployment was deleted after the metrics were collected.
+func FilterDeploymentMetrics(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	metrics []api.AgentDeploymentMetrics,
+) ([]api.AgentDeploymentMetrics, error) {
+	if len(metrics) == 0 {
+		return metrics, nil
+	}
+	revisionIDs := make([]uuid.UUID, len(metrics))
+	for i, m := range metrics {
+		revisionIDs[i] = m.DeploymentRevisionID
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT d.id, dr.id
+		FROM Deployment d
+		JOIN DeploymentRevision dr ON d.id = dr.deployment_id
+		WHERE d.deployment_target_id = @deploymentTargetId
+			AND dr.id = ANY(@revisionIds)`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID, "revisionIds": revisionIDs},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentRevision: %w", err)
+	}
+	existing := map[deploymentRevisionRef]struct{}{}
+	var ref deploymentRevisionRef
+	if _, err := pgx.ForEachRow(rows, []any{&ref.deploymentID, &ref.revisionID}, func() error {
+		existing[ref] = struct{}{}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentRevision: %w", err)
+	}
+	return slices.DeleteFunc(slices.Clone(metrics), func(m api.AgentDeploymentMetrics) bool {
+		_, ok := existing[deploymentRevisionRef{deploymentID: m.DeploymentID, revisionID: m.DeploymentRevisionID}]
+		return !ok
+	}), nil
+}
+
+// CreateDeploymentContainerMetrics inserts the container metrics of all given deployments.
+// If createdAt is nil, the current time is used.
+func CreateDeploymentContainerMetrics(
+	ctx context.Context,
+	metrics []api.AgentDeploymentMetrics,
+	createdAt *time.Time,
+) error {
+	timestamp := time.Now()
+	if createdAt != nil {
+		timestamp = *createdAt
+	}
+	var rows [][]any
+	for _, m := range metrics {
+		for _, c := range m.Containers {
+			rows = append(rows, []any{
+				m.DeploymentID, m.DeploymentRevisionID, c.Name, c.CPUUsageMillis, c.MemoryBytes, c.MemoryLimitBytes,
+				c.RestartCount, c.DiskReadBytes, c.DiskWriteBytes, c.NetworkRxBytes, c.NetworkTxBytes, timestamp,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	db := internalctx.GetDb(ctx)
+	_, err := db.CopyFrom(
+		ctx,
+		pgx.Identifier{"deploymentcontainermetrics"},
+		[]string{
+			"deployment_id", "deployment_revision_id", "name", "cpu_usage_millis", "memory_bytes", "memory_limit_bytes",
+			"restart_count", "disk_read_bytes", "disk_write_bytes", "network_rx_bytes", "network_tx_bytes", "created_at",
+		},
+		pgx.CopyFromRows(rows),
+	)
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) && pgerr.Code == pgerrcode.ForeignKeyViolation {
+		return apierrors.NewBadRequest("deployment does not exist")
+	} else if err != nil {
+		return fmt.Errorf("could not save DeploymentContainerMetrics: %w", err)
+	}
+	return nil
+}
+
+// GetDeploymentContainerMetrics returns the container metrics of a deployment, newest first.
+// If name is not empty, only metrics of the container with this name are returned.
+func GetDeploymentContainerMetrics(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	name string,
+	limit int,
+	before time.Time,
+	after time.Time,
+) ([]types.DeploymentContainerMetrics, error) {
+	if before.IsZero() {
+		before = time.Now()
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+deploymentContainerMetricsOutputExpr+`
+		FROM DeploymentContainerMetrics cm
+		WHERE cm.deployment_id = @deploymentId
+			AND (@name = '' OR cm.name = @name)
+			AND cm.created_at BETWEEN @after AND @before
+		ORDER BY cm.created_at DESC, cm.name
+		LIMIT @limit`,
+		pgx.NamedArgs{
+			"deploymentId": deploymentID,
+			"name":         name,
+			"limit":        limit,
+			"before":       before,
+			"after":        after,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentContainerMetrics: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.DeploymentContainerMetrics])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentContainerMetrics: %w", err)
+	}
+	return result, nil
+}
+
+// CleanupDeploymentContainerMetrics deletes all container metrics that are older than [env.MetricsEntriesMaxAge].
+//
+// If [env.MetricsEntriesMaxAge] is nil, no cleanup is performed.
+func CleanupDeploymentContainerMetrics(ctx context.Context) (int64, error) {
+	maxAge := env.MetricsEntriesMaxAge()
+	if maxAge == nil {
+		return 0, nil
+	}
+	db := internalctx.GetDb(ctx)
+	if cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM DeploymentContainerMetrics WHERE current_timestamp - created_at > @maxAge`,
+		pgx.NamedArgs{"maxAge": *maxAge},
+	); err != nil {
+		return 0, fmt.Errorf("could not delete DeploymentContainerMetrics: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	deploymentEventRecordOutputExpr = `
+	er.id, er.created_at, er.deployment_id, er.deployment_revision_id, er.uid, er.resource, er.type, er.reason,
+	er.message, er.count, er.first_timestamp, er.last_timestamp
+	`
+)
+
+// SaveDeploymentEventRecords inserts the given event records.
+// Records for events that already exist (identified by deployment and event UID) are updated instead.
+func SaveDeploymentEventRecords(ctx context.Context, records []api.DeploymentEventRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	// A single INSERT can not update the same row twice, so only the latest record of each event is saved.
+	type eventKey struct {
+		deploymentID uuid.UUID
+		uid          string
+	}
+	latest := make(map[eventKey]int, len(records))
+	var deduplicated []api.DeploymentEventRecord
+	for _, record := range records {
+		key := eventKey{record.DeploymentID, record.UID}
+		if idx, ok := latest[key]; !ok {
+			latest[key] = len(deduplicated)
+			deduplicated = append(deduplicated, record)
+		} else if !record.LastTimestamp.Before(deduplicated[idx].LastTimestamp) {
+			deduplicated[idx] = record
+		}
+	}
+
+	n := len(deduplicated)
+	deploymentIDs, revisionIDs := make([]uuid.UUID, n), make([]uuid.UUID, n)
+	uids, resources, eventTypes, reasons, messages := make([]string, n), make([]string, n), make([]string, n),
+		make([]string, n), make([]string, n)
+	counts := make([]int32, n)
+	firstTimestamps, lastTimestamps := make([]time.Time, n), make([]time.Time, n)
+	for i, record := range deduplicated {
+		deploymentIDs[i] = record.DeploymentID
+		revisionIDs[i] = record.DeploymentRevisionID
+		uids[i] = record.UID
+		resources[i] = record.Resource
+		eventTypes[i] = record.Type
+		reasons[i] = record.Reason
+		messages[i] = record.Message
+		counts[i] = max(record.Count, 1)
+		firstTimestamps[i] = record.FirstTimestamp
+		lastTimestamps[i] = record.LastTimestamp
+	}
+
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(
+		ctx,
+		`INSERT INTO DeploymentEventRecord AS er (
+			deployment_id, deployment_revision_id, uid, resource, type, reason, message, count, first_timestamp,
+			last_timestamp
+		)
+		SELECT * FROM unnest(
+			@deploymentIds::UUID[], @deploymentRevisionIds::UUID[], @uids::TEXT[], @resources::TEXT[],
+			@types::TEXT[], @reasons::TEXT[], @messages::TEXT[], @counts::INTEGER[], @firstTimestamps::TIMESTAMP[],
+			@lastTimestamps::TIMESTAMP[]
+		)
+		ON CONFLICT (deployment_id, uid) DO UPDATE SET
+			deployment_revision_id = EXCLUDED.deployment_revision_id,
+			type = EXCLUDED.type,
+			reason = EXCLUDED.reason,
+			message = EXCLUDED.message,
+			count = EXCLUDED.count,
+			last_timestamp = EXCLUDED.last_timestamp
+		WHERE er.last_timestamp <= EXCLUDED.last_timestamp`,
+		pgx.NamedArgs{
+			"deploymentIds":         deploymentIDs,
+			"deploymentRevisionIds": revisionIDs,
+			"uids":                  uids,
+			"resources":             resources,
+			"types":                 eventTypes,
+			"reasons":               reasons,
+			"messages":              messages,
+			"counts":                counts,
+			"firstTimestamps":       firstTimestamps,
+			"lastTimestamps":        lastTimestamps,
+		},
+	)
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) && pgerr.Code == pgerrcode.ForeignKeyViolation {
+		return apierrors.NewBadRequest("deployment does not exist")
+	} else if err != nil {
+		return fmt.Errorf("could not save DeploymentEventRecord: %w", err)
+	}
+	return nil
+}
+
+func ValidateDeploymentEventRecords(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	records []api.DeploymentEventRecord,
+) error {
+	revisions := make([]deploymentRevisionRef, len(records))
+	for i, record := range records {
+		revisions[i] = deploymentRevisionRef{
+			deploymentID: record.DeploymentID,
+			revisionID:   record.DeploymentRevisionID,
+		}
+	}
+	return validateDeploymentRevisions(ctx, deploymentTargetID, revisions)
+}
+
+func GetDeploymentEventRecords(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	limit int,
+	before time.Time,
+	after time.Time,
+) ([]types.DeploymentEventRecord, error) {
+	if before.IsZero() {
+		before = time.Now()
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+deploymentEventRecordOutputExpr+`
+		FROM DeploymentEventRecord er
+		WHERE er.deployment_id = @deploymentId
+			AND er.last_timestamp BETWEEN @after AND @before
+		ORDER BY er.last_timestamp DESC
+		LIMIT @limit`,
+		pgx.NamedArgs{
+			"deploymentId": deploymentID,
+			"limit":        limit,
+			"before":       before,
+			"after":        after,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentEventRecord: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.DeploymentEventRecord])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentEventRecord: %w", err)
+	}
+	return result, nil
+}
+
+// CleanupDeploymentEventRecords deletes all event records whose last occurrence is older than
+// [env.EventEntriesMaxAge].
+//
+// If [env.EventEntriesMaxAge] is nil, no cleanup is performed.
+func CleanupDeploymentEventRecords(ctx context.Context) (int64, error) {
+	maxAge := env.EventEntriesMaxAge()
+	if maxAge == nil {
+		return 0, nil
+	}
+	db := internalctx.GetDb(ctx)
+	if cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM DeploymentEventRecord WHERE current_timestamp - last_timestamp > @maxAge`,
+		pgx.NamedArgs{"maxAge": *maxAge},
+	); err != nil {
+		return 0, fmt.Errorf("could not delete DeploymentEventRecord: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
@@ -50,18 +50,33 @@ func ValidateDeploymentLogRecords(
 	deploymentTargetID uuid.UUID,
 	records []api.DeploymentLogRecord,
 ) error {
-	if len(records) == 0 {
+	revisions := make([]deploymentRevisionRef, len(records))
+	for i, record := range records {
+		revisions[i] = deploymentRevisionRef{
+			deploymentID: record.DeploymentID,
+			revisionID:   record.DeploymentRevisionID,
+		}
+	}
+	return validateDeploymentRevisions(ctx, deploymentTargetID, revisions)
+}
+
+type deploymentRevisionRef struct{ deploymentID, revisionID uuid.UUID }
+
+// validateDeploymentRevisions checks that all given deployment revisions belong to the given deployment target.
+func validateDeploymentRevisions(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	revisions []deploymentRevisionRef,
+) error {
+	if len(revisions) == 0 {
 		return nil
 	}
 
 	db := internalctx.GetDb(ctx)
 
-	tuples := map[struct{ deploymentID, revisionID uuid.UUID }]struct{}{}
-	for _, record := range records {
-		tuples[struct{ deploymentID, revisionID uuid.UUID }{
-			deploymentID: record.DeploymentID,
-			revisionID:   record.DeploymentRevisionID,
-		}] = struct{}{}
+	tuples := map[deploymentRevisionRef]struct{}{}
+	for _, revision := range revisions {
+		tuples[revision] = struct{}{}
 	}
 
 	for tuple := range tuples {
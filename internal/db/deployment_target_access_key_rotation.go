@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetDeploymentTargetAccessKeyRotation returns the rotation state of the access key of the given deployment target.
+// If the key was never rotated, a default state based on the creation date of the deployment target is returned.
+func GetDeploymentTargetAccessKeyRotation(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+) (*types.DeploymentTargetAccessKeyRotation, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT
+			dt.id AS deployment_target_id,
+			coalesce(r.access_key_created_at, dt.created_at) AS access_key_created_at,
+			r.requested_at,
+			r.pending_access_key_salt,
+			r.pending_access_key_hash,
+			r.pending_access_key_encrypted,
+			r.pending_access_key_issued_at,
+			r.previous_access_key_salt,
+			r.previous_access_key_hash,
+			r.previous_access_key_expires_at
+		FROM DeploymentTarget dt
+		LEFT JOIN DeploymentTargetAccessKeyRotation r ON r.deployment_target_id = dt.id
+		WHERE dt.id = @id`,
+		pgx.NamedArgs{"id": deploymentTargetID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DeploymentTargetAccessKeyRotation: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentTargetAccessKeyRotation])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get DeploymentTargetAccessKeyRotation: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
+// RequestDeploymentTargetAccessKeyRotation marks the access key of the given deployment target for rotation.
+// The new key is issued the next time the agent fetches its resource.
+func RequestDeploymentTargetAccessKeyRotation(ctx context.Context, deploymentTargetID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`INSERT INTO DeploymentTargetAccessKeyRotation (deployment_target_id, access_key_created_at, requested_at)
+		SELECT dt.id, dt.created_at, now() FROM DeploymentTarget dt WHERE dt.id = @id
+		ON CONFLICT (deployment_target_id) DO UPDATE SET requested_at = now()`,
+		pgx.NamedArgs{"id": deploymentTargetID},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to request DeploymentTargetAccessKeyRotation: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// SetPendingDeploymentTargetAccessKey stores a newly issued access key that has not yet been confirmed by the agent.
+// The encrypted key is kept, so that the same key can be sent again until the agent confirms it.
+// A previously issued pending key is replaced.
+func SetPendingDeploymentTargetAccessKey(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	salt, hash, encrypted []byte,
+) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`INSERT INTO DeploymentTargetAccessKeyRotation
+			(deployment_target_id, access_key_created_at, requested_at, pending_access_key_salt, pending_access_key_hash,
+				pending_access_key_encrypted, pending_access_key_issued_at)
+		SELECT dt.id, dt.created_at, now(), @salt, @hash, @encrypted, now() FROM DeploymentTarget dt WHERE dt.id = @id
+		ON CONFLICT (deployment_target_id) DO UPDATE SET
+			requested_at = coalesce(DeploymentTargetAccessKeyRotation.requested_at, now()),
+			pending_access_key_salt = @salt,
+			pending_access_key_hash = @hash,
+			pending_access_key_encrypted = @encrypted,
+			pending_access_key_issued_at = now()`,
+		pgx.NamedArgs{"id": deploymentTargetID, "salt": salt, "hash": hash, "encrypted": encrypted},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set pending access key: %w", err)
+	}
+	return nil
+}
+
+// PromotePendingDeploymentTargetAccessKey replaces the access key of the deployment target with the pending key.
+// The replaced key remains valid until previousExpiresAt.
+func PromotePendingDeploymentTargetAccessKey(
+	ctx context.Context,
+	dt *types.DeploymentTarget,
+	rotation *types.DeploymentTargetAccessKeyRotation,
+	previousExpiresAt time.Time,
+) error {
+	return RunTx(ctx, func(ctx context.Context) error {
+		db := internalctx.GetDb(ctx)
+		cmd, err := db.Exec(ctx,
+			`UPDATE DeploymentTargetAccessKeyRotation SET
+				access_key_created_at = now(),
+				requested_at = NULL,
+				pending_access_key_salt = NULL,
+				pending_access_key_hash = NULL,
+				pending_access_key_encrypted = NULL,
+				pending_access_key_issued_at = NULL,
+				previous_access_key_salt = @previousSalt,
+				previous_access_key_hash = @previousHash,
+				previous_access_key_expires_at = @previousExpiresAt
+			WHERE deployment_target_id = @id AND pending_access_key_hash = @pendingHash`,
+			pgx.NamedArgs{
+				"id":                dt.ID,
+				"pendingHash":       rotation.PendingAccessKeyHash,
+				"previousSalt":      dt.AccessKeySalt,
+				"previousHash":      dt.AccessKeyHash,
+				"previousExpiresAt": previousExpiresAt,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update DeploymentTargetAccessKeyRotation: %w", err)
+		} else if cmd.RowsAffected() == 0 {
+			return fmt.Errorf("%w: pending access key has changed", apierrors.ErrConflict)
+		}
+		if _, err := db.Exec(ctx,
+			`UPDATE DeploymentTarget SET access_key_salt = @salt, access_key_hash = @hash WHERE id = @id`,
+			pgx.NamedArgs{"id": dt.ID, "salt": rotation.PendingAccessKeySalt, "hash": rotation.PendingAccessKeyHash},
+		); err != nil {
+			return fmt.Errorf("failed to update DeploymentTarget: %w", err)
+		}
+		dt.AccessKeySalt = rotation.PendingAccessKeySalt
+		dt.AccessKeyHash = rotation.PendingAccessKeyHash
+		return nil
+	})
+}
+
+// ResetDeploymentTargetAccessKeyRotation discards all rotation state of the deployment target.
+// It must be called whenever a new access key is created outside of a rotation.
+func ResetDeploymentTargetAccessKeyRotation(ctx context.Context, deploymentTargetID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`INSERT INTO DeploymentTargetAccessKeyRotation (deployment_target_id, access_key_created_at)
+		VALUES (@id, now())
+		ON CONFLICT (deployment_target_id) DO UPDATE SET
+			access_key_created_at = now(),
+			requested_at = NULL,
+			pending_access_key_salt = NULL,
+			pending_access_key_hash = NULL,
+			pending_access_key_encrypted = NULL,
+			pending_access_key_issued_at = NULL,
+			previous_access_key_salt = NULL,
+			previous_access_key_hash = NULL,
+			previous_access_key_expires_at = NULL`,
+		pgx.NamedArgs{"id": deploymentTargetID},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reset DeploymentTargetAccessKeyRotation: %w", err)
+	}
+	return nil
+}
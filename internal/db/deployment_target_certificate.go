@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateDeploymentTargetCertificate records a client certificate that was issued for the given deployment target.
+// Records of expired certificates of the same deployment target are removed.
+func CreateDeploymentTargetCertificate(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	serialNumber string,
+	expiresAt time.Time,
+) error {
+	return RunTx(ctx, func(ctx context.Context) error {
+		db := internalctx.GetDb(ctx)
+		if _, err := db.Exec(ctx,
+			`DELETE FROM DeploymentTargetCertificate WHERE deployment_target_id = @id AND expires_at < now()`,
+			pgx.NamedArgs{"id": deploymentTargetID},
+		); err != nil {
+			return fmt.Errorf("failed to delete expired DeploymentTargetCertificates: %w", err)
+		}
+		if _, err := db.Exec(ctx,
+			`INSERT INTO DeploymentTargetCertificate (serial_number, deployment_target_id, expires_at)
+			VALUES (@serialNumber, @id, @expiresAt)`,
+			pgx.NamedArgs{"serialNumber": serialNumber, "id": deploymentTargetID, "expiresAt": expiresAt},
+		); err != nil {
+			return fmt.Errorf("failed to insert DeploymentTargetCertificate: %w", err)
+		}
+		return nil
+	})
+}
+
+// IsDeploymentTargetCertificateValid returns true if the certificate with the given serial number was issued for the
+// given deployment target and was not revoked since.
+func IsDeploymentTargetCertificateValid(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	serialNumber string,
+) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM DeploymentTargetCertificate
+			WHERE serial_number = @serialNumber AND deployment_target_id = @id AND expires_at > now()
+		)`,
+		pgx.NamedArgs{"serialNumber": serialNumber, "id": deploymentTargetID},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to query DeploymentTargetCertificate: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool])
+	if err != nil {
+		return false, fmt.Errorf("failed to get DeploymentTargetCertificate: %w", err)
+	}
+	return result, nil
+}
+
+// RevokeDeploymentTargetCertificates revokes all client certificates of the given deployment target.
+func RevokeDeploymentTargetCertificates(ctx context.Context, deploymentTargetID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(ctx,
+		`DELETE FROM DeploymentTargetCertificate WHERE deployment_target_id = @id`,
+		pgx.NamedArgs{"id": deploymentTargetID},
+	); err != nil {
+		return fmt.Errorf("failed to delete DeploymentTargetCertificates: %w", err)
+	}
+	return nil
+}
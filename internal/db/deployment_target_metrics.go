@@ -13,7 +13,10 @@ import (
 )
 
 type DeploymentTargetLatestMetrics struct {
-	ID uuid.UUID `db:"id" json:"id"`
+	ID                        uuid.UUID `db:"id" json:"id"`
+	DiskUsageWarningThreshold *float64  `db:"disk_usage_warning_threshold" json:"diskUsageWarningThreshold,omitempty"`
+	// DiskUsageWarnings contains all disks whose usage exceeds the warning threshold of the deployment target
+	DiskUsageWarnings []api.AgentDiskMetrics `db:"-" json:"diskUsageWarnings,omitempty"`
 	api.AgentDeploymentTargetMetrics
 }
 
@@ -26,7 +29,8 @@ func GetLatestDeploymentTargetMetrics(
 	isVendorUser := customerOrganizationID == nil
 
 	if rows, err := db.Query(ctx,
-		`SELECT dt.id, dtm.cpu_cores_millis, dtm.cpu_usage, dtm.memory_bytes, dtm.memory_usage FROM
+		`SELECT dt.id, dt.disk_usage_warning_threshold, dtm.cpu_cores_millis, dtm.cpu_usage, dtm.memory_bytes,
+			dtm.memory_usage, dtm.disks FROM
 			DeploymentTarget dt
 			LEFT JOIN CustomerOrganization co
 				ON dt.customer_organization_id = co.id
@@ -57,10 +61,36 @@ func GetLatestDeploymentTargetMetrics(
 	); err != nil {
 		return nil, fmt.Errorf("failed to get DeploymentTargets: %w", err)
 	} else {
+		for i, metrics := range result {
+			if metrics.DiskUsageWarningThreshold != nil {
+				result[i].DiskUsageWarnings = metrics.DisksAboveThreshold(*metrics.DiskUsageWarningThreshold)
+			}
+		}
 		return result, nil
 	}
 }
 
+// GetLatestDeploymentTargetDisks returns the disk metrics of the most recent metrics entry of a deployment target.
+func GetLatestDeploymentTargetDisks(ctx context.Context, deploymentTargetID uuid.UUID) ([]api.AgentDiskMetrics, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT coalesce(disks, '[]'::jsonb) FROM DeploymentTargetMetrics
+		WHERE deployment_target_id = @deploymentTargetId
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DeploymentTargetMetrics: %w", err)
+	} else if result, err := pgx.CollectRows(rows, pgx.RowTo[[]api.AgentDiskMetrics]); err != nil {
+		return nil, fmt.Errorf("failed to get DeploymentTargetMetrics: %w", err)
+	} else if len(result) == 0 {
+		return nil, nil
+	} else {
+		return result[0], nil
+	}
+}
+
 func CreateDeploymentTargetMetrics(
 	ctx context.Context,
 	dt *types.DeploymentTarget,
@@ -69,14 +99,17 @@ func CreateDeploymentTargetMetrics(
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx,
 		"INSERT INTO DeploymentTargetMetrics "+
-			"(deployment_target_id, cpu_cores_millis, cpu_usage, memory_bytes, memory_usage) "+
-			"VALUES (@deploymentTargetId, @cpuCoresMillis, @cpuUsage, @memoryBytes, @memoryUsage)",
+			"(deployment_target_id, cpu_cores_millis, cpu_usage, memory_bytes, memory_usage, disks, created_at) "+
+			"VALUES (@deploymentTargetId, @cpuCoresMillis, @cpuUsage, @memoryBytes, @memoryUsage, @disks, "+
+			"coalesce(@createdAt, now()))",
 		pgx.NamedArgs{
 			"deploymentTargetId": dt.ID,
 			"cpuCoresMillis":     metrics.CPUCoresMillis,
 			"cpuUsage":           metrics.CPUUsage,
 			"memoryBytes":        metrics.MemoryBytes,
 			"memoryUsage":        metrics.MemoryUsage,
+			"disks":              metrics.Disks,
+			"createdAt":          metrics.CreatedAt,
 		})
 	if err != nil {
 		return err
@@ -28,6 +28,13 @@ const (
 		dt.agent_version_id,
 		dt.reported_agent_version_id,
 		dt.metrics_enabled,
+		dt.http_proxy,
+		dt.https_proxy,
+		dt.no_proxy,
+		dt.ca_certificates,
+		dt.disk_usage_warning_threshold,
+		dt.prune_policy,
+		dt.volume_backup_policy,
 		CASE WHEN dt.resources_cpu_request IS NOT NULL THEN (
 			dt.resources_cpu_request,
 			dt.resources_memory_request,
@@ -188,14 +195,21 @@ func CreateDeploymentTarget(
 
 	db := internalctx.GetDb(ctx)
 	args := pgx.NamedArgs{
-		"name":           dt.Name,
-		"type":           dt.Type,
-		"orgId":          dt.OrganizationID,
-		"namespace":      dt.Namespace,
-		"scope":          dt.Scope,
-		"agentVersionId": dt.AgentVersionID,
-		"metricsEnabled": dt.MetricsEnabled,
-		"customerOrgId":  customerOrgID,
+		"name":                      dt.Name,
+		"type":                      dt.Type,
+		"orgId":                     dt.OrganizationID,
+		"namespace":                 dt.Namespace,
+		"scope":                     dt.Scope,
+		"agentVersionId":            dt.AgentVersionID,
+		"metricsEnabled":            dt.MetricsEnabled,
+		"customerOrgId":             customerOrgID,
+		"httpProxy":                 dt.HTTPProxy,
+		"httpsProxy":                dt.HTTPSProxy,
+		"noProxy":                   dt.NoProxy,
+		"caCertificates":            dt.CACertificates,
+		"diskUsageWarningThreshold": dt.DiskUsageWarningThreshold,
+		"prunePolicy":               dt.PrunePolicy,
+		"volumeBackupPolicy":        dt.VolumeBackupPolicy,
 	}
 
 	if dt.Resources != nil {
@@ -211,9 +225,11 @@ func CreateDeploymentTarget(
 			INSERT INTO DeploymentTarget
 			(name, type, organization_id, namespace, scope, agent_version_id, metrics_enabled,
 				customer_organization_id, resources_cpu_request, resources_memory_request, resources_cpu_limit,
-				resources_memory_limit)
+				resources_memory_limit, http_proxy, https_proxy, no_proxy, ca_certificates, disk_usage_warning_threshold,
+				prune_policy, volume_backup_policy)
 			VALUES (@name, @type, @orgId, @namespace, @scope, @agentVersionId, @metricsEnabled, @customerOrgId,
-				@resourcesCpuRequest, @resourcesMemoryRequest, @resourcesCpuLimit, @resourcesMemoryLimit)
+				@resourcesCpuRequest, @resourcesMemoryRequest, @resourcesCpuLimit, @resourcesMemoryLimit, @httpProxy,
+				@httpsProxy, @noProxy, @caCertificates, @diskUsageWarningThreshold, @prunePolicy, @volumeBackupPolicy)
 			RETURNING *
 		)
 		SELECT `+deploymentTargetOutputExpr+` FROM inserted dt`+deploymentTargetJoinExpr,
@@ -235,10 +251,17 @@ func UpdateDeploymentTarget(ctx context.Context, dt *types.DeploymentTargetWithC
 	agentUpdateStr := ""
 	db := internalctx.GetDb(ctx)
 	args := pgx.NamedArgs{
-		"id":             dt.ID,
-		"name":           dt.Name,
-		"orgId":          orgID,
-		"metricsEnabled": dt.MetricsEnabled,
+		"id":                        dt.ID,
+		"name":                      dt.Name,
+		"orgId":                     orgID,
+		"metricsEnabled":            dt.MetricsEnabled,
+		"httpProxy":                 dt.HTTPProxy,
+		"httpsProxy":                dt.HTTPSProxy,
+		"noProxy":                   dt.NoProxy,
+		"caCertificates":            dt.CACertificates,
+		"diskUsageWarningThreshold": dt.DiskUsageWarningThreshold,
+		"prunePolicy":               dt.PrunePolicy,
+		"volumeBackupPolicy":        dt.VolumeBackupPolicy,
 	}
 	if dt.AgentVersionID != nil {
 		args["agentVersionId"] = dt.AgentVersionID
@@ -258,7 +281,14 @@ func UpdateDeploymentTarget(ctx context.Context, dt *types.DeploymentTargetWithC
 				resources_cpu_request = @cpuRequest,
 				resources_cpu_limit = @cpuLimit,
 				resources_memory_request = @memoryRequest,
-				resources_memory_limit = @memoryLimit `+agentUpdateStr+`
+				resources_memory_limit = @memoryLimit,
+				http_proxy = @httpProxy,
+				https_proxy = @httpsProxy,
+				no_proxy = @noProxy,
+				ca_certificates = @caCertificates,
+				disk_usage_warning_threshold = @diskUsageWarningThreshold,
+				prune_policy = @prunePolicy,
+				volume_backup_policy = @volumeBackupPolicy `+agentUpdateStr+`
 			WHERE id = @id AND organization_id = @orgId RETURNING *
 		)
 		SELECT `+deploymentTargetWithStatusOutputExpr+` FROM updated dt`+deploymentTargetJoinExpr,
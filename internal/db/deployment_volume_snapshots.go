@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	deploymentVolumeSnapshotOutputExpr = `
+	vs.id, vs.created_at, vs.deployment_id, vs.deployment_revision_id, vs.volumes, vs.size_bytes
+	`
+)
+
+// ReplaceDeploymentVolumeSnapshots replaces all known volume snapshots of deployments of the given deployment target
+// with the snapshots that are reported by its agent.
+// Snapshots of deployments or revisions that do not exist in the deployment target anymore are ignored.
+func ReplaceDeploymentVolumeSnapshots(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+	snapshots []api.DeploymentVolumeSnapshot,
+) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(
+		ctx,
+		`DELETE FROM DeploymentVolumeSnapshot
+		WHERE deployment_id IN (SELECT id FROM Deployment WHERE deployment_target_id = @deploymentTargetId)`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID},
+	); err != nil {
+		return fmt.Errorf("could not delete DeploymentVolumeSnapshots: %w", err)
+	}
+	for _, snapshot := range snapshots {
+		if _, err := db.Exec(
+			ctx,
+			`INSERT INTO DeploymentVolumeSnapshot
+				(id, created_at, deployment_id, deployment_revision_id, volumes, size_bytes)
+			SELECT @id, @createdAt, d.id, dr.id, @volumes, @sizeBytes
+			FROM Deployment d
+				JOIN DeploymentRevision dr ON d.id = dr.deployment_id
+			WHERE d.deployment_target_id = @deploymentTargetId
+				AND d.id = @deploymentId
+				AND dr.id = @deploymentRevisionId
+			ON CONFLICT (id) DO NOTHING`,
+			pgx.NamedArgs{
+				"id":                   snapshot.ID,
+				"createdAt":            snapshot.CreatedAt,
+				"deploymentTargetId":   deploymentTargetID,
+				"deploymentId":         snapshot.DeploymentID,
+				"deploymentRevisionId": snapshot.DeploymentRevisionID,
+				"volumes":              snapshot.Volumes,
+				"sizeBytes":            snapshot.SizeBytes,
+			},
+		); err != nil {
+			return fmt.Errorf("could not insert DeploymentVolumeSnapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+func GetDeploymentVolumeSnapshots(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+) ([]types.DeploymentVolumeSnapshot, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentVolumeSnapshotOutputExpr+`
+		FROM DeploymentVolumeSnapshot vs
+		WHERE vs.deployment_id = @deploymentId
+		ORDER BY vs.created_at DESC`,
+		pgx.NamedArgs{"deploymentId": deploymentID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DeploymentVolumeSnapshots: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.DeploymentVolumeSnapshot])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect DeploymentVolumeSnapshots: %w", err)
+	}
+	return result, nil
+}
+
+func GetDeploymentVolumeSnapshot(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	id uuid.UUID,
+) (*types.DeploymentVolumeSnapshot, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentVolumeSnapshotOutputExpr+`
+		FROM DeploymentVolumeSnapshot vs
+		WHERE vs.deployment_id = @deploymentId AND vs.id = @id`,
+		pgx.NamedArgs{"deploymentId": deploymentID, "id": id},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DeploymentVolumeSnapshot: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToAddrOfStructByName[types.DeploymentVolumeSnapshot])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to collect DeploymentVolumeSnapshot: %w", err)
+	}
+	return result, nil
+}
+
+// CreateDeploymentRevisionForVolumeSnapshotRestore creates a new revision of the deployment that is a copy of the
+// revision that was running when the snapshot was created. The agent restores the volumes from the snapshot before it
+// applies this revision.
+func CreateDeploymentRevisionForVolumeSnapshotRestore(
+	ctx context.Context,
+	snapshot *types.DeploymentVolumeSnapshot,
+) (*types.DeploymentRevision, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO DeploymentRevision AS d
+			(deployment_id, application_version_id, values_yaml, env_file_data, force_restart, ignore_revision_skew,
+				run_helm_tests, pre_pull_images, restore_volume_snapshot_id)
+			SELECT deployment_id, application_version_id, values_yaml, env_file_data, false, ignore_revision_skew,
+				run_helm_tests, pre_pull_images, @snapshotId
+			FROM DeploymentRevision
+			WHERE id = @deploymentRevisionId AND deployment_id = @deploymentId
+			RETURNING d.id, d.created_at, d.deployment_id, d.application_version_id, d.force_restart,
+				d.ignore_revision_skew, d.run_helm_tests, d.pre_pull_images, d.restore_volume_snapshot_id`,
+		pgx.NamedArgs{
+			"snapshotId":           snapshot.ID,
+			"deploymentId":         snapshot.DeploymentID,
+			"deploymentRevisionId": snapshot.DeploymentRevisionID,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DeploymentRevision: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentRevision])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not save DeploymentRevision: %w", err)
+	}
+	return &result, nil
+}
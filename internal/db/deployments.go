@@ -24,7 +24,7 @@ import (
 const (
 	deploymentOutputExpr = `
 		d.id, d.created_at, d.deployment_target_id, d.release_name, d.application_license_id, d.docker_type,
-		d.logs_enabled
+		d.logs_enabled, d.namespace
 	`
 )
 
@@ -79,6 +79,9 @@ func GetDeploymentsForDeploymentTarget(
 				dr.created_at AS deployment_revision_created_at,
 				dr.force_restart AS force_restart,
 				dr.ignore_revision_skew AS ignore_revision_skew,
+				dr.run_helm_tests AS run_helm_tests,
+				dr.pre_pull_images AS pre_pull_images,
+				dr.restore_volume_snapshot_id AS restore_volume_snapshot_id,
 				a.id AS application_id,
 				a.name AS application_name,
 				av.name AS application_version_name,
@@ -195,8 +198,8 @@ func CreateDeployment(ctx context.Context, request *api.DeploymentRequest) error
 	rows, err := db.Query(
 		ctx,
 		`INSERT INTO Deployment AS d
-			(deployment_target_id, release_name, application_license_id, docker_type, logs_enabled)
-			VALUES (@deploymentTargetId, @releaseName, @applicationLicenseId, @dockerType, @logsEnabled)
+			(deployment_target_id, release_name, application_license_id, docker_type, logs_enabled, namespace)
+			VALUES (@deploymentTargetId, @releaseName, @applicationLicenseId, @dockerType, @logsEnabled, @namespace)
 			RETURNING`+deploymentOutputExpr,
 		pgx.NamedArgs{
 			"deploymentTargetId":   request.DeploymentTargetID,
@@ -204,6 +207,7 @@ func CreateDeployment(ctx context.Context, request *api.DeploymentRequest) error
 			"applicationLicenseId": request.ApplicationLicenseID,
 			"dockerType":           request.DockerType,
 			"logsEnabled":          request.LogsEnabled,
+			"namespace":            request.Namespace,
 		},
 	)
 	if err != nil {
@@ -332,9 +336,12 @@ func CreateDeploymentRevision(ctx context.Context, request *api.DeploymentReques
 	rows, err := db.Query(
 		ctx,
 		`INSERT INTO DeploymentRevision AS d
-			(deployment_id, application_version_id, values_yaml, env_file_data, force_restart, ignore_revision_skew)
-			VALUES (@deploymentId, @applicationVersionId, @valuesYaml, @envFileData, @forceRestart, @ignoreRevisionSkew)
-			RETURNING d.id, d.created_at, d.deployment_id, d.application_version_id, d.force_restart, d.ignore_revision_skew`,
+			(deployment_id, application_version_id, values_yaml, env_file_data, force_restart, ignore_revision_skew,
+				run_helm_tests, pre_pull_images)
+			VALUES (@deploymentId, @applicationVersionId, @valuesYaml, @envFileData, @forceRestart, @ignoreRevisionSkew,
+				@runHelmTests, @prePullImages)
+			RETURNING d.id, d.created_at, d.deployment_id, d.application_version_id, d.force_restart,
+				d.ignore_revision_skew, d.run_helm_tests, d.pre_pull_images, d.restore_volume_snapshot_id`,
 		pgx.NamedArgs{
 			"deploymentId":         request.DeploymentID,
 			"applicationVersionId": request.ApplicationVersionID,
@@ -342,6 +349,8 @@ func CreateDeploymentRevision(ctx context.Context, request *api.DeploymentReques
 			"envFileData":          request.EnvFileData,
 			"forceRestart":         request.ForceRestart,
 			"ignoreRevisionSkew":   request.IgnoreRevisionSkew,
+			"runHelmTests":         request.RunHelmTests,
+			"prePullImages":        request.PrePullImages,
 		},
 	)
 	if err != nil {
@@ -360,12 +369,18 @@ func CreateDeploymentRevisionStatus(
 	revisionID uuid.UUID,
 	statusType types.DeploymentStatusType,
 	message string,
+	createdAt *time.Time,
 ) error {
 	db := internalctx.GetDb(ctx)
 	_, err := db.Exec(ctx, `
-		INSERT INTO DeploymentRevisionStatus (deployment_revision_id, message, type)
-		VALUES (@deploymentRevisionId, @message, @type)`,
-		pgx.NamedArgs{"deploymentRevisionId": revisionID, "message": message, "type": statusType})
+		INSERT INTO DeploymentRevisionStatus (deployment_revision_id, message, type, created_at)
+		VALUES (@deploymentRevisionId, @message, @type, coalesce(@createdAt, now()))`,
+		pgx.NamedArgs{
+			"deploymentRevisionId": revisionID,
+			"message":              message,
+			"type":                 statusType,
+			"createdAt":            createdAt,
+		})
 	if err != nil {
 		var pgError *pgconn.PgError
 		if errors.As(err, &pgError) && pgError.Code == pgerrcode.ForeignKeyViolation {
@@ -537,3 +552,36 @@ func GetDeploymentStatusForExport(
 
 	return nil
 }
+
+// GetLatestDeploymentRevisionFiles returns the non-empty values and env files of the latest revision of every
+// deployment of the organization.
+func GetLatestDeploymentRevisionFiles(ctx context.Context, orgID uuid.UUID) ([][]byte, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT DISTINCT ON (dr.deployment_id) dr.values_yaml, dr.env_file_data
+		FROM DeploymentRevision dr
+		JOIN Deployment d ON d.id = dr.deployment_id
+		JOIN DeploymentTarget dt ON dt.id = d.deployment_target_id
+		WHERE dt.organization_id = @orgId
+		ORDER BY dr.deployment_id, dr.created_at DESC`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentRevision: %w", err)
+	}
+	var result [][]byte
+	var valuesYaml, envFileData []byte
+	_, err = pgx.ForEachRow(rows, []any{&valuesYaml, &envFileData}, func() error {
+		for _, data := range [][]byte{valuesYaml, envFileData} {
+			if len(data) > 0 {
+				result = append(result, data)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not iterate DeploymentRevision: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const registryMirrorOutputExpr = `
+	m.id,
+	m.created_at,
+	m.organization_id,
+	m.prefix,
+	m.upstream_repository,
+	m.username,
+	m.password`
+
+func GetRegistryMirrors(ctx context.Context, orgID uuid.UUID) ([]types.RegistryMirror, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT `+registryMirrorOutputExpr+`
+		FROM RegistryMirror m
+		WHERE m.organization_id = @orgId
+		ORDER BY m.prefix`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query RegistryMirror: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.RegistryMirror]); err != nil {
+		return nil, fmt.Errorf("could not collect RegistryMirror: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func CreateRegistryMirror(ctx context.Context, mirror *types.RegistryMirror) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO RegistryMirror AS m (organization_id, prefix, upstream_repository, username, password)
+		VALUES (@orgId, @prefix, @upstreamRepository, @username, @password)
+		RETURNING `+registryMirrorOutputExpr,
+		pgx.NamedArgs{
+			"orgId":              mirror.OrganizationID,
+			"prefix":             mirror.Prefix,
+			"upstreamRepository": mirror.UpstreamRepository,
+			"username":           mirror.Username,
+			"password":           mirror.Password,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert RegistryMirror: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.RegistryMirror]); err != nil {
+		var pgError *pgconn.PgError
+		if errors.As(err, &pgError) && pgError.Code == pgerrcode.UniqueViolation {
+			return apierrors.NewConflict("a mirror with this prefix already exists")
+		}
+		return fmt.Errorf("could not collect RegistryMirror: %w", err)
+	} else {
+		*mirror = result
+		return nil
+	}
+}
+
+func DeleteRegistryMirrorWithID(ctx context.Context, id uuid.UUID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM RegistryMirror WHERE id = @id AND organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete RegistryMirror: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// ClaimMirroredTagCheck returns true if the tag was not checked against the upstream registry (or pushed) within
+// maxAge and marks it as checked. Concurrent callers can not claim the same check.
+func ClaimMirroredTagCheck(
+	ctx context.Context,
+	orgID uuid.UUID,
+	artifactName, tag string,
+	maxAge time.Duration,
+) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`UPDATE ArtifactVersion v SET upstream_checked_at = current_timestamp
+		FROM Artifact a
+		WHERE a.id = v.artifact_id
+			AND a.organization_id = @orgId
+			AND a.name = @artifactName
+			AND v.name = @tag
+			AND current_timestamp - coalesce(v.upstream_checked_at, v.updated_at, v.created_at) > @maxAge`,
+		pgx.NamedArgs{"orgId": orgID, "artifactName": artifactName, "tag": tag, "maxAge": maxAge},
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not update ArtifactVersion: %w", err)
+	}
+	return cmd.RowsAffected() > 0, nil
+}
@@ -25,9 +25,15 @@ var (
 	inviteTokenValidDuration                time.Duration
 	resetTokenValidDuration                 time.Duration
 	agentTokenMaxValidDuration              time.Duration
+	agentAccessKeyRotationInterval          *time.Duration
+	agentAccessKeyRotationGracePeriod       time.Duration
+	agentAccessKeyEncryptionKey             []byte
+	agentMTLSConfig                         *AgentMTLSConfig
+	agentBundleKey                          []byte
 	agentInterval                           time.Duration
 	statusEntriesMaxAge                     *time.Duration
 	metricsEntriesMaxAge                    *time.Duration
+	eventEntriesMaxAge                      *time.Duration
 	logRecordEntriesMaxCount                *int
 	sentryDSN                               string
 	sentryDebug                             bool
@@ -47,8 +53,21 @@ var (
 	serverShutdownDelayDuration             *time.Duration
 	registration                            RegistrationMode
 	registryEnabled                         bool
+	registryStorageType                     RegistryStorageTypeString
 	registryS3Config                        S3Config
+	registryFilesystemPath                  string
 	registryScratchDir                      *string
+	registryGCCron                          *string
+	registryGCTimeout                       time.Duration
+	registryGCGracePeriod                   time.Duration
+	registryGCDryRun                        bool
+	artifactRetentionCron                   *string
+	artifactRetentionTimeout                time.Duration
+	artifactRetentionDryRun                 bool
+	artifactReplicationCron                 *string
+	artifactReplicationTimeout              time.Duration
+	registryWebhookDeliveryCron             string
+	registryWebhookDeliveryTimeout          time.Duration
 	artifactTagsDefaultLimitPerOrg          int
 	cleanupDeploymentRevisionStatusCron     *string
 	cleanupDeploymentRevisionStatusTimeout  time.Duration
@@ -60,6 +79,8 @@ var (
 	cleanupDeploymentLogRecordTimeout       time.Duration
 	cleanupDeploymentTargetLogRecordCron    *string
 	cleanupDeploymentTargetLogRecordTimeout time.Duration
+	cleanupDeploymentEventRecordCron        *string
+	cleanupDeploymentEventRecordTimeout     time.Duration
 	cleanupOIDCStateCron                    *string
 	cleanupOIDCStateCronTimeout             time.Duration
 	oidcGithubEnabled                       bool
@@ -81,6 +102,7 @@ var (
 	wellKnownMicrosoftIdentityAssociation   []byte
 	stripeWebhookSecret                     *string
 	stripeAPIKey                            *string
+	allowPrivateOutboundAddresses           bool
 )
 
 func Initialize() {
@@ -102,6 +124,7 @@ func Initialize() {
 	agentInterval = envutil.GetEnvParsedOrDefault("AGENT_INTERVAL", envparse.PositiveDuration, 5*time.Second)
 	statusEntriesMaxAge = envutil.GetEnvParsedOrNil("STATUS_ENTRIES_MAX_AGE", envparse.PositiveDuration)
 	metricsEntriesMaxAge = envutil.GetEnvParsedOrNil("METRICS_ENTRIES_MAX_AGE", envparse.PositiveDuration)
+	eventEntriesMaxAge = envutil.GetEnvParsedOrNil("EVENT_ENTRIES_MAX_AGE", envparse.PositiveDuration)
 	logRecordEntriesMaxCount = envutil.GetEnvParsedOrNil("LOG_RECORD_ENTRIES_MAX_COUNT", envparse.NonNegativeNumber)
 	enableQueryLogging = envutil.GetEnvParsedOrDefault("ENABLE_QUERY_LOGGING", strconv.ParseBool, false)
 	userEmailVerificationRequired = envutil.GetEnvParsedOrDefault(
@@ -118,6 +141,34 @@ func Initialize() {
 	agentTokenMaxValidDuration = envutil.GetEnvParsedOrDefault(
 		"AGENT_TOKEN_MAX_VALID_DURATION", envparse.PositiveDuration, 24*time.Hour,
 	)
+	agentAccessKeyRotationInterval = envutil.GetEnvParsedOrNil(
+		"AGENT_ACCESS_KEY_ROTATION_INTERVAL", envparse.PositiveDuration,
+	)
+	agentAccessKeyRotationGracePeriod = envutil.GetEnvParsedOrDefault(
+		"AGENT_ACCESS_KEY_ROTATION_GRACE_PERIOD", envparse.PositiveDuration, 24*time.Hour,
+	)
+	if agentAccessKeyRotationInterval != nil {
+		agentAccessKeyEncryptionKey = envutil.RequireEnvParsed("AGENT_ACCESS_KEY_ENCRYPTION_KEY", parseSecretKey)
+	} else {
+		agentAccessKeyEncryptionKey = envutil.GetEnvParsedOrDefault(
+			"AGENT_ACCESS_KEY_ENCRYPTION_KEY", parseSecretKey, nil,
+		)
+	}
+	if caCertificate := envutil.GetEnvParsedOrNil(
+		"AGENT_MTLS_CA_CERTIFICATE", base64.StdEncoding.DecodeString,
+	); caCertificate != nil {
+		agentMTLSConfig = &AgentMTLSConfig{
+			CACertificate: *caCertificate,
+			CAKey:         envutil.RequireEnvParsed("AGENT_MTLS_CA_KEY", base64.StdEncoding.DecodeString),
+			CertificateValidity: envutil.GetEnvParsedOrDefault(
+				"AGENT_MTLS_CERTIFICATE_VALIDITY", envparse.PositiveDuration, 30*24*time.Hour,
+			),
+			// The hub does not terminate TLS itself, so client certificates can only be received from a proxy.
+			ClientCertificateHeader: envutil.RequireEnv("AGENT_MTLS_CLIENT_CERTIFICATE_HEADER"),
+			TrustedProxies:          envutil.RequireEnvParsed("AGENT_MTLS_TRUSTED_PROXIES", envparse.Prefixes),
+		}
+	}
+	agentBundleKey = envutil.GetEnvParsedOrDefault("AGENT_BUNDLE_KEY", parseSecretKey, nil)
 
 	mailerConfig.Type = envutil.GetEnvParsedOrDefault("MAILER_TYPE", parseMailerType, MailerTypeUnspecified)
 	if mailerConfig.Type != MailerTypeUnspecified {
@@ -136,23 +187,52 @@ func Initialize() {
 	registryEnabled = envutil.GetEnvParsedOrDefault("REGISTRY_ENABLED", strconv.ParseBool, false)
 	if registryEnabled {
 		registryHost = envutil.RequireEnv("REGISTRY_HOST")
-		registryS3Config.Bucket = envutil.RequireEnv("REGISTRY_S3_BUCKET")
-		registryS3Config.Region = envutil.RequireEnv("REGISTRY_S3_REGION")
-		registryS3Config.Endpoint = envutil.GetEnvOrNil("REGISTRY_S3_ENDPOINT")
-		registryS3Config.AccessKeyID = envutil.GetEnvOrNil("REGISTRY_S3_ACCESS_KEY_ID")
-		registryS3Config.SecretAccessKey = envutil.GetEnvOrNil("REGISTRY_S3_SECRET_ACCESS_KEY")
-		registryS3Config.UsePathStyle = envutil.GetEnvParsedOrDefault("REGISTRY_S3_USE_PATH_STYLE", strconv.ParseBool, false)
-		registryS3Config.AllowRedirect = envutil.GetEnvParsedOrDefault("REGISTRY_S3_ALLOW_REDIRECT", strconv.ParseBool, true)
-		registryS3Config.RequestChecksumCalculationWhenRequired = envutil.GetEnvParsedOrDefault(
-			"REGISTRY_S3_REQUEST_CHECKSUM_CALCULATION", strconv.ParseBool, false,
+		registryStorageType = envutil.GetEnvParsedOrDefault(
+			"REGISTRY_STORAGE_TYPE", parseRegistryStorageType, RegistryStorageS3,
 		)
-		registryS3Config.ResponseChecksumValidationWhenRequired = envutil.GetEnvParsedOrDefault(
-			"REGISTRY_S3_RESPONSE_CHECKSUM_VALIDATION", strconv.ParseBool, false,
+		switch registryStorageType {
+		case RegistryStorageS3:
+			registryS3Config.Bucket = envutil.RequireEnv("REGISTRY_S3_BUCKET")
+			registryS3Config.Region = envutil.RequireEnv("REGISTRY_S3_REGION")
+			registryS3Config.Endpoint = envutil.GetEnvOrNil("REGISTRY_S3_ENDPOINT")
+			registryS3Config.AccessKeyID = envutil.GetEnvOrNil("REGISTRY_S3_ACCESS_KEY_ID")
+			registryS3Config.SecretAccessKey = envutil.GetEnvOrNil("REGISTRY_S3_SECRET_ACCESS_KEY")
+			registryS3Config.UsePathStyle = envutil.GetEnvParsedOrDefault("REGISTRY_S3_USE_PATH_STYLE", strconv.ParseBool, false)
+			registryS3Config.AllowRedirect = envutil.GetEnvParsedOrDefault("REGISTRY_S3_ALLOW_REDIRECT", strconv.ParseBool, true)
+			registryS3Config.RequestChecksumCalculationWhenRequired = envutil.GetEnvParsedOrDefault(
+				"REGISTRY_S3_REQUEST_CHECKSUM_CALCULATION", strconv.ParseBool, false,
+			)
+			registryS3Config.ResponseChecksumValidationWhenRequired = envutil.GetEnvParsedOrDefault(
+				"REGISTRY_S3_RESPONSE_CHECKSUM_VALIDATION", strconv.ParseBool, false,
+			)
+			registryS3Config.ResignForGCP = envutil.GetEnvParsedOrDefault(
+				"REGISTRY_RESIGN_FOR_GCP", strconv.ParseBool, false,
+			)
+		case RegistryStorageFilesystem:
+			registryFilesystemPath = envutil.RequireEnv("REGISTRY_FILESYSTEM_PATH")
+		}
+		registryScratchDir = envutil.GetEnvOrNil("REGISTRY_SCRATCH_DIR")
+		registryGCCron = envutil.GetEnvOrNil("REGISTRY_GC_CRON")
+		registryGCTimeout = envutil.GetEnvParsedOrDefault("REGISTRY_GC_TIMEOUT", envparse.PositiveDuration, 0)
+		registryGCGracePeriod = envutil.GetEnvParsedOrDefault(
+			"REGISTRY_GC_GRACE_PERIOD", envparse.PositiveDuration, 24*time.Hour,
 		)
-		registryS3Config.ResignForGCP = envutil.GetEnvParsedOrDefault(
-			"REGISTRY_RESIGN_FOR_GCP", strconv.ParseBool, false,
+		registryGCDryRun = envutil.GetEnvParsedOrDefault("REGISTRY_GC_DRY_RUN", strconv.ParseBool, false)
+		artifactRetentionCron = envutil.GetEnvOrNil("ARTIFACT_RETENTION_CRON")
+		artifactRetentionTimeout = envutil.GetEnvParsedOrDefault(
+			"ARTIFACT_RETENTION_TIMEOUT", envparse.PositiveDuration, 0,
+		)
+		artifactRetentionDryRun = envutil.GetEnvParsedOrDefault("ARTIFACT_RETENTION_DRY_RUN", strconv.ParseBool, false)
+		artifactReplicationCron = envutil.GetEnvOrNil("ARTIFACT_REPLICATION_CRON")
+		artifactReplicationTimeout = envutil.GetEnvParsedOrDefault(
+			"ARTIFACT_REPLICATION_TIMEOUT", envparse.PositiveDuration, 0,
+		)
+		registryWebhookDeliveryCron = envutil.GetEnvOrDefault(
+			"REGISTRY_WEBHOOK_DELIVERY_CRON", "* * * * *", envutil.GetEnvOpts{},
+		)
+		registryWebhookDeliveryTimeout = envutil.GetEnvParsedOrDefault(
+			"REGISTRY_WEBHOOK_DELIVERY_TIMEOUT", envparse.PositiveDuration, 0,
 		)
-		registryScratchDir = envutil.GetEnvOrNil("REGISTRY_SCRATCH_DIR")
 	}
 	artifactTagsDefaultLimitPerOrg = envutil.GetEnvParsedOrDefault(
 		"ARTIFACT_TAGS_DEFAULT_LIMIT_PER_ORG", envparse.NonNegativeNumber, 0,
@@ -176,6 +256,10 @@ func Initialize() {
 		}
 	}
 
+	allowPrivateOutboundAddresses = envutil.GetEnvParsedOrDefault(
+		"ALLOW_PRIVATE_OUTBOUND_ADDRESSES", strconv.ParseBool, false,
+	)
+
 	agentDockerConfig = envutil.GetEnvParsedOrDefault("AGENT_DOCKER_CONFIG", envparse.ByteSlice, nil)
 	frontendSentryDSN = envutil.GetEnvOrNil("FRONTEND_SENTRY_DSN")
 	frontendSentryTraceSampleRate = envutil.GetEnvParsedOrNil("FRONTEND_SENTRY_TRACE_SAMPLE_RATE", envparse.Float)
@@ -198,6 +282,9 @@ func Initialize() {
 	cleanupDeploymentTargetLogRecordCron = envutil.GetEnvOrNil("CLEANUP_DEPLOYMENT_TARGET_LOG_RECORD_CRON")
 	cleanupDeploymentTargetLogRecordTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_DEPLOYMENT_TARGET_LOG_RECORD_TIMEOUT",
 		envparse.PositiveDuration, 0)
+	cleanupDeploymentEventRecordCron = envutil.GetEnvOrNil("CLEANUP_DEPLOYMENT_EVENT_RECORD_CRON")
+	cleanupDeploymentEventRecordTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_DEPLOYMENT_EVENT_RECORD_TIMEOUT",
+		envparse.PositiveDuration, 0)
 	cleanupOIDCStateCron = envutil.GetEnvOrNil("CLEANUP_OIDC_STATE_CRON")
 	cleanupOIDCStateCronTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_OIDC_STATE_CRON_TIMEOUT",
 		envparse.PositiveDuration, 0)
@@ -269,6 +356,35 @@ func AgentTokenMaxValidDuration() time.Duration {
 	return agentTokenMaxValidDuration
 }
 
+// AgentAccessKeyRotationInterval is the maximum age of a deployment target access key.
+// Older keys are rotated automatically. If nil, keys are only rotated on request.
+func AgentAccessKeyRotationInterval() *time.Duration {
+	return agentAccessKeyRotationInterval
+}
+
+// AgentAccessKeyRotationGracePeriod is the duration the previous access key remains valid after a rotation.
+func AgentAccessKeyRotationGracePeriod() time.Duration {
+	return agentAccessKeyRotationGracePeriod
+}
+
+// AgentAccessKeyEncryptionKey is the key that rotated access keys are encrypted with until the agent confirms them.
+// If nil, access keys can not be rotated.
+func AgentAccessKeyEncryptionKey() []byte {
+	return agentAccessKeyEncryptionKey
+}
+
+// GetAgentMTLSConfig returns the configuration of the CA that issues client certificates for agents.
+// If nil, agents can only authenticate with a JWT.
+func GetAgentMTLSConfig() *AgentMTLSConfig {
+	return agentMTLSConfig
+}
+
+// AgentBundleKey returns the key from which the keys for signing and encrypting deployment bundles are derived.
+// If nil, deployment bundles can not be exported.
+func AgentBundleKey() []byte {
+	return agentBundleKey
+}
+
 func AgentInterval() time.Duration {
 	return agentInterval
 }
@@ -297,6 +413,10 @@ func MetricsEntriesMaxAge() *time.Duration {
 	return metricsEntriesMaxAge
 }
 
+func EventEntriesMaxAge() *time.Duration {
+	return eventEntriesMaxAge
+}
+
 func LogRecordEntriesMaxCount() *int {
 	return logRecordEntriesMaxCount
 }
@@ -341,14 +461,68 @@ func RegistryEnabled() bool {
 	return registryEnabled
 }
 
+func RegistryStorageType() RegistryStorageTypeString {
+	return registryStorageType
+}
+
 func RegistryS3Config() S3Config {
 	return registryS3Config
 }
 
+func RegistryFilesystemPath() string {
+	return registryFilesystemPath
+}
+
 func RegistryScratchDir() *string {
 	return registryScratchDir
 }
 
+func RegistryGCCron() *string {
+	return registryGCCron
+}
+
+func RegistryGCTimeout() time.Duration {
+	return registryGCTimeout
+}
+
+// RegistryGCGracePeriod is the minimum age of blobs and manifests before they can be deleted by the registry garbage
+// collection, so that uploads which are still in progress are not affected.
+func RegistryGCGracePeriod() time.Duration {
+	return registryGCGracePeriod
+}
+
+func RegistryGCDryRun() bool {
+	return registryGCDryRun
+}
+
+func ArtifactRetentionCron() *string {
+	return artifactRetentionCron
+}
+
+func ArtifactRetentionTimeout() time.Duration {
+	return artifactRetentionTimeout
+}
+
+func ArtifactRetentionDryRun() bool {
+	return artifactRetentionDryRun
+}
+
+func ArtifactReplicationCron() *string {
+	return artifactReplicationCron
+}
+
+func ArtifactReplicationTimeout() time.Duration {
+	return artifactReplicationTimeout
+}
+
+func RegistryWebhookDeliveryCron() string {
+	return registryWebhookDeliveryCron
+}
+
+func RegistryWebhookDeliveryTimeout() time.Duration {
+	return registryWebhookDeliveryTimeout
+}
+
 func ArtifactTagsDefaultLimitPerOrg() int {
 	return artifactTagsDefaultLimitPerOrg
 }
@@ -409,6 +583,14 @@ func CleanupDeploymentTargetLogRecordTimeout() time.Duration {
 	return cleanupDeploymentTargetLogRecordTimeout
 }
 
+func CleanupDeploymentEventRecordCron() *string {
+	return cleanupDeploymentEventRecordCron
+}
+
+func CleanupDeploymentEventRecordTimeout() time.Duration {
+	return cleanupDeploymentEventRecordTimeout
+}
+
 func CleanupOIDCStateCron() *string {
 	return cleanupOIDCStateCron
 }
@@ -490,3 +672,9 @@ func StripeWebhookSecret() *string {
 func StripeAPIKey() *string {
 	return stripeAPIKey
 }
+
+// AllowPrivateOutboundAddresses is true if requests to URLs configured by users, like registry mirrors, replication
+// targets and webhooks, may connect to loopback, private and link-local addresses.
+func AllowPrivateOutboundAddresses() bool {
+	return allowPrivateOutboundAddresses
+}
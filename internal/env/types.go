@@ -1,8 +1,12 @@
 package env
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/mail"
+	"net/netip"
+	"time"
 )
 
 type RegistrationMode string
@@ -30,6 +34,17 @@ const (
 	MailerTypeUnspecified MailerTypeString = ""
 )
 
+// parseSecretKey parses a base64 encoded key of at least 32 bytes.
+func parseSecretKey(value string) ([]byte, error) {
+	if key, err := base64.StdEncoding.DecodeString(value); err != nil {
+		return nil, err
+	} else if len(key) < 32 {
+		return nil, errors.New("invalid key: must be at least 32 bytes")
+	} else {
+		return key, nil
+	}
+}
+
 func parseMailerType(value string) (MailerTypeString, error) {
 	switch value {
 	case string(MailerTypeSES), string(MailerTypeSMTP), string(MailerTypeUnspecified):
@@ -53,6 +68,22 @@ type MailerSMTPConfig struct {
 	ImplicitTLS bool
 }
 
+type RegistryStorageTypeString string
+
+const (
+	RegistryStorageS3         RegistryStorageTypeString = "s3"
+	RegistryStorageFilesystem RegistryStorageTypeString = "filesystem"
+)
+
+func parseRegistryStorageType(value string) (RegistryStorageTypeString, error) {
+	switch value {
+	case string(RegistryStorageS3), string(RegistryStorageFilesystem):
+		return RegistryStorageTypeString(value), nil
+	default:
+		return "", fmt.Errorf("invalid RegistryStorageTypeString: %v", value)
+	}
+}
+
 type S3Config struct {
 	Bucket                                 string
 	Region                                 string
@@ -96,3 +127,17 @@ type SamplerConfig struct {
 	Sampler SamplerType
 	Arg     float64
 }
+
+type AgentMTLSConfig struct {
+	// CACertificate and CAKey are PEM encoded
+	CACertificate       []byte
+	CAKey               []byte
+	CertificateValidity time.Duration
+	// ClientCertificateHeader is the name of the header that contains the URL encoded client certificate.
+	// The header must be set by a trusted reverse proxy that terminates TLS and always overwrites this header, as
+	// the hub can not verify that the client possesses the key of a certificate passed in a header.
+	ClientCertificateHeader string
+	// TrustedProxies are the addresses of the reverse proxies that may set ClientCertificateHeader.
+	// The header is rejected in requests from any other address.
+	TrustedProxies []netip.Prefix
+}
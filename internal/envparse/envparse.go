@@ -3,7 +3,9 @@ package envparse
 import (
 	"errors"
 	"net/mail"
+	"net/netip"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -38,3 +40,16 @@ func NonNegativeNumber(value string) (int, error) {
 func Float(value string) (float64, error) {
 	return strconv.ParseFloat(value, 64)
 }
+
+// Prefixes parses a comma separated list of CIDR prefixes, like "10.0.0.0/8,192.168.1.10/32".
+func Prefixes(value string) ([]netip.Prefix, error) {
+	var result []netip.Prefix
+	for part := range strings.SplitSeq(value, ",") {
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(part)); err != nil {
+			return nil, err
+		} else {
+			result = append(result, prefix)
+		}
+	}
+	return result, nil
+}
@@ -3,15 +3,18 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentbundle"
 	"github.com/distr-sh/distr/internal/agentclient/useragent"
 	"github.com/distr-sh/distr/internal/agentconnect"
 	"github.com/distr-sh/distr/internal/agentmanifest"
@@ -22,6 +25,7 @@ import (
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/deploymentvalues"
 	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/mailsending"
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/security"
 	"github.com/distr-sh/distr/internal/types"
@@ -69,6 +73,8 @@ func AgentRouter(r chiopenapi.Router) {
 			r.Post("/status", angentPostStatusHandler)
 			r.Post("/metrics", agentPostMetricsHander)
 			r.Put("/logs", agentPutDeploymentLogsHandler())
+			r.Put("/events", agentPutDeploymentEventsHandler())
+			r.Put("/volume-snapshots", agentPutDeploymentVolumeSnapshotsHandler())
 			r.Put("/deployment-target-logs", agentPutDeploymentTargetLogsHandler())
 		})
 	})
@@ -138,8 +144,7 @@ func agentLoginHandler(w http.ResponseWriter, r *http.Request) {
 	log := internalctx.GetLogger(ctx)
 
 	if targetId, targetSecret, ok := r.BasicAuth(); !ok {
-		log.Error("invalid Basic Auth")
-		w.WriteHeader(http.StatusUnauthorized)
+		agentCertificateLogin(w, r)
 	} else if parsedTargetId, err := uuid.Parse(targetId); err != nil {
 		http.Error(w, "targetId is not a valid UUID", http.StatusBadRequest)
 	} else if agentLoginPerTargetIdRateLimiter.RespondOnLimit(w, r, targetId) {
@@ -162,121 +167,60 @@ func agentLoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// agentCertificateLogin issues an agent token for an agent that authenticated with a client certificate instead of
+// its target secret.
+func agentCertificateLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	if authInfo, err := auth.AgentCertificateAuthenticator.Authenticate(ctx, r); err != nil {
+		log.Error("invalid Basic Auth and client certificate", zap.Error(err))
+		w.WriteHeader(http.StatusUnauthorized)
+	} else if agentLoginPerTargetIdRateLimiter.RespondOnLimit(
+		w, r, authInfo.CurrentDeploymentTargetID().String(),
+	) {
+		return
+	} else if _, token, err := authjwt.GenerateAgentTokenValidFor(
+		authInfo.CurrentDeploymentTargetID(), authInfo.CurrentOrgID(), env.AgentTokenMaxValidDuration(),
+	); err != nil {
+		log.Error("failed to create agent token", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	} else if err := json.NewEncoder(w).Encode(api.AuthLoginResponse{Token: token}); err != nil {
+		log.Error("failed to write response", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func agentResourcesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
 	log := internalctx.GetLogger(ctx).With(zap.String("deploymentTargetId", deploymentTarget.ID.String()))
 
 	statusMessage := "OK"
-	deployments, err := db.GetDeploymentsForDeploymentTarget(ctx, deploymentTarget.ID)
-	if err != nil {
-		msg := "failed to get latest Deployment from DB"
-		log.Error(msg, zap.Error(err))
-		statusMessage = fmt.Sprintf("%v: %v", msg, err)
+	if agentResource, err := getAgentResource(ctx, deploymentTarget); err != nil {
+		log.Error("failed to get agent resource", zap.Error(err))
+		statusMessage = err.Error()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	} else {
-		agentResource := api.AgentResource{
-			Version:        deploymentTarget.AgentVersion,
-			MetricsEnabled: deploymentTarget.MetricsEnabled,
-		}
-		if deploymentTarget.Namespace != nil {
-			agentResource.Namespace = *deploymentTarget.Namespace
-		}
-
-		for _, deployment := range deployments {
-			appVersion, err := db.GetApplicationVersion(ctx, deployment.ApplicationVersionID)
-			if err != nil {
-				msg := "failed to get ApplicationVersion from DB"
-				log.Error(msg, zap.Error(err))
-				statusMessage = fmt.Sprintf("%v: %v", msg, err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				break
-			}
-
-			agentDeployment := api.AgentDeployment{
-				ID:                 deployment.ID,
-				RevisionID:         deployment.DeploymentRevisionID,
-				LogsEnabled:        deployment.LogsEnabled,
-				ForceRestart:       deployment.ForceRestart,
-				IgnoreRevisionSkew: deployment.IgnoreRevisionSkew,
-			}
-
-			if deployment.ApplicationLicenseID != nil {
-				if license, err := db.GetApplicationLicenseByID(ctx, *deployment.ApplicationLicenseID); err != nil {
-					msg := "failed to get ApplicationLicense from DB"
-					log.Error(msg, zap.Error(err))
-					statusMessage = fmt.Sprintf("%v: %v", msg, err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					break
-				} else if license.RegistryURL != nil {
-					agentDeployment.RegistryAuth = map[string]api.AgentRegistryAuth{
-						*license.RegistryURL: {
-							Username: *license.RegistryUsername,
-							Password: *license.RegistryPassword,
-						},
-					}
-				}
-			}
-
-			var secrets []types.SecretWithUpdatedBy
-			if secrets, err = db.GetSecretsForDeploymentTarget(ctx, deploymentTarget.DeploymentTarget); err != nil {
-				msg := "failed to get secrets from DB"
-				log.Error(msg, zap.Error(err))
-				statusMessage = fmt.Sprintf("%v: %v", msg, err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				break
-			}
-
-			if deploymentTarget.Type == types.DeploymentTypeDocker {
-				if composeYaml, err := appVersion.ParsedComposeFile(); err != nil {
-					log.Warn("parse error", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if patchedComposeFile, err := patchProjectName(composeYaml, deployment.ID); err != nil {
-					log.Warn("failed to patch project name", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if envFile, err := deploymentvalues.EnvFileReplaceSecrets(&deployment, secrets); err != nil {
-					log.Warn("failed to replace secrets", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else {
-					agentDeployment.ComposeFile = patchedComposeFile
-					agentDeployment.EnvFile = envFile
-					agentDeployment.DockerType = util.PtrCopy(deployment.DockerType)
-				}
-			} else {
-				agentDeployment.ReleaseName = *deployment.ReleaseName
-				agentDeployment.ChartUrl = *appVersion.ChartUrl
-				agentDeployment.ChartVersion = *appVersion.ChartVersion
-				if versionValues, err := appVersion.ParsedValuesFile(); err != nil {
-					log.Warn("parse error", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if deploymentValues, err := deploymentvalues.ParsedValuesFileReplaceSecrets(
-					&deployment,
-					secrets,
-				); err != nil {
-					log.Warn("parse error", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if merged, err := util.MergeAllRecursive(versionValues, deploymentValues); err != nil {
-					log.Warn("merge error", zap.Error(err))
-					http.Error(w, fmt.Sprintf("error merging values files: %v", err), http.StatusInternalServerError)
-					return
-				} else {
-					agentDeployment.Values = merged
-				}
-				if *appVersion.ChartType == types.HelmChartTypeRepository {
-					agentDeployment.ChartName = *appVersion.ChartName
-				}
-			}
-			agentResource.Deployments = append(agentResource.Deployments, agentDeployment)
+		if rotation, err := getAccessKeyRotation(ctx, deploymentTarget); err != nil {
+			// A failed rotation must not prevent the agent from receiving its resource.
+			log.Error("failed to rotate access key", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		} else {
+			agentResource.AccessKeyRotation = rotation
 		}
-
-		if statusMessage == "OK" {
-			RespondJSON(w, agentResource)
+		if certificate, err := getClientCertificateRenewal(r, deploymentTarget); err != nil {
+			log.Error("failed to renew client certificate", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		} else {
+			agentResource.ClientCertificate = certificate
+		}
+		if key := env.AgentBundleKey(); key != nil {
+			agentResource.BundleKey = base64.StdEncoding.EncodeToString(
+				agentbundle.TargetKey(key, deploymentTarget.ID),
+			)
 		}
+		RespondJSON(w, agentResource)
 	}
 
 	// not in a TX because insertion should not be rolled back when the cleanup fails
@@ -287,6 +231,154 @@ func agentResourcesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getAgentResource assembles the desired state of the given deployment target as it is delivered to the agent.
+func getAgentResource(
+	ctx context.Context,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+) (*api.AgentResource, error) {
+	deployments, err := db.GetDeploymentsForDeploymentTarget(ctx, deploymentTarget.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest Deployment from DB: %w", err)
+	}
+
+	agentResource := api.AgentResource{
+		Version:            deploymentTarget.AgentVersion,
+		MetricsEnabled:     deploymentTarget.MetricsEnabled,
+		PrunePolicy:        deploymentTarget.PrunePolicy,
+		VolumeBackupPolicy: deploymentTarget.VolumeBackupPolicy,
+	}
+	if deploymentTarget.Namespace != nil {
+		agentResource.Namespace = *deploymentTarget.Namespace
+	}
+
+	var secrets []types.SecretWithUpdatedBy
+	if len(deployments) > 0 {
+		if secrets, err = db.GetSecretsForDeploymentTarget(ctx, deploymentTarget.DeploymentTarget); err != nil {
+			return nil, fmt.Errorf("failed to get secrets from DB: %w", err)
+		}
+	}
+
+	var signatureRequired bool
+	var trustedSigningKeys []string
+	if len(deployments) > 0 {
+		signatureRequired, trustedSigningKeys, err = getTrustedSigningKeys(ctx, deploymentTarget.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, deployment := range deployments {
+		if agentDeployment, err := getAgentDeployment(ctx, deploymentTarget, deployment, secrets); err != nil {
+			return nil, err
+		} else {
+			agentDeployment.SignatureRequired = signatureRequired
+			agentDeployment.TrustedSigningKeys = trustedSigningKeys
+			agentResource.Deployments = append(agentResource.Deployments, *agentDeployment)
+		}
+	}
+
+	return &agentResource, nil
+}
+
+// getTrustedSigningKeys returns whether the organization requires signatures for deployments and, if it does, its
+// signing keys.
+func getTrustedSigningKeys(ctx context.Context, orgID uuid.UUID) (bool, []string, error) {
+	org, err := db.GetOrganizationByID(ctx, orgID)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get Organization from DB: %w", err)
+	} else if !org.HasFeature(types.FeatureArtifactSignatureRequired) {
+		return false, nil, nil
+	}
+	keys, err := db.GetArtifactSigningKeys(ctx, orgID)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get ArtifactSigningKeys from DB: %w", err)
+	}
+	result := make([]string, len(keys))
+	for i, key := range keys {
+		result[i] = key.PublicKey
+	}
+	return true, result, nil
+}
+
+func getAgentDeployment(
+	ctx context.Context,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+	deployment types.DeploymentWithLatestRevision,
+	secrets []types.SecretWithUpdatedBy,
+) (*api.AgentDeployment, error) {
+	appVersion, err := db.GetApplicationVersion(ctx, deployment.ApplicationVersionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ApplicationVersion from DB: %w", err)
+	}
+
+	agentDeployment := api.AgentDeployment{
+		ID:                 deployment.ID,
+		RevisionID:         deployment.DeploymentRevisionID,
+		LogsEnabled:        deployment.LogsEnabled,
+		ForceRestart:       deployment.ForceRestart,
+		IgnoreRevisionSkew: deployment.IgnoreRevisionSkew,
+		RunHelmTests:       deployment.RunHelmTests,
+		PrePullImages:      deployment.PrePullImages,
+	}
+
+	if deployment.ApplicationLicenseID != nil {
+		if license, err := db.GetApplicationLicenseByID(ctx, *deployment.ApplicationLicenseID); err != nil {
+			return nil, fmt.Errorf("failed to get ApplicationLicense from DB: %w", err)
+		} else if license.RegistryURL != nil {
+			agentDeployment.RegistryAuth = map[string]api.AgentRegistryAuth{
+				*license.RegistryURL: {
+					Username: *license.RegistryUsername,
+					Password: *license.RegistryPassword,
+				},
+			}
+		}
+	}
+
+	if deploymentTarget.Type == types.DeploymentTypeDocker {
+		if composeYaml, err := appVersion.ParsedComposeFile(); err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		} else if patchedComposeFile, err := patchProjectName(composeYaml, deployment.ID); err != nil {
+			return nil, fmt.Errorf("failed to patch project name: %w", err)
+		} else if envFile, err := deploymentvalues.EnvFileReplaceSecrets(&deployment, secrets); err != nil {
+			return nil, fmt.Errorf("failed to replace secrets: %w", err)
+		} else {
+			agentDeployment.ComposeFile = patchedComposeFile
+			agentDeployment.EnvFile = envFile
+			agentDeployment.DockerType = util.PtrCopy(deployment.DockerType)
+			agentDeployment.RestoreVolumeSnapshotID = deployment.RestoreVolumeSnapshotID
+		}
+	} else {
+		agentDeployment.ReleaseName = *deployment.ReleaseName
+		if deployment.Namespace != nil {
+			agentDeployment.Namespace = *deployment.Namespace
+		}
+		if versionValues, err := appVersion.ParsedValuesFile(); err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		} else if deploymentValues, err := deploymentvalues.ParsedValuesFileReplaceSecrets(
+			&deployment,
+			secrets,
+		); err != nil {
+			return nil, fmt.Errorf("parse error: %w", err)
+		} else if merged, err := util.MergeAllRecursive(versionValues, deploymentValues); err != nil {
+			return nil, fmt.Errorf("error merging values files: %w", err)
+		} else {
+			agentDeployment.Values = merged
+		}
+		if !appVersion.IsHelm() {
+			agentDeployment.KubernetesType = util.PtrCopy(appVersion.KubernetesType)
+			agentDeployment.ManifestFile = appVersion.ManifestFileData
+		} else {
+			agentDeployment.ChartUrl = *appVersion.ChartUrl
+			agentDeployment.ChartVersion = *appVersion.ChartVersion
+			if *appVersion.ChartType == types.HelmChartTypeRepository {
+				agentDeployment.ChartName = *appVersion.ChartName
+			}
+		}
+	}
+
+	return &agentDeployment, nil
+}
+
 func agentPutDeploymentLogsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -321,6 +413,64 @@ func agentPutDeploymentLogsHandler() http.HandlerFunc {
 	}
 }
 
+func agentPutDeploymentEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := internalctx.GetLogger(ctx)
+		auth := auth.AgentAuthentication.Require(ctx)
+		records, err := JsonBody[[]api.DeploymentEventRecord](w, r)
+		if err != nil {
+			return
+		}
+
+		if err := db.ValidateDeploymentEventRecords(ctx, auth.CurrentDeploymentTargetID(), records); err != nil {
+			if errors.Is(err, apierrors.ErrNotFound) {
+				http.Error(w, fmt.Sprintf("bad request: %v", err), http.StatusBadRequest)
+			} else {
+				log.Error("error saving deployment event records", zap.Error(err))
+				sentry.GetHubFromContext(ctx).CaptureException(err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if err := db.SaveDeploymentEventRecords(ctx, records); errors.Is(err, apierrors.ErrBadRequest) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			log.Error("error saving deployment event records", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func agentPutDeploymentVolumeSnapshotsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := internalctx.GetLogger(ctx)
+		auth := auth.AgentAuthentication.Require(ctx)
+		snapshots, err := JsonBody[[]api.DeploymentVolumeSnapshot](w, r)
+		if err != nil {
+			return
+		}
+
+		if err := db.RunTx(ctx, func(ctx context.Context) error {
+			return db.ReplaceDeploymentVolumeSnapshots(ctx, auth.CurrentDeploymentTargetID(), snapshots)
+		}); err != nil {
+			log.Error("error saving deployment volume snapshots", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func agentPutDeploymentTargetLogsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -363,7 +513,9 @@ func angentPostStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	if err := db.CreateDeploymentRevisionStatus(ctx, status.RevisionID, status.Type, status.Message); err != nil {
+	if err := db.CreateDeploymentRevisionStatus(
+		ctx, status.RevisionID, status.Type, status.Message, validQueuedAt(status.CreatedAt),
+	); err != nil {
 		if errors.Is(err, apierrors.ErrConflict) {
 			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		} else {
@@ -388,20 +540,78 @@ func agentPostMetricsHander(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
-	if err := db.CreateDeploymentTargetMetrics(ctx, &dt.DeploymentTarget, &metrics); err != nil {
-		if errors.Is(err, apierrors.ErrConflict) {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		} else {
-			log.Error("failed to create deployment target metrics – skipping cleanup of old metrics", zap.Error(err),
-				zap.Reflect("metrics", metrics))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
+	metrics.CreatedAt = validQueuedAt(metrics.CreatedAt)
+	if deployments, err := db.FilterDeploymentMetrics(ctx, dt.ID, metrics.Deployments); err != nil {
+		log.Error("failed to validate deployment metrics", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	} else if len(deployments) < len(metrics.Deployments) {
+		// The deployment might have been deleted after the metrics were collected. The remaining metrics are still
+		// stored, because rejecting them would make the agent resend the same metrics over and over.
+		log.Info("dropping metrics of unknown deployments",
+			zap.Int("dropped", len(metrics.Deployments)-len(deployments)))
+		metrics.Deployments = deployments
+	}
+	var previousDisks []api.AgentDiskMetrics
+	if dt.DiskUsageWarningThreshold != nil && len(metrics.Disks) > 0 {
+		if previousDisks, err = db.GetLatestDeploymentTargetDisks(ctx, dt.ID); err != nil {
+			log.Warn("failed to get previous disk metrics", zap.Error(err))
+		}
+	}
+	if err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.CreateDeploymentTargetMetrics(ctx, &dt.DeploymentTarget, &metrics); err != nil {
+			return err
 		}
+		return db.CreateDeploymentContainerMetrics(ctx, metrics.Deployments, metrics.CreatedAt)
+	}); errors.Is(err, apierrors.ErrBadRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err != nil {
+		log.Error("failed to create deployment target metrics", zap.Error(err), zap.Reflect("metrics", metrics))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	} else {
+		if dt.DiskUsageWarningThreshold != nil {
+			// mails are sent in the background, so that a slow mail server does not delay the agent
+			go notifyDiskUsageWarnings(context.WithoutCancel(ctx), dt.DeploymentTarget, previousDisks, metrics)
+		}
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
+// notifyDiskUsageWarnings sends a notification if any disk exceeds the disk usage warning threshold of the
+// deployment target that did not exceed it in the previous metrics entry.
+func notifyDiskUsageWarnings(
+	ctx context.Context,
+	dt types.DeploymentTarget,
+	previousDisks []api.AgentDiskMetrics,
+	metrics api.AgentDeploymentTargetMetrics,
+) {
+	previousWarnings := api.AgentDeploymentTargetMetrics{Disks: previousDisks}.
+		DisksAboveThreshold(*dt.DiskUsageWarningThreshold)
+	newWarnings := slices.DeleteFunc(metrics.DisksAboveThreshold(*dt.DiskUsageWarningThreshold),
+		func(disk api.AgentDiskMetrics) bool {
+			return slices.ContainsFunc(previousWarnings, func(previous api.AgentDiskMetrics) bool {
+				return previous.Type == disk.Type && previous.Name == disk.Name
+			})
+		})
+	if len(newWarnings) > 0 {
+		if err := mailsending.SendDiskUsageWarningMail(ctx, dt, newWarnings); err != nil {
+			internalctx.GetLogger(ctx).Warn("failed to send disk usage warning", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		}
+	}
+}
+
+// validQueuedAt returns the timestamp of an entry that was queued by the agent while the hub was unreachable.
+// Timestamps in the future are discarded, so that the current time is used instead.
+func validQueuedAt(createdAt *time.Time) *time.Time {
+	if createdAt == nil || createdAt.After(time.Now()) {
+		return nil
+	}
+	return createdAt
+}
+
 func queryAuthDeploymentTargetCtxMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -492,7 +702,10 @@ func getVerifiedDeploymentTarget(
 		return nil, errors.New("deployment target does not have key and salt")
 	} else if err := security.VerifyAccessKey(
 		*deploymentTarget.AccessKeySalt, *deploymentTarget.AccessKeyHash, targetSecret); err != nil {
-		return nil, fmt.Errorf("failed to verify access: %w", err)
+		if err := verifyRotatedAccessKey(ctx, deploymentTarget, targetSecret); err != nil {
+			return nil, fmt.Errorf("failed to verify access: %w", err)
+		}
+		return deploymentTarget, nil
 	} else {
 		return deploymentTarget, nil
 	}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/security"
+	"github.com/distr-sh/distr/internal/types"
+	"go.uber.org/zap"
+)
+
+// getAccessKeyRotation issues a new target secret for the given deployment target if a rotation was requested or the
+// current access key is older than [env.AgentAccessKeyRotationInterval]. Otherwise, nil is returned.
+//
+// The new key is stored as pending and only replaces the current key once the agent logs in with it.
+// Until then, the same pending key is sent again, which is why it is also stored encrypted with
+// [env.AgentAccessKeyEncryptionKey]. A pending key that was not confirmed within
+// [env.AgentAccessKeyRotationGracePeriod] is replaced by a new one.
+func getAccessKeyRotation(
+	ctx context.Context,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+) (*api.AgentAccessKeyRotation, error) {
+	encryptionKey, ok := accessKeyEncryptionKey()
+	if !ok {
+		return nil, nil
+	}
+
+	rotation, err := db.GetDeploymentTargetAccessKeyRotation(ctx, deploymentTarget.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotation.RequestedAt == nil {
+		if interval := env.AgentAccessKeyRotationInterval(); interval == nil ||
+			time.Since(rotation.AccessKeyCreatedAt) < *interval {
+			return nil, nil
+		}
+	}
+
+	if rotation.PendingAccessKeyEncrypted != nil && rotation.PendingAccessKeyIssuedAt != nil &&
+		time.Since(*rotation.PendingAccessKeyIssuedAt) < env.AgentAccessKeyRotationGracePeriod() {
+		if targetSecret, err := security.Decrypt(
+			encryptionKey, *rotation.PendingAccessKeyEncrypted, deploymentTarget.ID[:],
+		); err != nil {
+			return nil, fmt.Errorf("failed to decrypt pending access key: %w", err)
+		} else {
+			return &api.AgentAccessKeyRotation{TargetSecret: string(targetSecret)}, nil
+		}
+	}
+
+	targetSecret, err := security.GenerateAccessKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access key: %w", err)
+	}
+	salt, hash, err := security.HashAccessKey(targetSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash access key: %w", err)
+	}
+	encrypted, err := security.Encrypt(encryptionKey, []byte(targetSecret), deploymentTarget.ID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt access key: %w", err)
+	}
+	if err := db.SetPendingDeploymentTargetAccessKey(ctx, deploymentTarget.ID, salt, hash, encrypted); err != nil {
+		return nil, err
+	}
+	return &api.AgentAccessKeyRotation{TargetSecret: targetSecret}, nil
+}
+
+// accessKeyEncryptionKey returns the AES key for pending access keys, if access key rotation is enabled.
+func accessKeyEncryptionKey() ([]byte, bool) {
+	if key := env.AgentAccessKeyEncryptionKey(); key == nil {
+		return nil, false
+	} else {
+		sum := sha256.Sum256(key)
+		return sum[:], true
+	}
+}
+
+// verifyRotatedAccessKey checks the given target secret against the pending and previous access key of the deployment
+// target. A valid pending key is promoted to be the current key.
+func verifyRotatedAccessKey(
+	ctx context.Context,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+	targetSecret string,
+) error {
+	log := internalctx.GetLogger(ctx).With(zap.String("deploymentTargetId", deploymentTarget.ID.String()))
+
+	rotation, err := db.GetDeploymentTargetAccessKeyRotation(ctx, deploymentTarget.ID)
+	if err != nil {
+		return err
+	}
+
+	if rotation.PendingAccessKeySalt != nil && rotation.PendingAccessKeyHash != nil &&
+		security.VerifyAccessKey(*rotation.PendingAccessKeySalt, *rotation.PendingAccessKeyHash, targetSecret) == nil {
+		previousExpiresAt := time.Now().Add(env.AgentAccessKeyRotationGracePeriod())
+		if err := db.PromotePendingDeploymentTargetAccessKey(
+			ctx, &deploymentTarget.DeploymentTarget, rotation, previousExpiresAt,
+		); err != nil {
+			return err
+		}
+		log.Info("deployment target access key rotated", zap.Time("previousExpiresAt", previousExpiresAt))
+		return nil
+	}
+
+	if rotation.PreviousAccessKeySalt != nil && rotation.PreviousAccessKeyHash != nil &&
+		rotation.PreviousAccessKeyExpiresAt != nil && rotation.PreviousAccessKeyExpiresAt.After(time.Now()) &&
+		security.VerifyAccessKey(*rotation.PreviousAccessKeySalt, *rotation.PreviousAccessKeyHash, targetSecret) == nil {
+		// The agent is still using the previous key, so it probably failed to persist the new one.
+		// Requesting another rotation makes sure it gets a new key before the previous one expires.
+		log.Warn("agent logged in with previous access key, requesting another rotation")
+		if err := db.RequestDeploymentTargetAccessKeyRotation(ctx, deploymentTarget.ID); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return errors.Join(security.ErrInvalidAccessKey, errors.New("no matching pending or previous access key"))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentca"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+var (
+	verifyAgentCertificate = agentca.VerifyCertificate
+	issueAgentCertificate  = agentca.IssueCertificate
+)
+
+// getClientCertificateRenewal issues a new client certificate if the agent presented a client certificate for the
+// given deployment target that is about to expire. Otherwise, nil is returned.
+// The certificate is taken from the request instead of the authentication, because agents that authenticated with a
+// token still present their certificate.
+func getClientCertificateRenewal(
+	r *http.Request,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+) (*api.AgentClientCertificate, error) {
+	ctx := r.Context()
+	certificate, err := auth.AgentClientCertificate(r)
+	if err != nil || certificate == nil || !agentca.NeedsRenewal(certificate) {
+		return nil, nil
+	}
+	if err := verifyAgentCertificate(ctx, certificate); err != nil {
+		return nil, fmt.Errorf("client certificate is invalid: %w", err)
+	} else if targetID, orgID, err := agentca.Identity(certificate); err != nil {
+		return nil, err
+	} else if targetID != deploymentTarget.ID || orgID != deploymentTarget.OrganizationID {
+		return nil, errors.New("client certificate does not belong to the deployment target")
+	}
+	if renewed, err := issueAgentCertificate(
+		ctx, deploymentTarget.ID, deploymentTarget.OrganizationID,
+	); err != nil {
+		return nil, err
+	} else {
+		return &api.AgentClientCertificate{
+			Certificate: base64.StdEncoding.EncodeToString(renewed.Certificate),
+			Key:         base64.StdEncoding.EncodeToString(renewed.Key),
+		}, nil
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentca"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authjwt"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestGetClientCertificateRenewalWithTokenAuthentication(t *testing.T) {
+	g := NewWithT(t)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	caTemplate := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, &caTemplate, &caTemplate, caKey.Public(), caKey)
+	g.Expect(err).NotTo(HaveOccurred())
+	caCertificate, err := x509.ParseCertificate(caDer)
+	g.Expect(err).NotTo(HaveOccurred())
+	caKeyDer, err := x509.MarshalPKCS8PrivateKey(caKey)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	t.Setenv("DATABASE_URL", "postgres://localhost/distr")
+	t.Setenv("JWT_SECRET", base64.StdEncoding.EncodeToString([]byte("test-secret")))
+	t.Setenv("DISTR_HOST", "http://localhost:8080")
+	t.Setenv("AGENT_MTLS_CA_CERTIFICATE", base64.StdEncoding.EncodeToString(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDer})))
+	t.Setenv("AGENT_MTLS_CA_KEY", base64.StdEncoding.EncodeToString(
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: caKeyDer})))
+	t.Setenv("AGENT_MTLS_CLIENT_CERTIFICATE_HEADER", "X-SSL-Client-Cert")
+	t.Setenv("AGENT_MTLS_TRUSTED_PROXIES", "192.0.2.0/24")
+	env.Initialize()
+
+	target := &types.DeploymentTargetWithCreatedBy{}
+	target.ID = uuid.New()
+	target.OrganizationID = uuid.New()
+
+	// a certificate with less than a third of its validity left
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			CommonName:   target.ID.String(),
+			Organization: []string{target.OrganizationID.String()},
+		},
+		NotBefore:   time.Now().Add(-29 * 24 * time.Hour),
+		NotAfter:    time.Now().Add(24 * time.Hour),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, caCertificate, key.Public(), caKey)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var verified *x509.Certificate
+	verifyAgentCertificate = func(ctx context.Context, certificate *x509.Certificate) error {
+		verified = certificate
+		return nil
+	}
+	issueAgentCertificate = func(ctx context.Context, targetID, orgID uuid.UUID) (*agentca.Certificate, error) {
+		g.Expect(targetID).To(Equal(target.ID))
+		g.Expect(orgID).To(Equal(target.OrganizationID))
+		return &agentca.Certificate{Certificate: []byte("certificate"), Key: []byte("key")}, nil
+	}
+	t.Cleanup(func() {
+		verifyAgentCertificate = agentca.VerifyCertificate
+		issueAgentCertificate = agentca.IssueCertificate
+	})
+
+	_, token, err := authjwt.GenerateAgentTokenValidFor(target.ID, target.OrganizationID, time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var renewal *api.AgentClientCertificate
+	var renewalErr error
+	handler := middleware.PeerAddressMiddleware(auth.AgentAuthentication.Middleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			g.Expect(auth.AgentAuthentication.Require(r.Context()).CurrentDeploymentTargetID()).To(Equal(target.ID))
+			renewal, renewalErr = getClientCertificateRenewal(r, target)
+		}),
+	))
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/agent/resources", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("X-SSL-Client-Cert",
+		url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	g.Expect(recorder.Code).To(Equal(http.StatusOK))
+	g.Expect(renewalErr).NotTo(HaveOccurred())
+	g.Expect(verified).NotTo(BeNil())
+	g.Expect(verified.Raw).To(Equal(der))
+	g.Expect(renewal).To(Equal(&api.AgentClientCertificate{
+		Certificate: base64.StdEncoding.EncodeToString([]byte("certificate")),
+		Key:         base64.StdEncoding.EncodeToString([]byte("key")),
+	}))
+
+	// the header is not accepted from addresses that are not trusted proxies
+	renewal, verified = nil, nil
+	request.RemoteAddr = "198.51.100.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	g.Expect(renewalErr).NotTo(HaveOccurred())
+	g.Expect(verified).To(BeNil())
+	g.Expect(renewal).To(BeNil())
+}
@@ -11,8 +11,10 @@ import (
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
 	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/contenttype"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/kustomization"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/types"
@@ -122,6 +124,10 @@ func ApplicationsRouter(r chiopenapi.Router) {
 					With(option.Description("Get application version values file")).
 					With(option.Request(ApplicationVersionRequest{})).
 					With(option.Response(http.StatusOK, map[string]any{}, option.ContentType("application/yaml")))
+				r.Get("/manifest-file", getApplicationVersionManifestFile).
+					With(option.Description("Get application version manifests or kustomization archive")).
+					With(option.Request(ApplicationVersionRequest{})).
+					With(option.Response(http.StatusOK, nil, option.ContentType("application/octet-stream")))
 			})
 		})
 	})
@@ -381,6 +387,21 @@ func createApplicationVersion(w http.ResponseWriter, r *http.Request) {
 			// Some uses might use a non-yaml template here.
 			applicationVersion.TemplateFileData = data
 		}
+		if !applicationVersion.IsHelm() {
+			checkMediaType := contenttype.IsYaml
+			if *applicationVersion.KubernetesType == types.KubernetesTypeKustomize {
+				checkMediaType = contenttype.IsGzip
+			}
+			if data, ok := readMultipartFileWithMediaType(w, r, "manifestfile", checkMediaType); !ok {
+				return
+			} else if data != nil {
+				applicationVersion.ManifestFileData = data
+				if err := kustomization.Validate(*applicationVersion.KubernetesType, data); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		}
 	}
 
 	if err := applicationVersion.Validate(application.Type); err != nil {
@@ -452,6 +473,9 @@ var (
 	getApplicationVersionTemplateFile = getApplicationVersionFileHandler(func(av types.ApplicationVersion) []byte {
 		return av.TemplateFileData
 	})
+	getApplicationVersionManifestFile = getApplicationVersionFileHandler(func(av types.ApplicationVersion) []byte {
+		return av.ManifestFileData
+	})
 )
 
 func getApplicationVersionFileHandler(fileAccessor func(types.ApplicationVersion) []byte) http.HandlerFunc {
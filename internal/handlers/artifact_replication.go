@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/registry/replication"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+func ArtifactReplicationTargetsRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Artifacts"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor)
+	r.Get("/", getArtifactReplicationTargets).
+		With(option.Description("List the registries of customer organizations that licensed artifacts are copied to")).
+		With(option.Response(http.StatusOK, []types.ArtifactReplicationTarget{}))
+	r.With(middleware.RequireReadWriteOrAdmin).
+		Post("/", createArtifactReplicationTarget).
+		With(option.Description("Add a registry of a customer organization that licensed artifacts are copied to")).
+		With(option.Request(api.CreateArtifactReplicationTargetRequest{})).
+		With(option.Response(http.StatusOK, types.ArtifactReplicationTarget{}))
+	r.Route("/{artifactReplicationTargetId}", func(r chiopenapi.Router) {
+		type ArtifactReplicationTargetRequest struct {
+			ArtifactReplicationTargetID uuid.UUID `path:"artifactReplicationTargetId"`
+		}
+
+		r.Get("/versions", getArtifactReplicationVersionStatuses).
+			With(option.Description("Get the replication status of all artifact versions licensed to the customer")).
+			With(option.Request(ArtifactReplicationTargetRequest{})).
+			With(option.Response(http.StatusOK, []types.ArtifactReplicationVersionStatus{}))
+		r.With(middleware.RequireReadWriteOrAdmin).Group(func(r chiopenapi.Router) {
+			r.Put("/", updateArtifactReplicationTarget).
+				With(option.Description("Update a replication target. All versions are copied again by the next run.")).
+				With(option.Request(struct {
+					ArtifactReplicationTargetRequest
+					api.UpdateArtifactReplicationTargetRequest
+				}{})).
+				With(option.Response(http.StatusOK, types.ArtifactReplicationTarget{}))
+			r.Delete("/", deleteArtifactReplicationTarget).
+				With(option.Description("Delete a replication target. Copied artifacts are kept in the target registry.")).
+				With(option.Request(ArtifactReplicationTargetRequest{}))
+		})
+	})
+}
+
+func getArtifactReplicationTargets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if targets, err := db.GetArtifactReplicationTargets(ctx, *auth.CurrentOrgID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get artifact replication targets", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, targets)
+	}
+}
+
+func createArtifactReplicationTarget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request, err := JsonBody[api.CreateArtifactReplicationTargetRequest](w, r)
+	if err != nil {
+		return
+	}
+	target := types.ArtifactReplicationTarget{
+		OrganizationID:         *auth.CurrentOrgID(),
+		CustomerOrganizationID: request.CustomerOrganizationID,
+		RegistryURL:            strings.TrimSpace(request.RegistryURL),
+		Username:               request.Username,
+		PasswordSecretID:       request.PasswordSecretID,
+	}
+
+	if err := target.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err := db.ValidateCustomerOrgBelongsToOrg(
+		ctx, target.CustomerOrganizationID, target.OrganizationID,
+	); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if !validateArtifactReplicationTargetSecret(w, r, target) {
+		return
+	} else if err := db.CreateArtifactReplicationTarget(ctx, &target); errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to create artifact replication target", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, target)
+	}
+}
+
+func updateArtifactReplicationTarget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	target := getArtifactReplicationTarget(w, r)
+	if target == nil {
+		return
+	}
+	request, err := JsonBody[api.UpdateArtifactReplicationTargetRequest](w, r)
+	if err != nil {
+		return
+	}
+	target.OrganizationID = *auth.CurrentOrgID()
+	target.RegistryURL = strings.TrimSpace(request.RegistryURL)
+	target.Username = request.Username
+	target.PasswordSecretID = request.PasswordSecretID
+
+	if err := target.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if !validateArtifactReplicationTargetSecret(w, r, *target) {
+		return
+	} else if err := db.UpdateArtifactReplicationTarget(ctx, target); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to update artifact replication target", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, target)
+	}
+}
+
+func deleteArtifactReplicationTarget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if id, err := uuid.Parse(r.PathValue("artifactReplicationTargetId")); err != nil {
+		http.Error(w, "artifactReplicationTargetId is not a valid UUID", http.StatusBadRequest)
+	} else if err := db.DeleteArtifactReplicationTargetWithID(ctx, id, *auth.CurrentOrgID()); errors.Is(
+		err, apierrors.ErrNotFound,
+	) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to delete artifact replication target", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getArtifactReplicationVersionStatuses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	target := getArtifactReplicationTarget(w, r)
+	if target == nil {
+		return
+	}
+
+	if statuses, err := replication.VersionStatuses(ctx, *target); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get artifact replication status", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, statuses)
+	}
+}
+
+// getArtifactReplicationTarget returns the target from the path or writes an error response and returns nil.
+func getArtifactReplicationTarget(w http.ResponseWriter, r *http.Request) *types.ArtifactReplicationTarget {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("artifactReplicationTargetId"))
+	if err != nil {
+		http.Error(w, "artifactReplicationTargetId is not a valid UUID", http.StatusBadRequest)
+		return nil
+	}
+	target, err := db.GetArtifactReplicationTargetByID(ctx, id, *auth.CurrentOrgID())
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return nil
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get artifact replication target", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil
+	}
+	return target
+}
+
+// validateArtifactReplicationTargetSecret checks that the password secret is either a secret of the vendor or of the
+// customer organization of the target. It writes an error response and returns false otherwise.
+func validateArtifactReplicationTargetSecret(
+	w http.ResponseWriter,
+	r *http.Request,
+	target types.ArtifactReplicationTarget,
+) bool {
+	if target.PasswordSecretID == nil {
+		return true
+	}
+	ctx := r.Context()
+	secret, err := db.GetSecretByID(ctx, *target.PasswordSecretID, target.OrganizationID, nil)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.Error(w, "password secret not found", http.StatusBadRequest)
+		return false
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get secret", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	} else if secret.CustomerOrganizationID != nil && *secret.CustomerOrganizationID != target.CustomerOrganizationID {
+		http.Error(w, "password secret belongs to a different customer organization", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+func ArtifactRetentionPoliciesRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Artifacts"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor)
+	r.Get("/", getArtifactRetentionPolicies).
+		With(option.Description("List all artifact retention policies")).
+		With(option.Response(http.StatusOK, []types.ArtifactRetentionPolicy{}))
+	r.With(middleware.RequireReadWriteOrAdmin).Group(func(r chiopenapi.Router) {
+		r.Post("/", createArtifactRetentionPolicy).
+			With(option.Description("Create a new artifact retention policy")).
+			With(option.Request(types.ArtifactRetentionPolicy{})).
+			With(option.Response(http.StatusOK, types.ArtifactRetentionPolicy{}))
+		r.Route("/{artifactRetentionPolicyId}", func(r chiopenapi.Router) {
+			type ArtifactRetentionPolicyRequest struct {
+				ArtifactRetentionPolicyID uuid.UUID `path:"artifactRetentionPolicyId"`
+			}
+
+			r.Put("/", updateArtifactRetentionPolicy).
+				With(option.Description("Update an artifact retention policy")).
+				With(option.Request(struct {
+					ArtifactRetentionPolicyRequest
+					types.ArtifactRetentionPolicy
+				}{})).
+				With(option.Response(http.StatusOK, types.ArtifactRetentionPolicy{}))
+			r.Delete("/", deleteArtifactRetentionPolicy).
+				With(option.Description("Delete an artifact retention policy")).
+				With(option.Request(ArtifactRetentionPolicyRequest{}))
+		})
+	})
+}
+
+func getArtifactRetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if policies, err := db.GetArtifactRetentionPolicies(ctx, *auth.CurrentOrgID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get artifact retention policies", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, policies)
+	}
+}
+
+func createArtifactRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	policy, err := JsonBody[types.ArtifactRetentionPolicy](w, r)
+	if err != nil {
+		return
+	}
+	policy.OrganizationID = *auth.CurrentOrgID()
+
+	if err := policy.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err := db.CreateArtifactRetentionPolicy(ctx, &policy); errors.Is(err, apierrors.ErrBadRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to create artifact retention policy", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, policy)
+	}
+}
+
+func updateArtifactRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("artifactRetentionPolicyId"))
+	if err != nil {
+		http.Error(w, "artifactRetentionPolicyId is not a valid UUID", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := JsonBody[types.ArtifactRetentionPolicy](w, r)
+	if err != nil {
+		return
+	}
+	if policy.ID == uuid.Nil {
+		policy.ID = id
+	} else if policy.ID != id {
+		http.Error(w, "id does not match", http.StatusBadRequest)
+		return
+	}
+	policy.OrganizationID = *auth.CurrentOrgID()
+
+	if err := policy.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err := db.UpdateArtifactRetentionPolicy(ctx, &policy); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if errors.Is(err, apierrors.ErrBadRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to update artifact retention policy", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, policy)
+	}
+}
+
+func deleteArtifactRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if id, err := uuid.Parse(r.PathValue("artifactRetentionPolicyId")); err != nil {
+		http.Error(w, "artifactRetentionPolicyId is not a valid UUID", http.StatusBadRequest)
+	} else if err := db.DeleteArtifactRetentionPolicyWithID(ctx, id, *auth.CurrentOrgID()); errors.Is(
+		err, apierrors.ErrNotFound,
+	) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to delete artifact retention policy", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/signature"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+func ArtifactSigningKeysRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Artifacts"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor)
+	r.Get("/", getArtifactSigningKeys).
+		With(option.Description("List all public keys that are trusted to sign artifacts")).
+		With(option.Response(http.StatusOK, []types.ArtifactSigningKey{}))
+	r.With(middleware.RequireReadWriteOrAdmin).Group(func(r chiopenapi.Router) {
+		r.Post("/", createArtifactSigningKey).
+			With(option.Description("Add a trusted public key or certificate in PEM format")).
+			With(option.Request(types.ArtifactSigningKey{})).
+			With(option.Response(http.StatusOK, types.ArtifactSigningKey{}))
+		r.Delete("/{artifactSigningKeyId}", deleteArtifactSigningKey).
+			With(option.Description("Delete a trusted public key")).
+			With(option.Request(struct {
+				ArtifactSigningKeyID uuid.UUID `path:"artifactSigningKeyId"`
+			}{}))
+	})
+}
+
+func getArtifactSigningKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if keys, err := db.GetArtifactSigningKeys(ctx, *auth.CurrentOrgID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get artifact signing keys", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, keys)
+	}
+}
+
+func createArtifactSigningKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	key, err := JsonBody[types.ArtifactSigningKey](w, r)
+	if err != nil {
+		return
+	}
+	key.OrganizationID = *auth.CurrentOrgID()
+	key.Name = strings.TrimSpace(key.Name)
+
+	if key.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+	} else if _, err := signature.ParsePublicKey(key.PublicKey); err != nil {
+		http.Error(w, fmt.Sprintf("invalid public key: %v", err), http.StatusBadRequest)
+	} else if err := db.CreateArtifactSigningKey(ctx, &key); errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to create artifact signing key", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, key)
+	}
+}
+
+func deleteArtifactSigningKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if id, err := uuid.Parse(r.PathValue("artifactSigningKeyId")); err != nil {
+		http.Error(w, "artifactSigningKeyId is not a valid UUID", http.StatusBadRequest)
+	} else if err := db.DeleteArtifactSigningKeyWithID(ctx, id, *auth.CurrentOrgID()); errors.Is(
+		err, apierrors.ErrNotFound,
+	) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to delete artifact signing key", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
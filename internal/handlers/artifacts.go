@@ -3,7 +3,13 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
@@ -12,11 +18,16 @@ import (
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/registry/audit"
+	"github.com/distr-sh/distr/internal/registry/blob/storage"
+	"github.com/distr-sh/distr/internal/signature"
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
 	"github.com/getsentry/sentry-go"
 	"github.com/google/uuid"
 	"github.com/oaswrap/spec/adapter/chiopenapi"
 	"github.com/oaswrap/spec/option"
+	"github.com/opencontainers/go-digest"
 	"go.uber.org/zap"
 )
 
@@ -35,7 +46,21 @@ func ArtifactsRouter(r chiopenapi.Router) {
 			With(option.Description("Get an artifact by ID")).
 			With(option.Request(ArtifactRequest{})).
 			With(option.Response(http.StatusOK, []api.ArtifactResponse{}))
+		r.Get("/attachments/{attachmentId}/download", downloadArtifactAttachmentHandler).
+			With(option.Description("Download an SBOM or vulnerability report of an artifact version")).
+			With(option.Request(struct {
+				ArtifactRequest
+				AttachmentID uuid.UUID `path:"attachmentId"`
+			}{}))
 		r.With(middleware.RequireVendor).Group(func(r chiopenapi.Router) {
+			r.Get("/events", getArtifactEventsHandler).
+				With(option.Description("List the pushes, tag overwrites and deletions of an artifact")).
+				With(option.Request(struct {
+					ArtifactRequest
+					Before *time.Time `query:"before"`
+					Count  *int       `query:"count"`
+				}{})).
+				With(option.Response(http.StatusOK, []types.ArtifactEvent{}))
 			r.Patch("/image", patchImageArtifactHandler).
 				With(option.Description("Update artifact image")).
 				With(option.Request(struct {
@@ -93,7 +118,126 @@ func getArtifacts(w http.ResponseWriter, r *http.Request) {
 
 func getArtifact(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	RespondJSON(w, mapping.ArtifactToAPI(*internalctx.GetArtifact(ctx)))
+	log := internalctx.GetLogger(ctx)
+	auth := auth.Authentication.Require(ctx)
+	artifact := *internalctx.GetArtifact(ctx)
+	artifact.Versions = slices.Clone(artifact.Versions)
+
+	if sigs, err := db.GetArtifactVersionSignaturesForArtifact(ctx, artifact.ID); err != nil {
+		log.Error("failed to get artifact signatures", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	} else if len(sigs) > 0 {
+		keys, err := db.GetArtifactSigningKeys(ctx, *auth.CurrentOrgID())
+		if err != nil {
+			log.Error("failed to get artifact signing keys", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		trusted := signature.TrustedKeys(keys)
+		for i, version := range artifact.Versions {
+			artifact.Versions[i].SignatureStatus = signature.Status(sigs, types.Digest(version.Digest), trusted)
+		}
+	}
+
+	if attachments, err := db.GetArtifactVersionAttachmentsForArtifact(ctx, artifact.ID); err != nil {
+		log.Error("failed to get artifact attachments", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	} else {
+		for i, version := range artifact.Versions {
+			for _, attachment := range attachments {
+				if string(attachment.SubjectDigest) == version.Digest {
+					artifact.Versions[i].Attachments = append(artifact.Versions[i].Attachments, attachment)
+				}
+			}
+		}
+	}
+
+	RespondJSON(w, mapping.ArtifactToAPI(artifact))
+}
+
+func downloadArtifactAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	artifact := internalctx.GetArtifact(ctx)
+
+	attachmentID, err := uuid.Parse(r.PathValue("attachmentId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	attachment, err := db.GetArtifactVersionAttachment(ctx, artifact.ID, attachmentID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Error("failed to get artifact attachment", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// artifact.Versions only contains the versions that the current user has access to
+	if !slices.ContainsFunc(artifact.Versions, func(v types.TaggedArtifactVersion) bool {
+		return v.Digest == string(attachment.SubjectDigest)
+	}) {
+		http.NotFound(w, r)
+		return
+	}
+
+	rc, err := storage.NewBlobHandler(ctx).Get(ctx, artifact.Name, digest.Digest(attachment.BlobDigest), false)
+	if err != nil {
+		log.Error("failed to get artifact attachment blob", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", attachment.MediaType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("%v-%v.json", attachment.Type, digest.Digest(attachment.BlobDigest).Encoded()),
+	}))
+	if _, err := io.Copy(w, rc); err != nil {
+		log.Warn("failed to write artifact attachment", zap.Error(err))
+	}
+}
+
+func getArtifactEventsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	artifact := internalctx.GetArtifact(ctx)
+	before := time.Now()
+	count := 50
+	if s := r.FormValue("before"); s != "" {
+		if t, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			http.Error(w, "before must be a date", http.StatusBadRequest)
+			return
+		} else {
+			before = t
+		}
+	}
+	if s := r.FormValue("count"); s != "" {
+		if n, err := strconv.Atoi(s); err != nil || n < 1 {
+			http.Error(w, "count must be a positive number", http.StatusBadRequest)
+			return
+		} else {
+			count = n
+		}
+	}
+
+	if events, err := db.GetArtifactEvents(ctx, artifact.ID, count, before); err != nil {
+		log.Error("failed to get artifact events", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, events)
+	}
 }
 
 var patchImageArtifactHandler = patchImageHandler(func(ctx context.Context, body api.PatchImageRequest) (any, error) {
@@ -119,6 +263,20 @@ func deleteArtifactHandler(w http.ResponseWriter, r *http.Request) {
 			return apierrors.NewBadRequest("Cannot delete artifact: it is referenced in one or more licenses.")
 		}
 
+		tags, err := db.GetArtifactTags(ctx, artifact.ID)
+		if err != nil {
+			return err
+		}
+		tagIDs := make([]uuid.UUID, len(tags))
+		for i, tag := range tags {
+			tagIDs[i] = tag.ID
+		}
+		userID := auth.Authentication.Require(ctx).CurrentUserID()
+		remoteAddress := internalctx.GetRequestIPAddress(ctx)
+		if err := audit.RecordDeletions(ctx, tagIDs, &userID, &remoteAddress); err != nil {
+			return err
+		}
+
 		if err := db.DeleteArtifactWithID(ctx, artifact.ID); err != nil {
 			return err
 		}
@@ -153,37 +311,14 @@ func deleteArtifactTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var digestBefore types.Digest
 	err := db.RunTx(ctx, func(ctx context.Context) error {
-		// Step 1: Validate version exists and fetch it
-		version, err := db.GetArtifactVersionByTag(ctx, artifact.ID, tagName)
-		if err != nil {
-			return err
-		}
-
-		// Step 2: Fetch all versions with the same digest
-		versionsWithSameDigest, err := db.GetArtifactVersionsByDigest(ctx, artifact.ID, string(version.ManifestBlobDigest))
-		if err != nil {
-			return err
-		}
-
-		// Step 3: Enhanced license check
-		if err := db.CheckArtifactVersionDeletionForLicenses(ctx, artifact.ID, version, versionsWithSameDigest); err != nil {
+		if version, err := db.GetArtifactVersionByTag(ctx, artifact.ID, tagName); err != nil {
 			return err
+		} else {
+			digestBefore = version.ManifestBlobDigest
 		}
-
-		// Step 4: Check if this is the last non-SHA tag of the artifact
-		isLast, err := db.IsLastTagOfArtifact(ctx, artifact.ID, tagName)
-		if err != nil {
-			return err
-		}
-		if isLast {
-			return apierrors.NewConflict(
-				"Cannot delete tag: it is the last tag of the artifact. At least one tag must remain for the artifact.",
-			)
-		}
-
-		// Step 5: Delete the tag
-		return db.DeleteArtifactVersion(ctx, artifact.ID, tagName)
+		return db.DeleteArtifactTag(ctx, artifact.ID, tagName)
 	})
 	if err != nil {
 		if errors.Is(err, apierrors.ErrNotFound) {
@@ -204,6 +339,20 @@ func deleteArtifactTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auth := auth.Authentication.Require(ctx)
+	event := types.ArtifactEvent{
+		OrganizationID: artifact.OrganizationID,
+		ArtifactName:   artifact.Name,
+		Type:           types.ArtifactEventTypeDelete,
+		Reference:      tagName,
+		DigestBefore:   util.PtrTo(string(digestBefore)),
+		UserAccountID:  util.PtrTo(auth.CurrentUserID()),
+	}
+	if err := audit.RecordEvent(ctx, &event, auth.Token()); err != nil {
+		log.Warn("failed to audit-log artifact tag deletion", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentbundle"
+	"github.com/distr-sh/distr/internal/agentmanifest"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authjwt"
+	"github.com/distr-sh/distr/internal/buildconfig"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/customdomains"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	orasauth "oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// bundleRegistryTokenValidity is the validity of the agent token used to pull images and charts from the registry
+// while a bundle is created.
+const bundleRegistryTokenValidity = 30 * time.Minute
+
+func exportDeploymentBundleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := internalctx.GetLogger(ctx)
+		deployment := internalctx.GetDeployment(ctx)
+		org := auth.Authentication.Require(ctx).CurrentOrg()
+
+		includeAgentImage, err := QueryParam(r, "includeAgentImage", strconv.ParseBool)
+		if err != nil && !errors.Is(err, ErrParamNotDefined) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hubKey := env.AgentBundleKey()
+		if hubKey == nil {
+			http.Error(w, "deployment bundles are not enabled on this instance", http.StatusBadRequest)
+			return
+		}
+
+		deploymentTarget, err := db.GetDeploymentTargetForDeploymentID(ctx, deployment.ID)
+		if err != nil {
+			log.Error("failed to get deployment target", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		builder, err := buildDeploymentBundle(ctx, deploymentTarget, deployment.ID, *org, includeAgentImage)
+		if err != nil {
+			log.Error("failed to build deployment bundle", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer func() {
+			if err := builder.Close(); err != nil {
+				log.Warn("failed to clean up deployment bundle", zap.Error(err))
+			}
+		}()
+
+		filename := fmt.Sprintf("%s_%s_bundle.tar.gz", time.Now().Format("2006-01-02"), deployment.ID.String()[:8])
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		if err := builder.WriteTo(w, agentbundle.TargetKey(hubKey, deploymentTarget.ID)); err != nil {
+			log.Error("failed to write deployment bundle", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			// Note: If headers were already sent, we can't send error response
+		}
+	}
+}
+
+func buildDeploymentBundle(
+	ctx context.Context,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+	deploymentID uuid.UUID,
+	org types.Organization,
+	includeAgentImage bool,
+) (*agentbundle.Builder, error) {
+	resource, err := getAgentResource(ctx, deploymentTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	var agentDeployment *api.AgentDeployment
+	for _, d := range resource.Deployments {
+		if d.ID == deploymentID {
+			agentDeployment = &d
+			break
+		}
+	}
+	if agentDeployment == nil {
+		return nil, fmt.Errorf("deployment %v not found in agent resource", deploymentID)
+	}
+	resource.Deployments = []api.AgentDeployment{*agentDeployment}
+
+	builder, err := agentbundle.NewBuilder(
+		agentbundle.Metadata{
+			CreatedAt:          time.Now(),
+			DeploymentTargetID: deploymentTarget.ID,
+			DeploymentID:       agentDeployment.ID,
+			RevisionID:         agentDeployment.RevisionID,
+		},
+		*resource,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addBundleArtifacts(ctx, builder, deploymentTarget, *agentDeployment, org); err != nil {
+		_ = builder.Close()
+		return nil, err
+	}
+
+	if includeAgentImage {
+		if err := builder.AddAgentImage(ctx, agentmanifest.AgentImage(*deploymentTarget)); err != nil {
+			_ = builder.Close()
+			return nil, err
+		}
+	}
+
+	return builder, nil
+}
+
+// addBundleArtifacts adds all images and charts of the deployment that are hosted on the Distr registry to the bundle.
+// Artifacts from other registries are not included and must be made available in the target environment manually.
+func addBundleArtifacts(
+	ctx context.Context,
+	builder *agentbundle.Builder,
+	deploymentTarget *types.DeploymentTargetWithCreatedBy,
+	deployment api.AgentDeployment,
+	org types.Organization,
+) error {
+	if !env.RegistryEnabled() {
+		return nil
+	}
+
+	_, token, err := authjwt.GenerateAgentTokenValidFor(
+		deploymentTarget.ID,
+		deploymentTarget.OrganizationID,
+		bundleRegistryTokenValidity,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to generate registry token: %w", err)
+	}
+
+	registryHost := customdomains.RegistryDomainOrDefault(org)
+	client := &orasauth.Client{
+		Client: retry.DefaultClient,
+		Cache:  orasauth.NewCache(),
+		Credential: orasauth.StaticCredential(registryHost, orasauth.Credential{
+			Username: "-",
+			Password: token,
+		}),
+	}
+	plainHTTP := buildconfig.IsDevelopment()
+
+	if deploymentTarget.Type == types.DeploymentTypeDocker {
+		images, err := composeImages(deployment.ComposeFile)
+		if err != nil {
+			return err
+		}
+		for _, image := range images {
+			if strings.HasPrefix(image, registryHost+"/") {
+				if err := builder.AddImage(ctx, image, client, plainHTTP); err != nil {
+					return err
+				}
+			}
+		}
+	} else if strings.HasPrefix(deployment.ChartUrl, "oci://"+registryHost+"/") {
+		ref := agentbundle.ChartReference(deployment.ChartUrl, deployment.ChartVersion)
+		if err := builder.AddChart(ctx, ref, client, plainHTTP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func composeImages(composeFile []byte) ([]string, error) {
+	var compose struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(composeFile, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	var images []string
+	for _, service := range compose.Services {
+		if service.Image != "" {
+			images = append(images, service.Image)
+		}
+	}
+	slices.Sort(images)
+	return slices.Compact(images), nil
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap"
+)
+
+func getDeploymentContainerMetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		deployment := internalctx.GetDeployment(ctx)
+		limit, err := QueryParam(r, "limit", strconv.Atoi, Max(1000))
+		if errors.Is(err, ErrParamNotDefined) {
+			limit = 100
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		before, err := QueryParam(r, "before", ParseTimeFunc(time.RFC3339Nano))
+		if err != nil && !errors.Is(err, ErrParamNotDefined) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		after, err := QueryParam(r, "after", ParseTimeFunc(time.RFC3339Nano))
+		if err != nil && !errors.Is(err, ErrParamNotDefined) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		container := r.FormValue("container")
+
+		if metrics, err := db.GetDeploymentContainerMetrics(
+			ctx,
+			deployment.ID,
+			container,
+			limit,
+			before,
+			after,
+		); err != nil {
+			internalctx.GetLogger(ctx).Error("failed to get deployment container metrics", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		} else {
+			response := make([]api.DeploymentContainerMetrics, len(metrics))
+			for i, m := range metrics {
+				response[i] = api.DeploymentContainerMetrics{
+					CreatedAt:            m.CreatedAt,
+					DeploymentRevisionID: m.DeploymentRevisionID,
+					AgentContainerMetrics: api.AgentContainerMetrics{
+						Name:             m.Name,
+						CPUUsageMillis:   m.CPUUsageMillis,
+						MemoryBytes:      m.MemoryBytes,
+						MemoryLimitBytes: m.MemoryLimitBytes,
+						RestartCount:     m.RestartCount,
+						DiskReadBytes:    m.DiskReadBytes,
+						DiskWriteBytes:   m.DiskWriteBytes,
+						NetworkRxBytes:   m.NetworkRxBytes,
+						NetworkTxBytes:   m.NetworkTxBytes,
+					},
+				}
+			}
+			RespondJSON(w, response)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap"
+)
+
+func getDeploymentEventsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		deployment := internalctx.GetDeployment(ctx)
+		limit, err := QueryParam(r, "limit", strconv.Atoi, Max(100))
+		if errors.Is(err, ErrParamNotDefined) {
+			limit = 25
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		before, err := QueryParam(r, "before", ParseTimeFunc(time.RFC3339Nano))
+		if err != nil && !errors.Is(err, ErrParamNotDefined) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		after, err := QueryParam(r, "after", ParseTimeFunc(time.RFC3339Nano))
+		if err != nil && !errors.Is(err, ErrParamNotDefined) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var secrets []types.SecretWithUpdatedBy
+		if dt, err := db.GetDeploymentTargetForDeploymentID(ctx, deployment.ID); err != nil {
+			internalctx.GetLogger(ctx).Error("failed to get deployment target", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		} else if secrets, err = db.GetSecretsForDeploymentTarget(ctx, dt.DeploymentTarget); err != nil {
+			internalctx.GetLogger(ctx).Error("failed to get secrets", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if records, err := db.GetDeploymentEventRecords(ctx, deployment.ID, limit, before, after); err != nil {
+			internalctx.GetLogger(ctx).Error("failed to get event records", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		} else {
+			replacer := secretReplacer(secrets)
+			response := make([]api.DeploymentEventRecord, len(records))
+			for i, record := range records {
+				response[i] = api.DeploymentEventRecord{
+					DeploymentID:         record.DeploymentID,
+					DeploymentRevisionID: record.DeploymentRevisionID,
+					UID:                  record.UID,
+					Resource:             record.Resource,
+					Type:                 record.Type,
+					Reason:               record.Reason,
+					Message:              replacer.Replace(record.Message),
+					Count:                record.Count,
+					FirstTimestamp:       record.FirstTimestamp,
+					LastTimestamp:        record.LastTimestamp,
+				}
+			}
+			RespondJSON(w, response)
+		}
+	}
+}
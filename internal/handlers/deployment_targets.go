@@ -66,6 +66,9 @@ func DeploymentTargetsRouter(r chiopenapi.Router) {
 				With(option.Description("Create access token for deployment target")).
 				With(option.Request(DeploymentTargetIDRequest{})).
 				With(option.Response(http.StatusOK, api.DeploymentTargetAccessTokenResponse{}))
+			r.Post("/access-rotation", rotateAccessForDeploymentTarget).
+				With(option.Description("Rotate the access key of a connected deployment target")).
+				With(option.Request(DeploymentTargetIDRequest{}))
 		})
 		r.Route("/notes", func(r chiopenapi.Router) {
 			r.Get("/", getDeploymentTargetNotesHandler()).
@@ -187,6 +190,13 @@ func updateDeploymentTarget(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		return
 	}
+	if err := dt.ValidateNetworkSettings(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err := dt.ValidateDiskUsageWarningThreshold(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if dt.AgentVersion.ID != uuid.Nil {
 		dt.AgentVersionID = &dt.AgentVersion.ID
@@ -201,6 +211,16 @@ func updateDeploymentTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the type of a deployment target can not be changed
+	dt.Type = existing.Type
+	if err := dt.ValidatePrunePolicy(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err := dt.ValidateVolumeBackupPolicy(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := db.UpdateDeploymentTarget(ctx, &dt, *auth.CurrentOrgID()); err != nil {
 		log.Warn("could not update DeploymentTarget", zap.Error(err))
 		sentry.GetHubFromContext(ctx).CaptureException(err)
@@ -229,6 +249,24 @@ func deleteDeploymentTarget(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func rotateAccessForDeploymentTarget(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+
+	if _, ok := accessKeyEncryptionKey(); !ok {
+		http.Error(w, "access key rotation is not enabled on this instance", http.StatusBadRequest)
+	} else if deploymentTarget.AccessKeySalt == nil || deploymentTarget.AccessKeyHash == nil {
+		http.Error(w, "deployment target is not connected", http.StatusBadRequest)
+	} else if err := db.RequestDeploymentTargetAccessKeyRotation(ctx, deploymentTarget.ID); err != nil {
+		log.Warn("could not request access key rotation", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func createAccessForDeploymentTarget(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := internalctx.GetLogger(ctx)
@@ -260,6 +298,22 @@ func createAccessForDeploymentTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Any pending or previous key from an earlier rotation must no longer be accepted.
+	if err := db.ResetDeploymentTargetAccessKeyRotation(ctx, deploymentTarget.ID); err != nil {
+		log.Warn("could not reset access key rotation", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Client certificates of the previous connection must no longer be accepted.
+	if err := db.RevokeDeploymentTargetCertificates(ctx, deploymentTarget.ID); err != nil {
+		log.Warn("could not revoke client certificates", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	org := auth.CurrentOrg()
 	connectUrl, err := agentconnect.BuildConnectURL(deploymentTarget.ID, *org, targetSecret)
 	if err != nil {
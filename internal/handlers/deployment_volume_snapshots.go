@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func getDeploymentVolumeSnapshotsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		deployment := internalctx.GetDeployment(ctx)
+		if snapshots, err := db.GetDeploymentVolumeSnapshots(ctx, deployment.ID); err != nil {
+			internalctx.GetLogger(ctx).Error("failed to get deployment volume snapshots", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		} else {
+			response := make([]api.DeploymentVolumeSnapshot, len(snapshots))
+			for i, s := range snapshots {
+				response[i] = api.DeploymentVolumeSnapshot{
+					ID:                   s.ID,
+					CreatedAt:            s.CreatedAt,
+					DeploymentID:         s.DeploymentID,
+					DeploymentRevisionID: s.DeploymentRevisionID,
+					Volumes:              s.Volumes,
+					SizeBytes:            s.SizeBytes,
+				}
+			}
+			RespondJSON(w, response)
+		}
+	}
+}
+
+// restoreDeploymentVolumeSnapshotHandler creates a new revision of the deployment with the application version and
+// values of the snapshot's revision. The agent restores the volumes from the snapshot before applying it.
+func restoreDeploymentVolumeSnapshotHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := internalctx.GetLogger(ctx)
+		deployment := internalctx.GetDeployment(ctx)
+		snapshotID, err := uuid.Parse(r.PathValue("snapshotId"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		_ = db.RunTx(ctx, func(ctx context.Context) error {
+			snapshot, err := db.GetDeploymentVolumeSnapshot(ctx, deployment.ID, snapshotID)
+			if errors.Is(err, apierrors.ErrNotFound) {
+				http.NotFound(w, r)
+				return err
+			} else if err != nil {
+				log.Warn("could not get deployment volume snapshot", zap.Error(err))
+				sentry.GetHubFromContext(ctx).CaptureException(err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return err
+			}
+
+			if revision, err := db.CreateDeploymentRevisionForVolumeSnapshotRestore(ctx, snapshot); err != nil {
+				log.Warn("could not create deployment revision", zap.Error(err))
+				sentry.GetHubFromContext(ctx).CaptureException(err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return err
+			} else {
+				RespondJSON(w, revision)
+				return nil
+			}
+		})
+	}
+}
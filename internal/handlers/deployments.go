@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/distr-sh/distr/api"
@@ -23,6 +24,7 @@ import (
 	"github.com/oaswrap/spec/adapter/chiopenapi"
 	"github.com/oaswrap/spec/option"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func DeploymentsRouter(r chiopenapi.Router) {
@@ -72,6 +74,21 @@ func DeploymentsRouter(r chiopenapi.Router) {
 				ResourceRequest
 			}{})).
 			With(option.Response(http.StatusOK, nil, option.ContentType("text/plain")))
+		r.Get("/events", getDeploymentEventsHandler()).
+			With(option.Description("Get Kubernetes events of deployment resources")).
+			With(option.Request(DeploymentTimeseriesRequest{})).
+			With(option.Response(http.StatusOK, []api.DeploymentEventRecord{}))
+		r.Get("/metrics", getDeploymentContainerMetricsHandler()).
+			With(option.Description("Get resource usage of the containers of a deployment")).
+			With(option.Request(struct {
+				DeploymentTimeseriesRequest
+				Container string `query:"container"`
+			}{})).
+			With(option.Response(http.StatusOK, []api.DeploymentContainerMetrics{}))
+		r.Get("/volume-snapshots", getDeploymentVolumeSnapshotsHandler()).
+			With(option.Description("Get the volume snapshots that the Docker agent created for a deployment")).
+			With(option.Request(DeploymentIDRequest{})).
+			With(option.Response(http.StatusOK, []api.DeploymentVolumeSnapshot{}))
 		r.With(middleware.RequireReadWriteOrAdmin).Group(func(r chiopenapi.Router) {
 			r.Patch("/", patchDeploymentHandler()).
 				With(option.Description("Partially update a deployment")).
@@ -83,6 +100,20 @@ func DeploymentsRouter(r chiopenapi.Router) {
 			r.Delete("/", deleteDeploymentHandler()).
 				With(option.Description("Delete a deployment")).
 				With(option.Request(DeploymentIDRequest{}))
+			r.Get("/bundle", exportDeploymentBundleHandler()).
+				With(option.Description("Export a signed and encrypted bundle to install the deployment offline")).
+				With(option.Request(struct {
+					DeploymentIDRequest
+					IncludeAgentImage bool `query:"includeAgentImage"`
+				}{})).
+				With(option.Response(http.StatusOK, nil, option.ContentType("application/gzip")))
+			r.Post("/volume-snapshots/{snapshotId}/restore", restoreDeploymentVolumeSnapshotHandler()).
+				With(option.Description("Restore the volumes of a deployment together with the revision of the snapshot")).
+				With(option.Request(struct {
+					DeploymentIDRequest
+					SnapshotID uuid.UUID `path:"snapshotId"`
+				}{})).
+				With(option.Response(http.StatusOK, types.DeploymentRevision{}))
 		})
 	})
 }
@@ -294,6 +325,11 @@ func validateDeploymentRequest(
 		if existingDeployment.ApplicationID != app.ID {
 			return badRequestError(w, "can not change application of existing deployment")
 		}
+
+		if request.Namespace != nil &&
+			(existingDeployment.Namespace == nil || *request.Namespace != *existingDeployment.Namespace) {
+			return badRequestError(w, "can not change namespace of existing deployment")
+		}
 	}
 
 	if org.HasFeature(types.FeatureLicensing) {
@@ -329,6 +365,8 @@ func validateDeploymentRequest(
 		return err
 	} else if err = validateDeploymentRequestDeploymentTarget(ctx, w, request, target); err != nil {
 		return err
+	} else if request.RunHelmTests && !version.IsHelm() {
+		return badRequestError(w, "RunHelmTests is only supported for Helm chart application versions")
 	} else if err = validateDeploymentRequestValues(w, request, version, secrets); err != nil {
 		return err
 	} else {
@@ -422,6 +460,19 @@ func validateDeploymentRequestDeploymentTarget(
 		return badRequestError(w, "IgnoreRevisionSkew is only supported for Kubernetes deployments")
 	}
 
+	if request.RunHelmTests && target.Type != types.DeploymentTypeKubernetes {
+		return badRequestError(w, "RunHelmTests is only supported for Kubernetes deployments")
+	}
+
+	if request.Namespace != nil {
+		if target.Type != types.DeploymentTypeKubernetes || target.Scope == nil ||
+			*target.Scope != types.DeploymentTargetScopeCluster {
+			return badRequestError(w, "namespace is only supported for cluster scoped Kubernetes deployment targets")
+		} else if errs := validation.IsDNS1123Label(*request.Namespace); len(errs) > 0 {
+			return badRequestError(w, fmt.Sprintf("invalid namespace: %v", strings.Join(errs, ", ")))
+		}
+	}
+
 	return nil
 }
 
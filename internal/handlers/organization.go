@@ -219,6 +219,11 @@ func handleUpdateOrganization(
 			needsUpdate = true
 		}
 
+		if request.ArtifactSignatureRequired != org.HasFeature(types.FeatureArtifactSignatureRequired) {
+			org.SetFeature(types.FeatureArtifactSignatureRequired, request.ArtifactSignatureRequired)
+			needsUpdate = true
+		}
+
 		if needsUpdate {
 			return db.UpdateOrganization(ctx, org)
 		}
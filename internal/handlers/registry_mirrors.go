@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+func RegistryMirrorsRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Artifacts"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor)
+	r.Get("/", getRegistryMirrors).
+		With(option.Description("List all upstream registries that are mirrored by the registry")).
+		With(option.Response(http.StatusOK, []types.RegistryMirror{}))
+	r.With(middleware.RequireReadWriteOrAdmin).Group(func(r chiopenapi.Router) {
+		r.Post("/", createRegistryMirror).
+			With(option.Description("Add an upstream registry that is mirrored under the given prefix")).
+			With(option.Request(api.CreateRegistryMirrorRequest{})).
+			With(option.Response(http.StatusOK, types.RegistryMirror{}))
+		r.Delete("/{registryMirrorId}", deleteRegistryMirror).
+			With(option.Description("Delete a registry mirror. Artifacts that have already been mirrored are kept.")).
+			With(option.Request(struct {
+				RegistryMirrorID uuid.UUID `path:"registryMirrorId"`
+			}{}))
+	})
+}
+
+func getRegistryMirrors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if mirrors, err := db.GetRegistryMirrors(ctx, *auth.CurrentOrgID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get registry mirrors", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mirrors)
+	}
+}
+
+func createRegistryMirror(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request, err := JsonBody[api.CreateRegistryMirrorRequest](w, r)
+	if err != nil {
+		return
+	}
+	mirror := types.RegistryMirror{
+		OrganizationID:     *auth.CurrentOrgID(),
+		Prefix:             strings.Trim(strings.TrimSpace(request.Prefix), "/"),
+		UpstreamRepository: strings.TrimSpace(request.UpstreamRepository),
+		Username:           request.Username,
+		Password:           request.Password,
+	}
+
+	if err := mirror.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err := db.CreateRegistryMirror(ctx, &mirror); errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to create registry mirror", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mirror)
+	}
+}
+
+func deleteRegistryMirror(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if id, err := uuid.Parse(r.PathValue("registryMirrorId")); err != nil {
+		http.Error(w, "registryMirrorId is not a valid UUID", http.StatusBadRequest)
+	} else if err := db.DeleteRegistryMirrorWithID(ctx, id, *auth.CurrentOrgID()); errors.Is(
+		err, apierrors.ErrNotFound,
+	) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to delete registry mirror", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
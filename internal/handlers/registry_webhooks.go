@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+func RegistryWebhooksRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Artifacts"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor)
+	r.Get("/", getRegistryWebhooks).
+		With(option.Description("List the endpoints that pushes, tag overwrites and deletions of artifacts are sent to")).
+		With(option.Response(http.StatusOK, []types.RegistryWebhook{}))
+	r.With(middleware.RequireReadWriteOrAdmin).Group(func(r chiopenapi.Router) {
+		r.Post("/", createRegistryWebhook).
+			With(option.Description("Add a registry webhook. The response contains the secret that deliveries are " +
+				"signed with, which can not be retrieved later.")).
+			With(option.Request(api.CreateUpdateRegistryWebhookRequest{})).
+			With(option.Response(http.StatusOK, types.RegistryWebhook{}))
+		r.Route("/{registryWebhookId}", func(r chiopenapi.Router) {
+			type RegistryWebhookRequest struct {
+				RegistryWebhookID uuid.UUID `path:"registryWebhookId"`
+			}
+
+			r.Put("/", updateRegistryWebhook).
+				With(option.Description("Update a registry webhook")).
+				With(option.Request(struct {
+					RegistryWebhookRequest
+					api.CreateUpdateRegistryWebhookRequest
+				}{})).
+				With(option.Response(http.StatusOK, types.RegistryWebhook{}))
+			r.Delete("/", deleteRegistryWebhook).
+				With(option.Description("Delete a registry webhook")).
+				With(option.Request(RegistryWebhookRequest{}))
+		})
+	})
+}
+
+func getRegistryWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if webhooks, err := db.GetRegistryWebhooks(ctx, *auth.CurrentOrgID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get registry webhooks", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		for i := range webhooks {
+			webhooks[i].Secret = ""
+		}
+		RespondJSON(w, webhooks)
+	}
+}
+
+func createRegistryWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request, err := JsonBody[api.CreateUpdateRegistryWebhookRequest](w, r)
+	if err != nil {
+		return
+	}
+	webhook := types.RegistryWebhook{
+		OrganizationID: *auth.CurrentOrgID(),
+		URL:            strings.TrimSpace(request.URL),
+		Secret:         rand.Text(),
+	}
+
+	if err := webhook.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err := db.CreateRegistryWebhook(ctx, &webhook); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to create registry webhook", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, webhook)
+	}
+}
+
+func updateRegistryWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("registryWebhookId"))
+	if err != nil {
+		http.Error(w, "registryWebhookId is not a valid UUID", http.StatusBadRequest)
+		return
+	}
+	request, err := JsonBody[api.CreateUpdateRegistryWebhookRequest](w, r)
+	if err != nil {
+		return
+	}
+	webhook := types.RegistryWebhook{
+		ID:             id,
+		OrganizationID: *auth.CurrentOrgID(),
+		URL:            strings.TrimSpace(request.URL),
+	}
+
+	if err := webhook.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if err := db.UpdateRegistryWebhook(ctx, &webhook); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to update registry webhook", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		webhook.Secret = ""
+		RespondJSON(w, webhook)
+	}
+}
+
+func deleteRegistryWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	if id, err := uuid.Parse(r.PathValue("registryWebhookId")); err != nil {
+		http.Error(w, "registryWebhookId is not a valid UUID", http.StatusBadRequest)
+	} else if err := db.DeleteRegistryWebhookWithID(ctx, id, *auth.CurrentOrgID()); errors.Is(
+		err, apierrors.ErrNotFound,
+	) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to delete registry webhook", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -10,14 +10,21 @@ import (
 
 var ErrHttpStatus = errors.New("non-ok http status")
 
+// ErrHttpServerStatus is returned for 5xx responses. It wraps [ErrHttpStatus].
+var ErrHttpServerStatus = fmt.Errorf("%w (server error)", ErrHttpStatus)
+
 func CheckStatus(r *http.Response, err error) (*http.Response, error) {
 	if err != nil || StatusOK(r) {
 		return r, err
 	} else {
+		statusErr := ErrHttpStatus
+		if r.StatusCode >= 500 {
+			statusErr = ErrHttpServerStatus
+		}
 		if errorBody, err := io.ReadAll(r.Body); err == nil {
-			return r, fmt.Errorf("%w: %v (%v)", ErrHttpStatus, r.Status, strings.TrimSpace(string(errorBody)))
+			return r, fmt.Errorf("%w: %v (%v)", statusErr, r.Status, strings.TrimSpace(string(errorBody)))
 		}
-		return r, fmt.Errorf("%w: %v", ErrHttpStatus, r.Status)
+		return r, fmt.Errorf("%w: %v", statusErr, r.Status)
 	}
 }
 
@@ -0,0 +1,127 @@
+package kustomization
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/types"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const (
+	baseDir    = "/base"
+	overlayDir = "/overlay"
+)
+
+// Build renders the manifest file of an application version with the given overlay and returns the resulting
+// resources as multi-document YAML.
+//
+// The overlay is a kustomization (e.g. containing patches or images) that is applied on top of the manifests.
+// Its resources are always replaced with the manifests of the application version.
+func Build(kubernetesType types.KubernetesType, data []byte, overlay map[string]any) ([]byte, error) {
+	fs := filesys.MakeFsInMemory()
+	switch kubernetesType {
+	case types.KubernetesTypeManifests:
+		if err := fs.WriteFile(path.Join(baseDir, "manifests.yaml"), data); err != nil {
+			return nil, err
+		} else if err := fs.WriteFile(
+			path.Join(baseDir, konfig.DefaultKustomizationFileName()),
+			[]byte("resources:\n  - manifests.yaml\n"),
+		); err != nil {
+			return nil, err
+		}
+	case types.KubernetesTypeKustomize:
+		if err := extractArchive(data, func(name string, content []byte) error {
+			return fs.WriteFile(path.Join(baseDir, name), content)
+		}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported kubernetes type: %v", kubernetesType)
+	}
+
+	overlayKustomization := maps.Clone(overlay)
+	if overlayKustomization == nil {
+		overlayKustomization = map[string]any{}
+	}
+	overlayKustomization["resources"] = []any{"../base"}
+	if overlayData, err := yaml.Marshal(overlayKustomization); err != nil {
+		return nil, err
+	} else if err := fs.WriteFile(
+		path.Join(overlayDir, konfig.DefaultKustomizationFileName()),
+		overlayData,
+	); err != nil {
+		return nil, err
+	}
+
+	if resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, overlayDir); err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w", err)
+	} else {
+		return resources.AsYaml()
+	}
+}
+
+// Validate checks the manifest file of an application version without fetching any remote resources.
+func Validate(kubernetesType types.KubernetesType, data []byte) error {
+	switch kubernetesType {
+	case types.KubernetesTypeManifests:
+		_, err := Build(kubernetesType, data, nil)
+		return err
+	case types.KubernetesTypeKustomize:
+		var names []string
+		if err := extractArchive(data, func(name string, _ []byte) error {
+			names = append(names, name)
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, name := range konfig.RecognizedKustomizationFileNames() {
+			if slices.Contains(names, name) {
+				return nil
+			}
+		}
+		return errors.New("archive does not contain a kustomization file in its root directory")
+	default:
+		return fmt.Errorf("unsupported kubernetes type: %v", kubernetesType)
+	}
+}
+
+// extractArchive calls fn for every regular file in the given gzipped tar archive.
+func extractArchive(data []byte, fn func(name string, content []byte) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("kustomization is not a gzipped tar archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not read kustomization archive: %w", err)
+		} else if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("invalid file name in kustomization archive: %v", header.Name)
+		}
+		if content, err := io.ReadAll(tr); err != nil {
+			return fmt.Errorf("could not read kustomization archive: %w", err)
+		} else if err := fn(name, content); err != nil {
+			return err
+		}
+	}
+}
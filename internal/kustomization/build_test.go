@@ -0,0 +1,75 @@
+package kustomization_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/kustomization"
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+const manifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  key: value
+`
+
+func writeArchive(g *WithT, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		g.Expect(tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})).To(Succeed())
+		_, err := tw.Write([]byte(content))
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+	g.Expect(tw.Close()).To(Succeed())
+	g.Expect(gz.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func TestBuildManifests(t *testing.T) {
+	g := NewWithT(t)
+	result, err := kustomization.Build(types.KubernetesTypeManifests, []byte(manifests), map[string]any{
+		"patches": []any{map[string]any{
+			"patch": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  key: patched\n",
+		}},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(result)).To(ContainSubstring("key: patched"))
+}
+
+func TestBuildKustomize(t *testing.T) {
+	g := NewWithT(t)
+	archive := writeArchive(g, map[string]string{
+		"./kustomization.yaml": "resources:\n  - config.yaml\nnamePrefix: app-\n",
+		"./config.yaml":        manifests,
+	})
+	g.Expect(kustomization.Validate(types.KubernetesTypeKustomize, archive)).To(Succeed())
+	result, err := kustomization.Build(types.KubernetesTypeKustomize, archive, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(result)).To(ContainSubstring("name: app-config"))
+}
+
+func TestValidateKustomizeInvalid(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(kustomization.Validate(types.KubernetesTypeKustomize, []byte(manifests))).NotTo(Succeed())
+	g.Expect(kustomization.Validate(
+		types.KubernetesTypeKustomize,
+		writeArchive(g, map[string]string{"config.yaml": manifests}),
+	)).NotTo(Succeed())
+	g.Expect(kustomization.Validate(
+		types.KubernetesTypeKustomize,
+		writeArchive(g, map[string]string{"../kustomization.yaml": manifests}),
+	)).NotTo(Succeed())
+}
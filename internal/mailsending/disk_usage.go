@@ -0,0 +1,71 @@
+package mailsending
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/api"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/customdomains"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mail"
+	"github.com/distr-sh/distr/internal/mailtemplates"
+	"github.com/distr-sh/distr/internal/types"
+	"go.uber.org/zap"
+)
+
+// SendDiskUsageWarningMail notifies the vendor admins and the admins of the customer organization that owns the
+// deployment target about disks that exceed the disk usage warning threshold.
+// A failed mail does not prevent the mails to the other recipients from being sent.
+func SendDiskUsageWarningMail(
+	ctx context.Context,
+	deploymentTarget types.DeploymentTarget,
+	disks []api.AgentDiskMetrics,
+) error {
+	mailer := internalctx.GetMailer(ctx)
+	log := internalctx.GetLogger(ctx)
+
+	organization, err := db.GetOrganizationWithBranding(ctx, deploymentTarget.OrganizationID)
+	if err != nil {
+		return err
+	}
+	from, err := customdomains.EmailFromAddressParsedOrDefault(organization.Organization)
+	if err != nil {
+		return err
+	}
+	from.Name = organization.Name
+	userAccounts, err := db.GetUserAccountsByOrgID(ctx, deploymentTarget.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, userAccount := range userAccounts {
+		if userAccount.UserRole != types.UserRoleAdmin ||
+			(userAccount.CustomerOrganizationID != nil && (deploymentTarget.CustomerOrganizationID == nil ||
+				*userAccount.CustomerOrganizationID != *deploymentTarget.CustomerOrganizationID)) {
+			continue
+		}
+		email := mail.New(
+			mail.To(userAccount.Email),
+			mail.From(*from),
+			mail.Subject(fmt.Sprintf("Low disk space on deployment target %v", deploymentTarget.Name)),
+			mail.HtmlBodyTemplate(mailtemplates.DiskUsageWarning(
+				userAccount.AsUserAccount(),
+				*organization,
+				deploymentTarget,
+				disks,
+			)),
+		)
+		if err := mailer.Send(ctx, email); err != nil {
+			log.Error("could not send disk usage warning mail", zap.Error(err), zap.String("user", userAccount.Email))
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	log.Info("disk usage warning mails have been sent", zap.Stringer("deploymentTargetId", deploymentTarget.ID))
+	return nil
+}
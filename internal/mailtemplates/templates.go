@@ -2,11 +2,13 @@ package mailtemplates
 
 import (
 	"embed"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"net/url"
 	"path"
 
+	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/customdomains"
 	"github.com/distr-sh/distr/internal/env"
 	"github.com/distr-sh/distr/internal/types"
@@ -112,3 +114,36 @@ func UpdateEmail(userAccount types.UserAccount, org types.Organization, token st
 		"Token":       token,
 	}
 }
+
+func DiskUsageWarning(
+	userAccount types.UserAccount,
+	organization types.OrganizationWithBranding,
+	deploymentTarget types.DeploymentTarget,
+	disks []api.AgentDiskMetrics,
+) (*template.Template, any) {
+	diskData := make([]map[string]any, len(disks))
+	for i, disk := range disks {
+		var description string
+		switch disk.Type {
+		case api.AgentDiskTypeDockerRoot:
+			description = "Docker root directory"
+		case api.AgentDiskTypeVolume:
+			description = fmt.Sprintf("Docker volume %v", disk.Name)
+		case api.AgentDiskTypePersistentVolumeClaim:
+			description = fmt.Sprintf("PersistentVolumeClaim %v", disk.Name)
+		default:
+			description = disk.Name
+		}
+		diskData[i] = map[string]any{
+			"Description": description,
+			"Usage":       fmt.Sprintf("%.0f%%", disk.Usage()*100),
+		}
+	}
+	return templates.Lookup("disk-usage-warning.html"), map[string]any{
+		"UserAccount":      userAccount,
+		"Organization":     organization,
+		"Host":             customdomains.AppDomainOrDefault(organization.Organization),
+		"DeploymentTarget": deploymentTarget,
+		"Disks":            diskData,
+	}
+}
@@ -42,6 +42,14 @@ func ContextInjectorMiddleware(
 	}
 }
 
+// PeerAddressMiddleware stores the address of the direct peer in the context. It must be used before
+// [middleware.RealIP], which replaces the remote address of the request with the address from proxy headers.
+func PeerAddressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(internalctx.WithPeerAddress(r.Context(), r.RemoteAddr)))
+	})
+}
+
 func LoggerCtxMiddleware(logger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,10 @@
+CREATE TABLE DeploymentTargetAccessKeyRotation (
+  deployment_target_id UUID PRIMARY KEY REFERENCES DeploymentTarget(id) ON DELETE CASCADE,
+  access_key_created_at TIMESTAMP NOT NULL DEFAULT now(),
+  requested_at TIMESTAMP,
+  pending_access_key_salt BYTEA,
+  pending_access_key_hash BYTEA,
+  previous_access_key_salt BYTEA,
+  previous_access_key_hash BYTEA,
+  previous_access_key_expires_at TIMESTAMP
+);
@@ -0,0 +1,8 @@
+CREATE TABLE DeploymentTargetCertificate (
+  serial_number TEXT PRIMARY KEY,
+  deployment_target_id UUID NOT NULL REFERENCES DeploymentTarget(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT now(),
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX fk_DeploymentTargetCertificate_deployment_target_id ON DeploymentTargetCertificate(deployment_target_id);
@@ -0,0 +1,4 @@
+ALTER TABLE DeploymentTarget
+  DROP COLUMN https_proxy,
+  DROP COLUMN no_proxy,
+  DROP COLUMN ca_certificates;
@@ -0,0 +1,4 @@
+ALTER TABLE DeploymentTarget
+  ADD COLUMN https_proxy TEXT,
+  ADD COLUMN no_proxy TEXT,
+  ADD COLUMN ca_certificates TEXT;
@@ -0,0 +1,5 @@
+ALTER TABLE ApplicationVersion
+  DROP COLUMN kubernetes_type,
+  DROP COLUMN manifest_file_data;
+
+DROP TYPE KUBERNETES_TYPE;
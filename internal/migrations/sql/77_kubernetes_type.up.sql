@@ -0,0 +1,5 @@
+CREATE TYPE KUBERNETES_TYPE AS ENUM ('helm', 'manifests', 'kustomize');
+
+ALTER TABLE ApplicationVersion
+  ADD COLUMN kubernetes_type KUBERNETES_TYPE,
+  ADD COLUMN manifest_file_data BYTEA;
@@ -0,0 +1,18 @@
+CREATE TABLE DeploymentEventRecord (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP DEFAULT current_timestamp,
+  deployment_id UUID NOT NULL REFERENCES Deployment(id) ON DELETE CASCADE,
+  deployment_revision_id UUID NOT NULL REFERENCES DeploymentRevision(id) ON DELETE CASCADE,
+  uid TEXT NOT NULL,
+  resource TEXT NOT NULL,
+  type TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  message TEXT NOT NULL,
+  count INTEGER NOT NULL DEFAULT 1,
+  first_timestamp TIMESTAMP NOT NULL,
+  last_timestamp TIMESTAMP NOT NULL,
+  CONSTRAINT DeploymentEventRecord_uid_unique UNIQUE (deployment_id, uid)
+);
+
+CREATE INDEX fk_DeploymentEventRecord_deployment_revision_id ON DeploymentEventRecord (deployment_revision_id);
+CREATE INDEX DeploymentEventRecord_last_timestamp ON DeploymentEventRecord (deployment_id, last_timestamp);
@@ -0,0 +1 @@
+ALTER TABLE DeploymentRevision DROP COLUMN run_helm_tests;
@@ -0,0 +1,2 @@
+ALTER TABLE DeploymentRevision
+  ADD COLUMN run_helm_tests BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+ALTER TABLE Deployment DROP COLUMN namespace;
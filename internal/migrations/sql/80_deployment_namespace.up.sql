@@ -0,0 +1,2 @@
+ALTER TABLE Deployment
+  ADD COLUMN namespace TEXT;
@@ -0,0 +1,19 @@
+CREATE TABLE DeploymentContainerMetrics (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  deployment_id UUID NOT NULL REFERENCES Deployment(id) ON DELETE CASCADE,
+  deployment_revision_id UUID NOT NULL REFERENCES DeploymentRevision(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  cpu_usage_millis BIGINT NOT NULL,
+  memory_bytes BIGINT NOT NULL,
+  memory_limit_bytes BIGINT,
+  restart_count INTEGER NOT NULL DEFAULT 0,
+  disk_read_bytes BIGINT,
+  disk_write_bytes BIGINT,
+  network_rx_bytes BIGINT,
+  network_tx_bytes BIGINT
+);
+
+CREATE INDEX fk_DeploymentContainerMetrics_deployment_revision_id
+  ON DeploymentContainerMetrics (deployment_revision_id);
+CREATE INDEX DeploymentContainerMetrics_created_at ON DeploymentContainerMetrics (deployment_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE DeploymentTarget DROP COLUMN IF EXISTS disk_usage_warning_threshold;
+
+ALTER TABLE DeploymentTargetMetrics DROP COLUMN IF EXISTS disks;
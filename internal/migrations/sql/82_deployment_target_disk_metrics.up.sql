@@ -0,0 +1,4 @@
+ALTER TABLE DeploymentTargetMetrics ADD COLUMN disks JSONB;
+
+ALTER TABLE DeploymentTarget ADD COLUMN disk_usage_warning_threshold FLOAT
+  CHECK (disk_usage_warning_threshold > 0 AND disk_usage_warning_threshold <= 1);
@@ -0,0 +1 @@
+ALTER TABLE DeploymentTarget DROP COLUMN IF EXISTS prune_policy;
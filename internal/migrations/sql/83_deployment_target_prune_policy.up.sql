@@ -0,0 +1 @@
+ALTER TABLE DeploymentTarget ADD COLUMN prune_policy JSONB;
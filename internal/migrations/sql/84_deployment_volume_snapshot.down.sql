@@ -0,0 +1,5 @@
+ALTER TABLE DeploymentRevision DROP COLUMN IF EXISTS restore_volume_snapshot_id;
+
+DROP TABLE IF EXISTS DeploymentVolumeSnapshot;
+
+ALTER TABLE DeploymentTarget DROP COLUMN IF EXISTS volume_backup_policy;
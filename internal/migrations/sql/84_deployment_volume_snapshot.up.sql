@@ -0,0 +1,15 @@
+ALTER TABLE DeploymentTarget ADD COLUMN volume_backup_policy JSONB;
+
+CREATE TABLE DeploymentVolumeSnapshot (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  deployment_id UUID NOT NULL REFERENCES Deployment(id) ON DELETE CASCADE,
+  deployment_revision_id UUID NOT NULL REFERENCES DeploymentRevision(id) ON DELETE CASCADE,
+  volumes TEXT[] NOT NULL,
+  size_bytes BIGINT NOT NULL
+);
+
+CREATE INDEX fk_DeploymentVolumeSnapshot_deployment_id ON DeploymentVolumeSnapshot (deployment_id);
+CREATE INDEX fk_DeploymentVolumeSnapshot_deployment_revision_id ON DeploymentVolumeSnapshot (deployment_revision_id);
+
+ALTER TABLE DeploymentRevision ADD COLUMN restore_volume_snapshot_id UUID;
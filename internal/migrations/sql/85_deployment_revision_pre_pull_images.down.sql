@@ -0,0 +1 @@
+ALTER TABLE DeploymentRevision DROP COLUMN pre_pull_images;
@@ -0,0 +1,2 @@
+ALTER TABLE DeploymentRevision
+  ADD COLUMN pre_pull_images BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,13 @@
+CREATE TABLE ArtifactRetentionPolicy (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  artifact_id UUID REFERENCES Artifact (id) ON DELETE CASCADE,
+  tag_pattern TEXT,
+  keep_last_tags INTEGER CHECK (keep_last_tags > 0),
+  untagged_max_age_days INTEGER CHECK (untagged_max_age_days > 0),
+  CHECK (keep_last_tags IS NOT NULL OR untagged_max_age_days IS NOT NULL)
+);
+
+CREATE INDEX fk_ArtifactRetentionPolicy_organization_id ON ArtifactRetentionPolicy (organization_id);
+CREATE INDEX fk_ArtifactRetentionPolicy_artifact_id ON ArtifactRetentionPolicy (artifact_id);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS ArtifactVersionSignature;
+DROP TABLE IF EXISTS ArtifactSigningKey;
+
+ALTER TYPE FEATURE RENAME TO FEATURE_OLD;
+CREATE TYPE FEATURE AS ENUM ('licensing', 'pre_post_scripts', 'artifact_version_mutable');
+
+UPDATE Organization SET features = array_remove(features, 'artifact_signature_required') WHERE 'artifact_signature_required' = ANY(features);
+
+ALTER TABLE Organization ALTER COLUMN features DROP DEFAULT; -- otherwise the following wouldnt work:
+ALTER TABLE Organization
+  ALTER COLUMN features TYPE FEATURE[]
+    USING (features::text[]::FEATURE[]);
+ALTER TABLE Organization
+  ALTER COLUMN features SET DEFAULT ARRAY[]::FEATURE[];
+
+DROP TYPE FEATURE_OLD;
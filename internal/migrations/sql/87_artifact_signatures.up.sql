@@ -0,0 +1,26 @@
+ALTER TYPE FEATURE ADD VALUE 'artifact_signature_required';
+
+CREATE TABLE ArtifactSigningKey (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  public_key TEXT NOT NULL,
+  UNIQUE (organization_id, name)
+);
+
+CREATE TABLE ArtifactVersionSignature (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  artifact_version_id UUID NOT NULL REFERENCES ArtifactVersion (id) ON DELETE CASCADE,
+  subject_digest TEXT NOT NULL,
+  format TEXT NOT NULL,
+  signed_digest TEXT NOT NULL,
+  payload BYTEA NOT NULL,
+  signature BYTEA NOT NULL,
+  algorithm TEXT,
+  certificates BYTEA[] NOT NULL DEFAULT ARRAY[]::BYTEA[]
+);
+
+CREATE INDEX fk_ArtifactVersionSignature_artifact_version_id ON ArtifactVersionSignature (artifact_version_id);
+CREATE INDEX ArtifactVersionSignature_subject_digest ON ArtifactVersionSignature (subject_digest);
@@ -0,0 +1,17 @@
+CREATE TABLE ArtifactVersionAttachment (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  artifact_version_id UUID NOT NULL REFERENCES ArtifactVersion (id) ON DELETE CASCADE,
+  subject_digest TEXT NOT NULL,
+  type TEXT NOT NULL,
+  format TEXT NOT NULL,
+  media_type TEXT NOT NULL,
+  blob_digest TEXT NOT NULL,
+  size BIGINT NOT NULL,
+  package_count INT,
+  licenses TEXT[] NOT NULL DEFAULT ARRAY[]::TEXT[],
+  vulnerabilities JSONB
+);
+
+CREATE INDEX fk_ArtifactVersionAttachment_artifact_version_id ON ArtifactVersionAttachment (artifact_version_id);
+CREATE INDEX ArtifactVersionAttachment_subject_digest ON ArtifactVersionAttachment (subject_digest);
@@ -0,0 +1,10 @@
+CREATE TABLE RegistryMirror (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  prefix TEXT NOT NULL,
+  upstream_repository TEXT NOT NULL,
+  username TEXT,
+  password TEXT,
+  UNIQUE (organization_id, prefix)
+);
@@ -0,0 +1,2 @@
+DROP TABLE ArtifactReplicationStatus;
+DROP TABLE ArtifactReplicationTarget;
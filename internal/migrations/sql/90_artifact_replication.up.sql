@@ -0,0 +1,24 @@
+CREATE TABLE ArtifactReplicationTarget (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  customer_organization_id UUID NOT NULL REFERENCES CustomerOrganization (id) ON DELETE CASCADE,
+  registry_url TEXT NOT NULL,
+  username TEXT,
+  password_secret_id UUID REFERENCES Secret (id) ON DELETE SET NULL,
+  UNIQUE (customer_organization_id)
+);
+
+CREATE INDEX fk_ArtifactReplicationTarget_organization_id ON ArtifactReplicationTarget (organization_id);
+
+CREATE TABLE ArtifactReplicationStatus (
+  artifact_replication_target_id UUID NOT NULL REFERENCES ArtifactReplicationTarget (id) ON DELETE CASCADE,
+  artifact_version_id UUID NOT NULL REFERENCES ArtifactVersion (id) ON DELETE CASCADE,
+  updated_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  synced_at TIMESTAMP,
+  tags TEXT[] NOT NULL,
+  error TEXT,
+  PRIMARY KEY (artifact_replication_target_id, artifact_version_id)
+);
+
+CREATE INDEX fk_ArtifactReplicationStatus_artifact_version_id ON ArtifactReplicationStatus (artifact_version_id);
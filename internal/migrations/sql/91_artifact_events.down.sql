@@ -0,0 +1,3 @@
+DROP TABLE RegistryWebhook;
+DROP TABLE ArtifactEvent;
+DROP TYPE ARTIFACT_EVENT_TYPE;
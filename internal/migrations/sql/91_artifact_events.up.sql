@@ -0,0 +1,31 @@
+CREATE TYPE ARTIFACT_EVENT_TYPE AS ENUM ('push', 'tag_overwrite', 'delete');
+
+CREATE TABLE ArtifactEvent (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  artifact_id UUID REFERENCES Artifact (id) ON DELETE SET NULL,
+  artifact_name TEXT NOT NULL,
+  type ARTIFACT_EVENT_TYPE NOT NULL,
+  reference TEXT NOT NULL,
+  digest_before TEXT,
+  digest_after TEXT,
+  useraccount_id UUID REFERENCES UserAccount (id) ON DELETE SET NULL,
+  access_token_id UUID REFERENCES AccessToken (id) ON DELETE SET NULL,
+  remote_address TEXT
+);
+
+CREATE INDEX ArtifactEvent_organization_id_created_at ON ArtifactEvent (organization_id, created_at DESC);
+CREATE INDEX ArtifactEvent_artifact_id_created_at ON ArtifactEvent (artifact_id, created_at DESC);
+CREATE INDEX fk_ArtifactEvent_useraccount_id ON ArtifactEvent (useraccount_id);
+CREATE INDEX fk_ArtifactEvent_access_token_id ON ArtifactEvent (access_token_id);
+
+CREATE TABLE RegistryWebhook (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL
+);
+
+CREATE INDEX fk_RegistryWebhook_organization_id ON RegistryWebhook (organization_id);
@@ -0,0 +1,4 @@
+ALTER TABLE DeploymentTargetAccessKeyRotation ADD COLUMN pending_access_key_issued_at TIMESTAMP;
+
+-- pending keys that were issued before can not be sent again and are replaced by a new one
+UPDATE DeploymentTargetAccessKeyRotation SET pending_access_key_salt = NULL, pending_access_key_hash = NULL;
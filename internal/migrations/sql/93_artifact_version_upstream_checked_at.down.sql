@@ -0,0 +1 @@
+ALTER TABLE ArtifactVersion DROP COLUMN upstream_checked_at;
@@ -0,0 +1 @@
+ALTER TABLE ArtifactVersion ADD COLUMN upstream_checked_at TIMESTAMP;
@@ -0,0 +1,13 @@
+CREATE TABLE RegistryWebhookDelivery (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  registry_webhook_id UUID NOT NULL REFERENCES RegistryWebhook (id) ON DELETE CASCADE,
+  artifact_event_id UUID NOT NULL REFERENCES ArtifactEvent (id) ON DELETE CASCADE,
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  last_error TEXT
+);
+
+CREATE INDEX RegistryWebhookDelivery_next_attempt_at ON RegistryWebhookDelivery (next_attempt_at);
+CREATE INDEX fk_RegistryWebhookDelivery_registry_webhook_id ON RegistryWebhookDelivery (registry_webhook_id);
+CREATE INDEX fk_RegistryWebhookDelivery_artifact_event_id ON RegistryWebhookDelivery (artifact_event_id);
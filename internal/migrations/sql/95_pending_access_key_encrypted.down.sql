@@ -0,0 +1 @@
+ALTER TABLE DeploymentTargetAccessKeyRotation DROP COLUMN pending_access_key_encrypted;
@@ -0,0 +1,5 @@
+ALTER TABLE DeploymentTargetAccessKeyRotation ADD COLUMN pending_access_key_encrypted BYTEA;
+
+-- pending keys that were derived from the JWT secret can not be sent again and are replaced by a new one
+UPDATE DeploymentTargetAccessKeyRotation
+SET pending_access_key_salt = NULL, pending_access_key_hash = NULL, pending_access_key_issued_at = NULL;
@@ -0,0 +1 @@
+ALTER TABLE DeploymentTarget DROP COLUMN http_proxy;
@@ -0,0 +1 @@
+ALTER TABLE DeploymentTarget ADD COLUMN http_proxy TEXT;
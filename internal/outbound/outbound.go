@@ -0,0 +1,82 @@
+// Package outbound restricts the addresses that the hub connects to on behalf of users, like registry mirrors,
+// replication targets and webhooks, so that these can not be used to reach internal services (SSRF).
+//
+// Loopback, private, link-local and other non-public addresses are rejected unless
+// [env.AllowPrivateOutboundAddresses] is set. Host names are checked after they were resolved, when the connection is
+// made, so that a host name can not be pointed to an internal address after it was validated.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/distr-sh/distr/internal/env"
+)
+
+var ErrForbiddenAddress = errors.New("address is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not covered by [netip.Addr.IsPrivate].
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+var dialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+	Control:   control,
+}
+
+// ValidateHost returns an error if host (with or without port) is "localhost" or an IP address that is not allowed.
+// Other host names are only checked when a connection is made.
+func ValidateHost(host string) error {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if env.AllowPrivateOutboundAddresses() {
+		return nil
+	} else if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, host)
+	} else if addr, err := netip.ParseAddr(host); err == nil && !isAllowed(addr) {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// DialContext connects to the address like [net.Dialer.DialContext] but fails with [ErrForbiddenAddress] if the
+// resolved address is not allowed.
+func DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return dialer.DialContext(ctx, network, address)
+}
+
+// NewTransport returns a copy of [http.DefaultTransport] that only connects to allowed addresses.
+//
+// Proxies from the environment are not used, because the proxy would connect to the target instead and the address
+// could not be checked.
+func NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = DialContext
+	return transport
+}
+
+func control(network, address string, _ syscall.RawConn) error {
+	if env.AllowPrivateOutboundAddresses() {
+		return nil
+	} else if addrPort, err := netip.ParseAddrPort(address); err != nil {
+		return err
+	} else if !isAllowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: %v", ErrForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+func isAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
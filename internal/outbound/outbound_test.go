@@ -0,0 +1,30 @@
+package outbound
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateHost(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(ValidateHost("registry.example.com")).To(Succeed())
+	g.Expect(ValidateHost("registry.example.com:5000")).To(Succeed())
+	g.Expect(ValidateHost("8.8.8.8")).To(Succeed())
+	for _, host := range []string{
+		"localhost",
+		"localhost:5000",
+		"127.0.0.1",
+		"10.1.2.3:443",
+		"192.168.0.1",
+		"169.254.169.254",
+		"100.64.0.1",
+		"0.0.0.0",
+		"[::1]:8080",
+		"::ffff:127.0.0.1",
+		"fd00::1",
+		"fe80::1",
+	} {
+		g.Expect(ValidateHost(host)).To(MatchError(ErrForbiddenAddress), host)
+	}
+}
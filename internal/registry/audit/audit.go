@@ -4,13 +4,21 @@ import (
 	"context"
 
 	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authkey"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/registry/name"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	"github.com/opencontainers/go-digest"
 )
 
 type ArtifactAuditor interface {
 	AuditPull(ctx context.Context, name, reference string) error
+	// AuditPush records that reference was pushed. before is the digest the reference pointed to before, if any.
+	AuditPush(ctx context.Context, name, reference string, before *digest.Digest, after digest.Digest) error
+	AuditDelete(ctx context.Context, name, reference string, before digest.Digest) error
 }
 
 type auditor struct{}
@@ -36,3 +44,89 @@ func (a *auditor) AuditPull(ctx context.Context, nameStr string, reference strin
 		)
 	}
 }
+
+// AuditPush implements ArtifactAuditor.
+//
+// Pushing a reference that already points to a different digest is recorded as a tag overwrite.
+func (a *auditor) AuditPush(
+	ctx context.Context,
+	nameStr, reference string,
+	before *digest.Digest,
+	after digest.Digest,
+) error {
+	eventType := types.ArtifactEventTypePush
+	if before != nil && *before != after {
+		eventType = types.ArtifactEventTypeTagOverwrite
+	}
+	return a.audit(ctx, nameStr, reference, eventType, before, &after)
+}
+
+// AuditDelete implements ArtifactAuditor.
+func (a *auditor) AuditDelete(ctx context.Context, nameStr, reference string, before digest.Digest) error {
+	return a.audit(ctx, nameStr, reference, types.ArtifactEventTypeDelete, &before, nil)
+}
+
+func (a *auditor) audit(
+	ctx context.Context,
+	nameStr, reference string,
+	eventType types.ArtifactEventType,
+	before, after *digest.Digest,
+) error {
+	auth := auth.ArtifactsAuthentication.Require(ctx)
+	name, err := name.Parse(nameStr)
+	if err != nil {
+		return err
+	}
+	event := types.ArtifactEvent{
+		OrganizationID: *auth.CurrentOrgID(),
+		ArtifactName:   name.ArtifactName,
+		Type:           eventType,
+		Reference:      reference,
+	}
+	if before != nil {
+		event.DigestBefore = util.PtrTo(before.String())
+	}
+	if after != nil {
+		event.DigestAfter = util.PtrTo(after.String())
+	}
+	if userID := auth.CurrentUserID(); userID != uuid.Nil {
+		event.UserAccountID = &userID
+	}
+	return RecordEvent(ctx, &event, auth.Token())
+}
+
+// RecordEvent stores the event and queues it for delivery to the registry webhooks of the organization.
+//
+// The remote address is taken from the request. If token is a personal access token, the event is linked to it.
+func RecordEvent(ctx context.Context, event *types.ArtifactEvent, token any) error {
+	if addr := internalctx.GetRequestIPAddress(ctx); addr != "" {
+		event.RemoteAddress = &addr
+	}
+	var key *authkey.Key
+	if k, ok := token.(authkey.Key); ok {
+		key = &k
+	}
+	return db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.CreateArtifactEvent(ctx, event, key); err != nil {
+			return err
+		}
+		return db.CreateRegistryWebhookDeliveries(ctx, []uuid.UUID{event.ID})
+	})
+}
+
+// RecordDeletions records a delete event for each of the given artifact versions and queues the events for delivery
+// to the registry webhooks. It must be called before the versions are deleted.
+//
+// userID and remoteAddress are nil if the versions are deleted by the system, for example by the retention job.
+func RecordDeletions(ctx context.Context, versionIDs []uuid.UUID, userID *uuid.UUID, remoteAddress *string) error {
+	if len(versionIDs) == 0 {
+		return nil
+	}
+	return db.RunTx(ctx, func(ctx context.Context) error {
+		if eventIDs, err := db.CreateArtifactDeletionEvents(ctx, versionIDs, userID, remoteAddress); err != nil {
+			return err
+		} else {
+			return db.CreateRegistryWebhookDeliveries(ctx, eventIDs)
+		}
+	})
+}
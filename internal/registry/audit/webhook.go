@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/outbound"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookEventHeader     = "X-Distr-Event"
+	webhookDeliveryHeader  = "X-Distr-Delivery"
+	webhookSignatureHeader = "X-Distr-Signature"
+)
+
+// webhookDeliveryBatchSize is the number of deliveries that are claimed at once. All of them must be sent within
+// webhookDeliveryLease, otherwise they might be claimed again by another instance.
+const (
+	webhookDeliveryBatchSize = 20
+	webhookDeliveryLease     = 5 * time.Minute
+)
+
+var (
+	webhookClient = &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: outbound.NewTransport(),
+		// Redirects are not followed, so that a webhook can not be used to reach addresses that are not allowed as
+		// webhook URL.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	// webhookRetryDelays are the delays before the retries of a failed delivery.
+	webhookRetryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour}
+)
+
+type WebhookDeliveryResult struct {
+	Delivered int64
+	Failed    int64
+}
+
+// DeliverWebhooks sends all queued artifact events to the registry webhooks, until no delivery is due anymore.
+//
+// Failed deliveries are retried later with increasing delays. Deliveries that still fail after the last retry are
+// logged and dropped.
+func DeliverWebhooks(ctx context.Context) (*WebhookDeliveryResult, error) {
+	var result WebhookDeliveryResult
+	for {
+		deliveries, err := db.ClaimDueRegistryWebhookDeliveries(ctx, webhookDeliveryBatchSize, webhookDeliveryLease)
+		if err != nil {
+			return &result, err
+		} else if len(deliveries) == 0 {
+			return &result, nil
+		}
+		if err := deliverBatch(ctx, deliveries, &result); err != nil {
+			return &result, err
+		}
+	}
+}
+
+// RunWebhookDeliveryJob delivers the queued webhooks and logs the result.
+func RunWebhookDeliveryJob(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	result, err := DeliverWebhooks(ctx)
+	log.Info("registry webhook delivery finished",
+		zap.Int64("deliveriesSucceeded", result.Delivered),
+		zap.Int64("deliveriesFailed", result.Failed),
+		zap.Error(err))
+	return err
+}
+
+func deliverBatch(
+	ctx context.Context,
+	deliveries []types.RegistryWebhookDelivery,
+	result *WebhookDeliveryResult,
+) error {
+	eventIDs := make([]uuid.UUID, len(deliveries))
+	for i, delivery := range deliveries {
+		eventIDs[i] = delivery.ArtifactEventID
+	}
+	events, err := db.GetArtifactEventsWithIDs(ctx, eventIDs)
+	if err != nil {
+		return err
+	}
+	bodies := make(map[uuid.UUID][]byte, len(events))
+	eventsByID := make(map[uuid.UUID]types.ArtifactEvent, len(events))
+	for _, event := range events {
+		if body, err := json.Marshal(event); err != nil {
+			return err
+		} else {
+			bodies[event.ID] = body
+			eventsByID[event.ID] = event
+		}
+	}
+
+	for _, delivery := range deliveries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		log := internalctx.GetLogger(ctx).With(
+			zap.Stringer("registryWebhookId", delivery.RegistryWebhookID),
+			zap.Stringer("artifactEventId", delivery.ArtifactEventID))
+		webhook := types.RegistryWebhook{ID: delivery.RegistryWebhookID, URL: delivery.URL, Secret: delivery.Secret}
+		err := send(ctx, webhook, eventsByID[delivery.ArtifactEventID], bodies[delivery.ArtifactEventID])
+		if err == nil {
+			result.Delivered++
+			if err := db.DeleteRegistryWebhookDeliveryWithID(ctx, delivery.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result.Failed++
+		if delivery.Attempts >= len(webhookRetryDelays) {
+			log.Warn("registry webhook delivery failed", zap.Int("attempts", delivery.Attempts+1), zap.Error(err))
+			if err := db.DeleteRegistryWebhookDeliveryWithID(ctx, delivery.ID); err != nil {
+				return err
+			}
+		} else {
+			log.Debug("registry webhook delivery failed, retrying", zap.Error(err))
+			nextAttemptAt := time.Now().Add(webhookRetryDelays[delivery.Attempts])
+			if err := db.UpdateRegistryWebhookDeliveryFailed(ctx, delivery.ID, nextAttemptAt, err.Error()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func send(ctx context.Context, webhook types.RegistryWebhook, event types.ArtifactEvent, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, string(event.Type))
+	req.Header.Set(webhookDeliveryHeader, event.ID.String())
+	req.Header.Set(webhookSignatureHeader, signature(webhook.Secret, body))
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// signature returns the value of the signature header, which is the hex encoded HMAC-SHA256 of the body prefixed
+// with the algorithm, like the signatures of GitHub webhooks.
+func signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestSend(t *testing.T) {
+	g := NewWithT(t)
+	event := types.ArtifactEvent{ID: uuid.New(), Type: types.ArtifactEventTypeTagOverwrite}
+	body := []byte(`{"type":"tag_overwrite"}`)
+
+	var received *http.Request
+	var receivedBody []byte
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	// the test server listens on a loopback address, which the default webhook client refuses to connect to
+	defaultClient := webhookClient
+	webhookClient = server.Client()
+	defer func() { webhookClient = defaultClient }()
+	webhook := types.RegistryWebhook{URL: server.URL, Secret: "secret"}
+
+	g.Expect(send(context.Background(), webhook, event, body)).To(Succeed())
+	g.Expect(receivedBody).To(Equal(body))
+	g.Expect(received.Header.Get(webhookEventHeader)).To(Equal("tag_overwrite"))
+	g.Expect(received.Header.Get(webhookDeliveryHeader)).To(Equal(event.ID.String()))
+	g.Expect(received.Header.Get(webhookSignatureHeader)).
+		To(Equal("sha256=01e1f9729ca986456bd34d8e45bf337273030ff40965ab8f11f06430e1f7b668"))
+
+	status = http.StatusInternalServerError
+	g.Expect(send(context.Background(), webhook, event, body)).NotTo(Succeed())
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	g := NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer server.Close()
+	defaultTransport := webhookClient.Transport
+	webhookClient.Transport = server.Client().Transport
+	defer func() { webhookClient.Transport = defaultTransport }()
+
+	webhook := types.RegistryWebhook{URL: server.URL, Secret: "secret"}
+	g.Expect(send(context.Background(), webhook, types.ArtifactEvent{}, []byte("{}"))).
+		To(MatchError(ContainSubstring("unexpected status code 302")))
+}
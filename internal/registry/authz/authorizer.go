@@ -8,7 +8,9 @@ import (
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/registry/name"
+	"github.com/distr-sh/distr/internal/signature"
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
 	"github.com/opencontainers/go-digest"
 )
 
@@ -84,11 +86,45 @@ func (a *authorizer) AuthorizeReference(ctx context.Context, nameStr string, ref
 				return err
 			}
 		}
+		if org.HasFeature(types.FeatureArtifactSignatureRequired) {
+			if err := checkSignature(ctx, name.OrgName, name.ArtifactName, reference, *auth.CurrentOrgID()); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
+// checkSignature makes sure that the referenced version has a signature that can be verified with one of the
+// signing keys of the organization. Signature manifests themselves are always accessible, so that clients can verify
+// signatures.
+func checkSignature(ctx context.Context, orgName, artifactName, reference string, orgID uuid.UUID) error {
+	if isSignature, err := db.IsArtifactSignatureManifest(ctx, orgName, artifactName, reference); err != nil {
+		return err
+	} else if isSignature {
+		return nil
+	}
+
+	sigs, err := db.GetArtifactVersionSignaturesForReference(ctx, orgName, artifactName, reference)
+	if err != nil {
+		return err
+	} else if len(sigs) == 0 {
+		return ErrAccessDenied
+	}
+	keys, err := db.GetArtifactSigningKeys(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	trusted := signature.TrustedKeys(keys)
+	for _, sig := range sigs {
+		if signature.Verify(sig, sig.SubjectDigest, trusted) == nil {
+			return nil
+		}
+	}
+	return ErrAccessDenied
+}
+
 // AuthorizeBlob implements ArtifactsAuthorizer.
 func (a *authorizer) AuthorizeBlob(ctx context.Context, digest digest.Digest, action Action) error {
 	auth := auth.ArtifactsAuthentication.Require(ctx)
@@ -16,6 +16,7 @@ package registry
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +28,7 @@ import (
 	"github.com/distr-sh/distr/internal/registry/authz"
 	"github.com/distr-sh/distr/internal/registry/blob"
 	registryerror "github.com/distr-sh/distr/internal/registry/error"
+	"github.com/distr-sh/distr/internal/registry/mirror"
 	"github.com/distr-sh/distr/internal/registry/verify"
 	"github.com/google/uuid"
 	"github.com/opencontainers/go-digest"
@@ -58,6 +60,7 @@ func isBlob(req *http.Request) bool {
 type blobs struct {
 	blobHandler blob.BlobHandler
 	authz       authz.Authorizer
+	proxy       *mirror.Proxy
 	log         *zap.SugaredLogger
 }
 
@@ -163,6 +166,13 @@ func (b *blobs) handleHead(resp http.ResponseWriter, req *http.Request, repo, ta
 	var size int64
 	if bsh, ok := b.blobHandler.(blob.BlobStatHandler); ok {
 		size, err = bsh.Stat(req.Context(), repo, h)
+		if errors.Is(err, blob.ErrNotFound) {
+			if ok, rerr := b.fetchFromMirror(req.Context(), repo, h); rerr != nil {
+				return rerr
+			} else if ok {
+				size, err = bsh.Stat(req.Context(), repo, h)
+			}
+		}
 		if errors.Is(err, blob.ErrNotFound) {
 			return regErrBlobUnknown
 		} else if err != nil {
@@ -219,6 +229,13 @@ func (b *blobs) handleGet(resp http.ResponseWriter, req *http.Request, repo, tar
 	var r io.Reader
 	if bsh, ok := b.blobHandler.(blob.BlobStatHandler); ok {
 		size, err = bsh.Stat(req.Context(), repo, h)
+		if errors.Is(err, blob.ErrNotFound) {
+			if ok, rerr := b.fetchFromMirror(req.Context(), repo, h); rerr != nil {
+				return rerr
+			} else if ok {
+				size, err = bsh.Stat(req.Context(), repo, h)
+			}
+		}
 		if errors.Is(err, blob.ErrNotFound) {
 			return regErrBlobUnknown
 		} else if err != nil {
@@ -315,6 +332,21 @@ func (b *blobs) handleGet(resp http.ResponseWriter, req *http.Request, repo, tar
 	return nil
 }
 
+// fetchFromMirror fetches a blob that does not exist from the upstream registry if the repository is mirrored and
+// reports whether it was fetched.
+func (b *blobs) fetchFromMirror(ctx context.Context, repo string, h digest.Digest) (bool, *regError) {
+	if b.proxy == nil {
+		return false, nil
+	}
+	if err := b.proxy.FetchBlob(ctx, repo, h); errors.Is(err, mirror.ErrNotMirrored) ||
+		errors.Is(err, mirror.ErrUpstreamNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, regErrInternal(fmt.Errorf("failed to fetch blob from upstream: %w", err))
+	}
+	return true, nil
+}
+
 func (b *blobs) handlePost(resp http.ResponseWriter, req *http.Request, repo, target, digestArg string) *regError {
 	bph, ok := b.blobHandler.(blob.BlobPutHandler)
 	if !ok {
@@ -474,7 +506,11 @@ func (b *blobs) handlePut(
 	}
 
 	err = bph.CompleteSession(req.Context(), repo, target, h)
-	if err != nil {
+	if errors.As(err, &verify.Error{}) {
+		log := internalctx.GetLogger(req.Context())
+		log.Warn("Digest mismatch detected", zap.Error(err))
+		return regErrDigestMismatch
+	} else if err != nil {
 		return regErrInternal(err)
 	}
 
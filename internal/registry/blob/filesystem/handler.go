@@ -0,0 +1,288 @@
+// Package filesystem implements a blob storage backend that stores blobs in a directory of the local filesystem.
+//
+// Blobs are stored at blobs/<algorithm>/<encoded> relative to the root directory. Upload sessions are appended to
+// files in the uploads directory and moved to their final location once they are completed.
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/distr-sh/distr/internal/registry/verify"
+	"github.com/google/uuid"
+	"github.com/opencontainers/go-digest"
+	"go.uber.org/zap"
+)
+
+const (
+	blobsDir   = "blobs"
+	uploadsDir = "uploads"
+)
+
+type blobHandler struct {
+	root string
+}
+
+var (
+	_ blob.BlobHandler       = &blobHandler{}
+	_ blob.BlobStatHandler   = &blobHandler{}
+	_ blob.BlobPutHandler    = &blobHandler{}
+	_ blob.BlobDeleteHandler = &blobHandler{}
+	_ blob.BlobListHandler   = &blobHandler{}
+)
+
+// NewBlobHandler returns a blob handler that stores blobs in the given directory. Missing directories are created
+// when the first blob is written.
+func NewBlobHandler(root string) blob.BlobHandler {
+	return &blobHandler{root: root}
+}
+
+// Get implements blob.BlobHandler.
+func (handler *blobHandler) Get(
+	ctx context.Context,
+	repo string,
+	h digest.Digest,
+	allowRedirect bool,
+) (io.ReadCloser, error) {
+	if p, err := handler.blobPath(h); err != nil {
+		return nil, err
+	} else if file, err := os.Open(p); err != nil {
+		return nil, convertErrNotFound(err)
+	} else {
+		return file, nil
+	}
+}
+
+// Stat implements blob.BlobStatHandler.
+func (handler *blobHandler) Stat(ctx context.Context, repo string, h digest.Digest) (int64, error) {
+	if p, err := handler.blobPath(h); err != nil {
+		return 0, err
+	} else if info, err := os.Stat(p); err != nil {
+		return 0, convertErrNotFound(err)
+	} else {
+		return info.Size(), nil
+	}
+}
+
+// Put implements blob.BlobPutHandler.
+func (handler *blobHandler) Put(
+	ctx context.Context,
+	repo string,
+	h digest.Digest,
+	contentType string,
+	r io.Reader,
+) error {
+	if rc, ok := r.(io.Closer); ok {
+		defer rc.Close()
+	}
+
+	p, err := handler.blobPath(h)
+	if err != nil {
+		return err
+	}
+
+	// The blob is written to a temporary file first so that readers never observe a partially written blob and
+	// so that a failed verification of the contents does not leave anything behind.
+	tmpFile, err := handler.createTemp()
+	if err != nil {
+		return err
+	}
+	defer handler.removeTemp(ctx, tmpFile.Name())
+	if _, err := io.Copy(tmpFile, r); err != nil {
+		_ = tmpFile.Close()
+		return err
+	} else if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return handler.move(tmpFile.Name(), p)
+}
+
+func (handler *blobHandler) StartSession(ctx context.Context, repo string) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(handler.root, uploadsDir), 0o750); err != nil {
+		return "", err
+	}
+	if file, err := os.OpenFile(handler.uploadPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640); err != nil {
+		return "", err
+	} else if err := file.Close(); err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+func (handler *blobHandler) PutChunk(ctx context.Context, id string, r io.Reader, start int64) (int64, error) {
+	if rc, ok := r.(io.Closer); ok {
+		defer rc.Close()
+	}
+
+	file, err := handler.openUpload(id, os.O_WRONLY|os.O_APPEND)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err != nil {
+		return 0, err
+	} else if info.Size() != start {
+		return 0, blob.NewErrBadUpload("range is not as expected")
+	}
+
+	if n, err := io.Copy(file, r); err != nil {
+		// Truncate the upload to its previous size so that the client can retry the chunk.
+		return 0, errors.Join(err, file.Truncate(start))
+	} else if err := file.Close(); err != nil {
+		return 0, err
+	} else {
+		return start + n, nil
+	}
+}
+
+func (handler *blobHandler) GetUploadedPartsSize(ctx context.Context, id string) (int64, error) {
+	file, err := handler.openUpload(id, os.O_RDONLY)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil {
+		return 0, err
+	} else {
+		return info.Size(), nil
+	}
+}
+
+func (handler *blobHandler) CompleteSession(ctx context.Context, repo, id string, h digest.Digest) error {
+	p, err := handler.blobPath(h)
+	if err != nil {
+		return err
+	}
+
+	file, err := handler.openUpload(id, os.O_RDONLY)
+	if err != nil {
+		return err
+	}
+	vrc, err := verify.ReadCloser(file, verify.SizeUnknown, h)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	_, err = io.Copy(io.Discard, vrc)
+	if err := errors.Join(err, vrc.Close()); err != nil {
+		return err
+	}
+
+	return handler.move(file.Name(), p)
+}
+
+// Delete implements blob.BlobDeleteHandler.
+func (handler *blobHandler) Delete(ctx context.Context, repo string, h digest.Digest) error {
+	if p, err := handler.blobPath(h); err != nil {
+		return err
+	} else if err := os.Remove(p); err != nil {
+		return convertErrNotFound(err)
+	} else {
+		return nil
+	}
+}
+
+// List implements blob.BlobListHandler.
+func (handler *blobHandler) List(ctx context.Context, fn func(blob.BlobInfo) error) error {
+	dir := filepath.Join(handler.root, blobsDir)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if err := ctx.Err(); err != nil {
+			return err
+		} else if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		// files that are not named after a digest are not blobs
+		h, err := digest.Parse(filepath.Dir(rel) + ":" + filepath.Base(rel))
+		if err != nil {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// the blob was deleted concurrently
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return fn(blob.BlobInfo{Digest: h, Size: info.Size(), LastModified: info.ModTime()})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// nothing has been stored yet
+		return nil
+	}
+	return err
+}
+
+func (handler *blobHandler) blobPath(h digest.Digest) (string, error) {
+	// Validate makes sure that neither the algorithm nor the encoded part can be used to escape the root directory.
+	if err := h.Validate(); err != nil {
+		return "", err
+	}
+	return filepath.Join(handler.root, blobsDir, h.Algorithm().String(), h.Encoded()), nil
+}
+
+func (handler *blobHandler) uploadPath(id uuid.UUID) string {
+	return filepath.Join(handler.root, uploadsDir, id.String())
+}
+
+func (handler *blobHandler) openUpload(id string, flag int) (*os.File, error) {
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, blob.NewErrBadUpload("unknown upload session")
+	}
+	file, err := os.OpenFile(handler.uploadPath(parsedID), flag, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, blob.NewErrBadUpload("unknown upload session")
+	}
+	return file, err
+}
+
+func (handler *blobHandler) createTemp() (*os.File, error) {
+	dir := filepath.Join(handler.root, uploadsDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(dir, "blob-*.tmp")
+}
+
+func (handler *blobHandler) removeTemp(ctx context.Context, name string) {
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		internalctx.GetLogger(ctx).Warn("ephemeral resource cleanup error", zap.Error(err))
+	}
+}
+
+// move atomically moves the file at src to dst, replacing dst if it already exists.
+func (handler *blobHandler) move(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return err
+	} else if err := os.Rename(src, dst); err != nil {
+		return fmt.Errorf("failed to move blob: %w", err)
+	}
+	return nil
+}
+
+func convertErrNotFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return blob.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package filesystem
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/distr-sh/distr/internal/registry/verify"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+)
+
+func TestPutGetDelete(t *testing.T) {
+	g := NewWithT(t)
+	handler := NewBlobHandler(t.TempDir()).(*blobHandler)
+	h := digest.FromString("hello")
+
+	_, err := handler.Stat(t.Context(), "", h)
+	g.Expect(err).To(MatchError(blob.ErrNotFound))
+	g.Expect(handler.List(t.Context(), func(blob.BlobInfo) error { return nil })).To(Succeed())
+
+	g.Expect(handler.Put(t.Context(), "", h, "", strings.NewReader("hello"))).To(Succeed())
+	g.Expect(handler.Stat(t.Context(), "", h)).To(Equal(int64(5)))
+	rc, err := handler.Get(t.Context(), "", h, true)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(io.ReadAll(rc)).To(Equal([]byte("hello")))
+	g.Expect(rc.Close()).To(Succeed())
+
+	var listed []blob.BlobInfo
+	g.Expect(handler.List(t.Context(), func(info blob.BlobInfo) error {
+		listed = append(listed, info)
+		return nil
+	})).To(Succeed())
+	g.Expect(listed).To(HaveLen(1))
+	g.Expect(listed[0].Digest).To(Equal(h))
+	g.Expect(listed[0].Size).To(Equal(int64(5)))
+
+	g.Expect(handler.Delete(t.Context(), "", h)).To(Succeed())
+	g.Expect(handler.Delete(t.Context(), "", h)).To(MatchError(blob.ErrNotFound))
+
+	_, err = handler.Get(t.Context(), "", digest.Digest("sha256:../../etc/passwd"), false)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSession(t *testing.T) {
+	g := NewWithT(t)
+	handler := NewBlobHandler(t.TempDir()).(*blobHandler)
+	h := digest.FromString("hello world")
+
+	id, err := handler.StartSession(t.Context(), "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(handler.GetUploadedPartsSize(t.Context(), id)).To(Equal(int64(0)))
+
+	g.Expect(handler.PutChunk(t.Context(), id, strings.NewReader("hello"), 0)).To(Equal(int64(5)))
+	_, err = handler.PutChunk(t.Context(), id, strings.NewReader(" world"), 0)
+	g.Expect(err).To(MatchError(blob.ErrBadUpload))
+	g.Expect(handler.PutChunk(t.Context(), id, strings.NewReader(" world"), 5)).To(Equal(int64(11)))
+	g.Expect(handler.GetUploadedPartsSize(t.Context(), id)).To(Equal(int64(11)))
+
+	err = handler.CompleteSession(t.Context(), "", id, digest.FromString("other"))
+	g.Expect(errors.As(err, &verify.Error{})).To(BeTrue())
+	g.Expect(handler.CompleteSession(t.Context(), "", id, h)).To(Succeed())
+	g.Expect(handler.Stat(t.Context(), "", h)).To(Equal(int64(11)))
+
+	_, err = handler.GetUploadedPartsSize(t.Context(), id)
+	g.Expect(err).To(MatchError(blob.ErrBadUpload))
+	_, err = handler.PutChunk(t.Context(), "not-a-session", strings.NewReader(""), 0)
+	g.Expect(err).To(MatchError(blob.ErrBadUpload))
+}
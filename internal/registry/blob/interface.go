@@ -3,6 +3,7 @@ package blob
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/opencontainers/go-digest"
 )
@@ -44,3 +45,18 @@ type BlobDeleteHandler interface {
 	// Delete the blob contents.
 	Delete(ctx context.Context, repo string, h digest.Digest) error
 }
+
+// BlobInfo contains metadata about a stored blob.
+type BlobInfo struct {
+	Digest       digest.Digest
+	Size         int64
+	LastModified time.Time
+}
+
+// BlobListHandler is an extension interface representing a blob storage
+// backend that can enumerate all stored blobs.
+type BlobListHandler interface {
+	// List calls fn for every stored blob. Objects that are not blobs, such as
+	// chunks of uploads that are in progress, are omitted.
+	List(ctx context.Context, fn func(BlobInfo) error) error
+}
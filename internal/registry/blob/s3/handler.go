@@ -40,6 +40,7 @@ var (
 	_ blob.BlobStatHandler   = &blobHandler{}
 	_ blob.BlobPutHandler    = &blobHandler{}
 	_ blob.BlobDeleteHandler = &blobHandler{}
+	_ blob.BlobListHandler   = &blobHandler{}
 )
 
 func NewBlobHandler(ctx context.Context) blob.BlobHandler {
@@ -329,6 +330,32 @@ func (handler *blobHandler) Delete(ctx context.Context, repo string, h digest.Di
 	return nil
 }
 
+// List implements blob.BlobListHandler.
+func (handler *blobHandler) List(ctx context.Context, fn func(blob.BlobInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(handler.s3Client, &s3.ListObjectsV2Input{Bucket: &handler.bucket})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			// objects that are not named after a digest, e.g. upload chunks, are not blobs
+			h, err := digest.Parse(aws.ToString(obj.Key))
+			if err != nil {
+				continue
+			}
+			if err := fn(blob.BlobInfo{
+				Digest:       h,
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (handler *blobHandler) getUploadID(ctx context.Context, uploadKey string) (string, error) {
 	if uploads, err := handler.s3Client.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
 		Bucket: &handler.bucket,
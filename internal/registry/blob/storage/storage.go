@@ -0,0 +1,20 @@
+// Package storage creates the blob storage backend that is configured for the registry.
+package storage
+
+import (
+	"context"
+
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/distr-sh/distr/internal/registry/blob/filesystem"
+	"github.com/distr-sh/distr/internal/registry/blob/s3"
+)
+
+func NewBlobHandler(ctx context.Context) blob.BlobHandler {
+	switch env.RegistryStorageType() {
+	case env.RegistryStorageFilesystem:
+		return filesystem.NewBlobHandler(env.RegistryFilesystemPath())
+	default:
+		return s3.NewBlobHandler(ctx)
+	}
+}
@@ -119,6 +119,14 @@ var regErrDenied = &regError{
 	Message: "Access to the resource has been denied",
 }
 
+func regErrDeniedWithMessage(err error) *regError {
+	return &regError{
+		Status:  http.StatusForbidden,
+		Code:    "DENIED",
+		Message: err.Error(),
+	}
+}
+
 var regErrDeniedQuotaExceeded = &regError{
 	Status:  http.StatusForbidden,
 	Code:    "DENIED",
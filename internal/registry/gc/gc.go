@@ -0,0 +1,178 @@
+// Package gc implements a mark-and-sweep garbage collection for the artifact registry.
+//
+// In the mark phase, all untagged manifests that are not referenced by any other manifest are collected first.
+// Afterwards, the digests of all blobs that are still referenced by the remaining artifact versions are collected.
+// In the sweep phase, all stored blobs that are not referenced are deleted, unless they are younger than the grace
+// period, because their upload might still be in progress.
+package gc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/registry/audit"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type Options struct {
+	// GracePeriod is the minimum age of blobs and manifests that are deleted.
+	GracePeriod time.Duration
+	// DryRun only reports which blobs and manifests would be deleted.
+	DryRun bool
+}
+
+type Result struct {
+	DeletedManifests int64
+	DeletedBlobs     int64
+	DeletedBytes     int64
+}
+
+// Run runs the garbage collection. The given blob handler must support listing and deleting blobs.
+func Run(ctx context.Context, blobHandler blob.BlobHandler, opts Options) (*Result, error) {
+	listHandler, ok := blobHandler.(blob.BlobListHandler)
+	if !ok {
+		return nil, errors.New("blob handler does not support listing blobs")
+	}
+	deleteHandler, ok := blobHandler.(blob.BlobDeleteHandler)
+	if !ok {
+		return nil, errors.New("blob handler does not support deleting blobs")
+	}
+
+	var result Result
+	deletedVersionIDs, err := collectManifests(ctx, opts, &result)
+	if err != nil {
+		return &result, err
+	}
+
+	referenced, err := db.GetReferencedArtifactBlobDigests(ctx, deletedVersionIDs)
+	if err != nil {
+		return &result, err
+	}
+	referencedSet := make(map[types.Digest]struct{}, len(referenced))
+	for _, d := range referenced {
+		referencedSet[d] = struct{}{}
+	}
+
+	err = sweepBlobs(ctx, listHandler, deleteHandler, referencedSet, opts, &result)
+	return &result, err
+}
+
+// collectManifests deletes all untagged manifests that are not referenced by another manifest and returns the IDs of
+// the deleted artifact versions. Every deletion is recorded as artifact event.
+// Manifests that only become unreferenced because of this run are collected by the next run.
+func collectManifests(ctx context.Context, opts Options, result *Result) ([]uuid.UUID, error) {
+	log := internalctx.GetLogger(ctx)
+	versions, err := db.GetUntaggedArtifactVersions(ctx, nil, opts.GracePeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err = WithoutLiveReferrers(ctx, versions)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	for _, version := range versions {
+		log.Info("unreferenced manifest",
+			zap.Stringer("artifactId", version.ArtifactID),
+			zap.String("digest", version.Name),
+			zap.Bool("dryRun", opts.DryRun))
+		ids = append(ids, version.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	} else if opts.DryRun {
+		result.DeletedManifests = int64(len(ids))
+		return ids, nil
+	}
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		if err := audit.RecordDeletions(ctx, ids, nil, nil); err != nil {
+			return err
+		} else if count, err := db.DeleteArtifactVersionsWithIDs(ctx, ids); err != nil {
+			return err
+		} else {
+			result.DeletedManifests = count
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// WithoutLiveReferrers removes all referrers, such as signatures, whose subject still exists from the given versions.
+// Referrers are usually not tagged, but they must be kept as long as their subject exists.
+func WithoutLiveReferrers(ctx context.Context, versions []types.ArtifactVersion) ([]types.ArtifactVersion, error) {
+	var result []types.ArtifactVersion
+	for _, version := range versions {
+		if subject := getSubject(version.ManifestData); subject != "" {
+			if exists, err := db.ArtifactVersionWithDigestExists(ctx, version.ArtifactID, subject); err != nil {
+				return nil, err
+			} else if exists {
+				continue
+			}
+		}
+		result = append(result, version)
+	}
+	return result, nil
+}
+
+func sweepBlobs(
+	ctx context.Context,
+	listHandler blob.BlobListHandler,
+	deleteHandler blob.BlobDeleteHandler,
+	referenced map[types.Digest]struct{},
+	opts Options,
+	result *Result,
+) error {
+	log := internalctx.GetLogger(ctx)
+	deleteBefore := time.Now().Add(-opts.GracePeriod)
+	return listHandler.List(ctx, func(info blob.BlobInfo) error {
+		if _, ok := referenced[types.Digest(info.Digest)]; ok || info.LastModified.After(deleteBefore) {
+			return nil
+		}
+
+		log.Info("unreferenced blob",
+			zap.String("digest", info.Digest.String()),
+			zap.Int64("size", info.Size),
+			zap.Time("lastModified", info.LastModified),
+			zap.Bool("dryRun", opts.DryRun))
+		if !opts.DryRun {
+			// the blob might have been referenced by a manifest that was pushed after the mark phase
+			if isReferenced, err := db.IsArtifactBlobReferenced(ctx, types.Digest(info.Digest)); err != nil {
+				return err
+			} else if isReferenced {
+				return nil
+			} else if err := deleteHandler.Delete(ctx, "", info.Digest); err != nil &&
+				!errors.Is(err, blob.ErrNotFound) {
+				return fmt.Errorf("could not delete blob %v: %w", info.Digest, err)
+			}
+		}
+		result.DeletedBlobs++
+		result.DeletedBytes += info.Size
+		return nil
+	})
+}
+
+func getSubject(manifestData []byte) types.Digest {
+	var manifest struct {
+		Subject *struct {
+			Digest types.Digest `json:"digest"`
+		} `json:"subject"`
+	}
+	if err := json.Unmarshal(manifestData, &manifest); err != nil || manifest.Subject == nil {
+		return ""
+	}
+	return manifest.Subject.Digest
+}
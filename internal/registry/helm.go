@@ -0,0 +1,371 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/containers/image/v5/manifest"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/contenttype"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/registry/audit"
+	"github.com/distr-sh/distr/internal/registry/authz"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	registryerror "github.com/distr-sh/distr/internal/registry/error"
+	imanifest "github.com/distr-sh/distr/internal/registry/manifest"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"helm.sh/helm/v3/pkg/chart"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	helmChartConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+	helmChartLayerMediaType  = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	helmChartConfigMaxSize   = 1 << 20
+	// helmIndexCacheTTL limits how long changes that are not recorded as artifact events, such as license changes, take
+	// to show up in a cached index.
+	helmIndexCacheTTL = time.Minute
+)
+
+// helmRepository serves the Helm charts that are stored in the registry as a classic Helm chart repository for
+// clients that do not support OCI registries.
+//
+// The index of an organization is served at /helm/<org>/index.yaml and contains the charts that the current user is
+// allowed to pull. Chart archives are served at /helm/<org>/charts/<artifact>/<digest>/<chart>-<version>.tgz.
+//
+// Building the index requires an authorization check and a blob read for every chart version, so the index is cached
+// per organization and customer organization until the next artifact event of the organization.
+type helmRepository struct {
+	blobHandler     blob.BlobHandler
+	manifestHandler imanifest.ManifestHandler
+	authz           authz.Authorizer
+	audit           audit.ArtifactAuditor
+	log             *zap.SugaredLogger
+	indexCache      helmIndexCache
+}
+
+type helmIndexCacheKey struct {
+	orgID         uuid.UUID
+	customerOrgID uuid.UUID
+}
+
+type helmIndexCacheEntry struct {
+	data            []byte
+	latestEventTime *time.Time
+	expiresAt       time.Time
+}
+
+type helmIndexCache struct {
+	mut     sync.Mutex
+	entries map[helmIndexCacheKey]helmIndexCacheEntry
+}
+
+// get returns the cached index if it has not expired and no artifact event happened after it was built.
+func (c *helmIndexCache) get(key helmIndexCacheKey, latestEventTime *time.Time) []byte {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if entry, ok := c.entries[key]; !ok || time.Now().After(entry.expiresAt) ||
+		!util.PtrEq(entry.latestEventTime, latestEventTime) {
+		return nil
+	} else {
+		return entry.data
+	}
+}
+
+func (c *helmIndexCache) put(key helmIndexCacheKey, latestEventTime *time.Time, data []byte) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	now := time.Now()
+	if c.entries == nil {
+		c.entries = make(map[helmIndexCacheKey]helmIndexCacheEntry)
+	}
+	for k, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = helmIndexCacheEntry{
+		data:            data,
+		latestEventTime: latestEventTime,
+		expiresAt:       now.Add(helmIndexCacheTTL),
+	}
+}
+
+// helmIndexFile is the index.yaml of a Helm chart repository.
+type helmIndexFile struct {
+	APIVersion string                         `json:"apiVersion"`
+	Generated  time.Time                      `json:"generated"`
+	Entries    map[string][]*helmChartVersion `json:"entries"`
+}
+
+type helmChartVersion struct {
+	*chart.Metadata
+	URLs    []string  `json:"urls"`
+	Created time.Time `json:"created,omitempty"`
+	Digest  string    `json:"digest,omitempty"`
+}
+
+func isHelm(req *http.Request) bool {
+	elems := strings.Split(req.URL.Path, "/")
+	return len(elems) >= 4 && elems[1] == "helm"
+}
+
+func (h *helmRepository) handle(resp http.ResponseWriter, req *http.Request) *regError {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return regErrMethodUnknown
+	}
+
+	elems := strings.Split(req.URL.Path, "/")[2:]
+	orgName := elems[0]
+	if len(elems) == 2 && elems[1] == "index.yaml" {
+		return h.handleIndex(resp, req, orgName)
+	} else if len(elems) >= 5 && elems[1] == "charts" && strings.HasSuffix(elems[len(elems)-1], ".tgz") {
+		repo := orgName + "/" + strings.Join(elems[2:len(elems)-2], "/")
+		return h.handleDownload(resp, req, repo, elems[len(elems)-2])
+	}
+	return &regError{
+		Status:  http.StatusNotFound,
+		Code:    "METHOD_UNKNOWN",
+		Message: "We don't understand your method + url",
+	}
+}
+
+func (h *helmRepository) handleIndex(resp http.ResponseWriter, req *http.Request, orgName string) *regError {
+	ctx := req.Context()
+	auth := auth.ArtifactsAuthentication.Require(ctx)
+	if org := auth.CurrentOrg(); org.Slug == nil || *org.Slug != orgName {
+		return regErrDenied
+	}
+
+	cacheKey := helmIndexCacheKey{orgID: *auth.CurrentOrgID()}
+	if customerOrgID := auth.CurrentCustomerOrgID(); customerOrgID != nil {
+		cacheKey.customerOrgID = *customerOrgID
+	}
+	latestEventTime, err := db.GetLatestArtifactEventTime(ctx, *auth.CurrentOrgID())
+	if err != nil {
+		return regErrInternal(err)
+	}
+	data := h.indexCache.get(cacheKey, latestEventTime)
+	if data == nil {
+		if data, err = h.buildIndex(ctx, orgName); err != nil {
+			return regErrInternal(err)
+		}
+		h.indexCache.put(cacheKey, latestEventTime, data)
+	}
+
+	resp.Header().Set("Content-Type", contenttype.MediaTypeYAML)
+	resp.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	resp.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		if _, err := resp.Write(data); err != nil {
+			return regErrInternal(err)
+		}
+	}
+	return nil
+}
+
+// buildIndex returns the index.yaml with all charts of the organization that the current user is allowed to pull.
+func (h *helmRepository) buildIndex(ctx context.Context, orgName string) ([]byte, error) {
+	auth := auth.ArtifactsAuthentication.Require(ctx)
+	var licenseCustomerOrgID *uuid.UUID
+	if auth.CurrentOrg().HasFeature(types.FeatureLicensing) && auth.CurrentCustomerOrgID() != nil {
+		licenseCustomerOrgID = auth.CurrentCustomerOrgID()
+	}
+	var artifacts []types.ArtifactWithDownloads
+	var err error
+	if licenseCustomerOrgID != nil {
+		artifacts, err = db.GetArtifactsByLicenseOwnerID(ctx, *auth.CurrentOrgID(), *licenseCustomerOrgID)
+	} else {
+		artifacts, err = db.GetArtifactsByOrgID(ctx, *auth.CurrentOrgID())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	index := helmIndexFile{APIVersion: "v1", Generated: time.Now(), Entries: map[string][]*helmChartVersion{}}
+	for _, artifact := range artifacts {
+		repo := orgName + "/" + artifact.Name
+		versions, err := db.GetVersionsForArtifact(ctx, artifact.ID, licenseCustomerOrgID)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			if version.InferredType != types.ManifestTypeHelmChart {
+				continue
+			}
+			// The authorizer also enforces requirements other than licenses, such as signatures.
+			if err := h.authz.AuthorizeReference(ctx, repo, version.Digest, authz.ActionRead); errors.Is(
+				err, authz.ErrAccessDenied,
+			) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			cv, err := h.getChartVersion(ctx, repo, artifact.Name, version)
+			if err != nil {
+				// a single invalid chart should not make the whole repository unusable
+				h.log.Warnw("skipping invalid helm chart", "repo", repo, "digest", version.Digest, "error", err)
+				continue
+			}
+			index.Entries[cv.Name] = append(index.Entries[cv.Name], cv)
+		}
+	}
+	for _, entries := range index.Entries {
+		sortHelmChartVersions(entries)
+	}
+
+	return yaml.Marshal(index)
+}
+
+func (h *helmRepository) handleDownload(
+	resp http.ResponseWriter,
+	req *http.Request,
+	repo, reference string,
+) *regError {
+	ctx := req.Context()
+	if err := h.authz.AuthorizeReference(ctx, repo, reference, authz.ActionRead); err != nil {
+		if errors.Is(err, authz.ErrAccessDenied) {
+			return regErrDenied
+		} else if errors.Is(err, registryerror.ErrInvalidArtifactName) {
+			return regErrNameInvalid
+		}
+		return regErrInternal(err)
+	}
+
+	m, err := h.manifestHandler.Get(ctx, repo, reference)
+	if errors.Is(err, imanifest.ErrNameUnknown) || errors.Is(err, imanifest.ErrManifestUnknown) {
+		return regErrManifestUnknown
+	} else if err != nil {
+		return regErrInternal(err)
+	}
+	_, layer, err := parseHelmChartManifest(m.Data, m.ContentType)
+	if err != nil {
+		return regErrManifestUnknown
+	}
+
+	if req.Method == http.MethodHead {
+		resp.Header().Set("Content-Type", contenttype.MediaTypeGzip)
+		resp.Header().Set("Content-Length", fmt.Sprint(layer.Size))
+		resp.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	if err := h.audit.AuditPull(ctx, repo, reference); err != nil {
+		internalctx.GetLogger(ctx).Warn("failed to audit-log pull", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+	}
+
+	rc, err := h.blobHandler.Get(ctx, repo, layer.Digest, true)
+	if errors.Is(err, blob.ErrNotFound) {
+		return regErrBlobUnknown
+	} else if err != nil {
+		var rerr blob.RedirectError
+		if errors.As(err, &rerr) {
+			http.Redirect(resp, req, rerr.Location, rerr.Code)
+			return nil
+		}
+		return regErrInternal(err)
+	}
+	defer rc.Close()
+
+	resp.Header().Set("Content-Type", contenttype.MediaTypeGzip)
+	resp.Header().Set("Content-Length", fmt.Sprint(layer.Size))
+	resp.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(resp, rc); err != nil {
+		return regErrInternal(err)
+	}
+	return nil
+}
+
+// getChartVersion returns the index entry of the chart version. The chart metadata is read from the config blob of
+// the chart manifest.
+func (h *helmRepository) getChartVersion(
+	ctx context.Context,
+	repo, artifactName string,
+	version types.TaggedArtifactVersion,
+) (*helmChartVersion, error) {
+	config, layer, err := parseHelmChartManifest(version.ManifestData, version.ManifestContentType)
+	if err != nil {
+		return nil, err
+	} else if config.Size > helmChartConfigMaxSize {
+		return nil, fmt.Errorf("chart config is too large")
+	}
+
+	rc, err := h.blobHandler.Get(ctx, repo, config.Digest, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chart config: %w", err)
+	}
+	defer rc.Close()
+	var metadata chart.Metadata
+	if err := json.NewDecoder(io.LimitReader(rc, helmChartConfigMaxSize)).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode chart config: %w", err)
+	} else if err := metadata.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &helmChartVersion{
+		Metadata: &metadata,
+		URLs: []string{
+			fmt.Sprintf("charts/%v/%v/%v-%v.tgz", artifactName, version.Digest, metadata.Name, metadata.Version),
+		},
+		Created: version.CreatedAt,
+		Digest:  layer.Digest.Encoded(),
+	}, nil
+}
+
+// parseHelmChartManifest returns the config and chart layer of a Helm chart manifest.
+func parseHelmChartManifest(data []byte, contentType string) (manifest.LayerInfo, manifest.LayerInfo, error) {
+	var config, layer manifest.LayerInfo
+	if manifest.MIMETypeIsMultiImage(contentType) {
+		return config, layer, errors.New("not a helm chart")
+	}
+	m, err := manifest.FromBlob(data, contentType)
+	if err != nil {
+		return config, layer, err
+	}
+	config.BlobInfo = m.ConfigInfo()
+	if config.MediaType != helmChartConfigMediaType {
+		return config, layer, errors.New("not a helm chart")
+	}
+	idx := slices.IndexFunc(m.LayerInfos(), func(l manifest.LayerInfo) bool {
+		return l.MediaType == helmChartLayerMediaType
+	})
+	if idx < 0 {
+		return config, layer, errors.New("helm chart has no chart layer")
+	}
+	return config, m.LayerInfos()[idx], nil
+}
+
+// sortHelmChartVersions sorts chart versions by descending semantic version like the Helm client expects.
+// Versions that are not valid semantic versions are sorted last.
+func sortHelmChartVersions(versions []*helmChartVersion) {
+	slices.SortStableFunc(versions, func(a, b *helmChartVersion) int {
+		va, errA := semver.NewVersion(a.Version)
+		vb, errB := semver.NewVersion(b.Version)
+		switch {
+		case errA != nil && errB != nil:
+			return 0
+		case errA != nil:
+			return 1
+		case errB != nil:
+			return -1
+		default:
+			return vb.Compare(va)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart"
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseHelmChartManifest(t *testing.T) {
+	g := NewWithT(t)
+	config, layer := digest.FromString("config"), digest.FromString("chart")
+
+	data := fmt.Appendf(nil,
+		`{"schemaVersion":2,"config":{"mediaType":%q,"digest":%q,"size":6},"layers":[{"mediaType":%q,"digest":%q,"size":5}]}`,
+		helmChartConfigMediaType, config, helmChartLayerMediaType, layer)
+	configInfo, layerInfo, err := parseHelmChartManifest(data, imgspecv1.MediaTypeImageManifest)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(configInfo.Digest).To(Equal(config))
+	g.Expect(layerInfo.Digest).To(Equal(layer))
+	g.Expect(layerInfo.Size).To(Equal(int64(5)))
+
+	data = fmt.Appendf(nil,
+		`{"schemaVersion":2,"config":{"mediaType":%q,"digest":%q,"size":6},"layers":[]}`,
+		imgspecv1.MediaTypeImageConfig, config)
+	_, _, err = parseHelmChartManifest(data, imgspecv1.MediaTypeImageManifest)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestHelmIndexFile(t *testing.T) {
+	g := NewWithT(t)
+	versions := []*helmChartVersion{
+		{Metadata: &chart.Metadata{Name: "app", Version: "1.2.0"}},
+		{Metadata: &chart.Metadata{Name: "app", Version: "not-semver"}},
+		{Metadata: &chart.Metadata{Name: "app", Version: "1.10.0"}, URLs: []string{"charts/app/sha256:1/app-1.10.0.tgz"}},
+	}
+	sortHelmChartVersions(versions)
+	g.Expect(versions[0].Version).To(Equal("1.10.0"))
+	g.Expect(versions[1].Version).To(Equal("1.2.0"))
+	g.Expect(versions[2].Version).To(Equal("not-semver"))
+
+	data, err := yaml.Marshal(helmIndexFile{APIVersion: "v1", Entries: map[string][]*helmChartVersion{"app": versions}})
+	g.Expect(err).NotTo(HaveOccurred())
+	var parsed map[string]any
+	g.Expect(yaml.Unmarshal(data, &parsed)).To(Succeed())
+	g.Expect(parsed).To(HaveKeyWithValue("entries", HaveKeyWithValue("app", ContainElement(And(
+		HaveKeyWithValue("name", "app"),
+		HaveKeyWithValue("version", "1.10.0"),
+		HaveKeyWithValue("urls", ConsistOf("charts/app/sha256:1/app-1.10.0.tgz")),
+	)))))
+}
+
+func TestHelmIndexCache(t *testing.T) {
+	g := NewWithT(t)
+	var cache helmIndexCache
+	key := helmIndexCacheKey{orgID: uuid.New()}
+	eventTime := time.Now()
+
+	g.Expect(cache.get(key, nil)).To(BeNil())
+	cache.put(key, nil, []byte("index"))
+	g.Expect(cache.get(key, nil)).To(Equal([]byte("index")))
+	g.Expect(cache.get(helmIndexCacheKey{orgID: key.orgID, customerOrgID: uuid.New()}, nil)).To(BeNil())
+	g.Expect(cache.get(key, &eventTime)).To(BeNil())
+
+	cache.put(key, &eventTime, []byte("updated index"))
+	g.Expect(cache.get(key, &eventTime)).To(Equal([]byte("updated index")))
+	g.Expect(cache.get(key, util.PtrTo(eventTime.Add(time.Second)))).To(BeNil())
+}
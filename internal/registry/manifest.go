@@ -27,6 +27,7 @@ import (
 
 	"github.com/containers/image/v5/manifest"
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/attachment"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/registry/audit"
@@ -34,6 +35,10 @@ import (
 	"github.com/distr-sh/distr/internal/registry/blob"
 	registryerror "github.com/distr-sh/distr/internal/registry/error"
 	imanifest "github.com/distr-sh/distr/internal/registry/manifest"
+	"github.com/distr-sh/distr/internal/registry/mirror"
+	"github.com/distr-sh/distr/internal/registry/name"
+	"github.com/distr-sh/distr/internal/signature"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/getsentry/sentry-go"
 	"github.com/opencontainers/go-digest"
 	"github.com/opencontainers/image-spec/specs-go"
@@ -56,6 +61,7 @@ type manifests struct {
 	manifestHandler imanifest.ManifestHandler
 	authz           authz.Authorizer
 	audit           audit.ArtifactAuditor
+	proxy           *mirror.Proxy
 	log             *zap.SugaredLogger
 }
 
@@ -136,14 +142,16 @@ func (handler *manifests) handle(resp http.ResponseWriter, req *http.Request) *r
 			return regErrInternal(err)
 		}
 		return handler.handlePut(resp, req, repo, target)
-	// case http.MethodDelete:
-	// 	if err := handler.authz.AuthorizeReference(req.Context(), repo, target, authz.ActionWrite); err != nil {
-	// 		if errors.Is(err, authz.ErrAccessDenied) {
-	// 			return regErrDenied
-	// 		}
-	// 		return regErrInternal(err)
-	// 	}
-	// 	return handler.handleDelete(resp, req, repo, target)
+	case http.MethodDelete:
+		if err := handler.authz.AuthorizeReference(req.Context(), repo, target, authz.ActionWrite); err != nil {
+			if errors.Is(err, authz.ErrAccessDenied) {
+				return regErrDenied
+			} else if errors.Is(err, registryerror.ErrInvalidArtifactName) {
+				return regErrNameInvalid
+			}
+			return regErrInternal(err)
+		}
+		return handler.handleDelete(resp, req, repo, target)
 	default:
 		return regErrMethodUnknown
 	}
@@ -346,6 +354,15 @@ func (m *manifests) handleReferrers(resp http.ResponseWriter, req *http.Request)
 func (handler *manifests) handleGet(resp http.ResponseWriter, req *http.Request, repo, target string) *regError {
 	ctx := req.Context()
 	m, err := handler.manifestHandler.Get(ctx, repo, target)
+	if errors.Is(err, imanifest.ErrNameUnknown) || errors.Is(err, imanifest.ErrManifestUnknown) {
+		if fetched, rerr := handler.fetchFromMirror(ctx, repo, target); rerr != nil {
+			return rerr
+		} else if fetched != nil {
+			m, err = fetched, nil
+		}
+	} else if err == nil {
+		m = handler.refreshFromMirror(ctx, repo, target, m)
+	}
 	if errors.Is(err, imanifest.ErrNameUnknown) {
 		return regErrNameUnknown
 	} else if errors.Is(err, imanifest.ErrManifestUnknown) {
@@ -397,6 +414,15 @@ func (handler *manifests) handleGet(resp http.ResponseWriter, req *http.Request,
 func (handler *manifests) handleHead(resp http.ResponseWriter, req *http.Request, repo, target string) *regError {
 	ctx := req.Context()
 	m, err := handler.manifestHandler.Get(ctx, repo, target)
+	if errors.Is(err, imanifest.ErrNameUnknown) || errors.Is(err, imanifest.ErrManifestUnknown) {
+		if fetched, rerr := handler.fetchFromMirror(ctx, repo, target); rerr != nil {
+			return rerr
+		} else if fetched != nil {
+			m, err = fetched, nil
+		}
+	} else if err == nil {
+		m = handler.refreshFromMirror(ctx, repo, target, m)
+	}
 	if errors.Is(err, imanifest.ErrNameUnknown) {
 		return regErrNameUnknown
 	} else if errors.Is(err, imanifest.ErrManifestUnknown) {
@@ -435,9 +461,43 @@ func (handler *manifests) handlePut(resp http.ResponseWriter, req *http.Request,
 		},
 	}
 
+	ctx := req.Context()
+	// Only pushes of tags are audited. Manifests that are pushed by digest are usually the children of an index or
+	// referrers such as signatures, which would only add noise to the activity of the artifact.
+	_, err := digest.Parse(target)
+	isTag := err != nil
+	var before *digest.Digest
+	if isTag {
+		if m, err := handler.manifestHandler.Get(ctx, repo, target); err == nil {
+			before = &m.Digest
+		} else if !errors.Is(err, imanifest.ErrNameUnknown) && !errors.Is(err, imanifest.ErrManifestUnknown) {
+			return regErrInternal(err)
+		}
+	}
+
+	if err := handler.putManifest(ctx, repo, target, mf); err != nil {
+		return err
+	}
+
+	if isTag {
+		if err := handler.audit.AuditPush(ctx, repo, target, before, mf.Digest); err != nil {
+			internalctx.GetLogger(ctx).Warn("failed to audit-log push", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		}
+	}
+
+	resp.Header().Set("Docker-Content-Digest", mf.Digest.String())
+	resp.Header().Set("OCI-Subject", mf.Digest.String())
+	resp.Header().Set("Location", req.URL.JoinPath(mf.Blob.Digest.String()).Path)
+	resp.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// putManifest stores the manifest under its digest and the given target.
+func (handler *manifests) putManifest(ctx context.Context, repo, target string, mf imanifest.Manifest) *regError {
 	var blobs []imanifest.Blob
 	if manifest.MIMETypeIsMultiImage(mf.ContentType) {
-		im, err := manifest.ListFromBlob(buf.Bytes(), mf.ContentType)
+		im, err := manifest.ListFromBlob(mf.Data, mf.ContentType)
 		if err != nil {
 			return regErrManifestInvalid(err)
 		}
@@ -449,7 +509,7 @@ func (handler *manifests) handlePut(resp http.ResponseWriter, req *http.Request,
 			blobs = append(blobs, imanifest.Blob{Digest: i.Digest, Size: i.Size})
 		}
 	} else {
-		m, err := manifest.FromBlob(buf.Bytes(), mf.ContentType)
+		m, err := manifest.FromBlob(mf.Data, mf.ContentType)
 		if err != nil {
 			return regErrManifestInvalid(err)
 		}
@@ -460,16 +520,26 @@ func (handler *manifests) handlePut(resp http.ResponseWriter, req *http.Request,
 		}
 	}
 
-	if err := checkIncompatibleManifest(buf.Bytes()); err != nil {
+	if err := checkIncompatibleManifest(mf.Data); err != nil {
 		return err
 	}
 
+	// Attachments are read from their blobs before the transaction is started, so that large documents do not keep
+	// the transaction open.
+	attachments := handler.extractAttachments(ctx, repo, mf)
+
 	// Allow future references by target (tag) and immutable digest.
 	// See https://docs.docker.com/engine/reference/commandline/pull/#pull-an-image-by-digest-immutable-identifier.
-	err := db.RunTx(req.Context(), func(ctx context.Context) error {
-		return multierr.Combine(
+	err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := multierr.Combine(
 			handler.manifestHandler.Put(ctx, repo, mf.Digest.String(), mf, blobs),
 			handler.manifestHandler.Put(ctx, repo, target, mf, blobs),
+		); err != nil {
+			return err
+		}
+		return multierr.Combine(
+			handler.putSignatures(ctx, repo, target, mf),
+			handler.putAttachments(ctx, repo, mf, attachments),
 		)
 	})
 	if errors.Is(err, apierrors.ErrQuotaExceeded) {
@@ -479,26 +549,185 @@ func (handler *manifests) handlePut(resp http.ResponseWriter, req *http.Request,
 	} else if err != nil {
 		return regErrInternal(err)
 	}
-
-	resp.Header().Set("Docker-Content-Digest", mf.Digest.String())
-	resp.Header().Set("OCI-Subject", mf.Digest.String())
-	resp.Header().Set("Location", req.URL.JoinPath(mf.Blob.Digest.String()).Path)
-	resp.WriteHeader(http.StatusCreated)
 	return nil
 }
 
-// func (handler *manifests) handleDelete(resp http.ResponseWriter, req *http.Request, repo, target string) *regError {
-// 	if err := handler.manifestHandler.Delete(req.Context(), repo, target); errors.Is(err, manifest.ErrNameUnknown) {
-// 		return regErrNameUnknown
-// 	} else if errors.Is(err, manifest.ErrManifestUnknown) {
-// 		return regErrManifestUnknown
-// 	} else if err != nil {
-// 		regErrInternal(err)
-// 	}
-
-// 	resp.WriteHeader(http.StatusAccepted)
-// 	return nil
-// }
+// fetchFromMirror fetches a manifest that does not exist from the upstream registry if the repository is mirrored and
+// stores it like a pushed manifest.
+func (handler *manifests) fetchFromMirror(ctx context.Context, repo, target string) (*imanifest.Manifest, *regError) {
+	if handler.proxy == nil {
+		return nil, nil
+	}
+	upstream, err := handler.proxy.FetchManifest(ctx, repo, target)
+	if errors.Is(err, mirror.ErrNotMirrored) || errors.Is(err, mirror.ErrUpstreamNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, regErrInternal(fmt.Errorf("failed to fetch manifest from upstream: %w", err))
+	}
+
+	mf := upstreamManifest(upstream)
+	if rerr := handler.putManifest(ctx, repo, target, mf); rerr != nil {
+		// the manifest might have been stored by a concurrent pull in the meantime
+		if m, err := handler.manifestHandler.Get(ctx, repo, target); err == nil {
+			return m, nil
+		}
+		return nil, rerr
+	}
+	return &mf, nil
+}
+
+// refreshFromMirror replaces the manifest of a mirrored tag if the tag was moved in the upstream registry.
+// Failures are only logged, so that cached manifests can still be pulled while the upstream registry is unavailable.
+func (handler *manifests) refreshFromMirror(
+	ctx context.Context,
+	repo, target string,
+	m *imanifest.Manifest,
+) *imanifest.Manifest {
+	if handler.proxy == nil {
+		return m
+	} else if _, err := digest.Parse(target); err == nil {
+		return m
+	}
+	log := internalctx.GetLogger(ctx).With(zap.String("repo", repo), zap.String("tag", target))
+	upstream, err := handler.proxy.RevalidateTag(ctx, repo, target, m.Digest)
+	if err != nil {
+		log.Warn("failed to check mirrored tag against upstream registry", zap.Error(err))
+		return m
+	} else if upstream == nil {
+		return m
+	}
+	mf := upstreamManifest(upstream)
+	if rerr := handler.putManifest(ctx, repo, target, mf); rerr != nil {
+		log.Warn("failed to store updated manifest of mirrored tag", zap.Error(rerr.Error))
+		return m
+	}
+	log.Info("mirrored tag was updated from upstream registry", zap.Stringer("digest", mf.Digest))
+	return &mf
+}
+
+func upstreamManifest(upstream *mirror.Manifest) imanifest.Manifest {
+	mf := imanifest.Manifest{
+		ContentType: upstream.ContentType,
+		BlobWithData: imanifest.BlobWithData{
+			Data: upstream.Data,
+			Blob: imanifest.Blob{Digest: upstream.Digest, Size: int64(len(upstream.Data))},
+		},
+	}
+	if mf.ContentType == "" {
+		mf.ContentType = manifest.GuessMIMEType(mf.Data)
+	}
+	return mf
+}
+
+// putSignatures stores the cosign or Notation signatures contained in the manifest, if any, so that the signature
+// status of their subjects can be determined without fetching the signature manifests again.
+// Signature manifests that can not be parsed are stored like any other manifest.
+func (handler *manifests) putSignatures(ctx context.Context, repo, target string, mf imanifest.Manifest) error {
+	sigs, err := signature.Extract(ctx, mf.Data, target, func(ctx context.Context, d digest.Digest) ([]byte, error) {
+		rc, err := handler.blobHandler.Get(ctx, repo, d, false)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, signature.MaxLayerSize))
+	})
+	if err != nil {
+		internalctx.GetLogger(ctx).Warn("could not extract signatures from manifest",
+			zap.String("repo", repo), zap.Stringer("digest", mf.Digest), zap.Error(err))
+		return nil
+	} else if len(sigs) == 0 {
+		return nil
+	}
+
+	n, err := name.Parse(repo)
+	if err != nil {
+		return err
+	}
+	version, err := db.GetArtifactVersion(ctx, n.OrgName, n.ArtifactName, mf.Digest.String())
+	if err != nil {
+		return err
+	}
+	for i := range sigs {
+		sigs[i].ArtifactVersionID = version.ID
+	}
+	return db.SetArtifactVersionSignatures(ctx, version.ID, sigs)
+}
+
+// extractAttachments returns summaries of the SBOMs and vulnerability reports contained in the manifest, if any.
+// Documents that can not be parsed are stored like any other manifest but are not summarized.
+func (handler *manifests) extractAttachments(
+	ctx context.Context,
+	repo string,
+	mf imanifest.Manifest,
+) []types.ArtifactVersionAttachment {
+	open := func(ctx context.Context, d digest.Digest) (io.ReadCloser, error) {
+		return handler.blobHandler.Get(ctx, repo, d, false)
+	}
+	attachments, err := attachment.Extract(ctx, mf.Data, open)
+	if err != nil {
+		internalctx.GetLogger(ctx).Warn("could not extract attachments from manifest",
+			zap.String("repo", repo), zap.Stringer("digest", mf.Digest), zap.Error(err))
+		return nil
+	}
+	return attachments
+}
+
+// putAttachments stores the attachment summaries of the manifest, if any.
+func (handler *manifests) putAttachments(
+	ctx context.Context,
+	repo string,
+	mf imanifest.Manifest,
+	attachments []types.ArtifactVersionAttachment,
+) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	n, err := name.Parse(repo)
+	if err != nil {
+		return err
+	}
+	version, err := db.GetArtifactVersion(ctx, n.OrgName, n.ArtifactName, mf.Digest.String())
+	if err != nil {
+		return err
+	}
+	for i := range attachments {
+		attachments[i].ArtifactVersionID = version.ID
+	}
+	return db.SetArtifactVersionAttachments(ctx, version.ID, attachments)
+}
+
+// handleDelete deletes a tag or, if target is a digest, the manifest and all tags pointing to it.
+// Deletions that would leave the artifact without tags or break license access are denied.
+func (handler *manifests) handleDelete(resp http.ResponseWriter, req *http.Request, repo, target string) *regError {
+	ctx := req.Context()
+	m, err := handler.manifestHandler.Get(ctx, repo, target)
+	if errors.Is(err, imanifest.ErrNameUnknown) {
+		return regErrNameUnknown
+	} else if errors.Is(err, imanifest.ErrManifestUnknown) {
+		return regErrManifestUnknown
+	} else if err != nil {
+		return regErrInternal(err)
+	}
+
+	if err := handler.manifestHandler.Delete(ctx, repo, target); errors.Is(err, imanifest.ErrNameUnknown) {
+		return regErrNameUnknown
+	} else if errors.Is(err, imanifest.ErrManifestUnknown) {
+		return regErrManifestUnknown
+	} else if errors.Is(err, apierrors.ErrBadRequest) || errors.Is(err, apierrors.ErrConflict) {
+		return regErrDeniedWithMessage(err)
+	} else if err != nil {
+		return regErrInternal(err)
+	}
+
+	if err := handler.audit.AuditDelete(ctx, repo, target, m.Digest); err != nil {
+		internalctx.GetLogger(ctx).Warn("failed to audit-log delete", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+	}
+
+	resp.WriteHeader(http.StatusAccepted)
+	return nil
+}
 
 func checkIncompatibleManifest(data []byte) *regError {
 	var mf struct {
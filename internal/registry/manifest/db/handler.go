@@ -23,8 +23,31 @@ func NewManifestHandler() manifest.ManifestHandler {
 }
 
 // Delete implements manifest.ManifestHandler.
-func (h *handler) Delete(ctx context.Context, name string, reference string) error {
-	panic("TODO: implement")
+//
+// If reference is a digest, the manifest and all tags pointing to it are deleted. Otherwise, only the tag is deleted.
+func (h *handler) Delete(ctx context.Context, nameStr string, reference string) error {
+	name, err := name.Parse(nameStr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", manifest.ErrNameUnknown, err)
+	}
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		artifact, err := db.GetArtifactByName(ctx, name.OrgName, name.ArtifactName)
+		if err != nil {
+			if errors.Is(err, apierrors.ErrNotFound) {
+				return fmt.Errorf("%w: %w", manifest.ErrNameUnknown, err)
+			}
+			return err
+		}
+		if _, err := digest.Parse(reference); err == nil {
+			return db.DeleteArtifactManifest(ctx, artifact.ID, reference)
+		} else {
+			return db.DeleteArtifactTag(ctx, artifact.ID, reference)
+		}
+	})
+	if errors.Is(err, apierrors.ErrNotFound) && !errors.Is(err, manifest.ErrNameUnknown) {
+		return fmt.Errorf("%w: %w", manifest.ErrManifestUnknown, err)
+	}
+	return err
 }
 
 // Get implements manifest.ManifestHandler.
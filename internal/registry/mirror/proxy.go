@@ -0,0 +1,208 @@
+// Package mirror implements pull-through caching of artifacts from upstream registries.
+//
+// If a manifest or blob that is requested from the registry does not exist and the artifact name is covered by one
+// of the registry mirrors of the organization, it is fetched from the upstream registry and stored, so that
+// subsequent pulls are served from the Distr registry. Tags are checked against the upstream registry again once
+// they are older than [TagTTL].
+//
+// Connections to upstream registries are restricted by the outbound package.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/outbound"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/distr-sh/distr/internal/registry/name"
+	"github.com/distr-sh/distr/internal/registry/verify"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	orasauth "oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const (
+	// MaxManifestSize is the maximum size of a manifest that is fetched from an upstream registry.
+	MaxManifestSize = 4 << 20
+	// TagTTL is the duration after which a mirrored tag is checked against the upstream registry again.
+	TagTTL = 10 * time.Minute
+)
+
+var upstreamHTTPClient = &http.Client{Transport: retry.NewTransport(outbound.NewTransport())}
+
+var (
+	// ErrNotMirrored is returned if the artifact is not covered by any mirror of the organization.
+	ErrNotMirrored = errors.New("artifact is not mirrored")
+	// ErrUpstreamNotFound is returned if the upstream registry does not have the requested manifest or blob.
+	ErrUpstreamNotFound = errors.New("not found in upstream registry")
+)
+
+type Proxy struct {
+	blobHandler blob.BlobHandler
+}
+
+func NewProxy(blobHandler blob.BlobHandler) *Proxy {
+	return &Proxy{blobHandler: blobHandler}
+}
+
+// Manifest is a manifest that was fetched from an upstream registry.
+type Manifest struct {
+	ContentType string
+	Digest      digest.Digest
+	Data        []byte
+}
+
+// FetchManifest fetches the manifest with the given tag or digest from the upstream registry that is mirrored for
+// repo. The manifest is not stored; callers are expected to store it like a pushed manifest.
+func (p *Proxy) FetchManifest(ctx context.Context, repo, reference string) (*Manifest, error) {
+	upstream, err := p.upstreamRepository(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, rc, err := upstream.Manifests().FetchReference(ctx, reference)
+	if errors.Is(err, errdef.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %v", ErrUpstreamNotFound, err)
+	} else if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	if desc.Size > MaxManifestSize {
+		return nil, fmt.Errorf("upstream manifest %v is too large", desc.Digest)
+	}
+	vrc, err := verify.ReadCloser(rc, desc.Size, desc.Digest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(vrc)
+	if err != nil {
+		return nil, err
+	}
+	if d, err := digest.Parse(reference); err == nil && d != desc.Digest {
+		return nil, fmt.Errorf("upstream returned manifest %v for %v", desc.Digest, d)
+	}
+
+	return &Manifest{ContentType: desc.MediaType, Digest: desc.Digest, Data: data}, nil
+}
+
+// RevalidateTag checks whether the tag of a mirrored repository still points to the given digest in the upstream
+// registry, at most once per [TagTTL]. If the tag was moved, the new manifest is returned. Otherwise, or if the
+// repository is not mirrored or the check is not due yet, nil is returned.
+func (p *Proxy) RevalidateTag(ctx context.Context, repo, tag string, current digest.Digest) (*Manifest, error) {
+	upstream, err := p.upstreamRepository(ctx, repo)
+	if errors.Is(err, ErrNotMirrored) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	n, err := name.Parse(repo)
+	if err != nil {
+		return nil, err
+	}
+	orgID := *auth.ArtifactsAuthentication.Require(ctx).CurrentOrgID()
+	if claimed, err := db.ClaimMirroredTagCheck(ctx, orgID, n.ArtifactName, tag, TagTTL); err != nil || !claimed {
+		return nil, err
+	}
+
+	desc, err := upstream.Resolve(ctx, tag)
+	if errors.Is(err, errdef.ErrNotFound) {
+		// tags that were removed upstream are still served from the cache
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	} else if desc.Digest == current {
+		return nil, nil
+	}
+	return p.FetchManifest(ctx, repo, tag)
+}
+
+// FetchBlob fetches the blob with the given digest from the upstream registry that is mirrored for repo and stores it
+// with the blob handler.
+func (p *Proxy) FetchBlob(ctx context.Context, repo string, h digest.Digest) error {
+	bph, ok := p.blobHandler.(blob.BlobPutHandler)
+	if !ok {
+		return errors.New("blob handler does not support writing blobs")
+	}
+
+	upstream, err := p.upstreamRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	desc, err := upstream.Blobs().Resolve(ctx, h.String())
+	if errors.Is(err, errdef.ErrNotFound) {
+		return fmt.Errorf("%w: %v", ErrUpstreamNotFound, err)
+	} else if err != nil {
+		return err
+	}
+	rc, err := upstream.Blobs().Fetch(ctx, ocispec.Descriptor{MediaType: desc.MediaType, Digest: h, Size: desc.Size})
+	if err != nil {
+		return err
+	}
+	vrc, err := verify.ReadCloser(rc, desc.Size, h)
+	if err != nil {
+		_ = rc.Close()
+		return err
+	}
+	return bph.Put(ctx, repo, h, "", vrc)
+}
+
+func (p *Proxy) upstreamRepository(ctx context.Context, repo string) (*remote.Repository, error) {
+	n, err := name.Parse(repo)
+	if err != nil {
+		return nil, err
+	}
+	mirrors, err := db.GetRegistryMirrors(ctx, *auth.ArtifactsAuthentication.Require(ctx).CurrentOrgID())
+	if err != nil {
+		return nil, err
+	}
+	mirror, upstreamName, ok := Match(mirrors, n.ArtifactName)
+	if !ok {
+		return nil, ErrNotMirrored
+	}
+
+	ref, err := registry.ParseReference(upstreamName)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := remote.NewRepository(ref.Registry + "/" + ref.Repository)
+	if err != nil {
+		return nil, err
+	}
+	client := &orasauth.Client{Client: upstreamHTTPClient, Cache: orasauth.NewCache()}
+	if mirror.Username != nil && mirror.Password != nil {
+		client.Credential = orasauth.StaticCredential(ref.Registry, orasauth.Credential{
+			Username: *mirror.Username,
+			Password: *mirror.Password,
+		})
+	}
+	upstream.Client = client
+	return upstream, nil
+}
+
+// Match returns the mirror with the longest prefix that covers the given artifact name together with the name of the
+// corresponding upstream repository.
+func Match(mirrors []types.RegistryMirror, artifactName string) (*types.RegistryMirror, string, bool) {
+	var result *types.RegistryMirror
+	var upstreamName string
+	for i, mirror := range mirrors {
+		if name, ok := mirror.UpstreamRepositoryFor(artifactName); ok &&
+			(result == nil || len(mirror.Prefix) > len(result.Prefix)) {
+			result = &mirrors[i]
+			upstreamName = name
+		}
+	}
+	return result, upstreamName, result != nil
+}
@@ -0,0 +1,39 @@
+package mirror
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestMatch(t *testing.T) {
+	g := NewWithT(t)
+	mirrors := []types.RegistryMirror{
+		{Prefix: "ghcr", UpstreamRepository: "ghcr.io/acme"},
+		{Prefix: "ghcr/charts", UpstreamRepository: "ghcr.io/acme-charts"},
+		{Prefix: "hub", UpstreamRepository: "docker.io/library"},
+	}
+
+	mirror, upstream, ok := Match(mirrors, "ghcr/app")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(mirror.Prefix).To(Equal("ghcr"))
+	g.Expect(upstream).To(Equal("ghcr.io/acme/app"))
+
+	mirror, upstream, ok = Match(mirrors, "ghcr/charts/app")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(mirror.Prefix).To(Equal("ghcr/charts"))
+	g.Expect(upstream).To(Equal("ghcr.io/acme-charts/app"))
+
+	mirror, upstream, ok = Match(mirrors, "hub")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(mirror.Prefix).To(Equal("hub"))
+	g.Expect(upstream).To(Equal("docker.io/library"))
+
+	_, _, ok = Match(mirrors, "ghcrx/app")
+	g.Expect(ok).To(BeFalse())
+	_, _, ok = Match(mirrors, "app")
+	g.Expect(ok).To(BeFalse())
+	_, _, ok = Match(nil, "ghcr/app")
+	g.Expect(ok).To(BeFalse())
+}
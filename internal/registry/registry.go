@@ -38,9 +38,10 @@ import (
 	"github.com/distr-sh/distr/internal/registry/audit"
 	"github.com/distr-sh/distr/internal/registry/authz"
 	"github.com/distr-sh/distr/internal/registry/blob"
-	"github.com/distr-sh/distr/internal/registry/blob/s3"
+	"github.com/distr-sh/distr/internal/registry/blob/storage"
 	"github.com/distr-sh/distr/internal/registry/manifest"
 	"github.com/distr-sh/distr/internal/registry/manifest/db"
+	"github.com/distr-sh/distr/internal/registry/mirror"
 	"github.com/getsentry/sentry-go"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -52,6 +53,7 @@ type registry struct {
 	log              *zap.SugaredLogger
 	blobs            blobs
 	manifests        manifests
+	helm             helmRepository
 	referrersEnabled bool
 	warnings         map[float64]string
 	middlewares      []func(http.Handler) http.Handler
@@ -69,6 +71,9 @@ func (r *registry) v2(resp http.ResponseWriter, req *http.Request) *regError {
 		}
 	}
 
+	if isHelm(req) {
+		return r.helm.handle(resp, req)
+	}
 	if isBlob(req) {
 		return r.blobs.handle(resp, req)
 	}
@@ -132,10 +137,12 @@ func NewDefault(
 	mailer mail.Mailer,
 	tracer trace.TracerProvider,
 ) http.Handler {
+	blobHandler := storage.NewBlobHandler(ctx)
 	return New(
 		WithLogger(logger),
-		WithBlobHandler(s3.NewBlobHandler(ctx)),
+		WithBlobHandler(blobHandler),
 		WithManifestHandler(db.NewManifestHandler()),
+		WithPullThroughProxy(mirror.NewProxy(blobHandler)),
 		WithAuthorizer(authz.NewAuthorizer()),
 		WithAuditor(audit.NewAuditor()),
 		WithMiddlewares(
@@ -168,6 +175,7 @@ func WithLogger(l *zap.Logger) Option {
 		r.log = l.Sugar()
 		r.manifests.log = l.Sugar()
 		r.blobs.log = l.Sugar()
+		r.helm.log = l.Sugar()
 	}
 }
 
@@ -191,12 +199,23 @@ func WithBlobHandler(h blob.BlobHandler) Option {
 	return func(r *registry) {
 		r.blobs.blobHandler = h
 		r.manifests.blobHandler = h
+		r.helm.blobHandler = h
 	}
 }
 
 func WithManifestHandler(h manifest.ManifestHandler) Option {
 	return func(r *registry) {
 		r.manifests.manifestHandler = h
+		r.helm.manifestHandler = h
+	}
+}
+
+// WithPullThroughProxy enables fetching manifests and blobs that do not exist in the registry from the upstream
+// registries that are configured as mirrors.
+func WithPullThroughProxy(p *mirror.Proxy) Option {
+	return func(r *registry) {
+		r.blobs.proxy = p
+		r.manifests.proxy = p
 	}
 }
 
@@ -210,11 +229,13 @@ func WithAuthorizer(a authz.Authorizer) Option {
 	return func(r *registry) {
 		r.blobs.authz = a
 		r.manifests.authz = a
+		r.helm.authz = a
 	}
 }
 
 func WithAuditor(a audit.ArtifactAuditor) Option {
 	return func(r *registry) {
 		r.manifests.audit = a
+		r.helm.audit = a
 	}
 }
@@ -0,0 +1,274 @@
+// Package replication copies the artifact versions that are licensed to a customer organization to the registry that
+// is configured as replication target of the customer organization.
+//
+// Versions are copied together with all their tags and are copied again whenever their tags change, so that tags
+// which are moved to a new version also move in the target registry. Versions that are no longer licensed are not
+// removed from the target registry.
+//
+// Connections to target registries are restricted by the outbound package.
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/outbound"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/distr-sh/distr/internal/registry/blob/storage"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/registry/remote"
+	orasauth "oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+var targetHTTPClient = &http.Client{Transport: retry.NewTransport(outbound.NewTransport())}
+
+type Result struct {
+	SyncedVersions int64
+	FailedVersions int64
+}
+
+type licensedArtifact struct {
+	artifact types.ArtifactWithDownloads
+	versions []types.TaggedArtifactVersion
+}
+
+// Run replicates the licensed artifact versions to all replication targets. Versions that fail to be copied do not
+// prevent other versions from being copied. Their error is stored in the replication status and they are retried by
+// the next run.
+func Run(ctx context.Context, blobHandler blob.BlobHandler) (*Result, error) {
+	var result Result
+	targets, err := db.GetAllArtifactReplicationTargets(ctx)
+	if err != nil {
+		return &result, err
+	}
+
+	var errs []error
+	for _, target := range targets {
+		if err := runForTarget(ctx, blobHandler, target, &result); err != nil {
+			errs = append(errs, fmt.Errorf("replication target %v: %w", target.ID, err))
+		}
+	}
+	return &result, errors.Join(errs...)
+}
+
+// RunJob runs the replication with the configured blob storage and logs the result.
+func RunJob(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	result, err := Run(ctx, storage.NewBlobHandler(ctx))
+	log.Info("artifact replication finished",
+		zap.Int64("versionsSynced", result.SyncedVersions),
+		zap.Int64("versionsFailed", result.FailedVersions),
+		zap.Error(err))
+	return err
+}
+
+// VersionStatuses returns the replication status of every artifact version that is licensed to the customer
+// organization of the target.
+func VersionStatuses(
+	ctx context.Context,
+	target types.ArtifactReplicationTarget,
+) ([]types.ArtifactReplicationVersionStatus, error) {
+	artifacts, err := getLicensedArtifacts(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := getStatuses(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []types.ArtifactReplicationVersionStatus{}
+	for _, la := range artifacts {
+		for _, version := range la.versions {
+			vs := types.ArtifactReplicationVersionStatus{
+				ArtifactID:        la.artifact.ID,
+				ArtifactName:      la.artifact.Name,
+				ArtifactVersionID: version.ID,
+				Digest:            version.Digest,
+				Tags:              tagNames(version),
+				State:             types.ArtifactReplicationStatePending,
+			}
+			if status, ok := statuses[version.ID]; ok {
+				vs.SyncedAt = status.SyncedAt
+				vs.UpdatedAt = &status.UpdatedAt
+				if status.Error != nil {
+					vs.State = types.ArtifactReplicationStateFailed
+					vs.Error = status.Error
+				} else if slices.Equal(status.Tags, vs.Tags) {
+					vs.State = types.ArtifactReplicationStateSynced
+				}
+			}
+			result = append(result, vs)
+		}
+	}
+	return result, nil
+}
+
+func runForTarget(
+	ctx context.Context,
+	blobHandler blob.BlobHandler,
+	target types.ArtifactReplicationTarget,
+	result *Result,
+) error {
+	log := internalctx.GetLogger(ctx).With(
+		zap.Stringer("organizationId", target.OrganizationID),
+		zap.Stringer("customerOrganizationId", target.CustomerOrganizationID),
+		zap.String("registryUrl", target.RegistryURL),
+	)
+
+	// Without its credential, every version fails to be copied. This is recorded in the status of the versions
+	// instead of silently falling back to anonymous access.
+	credential, credentialErr := getCredential(ctx, target)
+	artifacts, err := getLicensedArtifacts(ctx, target)
+	if err != nil {
+		return err
+	}
+	statuses, err := getStatuses(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, la := range artifacts {
+		src := &source{blobHandler: blobHandler, orgSlug: la.artifact.OrganizationSlug, artifactName: la.artifact.Name}
+		dst, err := newTargetRepository(target, la.artifact.Name, credential)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("artifact %v: %w", la.artifact.Name, err))
+			continue
+		}
+
+		for _, version := range la.versions {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+
+			tags := tagNames(version)
+			if status, ok := statuses[version.ID]; ok && status.Error == nil && slices.Equal(status.Tags, tags) {
+				continue
+			}
+
+			status := types.ArtifactReplicationStatus{
+				ArtifactReplicationTargetID: target.ID,
+				ArtifactVersionID:           version.ID,
+				Tags:                        tags,
+			}
+			if credentialErr != nil {
+				status.Error = util.PtrTo(credentialErr.Error())
+				result.FailedVersions++
+			} else if err := copyVersion(ctx, src, dst, version.Digest, tags); err != nil {
+				log.Warn("artifact version replication failed",
+					zap.String("artifact", la.artifact.Name),
+					zap.String("digest", version.Digest),
+					zap.Error(err))
+				status.Error = util.PtrTo(err.Error())
+				result.FailedVersions++
+			} else {
+				result.SyncedVersions++
+			}
+			if err := db.SetArtifactReplicationStatus(ctx, &status); err != nil {
+				errs = append(errs, fmt.Errorf("artifact %v: %w", la.artifact.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func copyVersion(ctx context.Context, src *source, dst *remote.Repository, digest string, tags []string) error {
+	desc, err := src.Resolve(ctx, digest)
+	if err != nil {
+		return err
+	}
+	// Blobs that already exist in the target registry are skipped.
+	if err := oras.CopyGraph(ctx, src, dst, desc, oras.DefaultCopyGraphOptions); err != nil {
+		return err
+	}
+	if len(tags) > 0 {
+		_, err = oras.TagN(ctx, dst, digest, tags, oras.DefaultTagNOptions)
+	}
+	return err
+}
+
+func getLicensedArtifacts(ctx context.Context, target types.ArtifactReplicationTarget) ([]licensedArtifact, error) {
+	artifacts, err := db.GetArtifactsByLicenseOwnerID(ctx, target.OrganizationID, target.CustomerOrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]licensedArtifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		versions, err := db.GetVersionsForArtifact(ctx, artifact.ID, &target.CustomerOrganizationID)
+		if err != nil {
+			return nil, fmt.Errorf("artifact %v: %w", artifact.Name, err)
+		}
+		result = append(result, licensedArtifact{artifact: artifact, versions: versions})
+	}
+	return result, nil
+}
+
+func getStatuses(
+	ctx context.Context,
+	target types.ArtifactReplicationTarget,
+) (map[uuid.UUID]types.ArtifactReplicationStatus, error) {
+	statuses, err := db.GetArtifactReplicationStatuses(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]types.ArtifactReplicationStatus, len(statuses))
+	for _, status := range statuses {
+		result[status.ArtifactVersionID] = status
+	}
+	return result, nil
+}
+
+// getCredential returns the credential of the target. The password secret might have been deleted after the target
+// was created, which is an error if the target has a username.
+func getCredential(ctx context.Context, target types.ArtifactReplicationTarget) (orasauth.Credential, error) {
+	if target.Username == nil {
+		return orasauth.EmptyCredential, nil
+	} else if target.PasswordSecretID == nil {
+		return orasauth.EmptyCredential, errors.New("the password secret of the replication target was deleted")
+	}
+	secret, err := db.GetSecretByID(ctx, *target.PasswordSecretID, target.OrganizationID, nil)
+	if err != nil {
+		return orasauth.EmptyCredential, fmt.Errorf("failed to get password secret: %w", err)
+	}
+	return orasauth.Credential{Username: *target.Username, Password: secret.Value}, nil
+}
+
+func newTargetRepository(
+	target types.ArtifactReplicationTarget,
+	artifactName string,
+	credential orasauth.Credential,
+) (*remote.Repository, error) {
+	ref, err := target.RepositoryFor(artifactName)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := remote.NewRepository(ref.Registry + "/" + ref.Repository)
+	if err != nil {
+		return nil, err
+	}
+	client := &orasauth.Client{Client: targetHTTPClient, Cache: orasauth.NewCache()}
+	if credential != orasauth.EmptyCredential {
+		client.Credential = orasauth.StaticCredential(ref.Registry, credential)
+	}
+	repo.Client = client
+	return repo, nil
+}
+
+func tagNames(version types.TaggedArtifactVersion) []string {
+	tags := make([]string, len(version.Tags))
+	for i, tag := range version.Tags {
+		tags[i] = tag.Name
+	}
+	return tags
+}
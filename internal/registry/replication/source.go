@@ -0,0 +1,80 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// source exposes the manifests and blobs of an artifact stored in the Distr registry as an oras target, so that they
+// can be copied to another registry.
+type source struct {
+	blobHandler  blob.BlobHandler
+	orgSlug      string
+	artifactName string
+}
+
+var _ oras.ReadOnlyTarget = &source{}
+
+// Resolve implements content.Resolver.
+func (s *source) Resolve(ctx context.Context, reference string) (ocispec.Descriptor, error) {
+	av, err := db.GetArtifactVersion(ctx, s.orgSlug, s.artifactName, reference)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		return ocispec.Descriptor{}, errors.Join(errdef.ErrNotFound, err)
+	} else if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return ocispec.Descriptor{
+		MediaType: av.ManifestContentType,
+		Digest:    digest.Digest(av.ManifestBlobDigest),
+		Size:      av.ManifestBlobSize,
+	}, nil
+}
+
+// Fetch implements content.Fetcher.
+//
+// Manifests are stored in the database and everything else is stored with the blob handler, so the database is
+// checked first.
+func (s *source) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	if av, err := db.GetArtifactVersion(ctx, s.orgSlug, s.artifactName, target.Digest.String()); err == nil {
+		return io.NopCloser(bytes.NewReader(av.ManifestData)), nil
+	} else if !errors.Is(err, apierrors.ErrNotFound) {
+		return nil, err
+	}
+	rc, err := s.blobHandler.Get(ctx, s.repo(), target.Digest, false)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, errors.Join(errdef.ErrNotFound, err)
+	}
+	return rc, err
+}
+
+// Exists implements content.ReadOnlyStorage.
+func (s *source) Exists(ctx context.Context, target ocispec.Descriptor) (bool, error) {
+	if bsh, ok := s.blobHandler.(blob.BlobStatHandler); ok {
+		if _, err := bsh.Stat(ctx, s.repo(), target.Digest); err == nil {
+			return true, nil
+		} else if !errors.Is(err, blob.ErrNotFound) {
+			return false, err
+		}
+	}
+	rc, err := s.Fetch(ctx, target)
+	if errors.Is(err, errdef.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, rc.Close()
+}
+
+func (s *source) repo() string {
+	return s.orgSlug + "/" + s.artifactName
+}
@@ -0,0 +1,87 @@
+package retention
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/kustomization"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// references contains everything that can refer to an artifact of an organization and is in use, i.e. the
+// application versions that are licensed or currently deployed and the values of the current deployment revisions.
+// References that are assembled by templates, e.g. with the tag in a separate value, are not detected.
+type references struct {
+	helmCharts []helmChartReference
+	files      [][]byte
+}
+
+type helmChartReference struct {
+	url     string
+	version string
+}
+
+func loadReferences(ctx context.Context, orgID uuid.UUID) (*references, error) {
+	versions, err := db.GetApplicationVersionsInUse(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := db.GetLatestDeploymentRevisionFiles(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := references{files: files}
+	for _, av := range versions {
+		if av.ChartUrl != nil && av.ChartVersion != nil {
+			refs.helmCharts = append(refs.helmCharts, helmChartReference{url: *av.ChartUrl, version: *av.ChartVersion})
+		}
+		if av.KubernetesType != nil && *av.KubernetesType != types.KubernetesTypeHelm {
+			// kustomize archives are compressed, so the rendered manifests are searched instead
+			if rendered, err := kustomization.Build(*av.KubernetesType, av.ManifestFileData, nil); err != nil {
+				internalctx.GetLogger(ctx).Warn("could not render application version manifests",
+					zap.Stringer("applicationVersionId", av.ID), zap.Error(err))
+				refs.files = append(refs.files, av.ManifestFileData)
+			} else {
+				refs.files = append(refs.files, rendered)
+			}
+		}
+		for _, data := range [][]byte{av.ComposeFileData, av.ValuesFileData, av.TemplateFileData} {
+			if len(data) > 0 {
+				refs.files = append(refs.files, data)
+			}
+		}
+	}
+	return &refs, nil
+}
+
+// contains checks whether the given tag or digest of the artifact is referenced, either as the version of a Helm chart
+// or as part of an image reference like "registry.example.com/org/artifact:tag".
+func (r *references) contains(orgSlug, artifactName, reference string) bool {
+	repository := orgSlug + "/" + artifactName
+	for _, chart := range r.helmCharts {
+		if chart.version == reference && strings.HasSuffix(strings.TrimSuffix(chart.url, "/"), "/"+repository) {
+			return true
+		}
+	}
+
+	separator := ":"
+	if strings.Contains(reference, ":") {
+		separator = "@"
+	}
+	pattern := regexp.MustCompile(
+		`/` + regexp.QuoteMeta(repository+separator+reference) + `($|[^\w.-])`,
+	)
+	for _, data := range r.files {
+		if bytes.Contains(data, []byte(reference)) && pattern.Match(data) {
+			return true
+		}
+	}
+	return false
+}
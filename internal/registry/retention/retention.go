@@ -0,0 +1,279 @@
+// Package retention enforces the artifact retention policies that vendors configure for their artifacts.
+//
+// Tags are only deleted if every policy whose pattern matches them would delete them, so that the most permissive
+// policy wins. Policies of an artifact replace the organization-wide policies for this artifact.
+// Tags and manifests that are explicitly included in an artifact license or referenced by an application version that
+// is licensed or currently deployed are never deleted.
+// Signature, attestation and SBOM tags created by cosign ("sha256-<hex>.sig") are not subject to the policies. They are
+// deleted together with the last tag of the manifest they belong to.
+// Blobs that are no longer referenced after a tag or manifest was deleted are removed by the registry garbage
+// collection. Every deletion is recorded as artifact event.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/registry/audit"
+	"github.com/distr-sh/distr/internal/registry/gc"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// cosignTagPattern matches the tags that cosign uses to attach signatures, attestations and SBOMs to a manifest.
+var cosignTagPattern = regexp.MustCompile(`^(sha256)-([0-9a-f]{64})\.(sig|att|sbom)$`)
+
+type Options struct {
+	// DryRun only reports which tags and manifests would be deleted.
+	DryRun bool
+}
+
+type Result struct {
+	DeletedTags      int64
+	DeletedManifests int64
+}
+
+// Run enforces the retention policies of all organizations. An error for one artifact does not prevent the policies
+// of other artifacts from being enforced.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	var result Result
+	orgIDs, err := db.GetOrganizationIDsWithArtifactRetentionPolicies(ctx)
+	if err != nil {
+		return &result, err
+	}
+
+	var errs []error
+	for _, orgID := range orgIDs {
+		if err := runForOrganization(ctx, orgID, opts, &result); err != nil {
+			errs = append(errs, fmt.Errorf("organization %v: %w", orgID, err))
+		}
+	}
+	return &result, errors.Join(errs...)
+}
+
+func runForOrganization(ctx context.Context, orgID uuid.UUID, opts Options, result *Result) error {
+	policies, err := db.GetArtifactRetentionPolicies(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	artifacts, err := db.GetArtifactsByOrgID(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	refs, err := loadReferences(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, artifact := range artifacts {
+		artifactPolicies := policiesForArtifact(policies, artifact.ID)
+		if len(artifactPolicies) == 0 {
+			continue
+		}
+		var artifactResult Result
+		if err := db.RunTx(ctx, func(ctx context.Context) error {
+			return applyPolicies(ctx, artifact, artifactPolicies, refs, opts, &artifactResult)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("artifact %v: %w", artifact.Name, err))
+		} else {
+			result.DeletedTags += artifactResult.DeletedTags
+			result.DeletedManifests += artifactResult.DeletedManifests
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// policiesForArtifact returns the policies of the artifact or the organization-wide policies if the artifact has none.
+func policiesForArtifact(
+	policies []types.ArtifactRetentionPolicy,
+	artifactID uuid.UUID,
+) []types.ArtifactRetentionPolicy {
+	var artifactPolicies, orgPolicies []types.ArtifactRetentionPolicy
+	for _, policy := range policies {
+		if policy.ArtifactID == nil {
+			orgPolicies = append(orgPolicies, policy)
+		} else if *policy.ArtifactID == artifactID {
+			artifactPolicies = append(artifactPolicies, policy)
+		}
+	}
+	if len(artifactPolicies) > 0 {
+		return artifactPolicies
+	}
+	return orgPolicies
+}
+
+func applyPolicies(
+	ctx context.Context,
+	artifact types.ArtifactWithDownloads,
+	policies []types.ArtifactRetentionPolicy,
+	refs *references,
+	opts Options,
+	result *Result,
+) error {
+	log := internalctx.GetLogger(ctx).With(
+		zap.Stringer("organizationId", artifact.OrganizationID),
+		zap.String("artifact", artifact.OrganizationSlug+"/"+artifact.Name),
+		zap.Bool("dryRun", opts.DryRun),
+	)
+
+	licensedDigests, err := db.GetLicensedArtifactVersionDigests(ctx, artifact.ID)
+	if err != nil {
+		return err
+	}
+	isProtected := func(reference string, digest types.Digest) bool {
+		return slices.Contains(licensedDigests, digest) ||
+			refs.contains(artifact.OrganizationSlug, artifact.Name, reference) ||
+			refs.contains(artifact.OrganizationSlug, artifact.Name, string(digest))
+	}
+
+	tags, err := db.GetArtifactTags(ctx, artifact.ID)
+	if err != nil {
+		return err
+	}
+	expiredTags, err := selectExpiredTags(tags, policies)
+	if err != nil {
+		return err
+	}
+	var deletedTags []types.ArtifactVersion
+	for _, tag := range expiredTags {
+		if isProtected(tag.Name, tag.ManifestBlobDigest) {
+			log.Debug("keeping expired tag because it is in use", zap.String("tag", tag.Name))
+			continue
+		}
+		deletedTags = append(deletedTags, tag)
+	}
+	deletedTags = append(deletedTags, selectOrphanedCosignTags(tags, deletedTags)...)
+	for _, tag := range deletedTags {
+		log.Info("deleting tag",
+			zap.String("tag", tag.Name),
+			zap.String("digest", string(tag.ManifestBlobDigest)),
+			zap.Time("createdAt", tag.CreatedAt))
+		if !opts.DryRun {
+			if err := audit.RecordDeletions(ctx, []uuid.UUID{tag.ID}, nil, nil); err != nil {
+				return err
+			} else if err := db.DeleteArtifactVersion(ctx, artifact.ID, tag.Name); err != nil {
+				return err
+			}
+		}
+		result.DeletedTags++
+	}
+
+	var maxAge time.Duration
+	for _, policy := range policies {
+		if age := policy.UntaggedMaxAge(); age > 0 && (maxAge == 0 || age < maxAge) {
+			maxAge = age
+		}
+	}
+	if maxAge == 0 {
+		return nil
+	}
+
+	untagged, err := db.GetUntaggedArtifactVersions(ctx, &artifact.ID, maxAge)
+	if err != nil {
+		return err
+	}
+	if untagged, err = gc.WithoutLiveReferrers(ctx, untagged); err != nil {
+		return err
+	}
+	var deletedVersions []types.ArtifactVersion
+	for _, version := range untagged {
+		if isProtected(version.Name, version.ManifestBlobDigest) {
+			continue
+		}
+		log.Info("deleting untagged manifest",
+			zap.String("digest", version.Name),
+			zap.Time("createdAt", version.CreatedAt))
+		deletedVersions = append(deletedVersions, version)
+	}
+	if len(deletedVersions) == 0 {
+		return nil
+	} else if opts.DryRun {
+		result.DeletedManifests += int64(len(deletedVersions))
+		return nil
+	}
+	ids := make([]uuid.UUID, len(deletedVersions))
+	for i, version := range deletedVersions {
+		ids[i] = version.ID
+	}
+	if err := audit.RecordDeletions(ctx, ids, nil, nil); err != nil {
+		return err
+	} else if count, err := db.DeleteArtifactVersionsWithIDs(ctx, ids); err != nil {
+		return err
+	} else {
+		result.DeletedManifests += count
+	}
+	return nil
+}
+
+// selectExpiredTags returns all tags that are not among the most recent tags of any policy that matches them.
+// The tags must be ordered by creation time, most recent first. Tags that no policy matches and cosign tags are never
+// returned.
+func selectExpiredTags(
+	tags []types.ArtifactVersion,
+	policies []types.ArtifactRetentionPolicy,
+) ([]types.ArtifactVersion, error) {
+	matched := make([]bool, len(tags))
+	kept := make([]bool, len(tags))
+	for _, policy := range policies {
+		if policy.KeepLastTags == nil {
+			continue
+		}
+		pattern, err := policy.CompileTagPattern()
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag pattern of policy %v: %w", policy.ID, err)
+		}
+		var count int
+		for i, tag := range tags {
+			if cosignTagPattern.MatchString(tag.Name) || (pattern != nil && !pattern.MatchString(tag.Name)) {
+				continue
+			}
+			matched[i] = true
+			if count < *policy.KeepLastTags {
+				kept[i] = true
+			}
+			count++
+		}
+	}
+
+	var result []types.ArtifactVersion
+	for i, tag := range tags {
+		if matched[i] && !kept[i] {
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+// selectOrphanedCosignTags returns the cosign tags of all manifests that are no longer tagged once the deleted tags
+// are gone. Cosign tags of manifests that were not tagged before are kept, because their manifest might still be
+// in use.
+func selectOrphanedCosignTags(tags, deletedTags []types.ArtifactVersion) []types.ArtifactVersion {
+	deletedDigests := map[string]struct{}{}
+	for _, tag := range deletedTags {
+		deletedDigests[string(tag.ManifestBlobDigest)] = struct{}{}
+	}
+	for _, tag := range tags {
+		if !cosignTagPattern.MatchString(tag.Name) && !slices.ContainsFunc(deletedTags,
+			func(deleted types.ArtifactVersion) bool { return deleted.Name == tag.Name }) {
+			delete(deletedDigests, string(tag.ManifestBlobDigest))
+		}
+	}
+
+	var result []types.ArtifactVersion
+	for _, tag := range tags {
+		if match := cosignTagPattern.FindStringSubmatch(tag.Name); match != nil {
+			if _, ok := deletedDigests[match[1]+":"+match[2]]; ok {
+				result = append(result, tag)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package retention
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	. "github.com/onsi/gomega"
+)
+
+func tagNames(tags []types.ArtifactVersion) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+func TestSelectExpiredTags(t *testing.T) {
+	g := NewWithT(t)
+
+	var tags []types.ArtifactVersion
+	for _, name := range []string{"nightly-3", "1.2.0", "nightly-2", "1.1.0", "nightly-1", "1.0.0"} {
+		tags = append(tags, types.ArtifactVersion{Name: name})
+	}
+
+	expired, err := selectExpiredTags(tags, []types.ArtifactRetentionPolicy{
+		{KeepLastTags: util.PtrTo(1), TagPattern: util.PtrTo(`^nightly-`)},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tagNames(expired)).To(Equal([]string{"nightly-2", "nightly-1"}))
+
+	expired, err = selectExpiredTags(tags, []types.ArtifactRetentionPolicy{
+		{KeepLastTags: util.PtrTo(4)},
+		{KeepLastTags: util.PtrTo(1), TagPattern: util.PtrTo(`^\d+\.\d+\.\d+$`)},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tagNames(expired)).To(Equal([]string{"nightly-1", "1.0.0"}))
+
+	expired, err = selectExpiredTags(tags, []types.ArtifactRetentionPolicy{{UntaggedMaxAgeDays: util.PtrTo(1)}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(expired).To(BeEmpty())
+}
+
+func TestSelectExpiredTagsIgnoresCosignTags(t *testing.T) {
+	g := NewWithT(t)
+
+	hex1, hex2 := strings.Repeat("1", 64), strings.Repeat("2", 64)
+	tags := []types.ArtifactVersion{
+		{Name: "sha256-" + hex2 + ".sig", ManifestBlobDigest: types.Digest("sha256:" + strings.Repeat("a", 64))},
+		{Name: "2.0.0", ManifestBlobDigest: types.Digest("sha256:" + hex2)},
+		{Name: "sha256-" + hex1 + ".sig", ManifestBlobDigest: types.Digest("sha256:" + strings.Repeat("b", 64))},
+		{Name: "sha256-" + hex1 + ".att", ManifestBlobDigest: types.Digest("sha256:" + strings.Repeat("c", 64))},
+		{Name: "1.0.0", ManifestBlobDigest: types.Digest("sha256:" + hex1)},
+	}
+
+	expired, err := selectExpiredTags(tags, []types.ArtifactRetentionPolicy{{KeepLastTags: util.PtrTo(1)}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tagNames(expired)).To(Equal([]string{"1.0.0"}))
+	g.Expect(tagNames(selectOrphanedCosignTags(tags, expired))).
+		To(Equal([]string{"sha256-" + hex1 + ".sig", "sha256-" + hex1 + ".att"}))
+
+	// the manifest is still tagged by another tag
+	tags = append(tags, types.ArtifactVersion{Name: "1.0", ManifestBlobDigest: types.Digest("sha256:" + hex1)})
+	g.Expect(selectOrphanedCosignTags(tags, expired)).To(BeEmpty())
+}
+
+func TestReferencesContains(t *testing.T) {
+	g := NewWithT(t)
+
+	refs := references{
+		helmCharts: []helmChartReference{{url: "oci://registry.example.com/org/chart", version: "1.0.0"}},
+		files: [][]byte{
+			[]byte("services:\n  app:\n    image: registry.example.com/org/app:1.0\n"),
+			[]byte("image: registry.example.com/org/worker@sha256:abc\n"),
+		},
+	}
+
+	g.Expect(refs.contains("org", "chart", "1.0.0")).To(BeTrue())
+	g.Expect(refs.contains("org", "chart", "1.0.1")).To(BeFalse())
+	g.Expect(refs.contains("other", "chart", "1.0.0")).To(BeFalse())
+	g.Expect(refs.contains("org", "app", "1.0")).To(BeTrue())
+	g.Expect(refs.contains("org", "app", "1")).To(BeFalse())
+	g.Expect(refs.contains("org", "app", "1.0.1")).To(BeFalse())
+	g.Expect(refs.contains("rg", "app", "1.0")).To(BeFalse())
+	g.Expect(refs.contains("org", "worker", "sha256:abc")).To(BeTrue())
+	g.Expect(refs.contains("org", "worker", "sha256:ab")).To(BeFalse())
+}
@@ -2,8 +2,11 @@ package resources
 
 import (
 	"embed"
+	"encoding/json"
 	"fmt"
 	"io/fs"
+	"path"
+	"strings"
 	"text/template"
 
 	"github.com/distr-sh/distr/internal/util"
@@ -14,8 +17,20 @@ var (
 	embeddedFs embed.FS
 	fsys       = util.Require(fs.Sub(embeddedFs, "embedded"))
 	templates  = map[string]*template.Template{}
+	funcs      = template.FuncMap{
+		// quote returns the value as double-quoted YAML scalar, so that it can not break the surrounding document.
+		"quote": quote,
+		// composeQuote is like quote but also escapes "$", which Docker Compose interpolates in double-quoted values.
+		"composeQuote": func(s string) (string, error) { return quote(strings.ReplaceAll(s, "$", "$$")) },
+	}
 )
 
+func quote(s string) (string, error) {
+	// JSON strings are valid double-quoted YAML scalars
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
 func Get(name string) ([]byte, error) {
 	return fs.ReadFile(fsys, name)
 }
@@ -23,7 +38,7 @@ func Get(name string) ([]byte, error) {
 func GetTemplate(name string) (*template.Template, error) {
 	if tmpl, ok := templates[name]; ok {
 		return tmpl, nil
-	} else if tmpl, err := template.ParseFS(fsys, name); err != nil {
+	} else if tmpl, err := template.New(path.Base(name)).Funcs(funcs).ParseFS(fsys, name); err != nil {
 		return nil, fmt.Errorf("failed to parse template %v: %w", name, err)
 	} else {
 		templates[name] = tmpl
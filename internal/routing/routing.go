@@ -108,6 +108,7 @@ func ApiRouter(
 	return func(r chiopenapi.Router) {
 		r.Use(
 			chimiddleware.RequestID,
+			middleware.PeerAddressMiddleware,
 			chimiddleware.RealIP,
 			middleware.Sentry,
 			middleware.LoggerCtxMiddleware(logger),
@@ -150,6 +151,9 @@ func ApiRouter(
 					r.Route("/applications", handlers.ApplicationsRouter)
 					r.Route("/artifact-licenses", handlers.ArtifactLicensesRouter)
 					r.Route("/artifact-pulls", handlers.ArtifactPullsRouter)
+					r.Route("/artifact-replication-targets", handlers.ArtifactReplicationTargetsRouter)
+					r.Route("/artifact-retention-policies", handlers.ArtifactRetentionPoliciesRouter)
+					r.Route("/artifact-signing-keys", handlers.ArtifactSigningKeysRouter)
 					r.Route("/artifacts", handlers.ArtifactsRouter)
 					r.Route("/billing", handlers.BillingRouter)
 					r.Route("/context", handlers.ContextRouter)
@@ -161,6 +165,8 @@ func ApiRouter(
 					r.Route("/files", handlers.FileRouter)
 					r.Route("/organization", handlers.OrganizationRouter)
 					r.Route("/organizations", handlers.OrganizationsRouter)
+					r.Route("/registry-mirrors", handlers.RegistryMirrorsRouter)
+					r.Route("/registry-webhooks", handlers.RegistryWebhooksRouter)
 					r.Route("/secrets", handlers.SecretsRouter)
 					r.Route("/settings", handlers.SettingsRouter)
 					r.Route("/tutorial-progress", handlers.TutorialsRouter)
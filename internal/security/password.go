@@ -58,6 +58,12 @@ func VerifyAccessKey(accessKeySalt []byte, accessKeyHash []byte, accessKeySecret
 	}
 }
 
+// AccessKeyHash derives the hash for the given access key secret and salt.
+// It is the same value that is stored in the DB when the access key is created.
+func AccessKeyHash(accessKeySecret string, accessKeySalt []byte) []byte {
+	return generateHash(accessKeySecret, accessKeySalt)
+}
+
 func generateHash(password string, salt []byte) []byte {
 	return argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
 }
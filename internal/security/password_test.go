@@ -30,3 +30,16 @@ func TestVerifyPassword(t *testing.T) {
 	g.Expect(security.VerifyPassword(u, pw)).NotTo(HaveOccurred())
 	g.Expect(security.VerifyPassword(u, "wrong")).To(MatchError(security.ErrInvalidPassword))
 }
+
+func TestEncrypt(t *testing.T) {
+	g := NewWithT(t)
+	key := make([]byte, 32)
+	encrypted, err := security.Encrypt(key, []byte("secret"), []byte("id"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(encrypted).NotTo(ContainSubstring("secret"))
+	g.Expect(security.Decrypt(key, encrypted, []byte("id"))).To(Equal([]byte("secret")))
+	_, err = security.Decrypt(key, encrypted, []byte("other id"))
+	g.Expect(err).To(HaveOccurred())
+	_, err = security.Decrypt(make([]byte, 16), encrypted, []byte("id"))
+	g.Expect(err).To(HaveOccurred())
+}
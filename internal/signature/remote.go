@@ -0,0 +1,100 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// VerifyImage checks that the image with the given reference has a signature that can be verified with one of the
+// trusted keys. Signatures are discovered with the referrers API and, for cosign, with the signature tag of the
+// digest that the reference resolves to.
+func VerifyImage(
+	ctx context.Context,
+	ref string,
+	client remote.Client,
+	plainHTTP bool,
+	trusted []crypto.PublicKey,
+) error {
+	parsedRef, err := registry.ParseReference(ref)
+	if err != nil {
+		return err
+	}
+	repo, err := remote.NewRepository(parsedRef.Registry + "/" + parsedRef.Repository)
+	if err != nil {
+		return err
+	}
+	repo.Client = client
+	repo.PlainHTTP = plainHTTP
+	if parsedRef.Reference == "" {
+		parsedRef.Reference = "latest"
+	}
+
+	subject, err := repo.Resolve(ctx, parsedRef.Reference)
+	if err != nil {
+		return fmt.Errorf("could not resolve %v: %w", ref, err)
+	}
+
+	type signatureManifest struct {
+		desc ocispec.Descriptor
+		tag  string
+	}
+	var manifests []signatureManifest
+	if err := repo.Referrers(ctx, subject, "", func(referrers []ocispec.Descriptor) error {
+		for _, desc := range referrers {
+			if desc.ArtifactType == CosignArtifactType || desc.ArtifactType == NotationArtifactType {
+				manifests = append(manifests, signatureManifest{desc: desc, tag: desc.Digest.String()})
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("could not get referrers of %v: %w", ref, err)
+	}
+	tag := CosignTag(subject.Digest)
+	if desc, err := repo.Resolve(ctx, tag); err == nil {
+		manifests = append(manifests, signatureManifest{desc: desc, tag: tag})
+	} else if !errors.Is(err, errdef.ErrNotFound) {
+		return fmt.Errorf("could not resolve cosign signature of %v: %w", ref, err)
+	}
+
+	fetch := func(ctx context.Context, d digest.Digest) ([]byte, error) {
+		if desc, err := repo.Blobs().Resolve(ctx, d.String()); err != nil {
+			return nil, err
+		} else if desc.Size > MaxLayerSize {
+			return nil, fmt.Errorf("blob %v is too large", d)
+		} else {
+			return content.FetchAll(ctx, repo.Blobs(), desc)
+		}
+	}
+
+	var sigs []types.ArtifactVersionSignature
+	for _, m := range manifests {
+		if m.desc.Size > MaxLayerSize {
+			continue
+		}
+		// signature manifests that can not be fetched or parsed are ignored, another one might still be valid
+		if data, err := content.FetchAll(ctx, repo, m.desc); err != nil {
+			continue
+		} else if extracted, err := Extract(ctx, data, m.tag, fetch); err == nil {
+			sigs = append(sigs, extracted...)
+		}
+	}
+
+	switch Status(sigs, types.Digest(subject.Digest), trusted) {
+	case types.ArtifactSignatureStatusVerified:
+		return nil
+	case types.ArtifactSignatureStatusUnverified:
+		return fmt.Errorf("%w: %v", ErrUntrusted, ref)
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsigned, ref)
+	}
+}
@@ -0,0 +1,388 @@
+// Package signature extracts cosign and Notation signatures from signature manifests and verifies them with trusted
+// public keys.
+//
+// Signatures are either attached to their subject as OCI referrers or, for cosign, pushed with a tag derived from the
+// digest of the subject ("sha256-<hex>.sig"). Signatures that come with a certificate chain (e.g. Notation or keyless
+// cosign) are only trusted if the public key of the signing certificate itself is trusted. Trusting the key of a CA
+// is not supported, because that would require checking the identity of the signer and a transparency log or
+// timestamp, e.g. every Sigstore user can obtain a certificate from the Fulcio CA. Validity periods of certificates are
+// not checked, because signatures are usually verified long after the signing certificate expired.
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/opencontainers/go-digest"
+)
+
+const (
+	CosignArtifactType            = "application/vnd.dev.cosign.artifact.sig.v1+json"
+	cosignSimpleSigningMediaType  = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureAnnotation     = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation   = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation         = "dev.sigstore.cosign/chain"
+	NotationArtifactType          = "application/vnd.cncf.notary.signature"
+	notationJWSEnvelopeMediaType  = "application/jose+json"
+	maxSignatureLayersPerManifest = 16
+	// MaxLayerSize is the maximum size of a signature layer. Fetchers should not read more than this from a blob.
+	MaxLayerSize = 1 << 20
+)
+
+var (
+	ErrDigestMismatch = errors.New("signed digest does not match the subject")
+	ErrUntrusted      = errors.New("signature could not be verified with any trusted key")
+	ErrUnsigned       = errors.New("no valid signature found")
+
+	cosignTagPattern = regexp.MustCompile(`^sha256-([0-9a-f]{64})\.sig$`)
+)
+
+// BlobFetcher returns the content of the blob with the given digest.
+type BlobFetcher func(ctx context.Context, digest digest.Digest) ([]byte, error)
+
+type signatureManifest struct {
+	ArtifactType string `json:"artifactType"`
+	Config       struct {
+		MediaType string `json:"mediaType"`
+	} `json:"config"`
+	Layers []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      digest.Digest     `json:"digest"`
+		Size        int64             `json:"size"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+	Subject *struct {
+		Digest digest.Digest `json:"digest"`
+	} `json:"subject"`
+}
+
+// CosignTag returns the tag that cosign uses for signatures of the given digest if the registry does not support the
+// referrers API.
+func CosignTag(subject digest.Digest) string {
+	return fmt.Sprintf("%v-%v.sig", subject.Algorithm(), subject.Encoded())
+}
+
+// Extract returns all signatures contained in the given manifest. If the manifest is not a signature manifest, an
+// empty result is returned. Layers in formats that are not supported, such as COSE envelopes, are ignored.
+func Extract(ctx context.Context, manifestData []byte, tag string, fetch BlobFetcher) (
+	[]types.ArtifactVersionSignature,
+	error,
+) {
+	var manifest signatureManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil
+	}
+
+	var subject types.Digest
+	if manifest.Subject != nil {
+		subject = types.Digest(manifest.Subject.Digest)
+	} else if match := cosignTagPattern.FindStringSubmatch(tag); match != nil {
+		subject = types.Digest("sha256:" + match[1])
+	} else {
+		return nil, nil
+	}
+
+	isNotation := manifest.ArtifactType == NotationArtifactType || manifest.Config.MediaType == NotationArtifactType
+	var result []types.ArtifactVersionSignature
+	for i, layer := range manifest.Layers {
+		if i >= maxSignatureLayersPerManifest {
+			break
+		}
+		var extract func([]byte, map[string]string) (*types.ArtifactVersionSignature, error)
+		switch {
+		case layer.MediaType == cosignSimpleSigningMediaType && layer.Annotations[cosignSignatureAnnotation] != "":
+			extract = extractCosign
+		case isNotation && layer.MediaType == notationJWSEnvelopeMediaType:
+			extract = extractNotationJWS
+		default:
+			continue
+		}
+		if err := layer.Digest.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid signature layer digest: %w", err)
+		} else if layer.Size > MaxLayerSize {
+			return nil, fmt.Errorf("signature layer %v is too large", layer.Digest)
+		}
+
+		data, err := fetch(ctx, layer.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch signature layer %v: %w", layer.Digest, err)
+		} else if layer.Digest.Algorithm().FromBytes(data) != layer.Digest {
+			return nil, fmt.Errorf("content of signature layer %v does not match its digest", layer.Digest)
+		}
+		sig, err := extract(data, layer.Annotations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature layer %v: %w", layer.Digest, err)
+		}
+		sig.SubjectDigest = subject
+		result = append(result, *sig)
+	}
+	return result, nil
+}
+
+func extractCosign(payload []byte, annotations map[string]string) (*types.ArtifactVersionSignature, error) {
+	var simpleSigning struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest types.Digest `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simpleSigning); err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(annotations[cosignSignatureAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature annotation: %w", err)
+	}
+	certificates, err := decodePEMCertificates(
+		annotations[cosignCertificateAnnotation] + "\n" + annotations[cosignChainAnnotation],
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &types.ArtifactVersionSignature{
+		Format:       types.ArtifactSignatureFormatCosign,
+		SignedDigest: simpleSigning.Critical.Image.DockerManifestDigest,
+		Payload:      payload,
+		Signature:    sig,
+		Certificates: certificates,
+	}, nil
+}
+
+func extractNotationJWS(data []byte, _ map[string]string) (*types.ArtifactVersionSignature, error) {
+	var envelope struct {
+		Payload   string `json:"payload"`
+		Protected string `json:"protected"`
+		Header    struct {
+			X5c []string `json:"x5c"`
+		} `json:"header"`
+		Signature string `json:"signature"`
+	}
+	var protected struct {
+		Alg string `json:"alg"`
+	}
+	var payload struct {
+		TargetArtifact struct {
+			Digest types.Digest `json:"digest"`
+		} `json:"targetArtifact"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	} else if protectedData, err := base64.RawURLEncoding.DecodeString(envelope.Protected); err != nil {
+		return nil, fmt.Errorf("invalid protected header: %w", err)
+	} else if err := json.Unmarshal(protectedData, &protected); err != nil {
+		return nil, fmt.Errorf("invalid protected header: %w", err)
+	} else if payloadData, err := base64.RawURLEncoding.DecodeString(envelope.Payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	} else if err := json.Unmarshal(payloadData, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	certificates := make([][]byte, len(envelope.Header.X5c))
+	for i, cert := range envelope.Header.X5c {
+		if certificates[i], err = base64.StdEncoding.DecodeString(cert); err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+	}
+	return &types.ArtifactVersionSignature{
+		Format:       types.ArtifactSignatureFormatNotation,
+		SignedDigest: payload.TargetArtifact.Digest,
+		// the JWS signing input is the encoded protected header and payload
+		Payload:      []byte(envelope.Protected + "." + envelope.Payload),
+		Signature:    sig,
+		Algorithm:    &protected.Alg,
+		Certificates: certificates,
+	}, nil
+}
+
+func decodePEMCertificates(data string) ([][]byte, error) {
+	var result [][]byte
+	rest := []byte(strings.TrimSpace(data))
+	for len(rest) > 0 {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return nil, errors.New("invalid PEM certificate")
+		} else if block.Type == "CERTIFICATE" {
+			result = append(result, block.Bytes)
+		}
+	}
+	return result, nil
+}
+
+// ParsePublicKey parses a PEM encoded public key or certificate.
+func ParsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		if cert, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, err
+		} else {
+			return cert.PublicKey, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %v", block.Type)
+	}
+}
+
+// ParsePublicKeys parses all given PEM encoded public keys or certificates.
+func ParsePublicKeys(data []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, len(data))
+	for i, d := range data {
+		if key, err := ParsePublicKey(d); err != nil {
+			return nil, err
+		} else {
+			keys[i] = key
+		}
+	}
+	return keys, nil
+}
+
+// TrustedKeys returns the public keys of the given signing keys. Keys that can not be parsed are skipped, because
+// they are validated when they are created.
+func TrustedKeys(keys []types.ArtifactSigningKey) []crypto.PublicKey {
+	result := make([]crypto.PublicKey, 0, len(keys))
+	for _, key := range keys {
+		if publicKey, err := ParsePublicKey(key.PublicKey); err == nil {
+			result = append(result, publicKey)
+		}
+	}
+	return result
+}
+
+// Verify checks that the signature was created for the given digest with one of the trusted keys.
+func Verify(sig types.ArtifactVersionSignature, subject types.Digest, trusted []crypto.PublicKey) error {
+	if sig.SubjectDigest != subject || sig.SignedDigest != subject {
+		return ErrDigestMismatch
+	}
+
+	if len(sig.Certificates) > 0 {
+		chain := make([]*x509.Certificate, len(sig.Certificates))
+		for i, der := range sig.Certificates {
+			if cert, err := x509.ParseCertificate(der); err != nil {
+				return fmt.Errorf("invalid certificate: %w", err)
+			} else {
+				chain[i] = cert
+			}
+		}
+		if !isTrustedLeaf(chain[0], trusted) {
+			return ErrUntrusted
+		}
+		return verifyWithKey(chain[0].PublicKey, sig)
+	}
+
+	for _, key := range trusted {
+		if err := verifyWithKey(key, sig); err == nil {
+			return nil
+		}
+	}
+	return ErrUntrusted
+}
+
+// Status returns the signature status of the given digest based on all signatures of this digest.
+func Status(
+	sigs []types.ArtifactVersionSignature,
+	subject types.Digest,
+	trusted []crypto.PublicKey,
+) types.ArtifactSignatureStatus {
+	var status types.ArtifactSignatureStatus
+	for _, sig := range sigs {
+		if sig.SubjectDigest != subject {
+			continue
+		} else if Verify(sig, subject, trusted) == nil {
+			return types.ArtifactSignatureStatusVerified
+		}
+		status = types.ArtifactSignatureStatusUnverified
+	}
+	return status
+}
+
+// isTrustedLeaf returns true if the public key of the signing certificate is trusted. The rest of the chain is
+// ignored on purpose, see the package documentation.
+func isTrustedLeaf(cert *x509.Certificate, trusted []crypto.PublicKey) bool {
+	k, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && slices.ContainsFunc(trusted, k.Equal)
+}
+
+func verifyWithKey(key crypto.PublicKey, sig types.ArtifactVersionSignature) error {
+	if sig.Algorithm == nil {
+		// cosign signs the SHA-256 hash of the payload, except for ed25519 keys
+		hash := crypto.SHA256.New()
+		hash.Write(sig.Payload)
+		sum := hash.Sum(nil)
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, sum, sig.Signature) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum, sig.Signature)
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, sig.Payload, sig.Signature) {
+				return nil
+			}
+		default:
+			return fmt.Errorf("unsupported key type %T", key)
+		}
+		return errors.New("invalid signature")
+	}
+
+	var hashFunc crypto.Hash
+	switch alg := *sig.Algorithm; strings.TrimLeft(alg, "EPS") {
+	case "256":
+		hashFunc = crypto.SHA256
+	case "384":
+		hashFunc = crypto.SHA384
+	case "512":
+		hashFunc = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm: %v", alg)
+	}
+	hash := hashFunc.New()
+	hash.Write(sig.Payload)
+	sum := hash.Sum(nil)
+
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(*sig.Algorithm, "ES") || len(sig.Signature)%2 != 0 {
+			return errors.New("invalid signature")
+		}
+		// JWS uses the concatenation of r and s instead of ASN.1
+		size := len(sig.Signature) / 2
+		r := new(big.Int).SetBytes(sig.Signature[:size])
+		s := new(big.Int).SetBytes(sig.Signature[size:])
+		if ecdsa.Verify(key, sum, r, s) {
+			return nil
+		}
+		return errors.New("invalid signature")
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(*sig.Algorithm, "PS") {
+			return errors.New("invalid signature")
+		}
+		return rsa.VerifyPSS(key, hashFunc, sum, sig.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+}
@@ -0,0 +1,166 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/go-digest"
+)
+
+const subject = types.Digest("sha256:0000000000000000000000000000000000000000000000000000000000000001")
+
+func blobs(data ...[]byte) (map[digest.Digest][]byte, BlobFetcher) {
+	m := map[digest.Digest][]byte{}
+	for _, d := range data {
+		m[digest.FromBytes(d)] = d
+	}
+	return m, func(ctx context.Context, d digest.Digest) ([]byte, error) {
+		if data, ok := m[d]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("blob %v not found", d)
+	}
+}
+
+func TestCosign(t *testing.T) {
+	g := NewWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	payload := fmt.Appendf(nil, `{"critical":{"image":{"docker-manifest-digest":%q}}}`, subject)
+	sum := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	g.Expect(err).NotTo(HaveOccurred())
+
+	manifest := fmt.Appendf(nil, `{"layers":[{"mediaType":%q,"digest":%q,"size":%v,"annotations":{%q:%q}}]}`,
+		cosignSimpleSigningMediaType, digest.FromBytes(payload), len(payload),
+		cosignSignatureAnnotation, base64.StdEncoding.EncodeToString(sig))
+	_, fetch := blobs(payload)
+
+	sigs, err := Extract(t.Context(), manifest, "latest", fetch)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sigs).To(BeEmpty())
+
+	sigs, err = Extract(t.Context(), manifest, CosignTag(digest.Digest(subject)), fetch)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sigs).To(HaveLen(1))
+	g.Expect(sigs[0].Format).To(Equal(types.ArtifactSignatureFormatCosign))
+	g.Expect(sigs[0].SubjectDigest).To(Equal(subject))
+
+	g.Expect(Verify(sigs[0], subject, []crypto.PublicKey{otherKey.Public(), key.Public()})).To(Succeed())
+	g.Expect(Verify(sigs[0], subject, []crypto.PublicKey{otherKey.Public()})).To(MatchError(ErrUntrusted))
+	g.Expect(Verify(sigs[0], "sha256:other", []crypto.PublicKey{key.Public()})).To(MatchError(ErrDigestMismatch))
+	g.Expect(Status(sigs, subject, []crypto.PublicKey{key.Public()})).To(Equal(types.ArtifactSignatureStatusVerified))
+	g.Expect(Status(sigs, subject, nil)).To(Equal(types.ArtifactSignatureStatusUnverified))
+	g.Expect(Status(sigs, "sha256:other", nil)).To(BeEmpty())
+}
+
+func TestNotationJWS(t *testing.T) {
+	g := NewWithT(t)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	caTemplate := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, &caTemplate, &caTemplate, caKey.Public(), caKey)
+	g.Expect(err).NotTo(HaveOccurred())
+	caCert, err := x509.ParseCertificate(caDER)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	leafTemplate := x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &leafTemplate, caCert, leafKey.Public(), caKey)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	protected := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`))
+	payload := base64.RawURLEncoding.EncodeToString(
+		fmt.Appendf(nil, `{"targetArtifact":{"digest":%q}}`, subject),
+	)
+	sum := sha256.Sum256([]byte(protected + "." + payload))
+	r, s, err := ecdsa.Sign(rand.Reader, leafKey, sum[:])
+	g.Expect(err).NotTo(HaveOccurred())
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	envelope, err := json.Marshal(map[string]any{
+		"protected": protected,
+		"payload":   payload,
+		"signature": base64.RawURLEncoding.EncodeToString(sig),
+		"header": map[string]any{
+			"x5c": []string{base64.StdEncoding.EncodeToString(leafDER), base64.StdEncoding.EncodeToString(caDER)},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	manifest := fmt.Appendf(nil, `{"artifactType":%q,"layers":[{"mediaType":%q,"digest":%q}],"subject":{"digest":%q}}`,
+		NotationArtifactType, notationJWSEnvelopeMediaType, digest.FromBytes(envelope), subject)
+	_, fetch := blobs(envelope)
+
+	sigs, err := Extract(t.Context(), manifest, digest.FromBytes(manifest).String(), fetch)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sigs).To(HaveLen(1))
+	g.Expect(sigs[0].Format).To(Equal(types.ArtifactSignatureFormatNotation))
+	g.Expect(sigs[0].Certificates).To(HaveLen(2))
+
+	// trusting the CA is not enough, as it might issue certificates to anyone
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	trustedCA, err := ParsePublicKeys([]string{caPEM})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(Verify(sigs[0], subject, trustedCA)).To(MatchError(ErrUntrusted))
+
+	leafPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}))
+	trusted, err := ParsePublicKeys([]string{leafPEM})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(Verify(sigs[0], subject, trusted)).To(Succeed())
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(Verify(sigs[0], subject, []crypto.PublicKey{otherKey.Public()})).To(MatchError(ErrUntrusted))
+
+	sigs[0].Signature[0] ^= 0xff
+	g.Expect(Verify(sigs[0], subject, trusted)).NotTo(Succeed())
+}
+
+func TestParsePublicKey(t *testing.T) {
+	g := NewWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	parsed, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(key.PublicKey.Equal(parsed)).To(BeTrue())
+
+	_, err = ParsePublicKey("not a key")
+	g.Expect(err).To(HaveOccurred())
+}
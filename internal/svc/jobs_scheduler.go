@@ -4,6 +4,8 @@ import (
 	"github.com/distr-sh/distr/internal/cleanup"
 	"github.com/distr-sh/distr/internal/env"
 	"github.com/distr-sh/distr/internal/jobs"
+	"github.com/distr-sh/distr/internal/registry/audit"
+	"github.com/distr-sh/distr/internal/registry/replication"
 )
 
 func (r *Registry) GetJobsScheduler() *jobs.Scheduler {
@@ -86,6 +88,20 @@ func (r *Registry) createJobsScheduler() (*jobs.Scheduler, error) {
 		}
 	}
 
+	if cron := env.CleanupDeploymentEventRecordCron(); cron != nil {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob(
+				"DeploymentEventRecordCleanup",
+				cleanup.RunDeploymentEventRecordCleanup,
+				env.CleanupDeploymentEventRecordTimeout(),
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if cron := env.CleanupOIDCStateCron(); cron != nil {
 		err = scheduler.RegisterCronJob(
 			*cron,
@@ -96,5 +112,45 @@ func (r *Registry) createJobsScheduler() (*jobs.Scheduler, error) {
 		}
 	}
 
+	if cron := env.RegistryGCCron(); cron != nil && env.RegistryEnabled() {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("RegistryGarbageCollection", cleanup.RunRegistryGarbageCollection, env.RegistryGCTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cron := env.ArtifactRetentionCron(); cron != nil && env.RegistryEnabled() {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("ArtifactRetention", cleanup.RunArtifactRetention, env.ArtifactRetentionTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cron := env.ArtifactReplicationCron(); cron != nil && env.RegistryEnabled() {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("ArtifactReplication", replication.RunJob, env.ArtifactReplicationTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if env.RegistryEnabled() {
+		err = scheduler.RegisterCronJob(
+			env.RegistryWebhookDeliveryCron(),
+			jobs.NewJob("RegistryWebhookDelivery", audit.RunWebhookDeliveryJob, env.RegistryWebhookDeliveryTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return scheduler, nil
 }
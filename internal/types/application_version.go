@@ -11,16 +11,17 @@ import (
 
 type ApplicationVersion struct {
 	// unfortunately Base nested type doesn't work when ApplicationVersion is a nested row in an SQL query
-	ID            uuid.UUID      `db:"id" json:"id"`
-	CreatedAt     time.Time      `db:"created_at" json:"createdAt"`
-	ArchivedAt    *time.Time     `db:"archived_at" json:"archivedAt,omitempty"`
-	Name          string         `db:"name" json:"name"`
-	LinkTemplate  string         `db:"link_template" json:"linkTemplate"`
-	ApplicationID uuid.UUID      `db:"application_id" json:"applicationId"`
-	ChartType     *HelmChartType `db:"chart_type" json:"chartType,omitempty"`
-	ChartName     *string        `db:"chart_name" json:"chartName,omitempty"`
-	ChartUrl      *string        `db:"chart_url" json:"chartUrl,omitempty"`
-	ChartVersion  *string        `db:"chart_version" json:"chartVersion,omitempty"`
+	ID             uuid.UUID       `db:"id" json:"id"`
+	CreatedAt      time.Time       `db:"created_at" json:"createdAt"`
+	ArchivedAt     *time.Time      `db:"archived_at" json:"archivedAt,omitempty"`
+	Name           string          `db:"name" json:"name"`
+	LinkTemplate   string          `db:"link_template" json:"linkTemplate"`
+	ApplicationID  uuid.UUID       `db:"application_id" json:"applicationId"`
+	ChartType      *HelmChartType  `db:"chart_type" json:"chartType,omitempty"`
+	ChartName      *string         `db:"chart_name" json:"chartName,omitempty"`
+	ChartUrl       *string         `db:"chart_url" json:"chartUrl,omitempty"`
+	ChartVersion   *string         `db:"chart_version" json:"chartVersion,omitempty"`
+	KubernetesType *KubernetesType `db:"kubernetes_type" json:"kubernetesType,omitempty"`
 
 	// awful but relevant: the following must be defined after the ChartType, because somehow order matters
 	// for pgx at collecting the subrows (relevant at getting application + list of its versions with these
@@ -29,6 +30,15 @@ type ApplicationVersion struct {
 	ValuesFileData   []byte `db:"values_file_data" json:"-"`
 	TemplateFileData []byte `db:"template_file_data" json:"-"`
 	ComposeFileData  []byte `db:"compose_file_data" json:"-"`
+	// ManifestFileData contains multi-document YAML for [KubernetesTypeManifests] or a gzipped tar archive of the
+	// kustomization directory for [KubernetesTypeKustomize].
+	ManifestFileData []byte `db:"manifest_file_data" json:"-"`
+}
+
+// IsHelm returns true if the application version is deployed as a Helm chart.
+// KubernetesType is nil for versions that were created before other kubernetes types were supported.
+func (av ApplicationVersion) IsHelm() bool {
+	return av.KubernetesType == nil || *av.KubernetesType == KubernetesTypeHelm
 }
 
 func (av ApplicationVersion) ParsedValuesFile() (result map[string]any, err error) {
@@ -64,18 +74,30 @@ func (av ApplicationVersion) Validate(deplType DeploymentType) error {
 		if av.ComposeFileData == nil {
 			return errors.New("missing compose file")
 		} else if av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil || av.ChartVersion != nil ||
-			av.ValuesFileData != nil {
+			av.KubernetesType != nil || av.ValuesFileData != nil || av.ManifestFileData != nil {
 			return errors.New("unexpected kubernetes specifics in docker application")
 		}
 	case DeploymentTypeKubernetes:
-		if av.ChartType == nil || *av.ChartType == "" ||
-			av.ChartUrl == nil || *av.ChartUrl == "" ||
-			av.ChartVersion == nil || *av.ChartVersion == "" {
-			return errors.New("not all of chart type, url and version are given")
-		} else if *av.ChartType == HelmChartTypeRepository && (av.ChartName == nil || *av.ChartName == "") {
-			return errors.New("missing chart name")
-		} else if av.ComposeFileData != nil {
+		if av.ComposeFileData != nil {
 			return errors.New("unexpected docker file in kubernetes application")
+		} else if av.IsHelm() {
+			if av.ChartType == nil || *av.ChartType == "" ||
+				av.ChartUrl == nil || *av.ChartUrl == "" ||
+				av.ChartVersion == nil || *av.ChartVersion == "" {
+				return errors.New("not all of chart type, url and version are given")
+			} else if *av.ChartType == HelmChartTypeRepository && (av.ChartName == nil || *av.ChartName == "") {
+				return errors.New("missing chart name")
+			} else if av.ManifestFileData != nil {
+				return errors.New("unexpected manifest file in helm application")
+			}
+		} else if *av.KubernetesType == KubernetesTypeManifests || *av.KubernetesType == KubernetesTypeKustomize {
+			if len(av.ManifestFileData) == 0 {
+				return errors.New("missing manifest file")
+			} else if av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil || av.ChartVersion != nil {
+				return fmt.Errorf("unexpected chart specifics in %v application", *av.KubernetesType)
+			}
+		} else {
+			return fmt.Errorf("invalid kubernetes type: %v", *av.KubernetesType)
 		}
 	}
 	return nil
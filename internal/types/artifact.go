@@ -48,7 +48,9 @@ type TaggedArtifactVersion struct {
 	DownloadedByCount int         `db:"downloaded_by_count" json:"downloadedByCount"`
 	DownloadedByUsers []uuid.UUID `db:"downloaded_by_users" json:"downloadedByUsers,omitempty"`
 
-	InferredType ManifestType `db:"-" json:"inferredType"`
+	InferredType    ManifestType                `db:"-" json:"inferredType"`
+	SignatureStatus ArtifactSignatureStatus     `db:"-" json:"signatureStatus,omitempty"`
+	Attachments     []ArtifactVersionAttachment `db:"-" json:"attachments,omitempty"`
 }
 
 type ArtifactWithDownloads struct {
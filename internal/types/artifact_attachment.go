@@ -0,0 +1,50 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ArtifactAttachmentType string
+
+const (
+	ArtifactAttachmentTypeSBOM                ArtifactAttachmentType = "sbom"
+	ArtifactAttachmentTypeVulnerabilityReport ArtifactAttachmentType = "vulnerability_report"
+)
+
+type ArtifactAttachmentFormat string
+
+const (
+	ArtifactAttachmentFormatSPDX      ArtifactAttachmentFormat = "spdx"
+	ArtifactAttachmentFormatCycloneDX ArtifactAttachmentFormat = "cyclonedx"
+	ArtifactAttachmentFormatSARIF     ArtifactAttachmentFormat = "sarif"
+)
+
+// ArtifactVersionAttachment is the summary of an SBOM or vulnerability report that was pushed as a referrer of an
+// artifact version. The full document is the blob with BlobDigest.
+type ArtifactVersionAttachment struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	// ArtifactVersionID is the ID of the version of the referrer manifest.
+	ArtifactVersionID uuid.UUID                `db:"artifact_version_id" json:"-"`
+	SubjectDigest     Digest                   `db:"subject_digest" json:"-"`
+	Type              ArtifactAttachmentType   `db:"type" json:"type"`
+	Format            ArtifactAttachmentFormat `db:"format" json:"format"`
+	MediaType         string                   `db:"media_type" json:"mediaType"`
+	BlobDigest        Digest                   `db:"blob_digest" json:"-"`
+	Size              int64                    `db:"size" json:"size"`
+	// PackageCount is nil for vulnerability reports.
+	PackageCount *int     `db:"package_count" json:"packageCount,omitempty"`
+	Licenses     []string `db:"licenses" json:"licenses,omitempty"`
+	// Vulnerabilities is nil if the document does not contain vulnerabilities.
+	Vulnerabilities *VulnerabilityCounts `db:"vulnerabilities" json:"vulnerabilities,omitempty"`
+}
+
+type VulnerabilityCounts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/distr-sh/distr/internal/outbound"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+type ArtifactEventType string
+
+const (
+	ArtifactEventTypePush         ArtifactEventType = "push"
+	ArtifactEventTypeTagOverwrite ArtifactEventType = "tag_overwrite"
+	ArtifactEventTypeDelete       ArtifactEventType = "delete"
+)
+
+// ArtifactEvent records a change of a reference in the registry, together with who made the change.
+//
+// DigestBefore is the digest the reference pointed to before the change and is nil for pushes of new references.
+// DigestAfter is nil for deletions.
+type ArtifactEvent struct {
+	ID               uuid.UUID         `db:"id" json:"id"`
+	CreatedAt        time.Time         `db:"created_at" json:"createdAt"`
+	OrganizationID   uuid.UUID         `db:"organization_id" json:"-"`
+	ArtifactID       *uuid.UUID        `db:"artifact_id" json:"artifactId,omitempty"`
+	ArtifactName     string            `db:"artifact_name" json:"artifactName"`
+	Type             ArtifactEventType `db:"type" json:"type"`
+	Reference        string            `db:"reference" json:"reference"`
+	DigestBefore     *string           `db:"digest_before" json:"digestBefore,omitempty"`
+	DigestAfter      *string           `db:"digest_after" json:"digestAfter,omitempty"`
+	UserAccountID    *uuid.UUID        `db:"useraccount_id" json:"userAccountId,omitempty"`
+	UserAccountEmail *string           `db:"useraccount_email" json:"userAccountEmail,omitempty"`
+	AccessTokenID    *uuid.UUID        `db:"access_token_id" json:"accessTokenId,omitempty"`
+	AccessTokenLabel *string           `db:"access_token_label" json:"accessTokenLabel,omitempty"`
+	RemoteAddress    *string           `db:"remote_address" json:"remoteAddress,omitempty"`
+}
+
+// RegistryWebhook is an endpoint of a vendor that artifact events are delivered to.
+// Deliveries are signed with the secret, which is only returned when the webhook is created.
+type RegistryWebhook struct {
+	ID             uuid.UUID `db:"id" json:"id"`
+	CreatedAt      time.Time `db:"created_at" json:"createdAt"`
+	OrganizationID uuid.UUID `db:"organization_id" json:"-"`
+	URL            string    `db:"url" json:"url"`
+	Secret         string    `db:"secret" json:"secret,omitempty"`
+}
+
+func (w RegistryWebhook) Validate() error {
+	if u, err := url.Parse(w.URL); err != nil || u.Host == "" {
+		return validation.NewValidationFailedError("url must be an absolute URL")
+	} else if u.Scheme != "https" && u.Scheme != "http" {
+		return validation.NewValidationFailedError("url must be an http or https URL")
+	} else if err := outbound.ValidateHost(u.Host); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid url: %v", err))
+	}
+	return nil
+}
+
+// RegistryWebhookDelivery is an artifact event that still has to be delivered to a registry webhook.
+type RegistryWebhookDelivery struct {
+	ID                uuid.UUID `db:"id"`
+	Attempts          int       `db:"attempts"`
+	RegistryWebhookID uuid.UUID `db:"registry_webhook_id"`
+	URL               string    `db:"url"`
+	Secret            string    `db:"secret"`
+	ArtifactEventID   uuid.UUID `db:"artifact_event_id"`
+}
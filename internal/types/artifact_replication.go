@@ -0,0 +1,76 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/outbound"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+	"oras.land/oras-go/v2/registry"
+)
+
+type ArtifactReplicationState string
+
+const (
+	ArtifactReplicationStatePending ArtifactReplicationState = "pending"
+	ArtifactReplicationStateSynced  ArtifactReplicationState = "synced"
+	ArtifactReplicationStateFailed  ArtifactReplicationState = "failed"
+)
+
+// ArtifactReplicationTarget is a registry owned by a customer organization to which the artifact versions that are
+// licensed to the customer are copied.
+type ArtifactReplicationTarget struct {
+	ID                     uuid.UUID `db:"id" json:"id"`
+	CreatedAt              time.Time `db:"created_at" json:"createdAt"`
+	OrganizationID         uuid.UUID `db:"organization_id" json:"-"`
+	CustomerOrganizationID uuid.UUID `db:"customer_organization_id" json:"customerOrganizationId"`
+	// RegistryURL is the registry and an optional repository path that artifact names are appended to, e.g.
+	// "harbor.example.com/acme".
+	RegistryURL      string     `db:"registry_url" json:"registryUrl"`
+	Username         *string    `db:"username" json:"username,omitempty"`
+	PasswordSecretID *uuid.UUID `db:"password_secret_id" json:"passwordSecretId,omitempty"`
+}
+
+func (t ArtifactReplicationTarget) Validate() error {
+	if strings.Contains(t.RegistryURL, "://") {
+		return validation.NewValidationFailedError("registryUrl must not contain a scheme")
+	} else if ref, err := t.RepositoryFor("artifact"); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid registryUrl: %v", err))
+	} else if ref.Reference != "" {
+		return validation.NewValidationFailedError("registryUrl must not contain a tag or digest")
+	} else if err := outbound.ValidateHost(ref.Registry); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid registryUrl: %v", err))
+	} else if (t.Username == nil) != (t.PasswordSecretID == nil) {
+		return validation.NewValidationFailedError("username and passwordSecretId must be set together")
+	}
+	return nil
+}
+
+// RepositoryFor returns the repository in the target registry that the artifact with the given name is copied to.
+func (t ArtifactReplicationTarget) RepositoryFor(artifactName string) (registry.Reference, error) {
+	return registry.ParseReference(strings.TrimSuffix(t.RegistryURL, "/") + "/" + artifactName)
+}
+
+// ArtifactReplicationStatus is the result of the last replication of an artifact version to a target.
+type ArtifactReplicationStatus struct {
+	ArtifactReplicationTargetID uuid.UUID  `db:"artifact_replication_target_id"`
+	ArtifactVersionID           uuid.UUID  `db:"artifact_version_id"`
+	UpdatedAt                   time.Time  `db:"updated_at"`
+	SyncedAt                    *time.Time `db:"synced_at"`
+	Tags                        []string   `db:"tags"`
+	Error                       *string    `db:"error"`
+}
+
+type ArtifactReplicationVersionStatus struct {
+	ArtifactID        uuid.UUID                `json:"artifactId"`
+	ArtifactName      string                   `json:"artifactName"`
+	ArtifactVersionID uuid.UUID                `json:"artifactVersionId"`
+	Digest            string                   `json:"digest"`
+	Tags              []string                 `json:"tags"`
+	State             ArtifactReplicationState `json:"state"`
+	Error             *string                  `json:"error,omitempty"`
+	SyncedAt          *time.Time               `json:"syncedAt,omitempty"`
+	UpdatedAt         *time.Time               `json:"updatedAt,omitempty"`
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+// ArtifactRetentionPolicy defines which tags and untagged manifests of an artifact are deleted automatically.
+// A policy without ArtifactID applies to all artifacts of the organization that have no policy of their own.
+type ArtifactRetentionPolicy struct {
+	ID             uuid.UUID  `db:"id" json:"id"`
+	CreatedAt      time.Time  `db:"created_at" json:"createdAt"`
+	OrganizationID uuid.UUID  `db:"organization_id" json:"-"`
+	ArtifactID     *uuid.UUID `db:"artifact_id" json:"artifactId,omitempty"`
+	// TagPattern is a regular expression that selects the tags KeepLastTags applies to. All tags are selected if it is
+	// not set.
+	TagPattern *string `db:"tag_pattern" json:"tagPattern,omitempty"`
+	// KeepLastTags is the number of most recently pushed tags matching TagPattern that are kept.
+	KeepLastTags *int `db:"keep_last_tags" json:"keepLastTags,omitempty"`
+	// UntaggedMaxAgeDays is the number of days after which manifests that are not tagged are deleted.
+	UntaggedMaxAgeDays *int `db:"untagged_max_age_days" json:"untaggedMaxAgeDays,omitempty"`
+}
+
+func (p ArtifactRetentionPolicy) Validate() error {
+	if p.KeepLastTags == nil && p.UntaggedMaxAgeDays == nil {
+		return validation.NewValidationFailedError("at least one of keepLastTags and untaggedMaxAgeDays is required")
+	} else if p.KeepLastTags != nil && *p.KeepLastTags < 1 {
+		return validation.NewValidationFailedError("keepLastTags must be at least 1")
+	} else if p.UntaggedMaxAgeDays != nil && *p.UntaggedMaxAgeDays < 1 {
+		return validation.NewValidationFailedError("untaggedMaxAgeDays must be at least 1")
+	} else if p.TagPattern != nil && p.KeepLastTags == nil {
+		return validation.NewValidationFailedError("tagPattern can only be used together with keepLastTags")
+	} else if _, err := p.CompileTagPattern(); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid tagPattern: %v", err))
+	}
+	return nil
+}
+
+// CompileTagPattern returns the compiled TagPattern or nil if the policy applies to all tags.
+func (p ArtifactRetentionPolicy) CompileTagPattern() (*regexp.Regexp, error) {
+	if p.TagPattern == nil || *p.TagPattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(*p.TagPattern)
+}
+
+// UntaggedMaxAge returns the age after which untagged manifests are deleted or 0 if they are kept.
+func (p ArtifactRetentionPolicy) UntaggedMaxAge() time.Duration {
+	if p.UntaggedMaxAgeDays == nil {
+		return 0
+	}
+	return time.Duration(*p.UntaggedMaxAgeDays) * 24 * time.Hour
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ArtifactSignatureFormat string
+
+const (
+	ArtifactSignatureFormatCosign   ArtifactSignatureFormat = "cosign"
+	ArtifactSignatureFormatNotation ArtifactSignatureFormat = "notation"
+)
+
+type ArtifactSignatureStatus string
+
+const (
+	// ArtifactSignatureStatusVerified means that at least one signature could be verified with a trusted key.
+	ArtifactSignatureStatusVerified ArtifactSignatureStatus = "verified"
+	// ArtifactSignatureStatusUnverified means that the version is signed, but no signature could be verified.
+	ArtifactSignatureStatusUnverified ArtifactSignatureStatus = "unverified"
+)
+
+// ArtifactVersionSignature is a signature that was extracted from a signature manifest (cosign or Notation) when it
+// was pushed. It is verified against the trusted keys of the organization whenever the signature status of its
+// subject is needed, so that keys can be added and removed later.
+type ArtifactVersionSignature struct {
+	ID        uuid.UUID `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	// ArtifactVersionID is the ID of the version of the signature manifest.
+	ArtifactVersionID uuid.UUID               `db:"artifact_version_id"`
+	SubjectDigest     Digest                  `db:"subject_digest"`
+	Format            ArtifactSignatureFormat `db:"format"`
+	// SignedDigest is the digest that is contained in the signed payload. It must match SubjectDigest.
+	SignedDigest Digest `db:"signed_digest"`
+	// Payload contains the bytes that were signed.
+	Payload   []byte `db:"payload"`
+	Signature []byte `db:"signature"`
+	// Algorithm is the JWS algorithm of Notation signatures.
+	Algorithm *string `db:"algorithm"`
+	// Certificates contains the DER encoded certificate chain of the signing key, leaf first, if there is one.
+	Certificates [][]byte `db:"certificates"`
+}
+
+type ArtifactSigningKey struct {
+	ID             uuid.UUID `db:"id" json:"id"`
+	CreatedAt      time.Time `db:"created_at" json:"createdAt"`
+	OrganizationID uuid.UUID `db:"organization_id" json:"-"`
+	Name           string    `db:"name" json:"name"`
+	// PublicKey is a PEM encoded public key or certificate.
+	PublicKey string `db:"public_key" json:"publicKey"`
+}
@@ -13,6 +13,9 @@ type Deployment struct {
 	ApplicationLicenseID *uuid.UUID  `db:"application_license_id" json:"applicationLicenseId,omitempty"`
 	DockerType           *DockerType `db:"docker_type" json:"dockerType,omitempty"`
 	LogsEnabled          bool        `db:"logs_enabled" json:"logsEnabled"`
+	// Namespace is the Kubernetes namespace of the deployment. If it is nil, the namespace of the deployment target
+	// is used.
+	Namespace *string `db:"namespace" json:"namespace,omitempty"`
 }
 
 type DeploymentWithLatestRevision struct {
@@ -30,6 +33,9 @@ type DeploymentWithLatestRevision struct {
 	LatestStatus                *DeploymentRevisionStatus `db:"latest_status" json:"latestStatus,omitempty"`
 	ForceRestart                bool                      `db:"force_restart" json:"forceRestart"`
 	IgnoreRevisionSkew          bool                      `db:"ignore_revision_skew" json:"ignoreRevisionSkew"`
+	RunHelmTests                bool                      `db:"run_helm_tests" json:"runHelmTests"`
+	PrePullImages               bool                      `db:"pre_pull_images" json:"prePullImages"`
+	RestoreVolumeSnapshotID     *uuid.UUID                `db:"restore_volume_snapshot_id" json:"restoreVolumeSnapshotId,omitempty"` //nolint:lll
 }
 
 func (d *DeploymentWithLatestRevision) GetValuesYAML() []byte {
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeploymentContainerMetrics struct {
+	ID                   uuid.UUID `db:"id"`
+	CreatedAt            time.Time `db:"created_at"`
+	DeploymentID         uuid.UUID `db:"deployment_id"`
+	DeploymentRevisionID uuid.UUID `db:"deployment_revision_id"`
+	Name                 string    `db:"name"`
+	CPUUsageMillis       int64     `db:"cpu_usage_millis"`
+	MemoryBytes          int64     `db:"memory_bytes"`
+	MemoryLimitBytes     *int64    `db:"memory_limit_bytes"`
+	RestartCount         int32     `db:"restart_count"`
+	DiskReadBytes        *int64    `db:"disk_read_bytes"`
+	DiskWriteBytes       *int64    `db:"disk_write_bytes"`
+	NetworkRxBytes       *int64    `db:"network_rx_bytes"`
+	NetworkTxBytes       *int64    `db:"network_tx_bytes"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeploymentEventRecord struct {
+	ID                   uuid.UUID `db:"id"`
+	CreatedAt            time.Time `db:"created_at"`
+	DeploymentID         uuid.UUID `db:"deployment_id"`
+	DeploymentRevisionID uuid.UUID `db:"deployment_revision_id"`
+	UID                  string    `db:"uid"`
+	Resource             string    `db:"resource"`
+	Type                 string    `db:"type"`
+	Reason               string    `db:"reason"`
+	Message              string    `db:"message"`
+	Count                int32     `db:"count"`
+	FirstTimestamp       time.Time `db:"first_timestamp"`
+	LastTimestamp        time.Time `db:"last_timestamp"`
+}
@@ -10,4 +10,8 @@ type DeploymentRevision struct {
 	EnvFileData          []byte    `db:"-" json:"-"`
 	ForceRestart         bool      `db:"force_restart" json:"forceRestart"`
 	IgnoreRevisionSkew   bool      `db:"ignore_revision_skew" json:"ignoreRevisionSkew"`
+	RunHelmTests         bool      `db:"run_helm_tests" json:"runHelmTests"`
+	PrePullImages        bool      `db:"pre_pull_images" json:"prePullImages"`
+	// RestoreVolumeSnapshotID is set if the revision was created to restore the volumes of the deployment
+	RestoreVolumeSnapshotID *uuid.UUID `db:"restore_volume_snapshot_id" json:"restoreVolumeSnapshotId,omitempty"`
 }
@@ -1,13 +1,23 @@
 package types
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
 
 	"github.com/distr-sh/distr/internal/validation"
 	"github.com/google/uuid"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+// noProxyEntryPattern matches a single entry of a NO_PROXY list, which is a host name, domain suffix, IP address or
+// CIDR range with an optional port, or "*".
+var noProxyEntryPattern = regexp.MustCompile(`^(\*|[A-Za-z0-9._\-:\[\]/]+)$`)
+
 type DeploymentTarget struct {
 	Base
 	Name                   string                     `db:"name" json:"name"`
@@ -23,6 +33,33 @@ type DeploymentTarget struct {
 	ReportedAgentVersionID *uuid.UUID                 `db:"reported_agent_version_id" json:"reportedAgentVersionId,omitempty"` //nolint:lll
 	MetricsEnabled         bool                       `db:"metrics_enabled" json:"metricsEnabled"`
 	Resources              *DeploymentTargetResources `db:"resources" json:"resources,omitempty"`
+	// HTTPProxy, HTTPSProxy, NoProxy and CACertificates are used by the agent for all connections, including the
+	// image pulls of the Docker agent.
+	HTTPProxy      *string `db:"http_proxy" json:"httpProxy,omitempty"`
+	HTTPSProxy     *string `db:"https_proxy" json:"httpsProxy,omitempty"`
+	NoProxy        *string `db:"no_proxy" json:"noProxy,omitempty"`
+	CACertificates *string `db:"ca_certificates" json:"caCertificates,omitempty"`
+	// DiskUsageWarningThreshold is the fraction of a disk that must be used to raise a low disk warning
+	DiskUsageWarningThreshold *float64 `db:"disk_usage_warning_threshold" json:"diskUsageWarningThreshold,omitempty"`
+	// PrunePolicy is only supported for Docker deployment targets
+	PrunePolicy *DockerPrunePolicy `db:"prune_policy" json:"prunePolicy,omitempty"`
+	// VolumeBackupPolicy is only supported for Docker deployment targets
+	VolumeBackupPolicy *DockerVolumeBackupPolicy `db:"volume_backup_policy" json:"volumeBackupPolicy,omitempty"`
+}
+
+// DockerPrunePolicy configures how the Docker agent cleans up images and containers after a deployment was upgraded.
+type DockerPrunePolicy struct {
+	// KeepRevisions is the number of revisions of each deployment whose images are kept, including the current one.
+	KeepRevisions          int  `json:"keepRevisions"`
+	PruneDanglingImages    bool `json:"pruneDanglingImages"`
+	PruneStoppedContainers bool `json:"pruneStoppedContainers"`
+}
+
+// DockerVolumeBackupPolicy configures snapshots of the named volumes of a Docker Compose deployment that the Docker
+// agent creates before a new revision is applied.
+type DockerVolumeBackupPolicy struct {
+	// KeepSnapshots is the number of snapshots that are kept for each deployment.
+	KeepSnapshots int `json:"keepSnapshots"`
 }
 
 type DeploymentTargetResources struct {
@@ -64,6 +101,85 @@ func (dt *DeploymentTarget) Validate() error {
 	default:
 		return validation.NewValidationFailedError("invalid deployment target type")
 	}
+	if err := dt.ValidateNetworkSettings(); err != nil {
+		return err
+	} else if err := dt.ValidateDiskUsageWarningThreshold(); err != nil {
+		return err
+	}
+	if err := dt.ValidatePrunePolicy(); err != nil {
+		return err
+	}
+	return dt.ValidateVolumeBackupPolicy()
+}
+
+func (dt *DeploymentTarget) ValidatePrunePolicy() error {
+	if dt.PrunePolicy == nil {
+		return nil
+	} else if dt.Type != DeploymentTypeDocker {
+		return validation.NewValidationFailedError("prune policy is only supported for Docker deployment targets")
+	} else if dt.PrunePolicy.KeepRevisions < 1 {
+		return validation.NewValidationFailedError("prune policy must keep the images of at least one revision")
+	}
+	return nil
+}
+
+func (dt *DeploymentTarget) ValidateVolumeBackupPolicy() error {
+	if dt.VolumeBackupPolicy == nil {
+		return nil
+	} else if dt.Type != DeploymentTypeDocker {
+		return validation.NewValidationFailedError("volume backup policy is only supported for Docker deployment targets")
+	} else if dt.VolumeBackupPolicy.KeepSnapshots < 1 {
+		return validation.NewValidationFailedError("volume backup policy must keep at least one snapshot")
+	}
+	return nil
+}
+
+func (dt *DeploymentTarget) ValidateDiskUsageWarningThreshold() error {
+	if dt.DiskUsageWarningThreshold != nil &&
+		(*dt.DiskUsageWarningThreshold <= 0 || *dt.DiskUsageWarningThreshold > 1) {
+		return validation.NewValidationFailedError("disk usage warning threshold must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// ValidateNetworkSettings checks the proxy and CA certificate settings of the deployment target.
+func (dt *DeploymentTarget) ValidateNetworkSettings() error {
+	if dt.HTTPProxy != nil {
+		if u, err := url.Parse(*dt.HTTPProxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return validation.NewValidationFailedError("HTTP proxy must be a valid URL")
+		}
+	}
+	if dt.HTTPSProxy != nil {
+		if u, err := url.Parse(*dt.HTTPSProxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return validation.NewValidationFailedError("HTTPS proxy must be a valid URL")
+		}
+	}
+	if dt.NoProxy != nil {
+		for entry := range strings.SplitSeq(*dt.NoProxy, ",") {
+			if !noProxyEntryPattern.MatchString(strings.TrimSpace(entry)) {
+				return validation.NewValidationFailedError(
+					"no proxy must be a comma separated list of host names, domains, IP addresses or CIDR ranges")
+			}
+		}
+	}
+	if dt.CACertificates != nil {
+		rest := []byte(*dt.CACertificates)
+		var count int
+		for {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			} else if block.Type != "CERTIFICATE" {
+				return validation.NewValidationFailedError("CA certificates must only contain certificates")
+			} else if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+				return validation.NewValidationFailedError(fmt.Sprintf("failed to parse CA certificate: %s", err))
+			}
+			count++
+		}
+		if count == 0 || len(bytes.TrimSpace(rest)) > 0 {
+			return validation.NewValidationFailedError("CA certificates must be PEM encoded")
+		}
+	}
 	return nil
 }
 
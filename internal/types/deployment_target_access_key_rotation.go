@@ -0,0 +1,20 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeploymentTargetAccessKeyRotation struct {
+	DeploymentTargetID         uuid.UUID  `db:"deployment_target_id"`
+	AccessKeyCreatedAt         time.Time  `db:"access_key_created_at"`
+	RequestedAt                *time.Time `db:"requested_at"`
+	PendingAccessKeySalt       *[]byte    `db:"pending_access_key_salt"`
+	PendingAccessKeyHash       *[]byte    `db:"pending_access_key_hash"`
+	PendingAccessKeyEncrypted  *[]byte    `db:"pending_access_key_encrypted"`
+	PendingAccessKeyIssuedAt   *time.Time `db:"pending_access_key_issued_at"`
+	PreviousAccessKeySalt      *[]byte    `db:"previous_access_key_salt"`
+	PreviousAccessKeyHash      *[]byte    `db:"previous_access_key_hash"`
+	PreviousAccessKeyExpiresAt *time.Time `db:"previous_access_key_expires_at"`
+}
@@ -0,0 +1,16 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeploymentVolumeSnapshot struct {
+	ID                   uuid.UUID `db:"id"`
+	CreatedAt            time.Time `db:"created_at"`
+	DeploymentID         uuid.UUID `db:"deployment_id"`
+	DeploymentRevisionID uuid.UUID `db:"deployment_revision_id"`
+	Volumes              []string  `db:"volumes"`
+	SizeBytes            int64     `db:"size_bytes"`
+}
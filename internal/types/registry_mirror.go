@@ -0,0 +1,57 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/outbound"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+	"oras.land/oras-go/v2/registry"
+)
+
+var registryMirrorPrefixPattern = regexp.MustCompile(
+	`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`,
+)
+
+// RegistryMirror configures an upstream registry from which artifacts whose name starts with Prefix are fetched if
+// they have not been pushed to the Distr registry.
+type RegistryMirror struct {
+	ID             uuid.UUID `db:"id" json:"id"`
+	CreatedAt      time.Time `db:"created_at" json:"createdAt"`
+	OrganizationID uuid.UUID `db:"organization_id" json:"-"`
+	// Prefix is the path within the organization that is mirrored, e.g. "ghcr" for "<org>/ghcr/...".
+	Prefix string `db:"prefix" json:"prefix"`
+	// UpstreamRepository is the upstream registry and repository path that Prefix maps to, e.g. "ghcr.io/acme".
+	UpstreamRepository string  `db:"upstream_repository" json:"upstreamRepository"`
+	Username           *string `db:"username" json:"username,omitempty"`
+	Password           *string `db:"password" json:"-"`
+}
+
+func (m RegistryMirror) Validate() error {
+	if !registryMirrorPrefixPattern.MatchString(m.Prefix) {
+		return validation.NewValidationFailedError("prefix must be a valid repository path")
+	} else if ref, err := registry.ParseReference(m.UpstreamRepository); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid upstreamRepository: %v", err))
+	} else if ref.Reference != "" {
+		return validation.NewValidationFailedError("upstreamRepository must not contain a tag or digest")
+	} else if err := outbound.ValidateHost(ref.Registry); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid upstreamRepository: %v", err))
+	} else if (m.Username == nil) != (m.Password == nil) {
+		return validation.NewValidationFailedError("username and password must be set together")
+	}
+	return nil
+}
+
+// UpstreamRepositoryFor returns the upstream repository for the given artifact name and whether the artifact name
+// is covered by this mirror.
+func (m RegistryMirror) UpstreamRepositoryFor(artifactName string) (string, bool) {
+	if artifactName == m.Prefix {
+		return m.UpstreamRepository, true
+	} else if rest, ok := strings.CutPrefix(artifactName, m.Prefix+"/"); ok {
+		return m.UpstreamRepository + "/" + rest, true
+	}
+	return "", false
+}
@@ -67,6 +67,9 @@ const (
 	FeatureLicensing              Feature = "licensing"
 	FeaturePrePostScripts         Feature = "pre_post_scripts"
 	FeatureArtifactVersionMutable Feature = "artifact_version_mutable"
+	// FeatureArtifactSignatureRequired denies pulls of unsigned artifact versions by customers and makes agents verify
+	// the signatures of images from the registry before deploying them.
+	FeatureArtifactSignatureRequired Feature = "artifact_signature_required"
 )
 
 type DeploymentStatusType string
@@ -110,6 +113,7 @@ func (ref *DeploymentStatusType) UnmarshalJSON(data []byte) error {
 type (
 	DeploymentType        string
 	HelmChartType         string
+	KubernetesType        string
 	DeploymentTargetScope string
 	DockerType            string
 	Tutorial              string
@@ -124,6 +128,10 @@ const (
 	HelmChartTypeRepository HelmChartType = "repository"
 	HelmChartTypeOCI        HelmChartType = "oci"
 
+	KubernetesTypeHelm      KubernetesType = "helm"
+	KubernetesTypeManifests KubernetesType = "manifests"
+	KubernetesTypeKustomize KubernetesType = "kustomize"
+
 	DockerTypeCompose DockerType = "compose"
 	DockerTypeSwarm   DockerType = "swarm"
 
@@ -1,9 +1,19 @@
 package types
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"math/big"
 	"testing"
 
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
 	. "github.com/onsi/gomega"
 )
 
@@ -25,3 +35,121 @@ func TestDeploymentStatusTypeParsing(t *testing.T) {
 	err = json.Unmarshal([]byte(`{"type": "does-not-exist"}`), &target)
 	g.Expect(err).To(MatchError(ErrInvalidDeploymentStatusType))
 }
+
+func TestDeploymentTargetValidateNetworkSettings(t *testing.T) {
+	g := NewWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	template := x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test"}}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, key.Public(), key)
+	g.Expect(err).NotTo(HaveOccurred())
+	certificate := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	dt := DeploymentTarget{HTTPSProxy: util.PtrTo("http://proxy:3128"), CACertificates: &certificate}
+	g.Expect(dt.ValidateNetworkSettings()).To(Succeed())
+
+	dt = DeploymentTarget{HTTPSProxy: util.PtrTo("proxy")}
+	g.Expect(dt.ValidateNetworkSettings()).NotTo(Succeed())
+
+	dt = DeploymentTarget{HTTPProxy: util.PtrTo("http://proxy:3128")}
+	g.Expect(dt.ValidateNetworkSettings()).To(Succeed())
+
+	dt = DeploymentTarget{HTTPProxy: util.PtrTo("proxy:3128")}
+	g.Expect(dt.ValidateNetworkSettings()).NotTo(Succeed())
+
+	dt = DeploymentTarget{NoProxy: util.PtrTo("localhost, .svc,10.0.0.0/8,[::1]:8080")}
+	g.Expect(dt.ValidateNetworkSettings()).To(Succeed())
+
+	dt = DeploymentTarget{NoProxy: util.PtrTo("localhost\"\nX: y")}
+	g.Expect(dt.ValidateNetworkSettings()).NotTo(Succeed())
+
+	dt = DeploymentTarget{NoProxy: util.PtrTo("localhost,,.svc")}
+	g.Expect(dt.ValidateNetworkSettings()).NotTo(Succeed())
+
+	dt = DeploymentTarget{CACertificates: util.PtrTo(certificate + "garbage")}
+	g.Expect(dt.ValidateNetworkSettings()).NotTo(Succeed())
+}
+
+func TestDeploymentTargetValidateDiskUsageWarningThreshold(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect((&DeploymentTarget{}).ValidateDiskUsageWarningThreshold()).To(Succeed())
+	g.Expect((&DeploymentTarget{DiskUsageWarningThreshold: util.PtrTo(0.85)}).ValidateDiskUsageWarningThreshold()).
+		To(Succeed())
+	g.Expect((&DeploymentTarget{DiskUsageWarningThreshold: util.PtrTo(0.0)}).ValidateDiskUsageWarningThreshold()).
+		NotTo(Succeed())
+	g.Expect((&DeploymentTarget{DiskUsageWarningThreshold: util.PtrTo(85.0)}).ValidateDiskUsageWarningThreshold()).
+		NotTo(Succeed())
+}
+
+func TestDeploymentTargetValidatePrunePolicy(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect((&DeploymentTarget{Type: DeploymentTypeDocker}).ValidatePrunePolicy()).To(Succeed())
+	g.Expect((&DeploymentTarget{Type: DeploymentTypeDocker, PrunePolicy: &DockerPrunePolicy{KeepRevisions: 2}}).
+		ValidatePrunePolicy()).To(Succeed())
+	g.Expect((&DeploymentTarget{Type: DeploymentTypeDocker, PrunePolicy: &DockerPrunePolicy{}}).
+		ValidatePrunePolicy()).NotTo(Succeed())
+	g.Expect((&DeploymentTarget{Type: DeploymentTypeKubernetes, PrunePolicy: &DockerPrunePolicy{KeepRevisions: 2}}).
+		ValidatePrunePolicy()).NotTo(Succeed())
+}
+
+func TestDeploymentTargetValidateVolumeBackupPolicy(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect((&DeploymentTarget{Type: DeploymentTypeDocker}).ValidateVolumeBackupPolicy()).To(Succeed())
+	g.Expect((&DeploymentTarget{
+		Type:               DeploymentTypeDocker,
+		VolumeBackupPolicy: &DockerVolumeBackupPolicy{KeepSnapshots: 3},
+	}).ValidateVolumeBackupPolicy()).To(Succeed())
+	g.Expect((&DeploymentTarget{
+		Type:               DeploymentTypeDocker,
+		VolumeBackupPolicy: &DockerVolumeBackupPolicy{},
+	}).ValidateVolumeBackupPolicy()).NotTo(Succeed())
+	g.Expect((&DeploymentTarget{
+		Type:               DeploymentTypeKubernetes,
+		VolumeBackupPolicy: &DockerVolumeBackupPolicy{KeepSnapshots: 3},
+	}).ValidateVolumeBackupPolicy()).NotTo(Succeed())
+}
+
+func TestArtifactRetentionPolicyValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ArtifactRetentionPolicy{}.Validate()).To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactRetentionPolicy{KeepLastTags: util.PtrTo(10)}.Validate()).To(Succeed())
+	g.Expect(ArtifactRetentionPolicy{UntaggedMaxAgeDays: util.PtrTo(7)}.Validate()).To(Succeed())
+	g.Expect(ArtifactRetentionPolicy{KeepLastTags: util.PtrTo(10), TagPattern: util.PtrTo(`^nightly-`)}.Validate()).
+		To(Succeed())
+	g.Expect(ArtifactRetentionPolicy{KeepLastTags: util.PtrTo(0)}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactRetentionPolicy{UntaggedMaxAgeDays: util.PtrTo(0)}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactRetentionPolicy{KeepLastTags: util.PtrTo(10), TagPattern: util.PtrTo(`(`)}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactRetentionPolicy{UntaggedMaxAgeDays: util.PtrTo(7), TagPattern: util.PtrTo(`.*`)}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+}
+
+func TestArtifactReplicationTargetValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ArtifactReplicationTarget{RegistryURL: "harbor.example.com/acme"}.Validate()).To(Succeed())
+	g.Expect(ArtifactReplicationTarget{RegistryURL: "123.dkr.ecr.eu-west-1.amazonaws.com"}.Validate()).To(Succeed())
+	g.Expect(ArtifactReplicationTarget{
+		RegistryURL:      "registry.example.com:5000/",
+		Username:         util.PtrTo("robot"),
+		PasswordSecretID: util.PtrTo(uuid.New()),
+	}.Validate()).To(Succeed())
+	g.Expect(ArtifactReplicationTarget{}.Validate()).To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactReplicationTarget{RegistryURL: "https://harbor.example.com"}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactReplicationTarget{RegistryURL: "harbor.example.com/acme:latest"}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactReplicationTarget{RegistryURL: "169.254.169.254/latest"}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+	g.Expect(ArtifactReplicationTarget{RegistryURL: "harbor.example.com", Username: util.PtrTo("robot")}.Validate()).
+		To(MatchError(validation.ErrValidationFailed))
+
+	ref, err := ArtifactReplicationTarget{RegistryURL: "harbor.example.com/acme/"}.RepositoryFor("app")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(ref.Registry).To(Equal("harbor.example.com"))
+	g.Expect(ref.Repository).To(Equal("acme/app"))
+}
@@ -18,6 +18,11 @@ export type ApplicationVersionFiles = {
   composeFile?: string;
   baseValuesFile?: string;
   templateFile?: string;
+  /**
+   * Multi-document YAML for 'manifests' versions or a gzipped tar archive of the kustomization for 'kustomize'
+   * versions.
+   */
+  manifestFile?: string | Blob;
 };
 
 /**
@@ -68,6 +73,14 @@ export class Client {
     if (files?.templateFile) {
       formData.append('templatefile', new Blob([files.templateFile], {type: 'application/yaml'}));
     }
+    if (files?.manifestFile) {
+      formData.append(
+        'manifestfile',
+        new Blob([files.manifestFile], {
+          type: version.kubernetesType === 'kustomize' ? 'application/gzip' : 'application/yaml',
+        })
+      );
+    }
     const path = `applications/${applicationId}/versions`;
     const response = await fetch(`${this.config.apiBase}${path}`, {
       method: 'POST',
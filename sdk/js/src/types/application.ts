@@ -1,5 +1,5 @@
 import {BaseModel, Named} from './base';
-import {DeploymentType, HelmChartType} from './deployment';
+import {DeploymentType, HelmChartType, KubernetesType} from './deployment';
 
 export interface Application extends BaseModel, Named {
   type: DeploymentType;
@@ -18,6 +18,7 @@ export interface ApplicationVersion {
   chartName?: string;
   chartUrl?: string;
   chartVersion?: string;
+  kubernetesType?: KubernetesType;
 }
 
 export interface PatchApplicationRequest {
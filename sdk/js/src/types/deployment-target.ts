@@ -15,6 +15,23 @@ export interface DeploymentTarget extends BaseModel, Named {
   reportedAgentVersionId?: string;
   metricsEnabled: boolean;
   resources?: DeploymentTargetResources;
+  httpProxy?: string;
+  httpsProxy?: string;
+  noProxy?: string;
+  caCertificates?: string;
+  diskUsageWarningThreshold?: number;
+  prunePolicy?: DockerPrunePolicy;
+  volumeBackupPolicy?: DockerVolumeBackupPolicy;
+}
+
+export interface DockerPrunePolicy {
+  keepRevisions: number;
+  pruneDanglingImages: boolean;
+  pruneStoppedContainers: boolean;
+}
+
+export interface DockerVolumeBackupPolicy {
+  keepSnapshots: number;
 }
 
 export interface DeploymentTargetResources {
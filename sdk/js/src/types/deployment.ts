@@ -5,6 +5,7 @@ export interface Deployment extends BaseModel {
   releaseName?: string;
   dockerType?: DockerType;
   logsEnabled: boolean;
+  namespace?: string;
 }
 
 export interface DeploymentRequest {
@@ -13,12 +14,15 @@ export interface DeploymentRequest {
   deploymentId?: string;
   applicationLicenseId?: string;
   releaseName?: string;
+  namespace?: string;
   dockerType?: DockerType;
   valuesYaml?: string;
   envFileData?: string;
   logsEnabled?: boolean;
   forceRestart?: boolean;
   ignoreRevisionSkew?: boolean;
+  runHelmTests?: boolean;
+  prePullImages?: boolean;
 }
 
 export interface PatchDeploymentRequest {
@@ -39,6 +43,15 @@ export interface DeploymentWithLatestRevision extends Deployment {
   latestStatus?: DeploymentRevisionStatus;
 }
 
+export interface DeploymentVolumeSnapshot {
+  id: string;
+  createdAt: string;
+  deploymentId: string;
+  deploymentRevisionId: string;
+  volumes: string[];
+  sizeBytes: number;
+}
+
 export interface DeploymentRevisionStatus extends BaseModel {
   type: DeploymentStatusType;
   message: string;
@@ -48,6 +61,8 @@ export type DeploymentType = 'docker' | 'kubernetes';
 
 export type HelmChartType = 'repository' | 'oci';
 
+export type KubernetesType = 'helm' | 'manifests' | 'kustomize';
+
 export type DockerType = 'compose' | 'swarm';
 
 export type DeploymentStatusType = 'healthy' | 'running' | 'progressing' | 'error';